@@ -13,6 +13,7 @@ import (
 	"victortillett.net/internal-inventory-tracker/internal/db"
 	"victortillett.net/internal-inventory-tracker/internal/server"
 	"victortillett.net/internal-inventory-tracker/internal/config"
+	"victortillett.net/internal-inventory-tracker/internal/services"
 )
 
 func main() {
@@ -34,6 +35,11 @@ func main() {
 		}
 	}()
 
+	// Raise recurring and maintenance tickets in the background until shutdown
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	go services.NewRecurringTicketScheduler(database).Run(schedulerCtx)
+	go services.NewMaintenanceTicketScheduler(database).Run(schedulerCtx)
+
 	// Graceful shutdown setup
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -41,6 +47,7 @@ func main() {
 	<-stop // Wait for interrupt signal
 
 	fmt.Println("\nShutting down server...")
+	stopScheduler()
 
 	// Allow active connections to finish
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
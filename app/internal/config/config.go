@@ -12,6 +12,8 @@ type Config struct {
 	SMTPFrom           string
 	SMTPUsername       string 
 	SMTPPassword       string 
+	UploadDir          string // where attachment files are stored
+	AppURL             string // public base URL of the API, used in email links
 }
 
 // LoadConfig loads environment variables into a Config struct
@@ -26,6 +28,8 @@ func LoadConfig() Config {
 		SMTPFrom:           getEnv("SMTP_FROM", "noreply@example.com"),
 		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
 		SMTPPassword:       getEnv("SMTP_PASSWORD", ""), 
+		UploadDir:          getEnv("UPLOAD_DIR", "./uploads"),
+		AppURL:             getEnv("APP_URL", "http://localhost:8081"),
 	}
 }
 
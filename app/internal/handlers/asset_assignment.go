@@ -4,26 +4,72 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	//"fmt"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
 	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
 )
 
 type AssetAssignmentHandler struct {
-	AssetsModel *models.AssetsModel
-	UsersModel  *models.UsersModel
+	AssetsModel         *models.AssetsModel
+	UsersModel          *models.UsersModel
+	CustodyModel        *models.CustodyModel
+	NotificationService *services.NotificationService
 }
 
 func NewAssetAssignmentHandler(db *sql.DB) *AssetAssignmentHandler {
 	return &AssetAssignmentHandler{
-		AssetsModel: models.NewAssetsModel(db),
-		UsersModel:  models.NewUsersModel(db),
+		AssetsModel:         models.NewAssetsModel(db),
+		UsersModel:          models.NewUsersModel(db),
+		CustodyModel:        models.NewCustodyModel(db),
+		NotificationService: services.NewNotificationService(db),
 	}
 }
 
+// assignWithCustody assigns an asset together with a pending handover and
+// asks the recipient to acknowledge it
+func (h *AssetAssignmentHandler) assignWithCustody(assetID, userID int64, assignedBy *int64, window time.Duration) (*models.Asset, *models.AssetCustody, error) {
+	custody := &models.AssetCustody{
+		AssetID:       assetID,
+		UserID:        userID,
+		AssignedBy:    assignedBy,
+		AcknowledgeBy: time.Now().Add(window),
+	}
+
+	if err := h.AssetsModel.AssignWithCustody(custody); err != nil {
+		return nil, nil, err
+	}
+
+	asset, err := h.AssetsModel.GetByID(assetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyCustodyPending(custody, asset); err != nil {
+			fmt.Printf("Failed to send custody notification: %v\n", err)
+		}
+	}()
+
+	return asset, custody, nil
+}
+
+// currentUserID returns the authenticated user's ID, or nil if it is missing
+func currentUserID(r *http.Request) *int64 {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		return nil
+	}
+	id := int64(userID)
+	return &id
+}
+
 // POST /api/v1/assets/{id}/assign
 func (h *AssetAssignmentHandler) AssignAsset(w http.ResponseWriter, r *http.Request) {
 	// Extract asset ID from URL
@@ -41,7 +87,8 @@ func (h *AssetAssignmentHandler) AssignAsset(w http.ResponseWriter, r *http.Requ
 	}
 	
 	var input struct {
-		UserID int64 `json:"user_id"`
+		UserID                 int64 `json:"user_id"`
+		AcknowledgeWithinHours int   `json:"acknowledge_within_hours"` // Optional, defaults to 48
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -49,8 +96,13 @@ func (h *AssetAssignmentHandler) AssignAsset(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	// Assign asset to user
-	err = h.AssetsModel.AssignAsset(assetID, input.UserID)
+	window := models.DefaultAcknowledgeWindow
+	if input.AcknowledgeWithinHours > 0 {
+		window = time.Duration(input.AcknowledgeWithinHours) * time.Hour
+	}
+	
+	// Assign asset to user; the recipient must acknowledge the handover
+	asset, custody, err := h.assignWithCustody(assetID, input.UserID, currentUserID(r), window)
 	if err != nil {
 		if err.Error() == "user not found" {
 			http.Error(w, "User not found", http.StatusBadRequest)
@@ -64,17 +116,11 @@ func (h *AssetAssignmentHandler) AssignAsset(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	// Get updated asset to return
-	asset, err := h.AssetsModel.GetByID(assetID)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Asset assigned successfully",
+		"message": "Asset assigned successfully, awaiting acknowledgement",
 		"asset":   asset,
+		"custody": custody,
 	})
 }
 
@@ -94,8 +140,29 @@ func (h *AssetAssignmentHandler) UnassignAsset(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	// Unassign asset
-	err = h.AssetsModel.UnassignAsset(assetID)
+	// Check-in details are optional so existing clients can keep posting an empty body
+	var input struct {
+		Condition string `json:"condition"`
+		Notes     string `json:"notes"`
+	}
+	
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	if input.Condition != "" && !models.IsValidCustodyCondition(input.Condition) {
+		http.Error(w, "Invalid condition. Must be one of: new, good, fair, poor, damaged", http.StatusBadRequest)
+		return
+	}
+	
+	// Unassign asset and close its handover; assets assigned before custody
+	// tracking have none
+	custodyID, err := h.AssetsModel.UnassignAsset(assetID, models.CustodyCheckIn{
+		ReceivedBy: currentUserID(r),
+		Condition:  input.Condition,
+		Notes:      input.Notes,
+	})
 	if err != nil {
 		if err.Error() == "asset not found" {
 			http.Error(w, "Asset not found", http.StatusNotFound)
@@ -105,6 +172,15 @@ func (h *AssetAssignmentHandler) UnassignAsset(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
+	var custody *models.AssetCustody
+	if custodyID != 0 {
+		custody, err = h.CustodyModel.GetByID(custodyID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	
 	// Get updated asset to return
 	asset, err := h.AssetsModel.GetByID(assetID)
 	if err != nil {
@@ -116,6 +192,7 @@ func (h *AssetAssignmentHandler) UnassignAsset(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Asset unassigned successfully",
 		"asset":   asset,
+		"custody": custody,
 	})
 }
 
@@ -153,11 +230,43 @@ func (h *AssetAssignmentHandler) GetUserAssets(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(assets)
 }
 
-// GET /api/v1/assets/available
+// GET /api/v1/assets/available?type=&from=&to=
 func (h *AssetAssignmentHandler) GetAvailableAssets(w http.ResponseWriter, r *http.Request) {
 	assetType := r.URL.Query().Get("type")
+	fromStr := r.URL.Query().Get("from")
+	toStr := r.URL.Query().Get("to")
+	
+	var assets []models.Asset
+	var err error
 	
-	assets, err := h.AssetsModel.GetAvailableAssets(assetType)
+	if fromStr != "" || toStr != "" {
+		// Loaner pool lookup: assets free for the whole window
+		if fromStr == "" || toStr == "" {
+			http.Error(w, "Both from and to are required for a date range", http.StatusBadRequest)
+			return
+		}
+		from, err := parseReservationTime(fromStr)
+		if err != nil {
+			http.Error(w, "From: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := parseReservationTime(toStr)
+		if err != nil {
+			http.Error(w, "To: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !to.After(from) {
+			http.Error(w, "to must be after from", http.StatusBadRequest)
+			return
+		}
+		assets, err = h.AssetsModel.GetAvailableBetween(assetType, from, to)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		assets, err = h.AssetsModel.GetAvailableAssets(assetType)
+	}
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
@@ -205,9 +314,11 @@ func (h *AssetAssignmentHandler) BulkAssignAssets(w http.ResponseWriter, r *http
 		Failed:  []failedAssignment{},
 	}
 	
+	assignedBy := currentUserID(r)
+	
 	// Assign each asset
 	for _, assetID := range input.AssetIDs {
-		err := h.AssetsModel.AssignAsset(assetID, input.UserID)
+		_, _, err := h.assignWithCustody(assetID, input.UserID, assignedBy, models.DefaultAcknowledgeWindow)
 		if err != nil {
 			results.Failed = append(results.Failed, failedAssignment{
 				AssetID: assetID,
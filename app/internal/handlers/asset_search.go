@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"victortillett.net/internal-inventory-tracker/internal/models"
@@ -27,6 +28,7 @@ func (h *AssetSearchHandler) SearchAssets(w http.ResponseWriter, r *http.Request
 	status := r.URL.Query().Get("status")
 	manufacturer := r.URL.Query().Get("manufacturer")
 	inUseByStr := r.URL.Query().Get("in_use_by")
+	location := r.URL.Query().Get("location")
 	
 	// Parse date filters
 	purchasedAfterStr := r.URL.Query().Get("purchased_after")
@@ -35,6 +37,7 @@ func (h *AssetSearchHandler) SearchAssets(w http.ResponseWriter, r *http.Request
 	// Parse service filters
 	needsServiceStr := r.URL.Query().Get("needs_service")
 	overdueServiceStr := r.URL.Query().Get("overdue_service")
+	includeDisposedStr := r.URL.Query().Get("include_disposed")
 	
 	// Parse pagination and sorting
 	limitStr := r.URL.Query().Get("limit")
@@ -47,6 +50,7 @@ func (h *AssetSearchHandler) SearchAssets(w http.ResponseWriter, r *http.Request
 		AssetType:    assetType,
 		Status:       status,
 		Manufacturer: manufacturer,
+		Location:     location,
 		SortBy:       sortBy,
 		SortOrder:    sortOrder,
 	}
@@ -80,6 +84,21 @@ func (h *AssetSearchHandler) SearchAssets(w http.ResponseWriter, r *http.Request
 		filters.OverdueService = true
 	}
 	
+	if includeDisposedStr == "true" {
+		filters.IncludeDisposed = true
+	}
+	
+	// Custom field filters: ?cf.<key>=value
+	customFilters := map[string]string{}
+	for param, values := range r.URL.Query() {
+		if key := strings.TrimPrefix(param, "cf."); key != param && len(values) > 0 && values[0] != "" {
+			customFilters[key] = values[0]
+		}
+	}
+	if len(customFilters) > 0 {
+		filters.CustomFields = customFilters
+	}
+	
 	// Parse pagination
 	if limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
@@ -108,6 +127,8 @@ func (h *AssetSearchHandler) SearchAssets(w http.ResponseWriter, r *http.Request
 			"status":       status,
 			"manufacturer": manufacturer,
 			"in_use_by":    inUseByStr,
+			"location":     location,
+			"custom_fields": customFilters,
 			"limit":        filters.Limit,
 			"offset":       filters.Offset,
 			"sort_by":      sortBy,
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,20 +11,49 @@ import (
 	"fmt"
 
 	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
 )
 
 type AssetServiceHandler struct {
-	ServiceModel *models.AssetServiceModel
-	AssetsModel  *models.AssetsModel
+	ServiceModel        *models.AssetServiceModel
+	AssetsModel         *models.AssetsModel
+	MaintenanceModel    *models.MaintenanceModel
+	StockModel          *models.StockModel
+	AttachmentModel     *models.AttachmentModel
+	NotificationService *services.NotificationService
+	FileStorage         services.FileStorage
 }
 
-func NewAssetServiceHandler(db *sql.DB) *AssetServiceHandler {
+func NewAssetServiceHandler(db *sql.DB, fileStorage services.FileStorage) *AssetServiceHandler {
 	return &AssetServiceHandler{
-		ServiceModel: models.NewAssetServiceModel(db),
-		AssetsModel:  models.NewAssetsModel(db),
+		ServiceModel:        models.NewAssetServiceModel(db),
+		AssetsModel:         models.NewAssetsModel(db),
+		MaintenanceModel:    models.NewMaintenanceModel(db),
+		StockModel:          models.NewStockModel(db),
+		AttachmentModel:     models.NewAttachmentModel(db),
+		NotificationService: services.NewNotificationService(db),
+		FileStorage:         fileStorage,
 	}
 }
 
+// servicePartInput is a part taken from stock for a service
+type servicePartInput struct {
+	ItemID   int64  `json:"item_id"`
+	Location string `json:"location"`
+	Quantity int    `json:"quantity"`
+}
+
+// validateServiceCosts checks the labor and cost fields shared by create and update
+func validateServiceCosts(laborMinutes int, externalCost *float64) string {
+	if laborMinutes < 0 {
+		return "labor_minutes cannot be negative"
+	}
+	if externalCost != nil && *externalCost < 0 {
+		return "external_cost cannot be negative"
+	}
+	return ""
+}
+
 // POST /api/v1/assets/{id}/service-logs
 func (h *AssetServiceHandler) CreateServiceLog(w http.ResponseWriter, r *http.Request) {
 	// Extract asset ID from URL
@@ -57,6 +87,10 @@ func (h *AssetServiceHandler) CreateServiceLog(w http.ResponseWriter, r *http.Re
 		ServiceType      string `json:"service_type"` // MAINTENANCE, REPAIR, UPGRADE
 		NextServiceDate  string `json:"next_service_date"`
 		Notes            string `json:"notes"`
+		LaborMinutes     int    `json:"labor_minutes"`
+		ExternalCost     *float64 `json:"external_cost"`
+		Technician       string `json:"technician"`
+		Parts            []servicePartInput `json:"parts"` // Taken out of stock with the log
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -69,6 +103,10 @@ func (h *AssetServiceHandler) CreateServiceLog(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Service type is required", http.StatusBadRequest)
 		return
 	}
+	if msg := validateServiceCosts(input.LaborMinutes, input.ExternalCost); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 	
 	// Parse dates
 	parseDate := func(dateStr string) (*time.Time, error) {
@@ -102,6 +140,16 @@ func (h *AssetServiceHandler) CreateServiceLog(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
+	// Without an explicit date, the asset's maintenance plans decide when it is next due
+	if nextServiceDate == nil {
+		nextServiceDate, err = h.MaintenanceModel.NextDueAfterService(assetID, input.ServiceType, performedAt)
+		if err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Warning: Failed to compute next service date from maintenance plans: %v\n", err)
+			nextServiceDate = nil
+		}
+	}
+	
 	// Create service log
 	serviceLog := &models.AssetServiceLog{
 		AssetID:         assetID,
@@ -110,14 +158,33 @@ func (h *AssetServiceHandler) CreateServiceLog(w http.ResponseWriter, r *http.Re
 		ServiceType:     input.ServiceType,
 		NextServiceDate: nextServiceDate,
 		Notes:           input.Notes,
+		LaborMinutes:    input.LaborMinutes,
+		ExternalCost:    input.ExternalCost,
+		Technician:      input.Technician,
+	}
+	for _, part := range input.Parts {
+		serviceLog.Parts = append(serviceLog.Parts, models.StockMovement{
+			ItemID:   part.ItemID,
+			Location: part.Location,
+			Quantity: part.Quantity,
+		})
 	}
 	
-	err = h.ServiceModel.Insert(serviceLog)
+	lowStock, err := h.ServiceModel.Insert(serviceLog)
 	if err != nil {
+		// Part errors are wrapped stock errors, e.g. "part 5: insufficient stock at this location"
+		if inner := errors.Unwrap(err); inner != nil {
+			http.Error(w, err.Error(), stockErrorStatus(inner))
+			return
+		}
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	
+	for _, itemID := range lowStock {
+		notifyLowStock(h.StockModel, h.NotificationService, itemID)
+	}
+	
 	// Update asset's service dates
 	err = h.ServiceModel.UpdateAssetServiceDate(assetID, performedAt, nextServiceDate)
 	if err != nil {
@@ -188,4 +255,130 @@ func (h *AssetServiceHandler) GetServiceLog(w http.ResponseWriter, r *http.Reque
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(log)
-}
\ No newline at end of file
+}
+
+// serviceLogIDFromPath extracts the log ID from /api/v1/service-logs/{id}
+func serviceLogIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/service-logs/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// PUT /api/v1/service-logs/{id}
+func (h *AssetServiceHandler) UpdateServiceLog(w http.ResponseWriter, r *http.Request) {
+	id, err := serviceLogIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid service log ID", http.StatusBadRequest)
+		return
+	}
+
+	serviceLog, err := h.ServiceModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "service log not found" {
+			http.Error(w, "Service log not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var input struct {
+		PerformedBy     *int64   `json:"performed_by"`
+		PerformedAt     *string  `json:"performed_at"`
+		ServiceType     *string  `json:"service_type"`
+		NextServiceDate *string  `json:"next_service_date"` // "" clears it
+		Notes           *string  `json:"notes"`
+		LaborMinutes    *int     `json:"labor_minutes"`
+		ExternalCost    *float64 `json:"external_cost"`
+		Technician      *string  `json:"technician"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.PerformedBy != nil {
+		serviceLog.PerformedBy = input.PerformedBy
+	}
+	if input.PerformedAt != nil {
+		performedAt, err := parseOptionalDate(*input.PerformedAt)
+		if err != nil || performedAt == nil {
+			http.Error(w, "PerformedAt: expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		serviceLog.PerformedAt = *performedAt
+	}
+	if input.ServiceType != nil {
+		if *input.ServiceType == "" {
+			http.Error(w, "Service type cannot be empty", http.StatusBadRequest)
+			return
+		}
+		serviceLog.ServiceType = *input.ServiceType
+	}
+	if input.NextServiceDate != nil {
+		serviceLog.NextServiceDate, err = parseOptionalDate(*input.NextServiceDate)
+		if err != nil {
+			http.Error(w, "NextServiceDate: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if input.Notes != nil {
+		serviceLog.Notes = *input.Notes
+	}
+	if input.LaborMinutes != nil {
+		serviceLog.LaborMinutes = *input.LaborMinutes
+	}
+	if input.ExternalCost != nil {
+		serviceLog.ExternalCost = input.ExternalCost
+	}
+	if input.Technician != nil {
+		serviceLog.Technician = *input.Technician
+	}
+
+	if msg := validateServiceCosts(serviceLog.LaborMinutes, serviceLog.ExternalCost); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ServiceModel.Update(serviceLog); err != nil {
+		if err.Error() == "service log not found" {
+			http.Error(w, "Service log not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceLog)
+}
+
+// DELETE /api/v1/service-logs/{id}
+// Parts used go back into stock and attached files are removed
+func (h *AssetServiceHandler) DeleteServiceLog(w http.ResponseWriter, r *http.Request) {
+	id, err := serviceLogIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid service log ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.AttachmentModel.GetByServiceLog(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.ServiceModel.Delete(id); err != nil {
+		if err.Error() == "service log not found" {
+			http.Error(w, "Service log not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The attachment rows went with the log; their files have to be removed here
+	removeAttachmentFiles(h.FileStorage, attachments)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +22,7 @@ func setupHandlerTest(t *testing.T) (*AssetsHandler, sqlmock.Sqlmock, func()) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 
-	handler := NewAssetsHandler(db)
+	handler := NewAssetsHandler(db, services.NewLocalFileStorage(t.TempDir()))
 	
 	teardown := func() {
 		db.Close()
@@ -3,34 +3,58 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 	"fmt"
 
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
 	"victortillett.net/internal-inventory-tracker/internal/models"
 	"victortillett.net/internal-inventory-tracker/internal/services"
 )
 
 type AssetsHandler struct {
 	Model *models.AssetsModel
+	CustomFieldModel *models.CustomFieldModel
+	AttachmentModel *models.AttachmentModel
 	NotificationService *services.NotificationService
+	FileStorage services.FileStorage
 }
 
-func NewAssetsHandler(db *sql.DB) *AssetsHandler {
+func NewAssetsHandler(db *sql.DB, fileStorage services.FileStorage) *AssetsHandler {
 	return &AssetsHandler{
 		Model: models.NewAssetsModel(db),
+		CustomFieldModel: models.NewCustomFieldModel(db),
+		AttachmentModel: models.NewAttachmentModel(db),
 		NotificationService: services.NewNotificationService(db),
+		FileStorage: fileStorage,
 	}
 }
 
+// validateCustomFields checks an asset's custom field values against its type's
+// schema, writing a 400 for bad values. Returns false if the request is done.
+func (h *AssetsHandler) validateCustomFields(w http.ResponseWriter, asset *models.Asset) bool {
+	if err := h.CustomFieldModel.ValidateForAsset(asset); err != nil {
+		if _, ok := err.(*models.CustomFieldError); ok {
+			http.Error(w, "Custom fields: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 // GET /api/v1/assets
 func (h *AssetsHandler) ListAssets(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters for filtering
 	assetType := r.URL.Query().Get("type")
 	status := r.URL.Query().Get("status")
 	inUseByStr := r.URL.Query().Get("in_use_by")
+	location := r.URL.Query().Get("location")
+	loanableStr := r.URL.Query().Get("loanable")
 	
 	var filters []models.AssetFilter
 	
@@ -45,6 +69,14 @@ func (h *AssetsHandler) ListAssets(w http.ResponseWriter, r *http.Request) {
 			filters = append(filters, models.AssetFilter{InUseBy: &inUseBy})
 		}
 	}
+	if location != "" {
+		filters = append(filters, models.AssetFilter{Location: location})
+	}
+	if loanableStr != "" {
+		if loanable, err := strconv.ParseBool(loanableStr); err == nil {
+			filters = append(filters, models.AssetFilter{Loanable: &loanable})
+		}
+	}
 	
 	assets, err := h.Model.GetAll(filters...)
 	if err != nil {
@@ -94,6 +126,10 @@ func (h *AssetsHandler) CreateAsset(w http.ResponseWriter, r *http.Request) {
 		DatePurchased   string  `json:"date_purchased"`    // Change to string
 		LastServiceDate string  `json:"last_service_date"` // Change to string
 		NextServiceDate string  `json:"next_service_date"` // Change to string
+		Location        string  `json:"location"`
+		Loanable        bool    `json:"loanable"`
+		PurchaseCost    *float64 `json:"purchase_cost"`
+		CustomFields    map[string]interface{} `json:"custom_fields"`
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -152,6 +188,10 @@ func (h *AssetsHandler) CreateAsset(w http.ResponseWriter, r *http.Request) {
 		DatePurchased:   datePurchased,
 		LastServiceDate: lastServiceDate,
 		NextServiceDate: nextServiceDate,
+		Location:        input.Location,
+		Loanable:        input.Loanable,
+		PurchaseCost:    input.PurchaseCost,
+		CustomFields:    input.CustomFields,
 	}
 	
 	// Set default status if not provided
@@ -159,6 +199,10 @@ func (h *AssetsHandler) CreateAsset(w http.ResponseWriter, r *http.Request) {
 		asset.Status = "IN_STORAGE"
 	}
 	
+	if !h.validateCustomFields(w, asset) {
+		return
+	}
+	
 	err = h.Model.Insert(asset)
 	if err != nil {
 		// Check for duplicate internal_id
@@ -204,6 +248,10 @@ func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 		DatePurchased   string  `json:"date_purchased"`
 		LastServiceDate string  `json:"last_service_date"`
 		NextServiceDate string  `json:"next_service_date"`
+		Location        string  `json:"location"`
+		Loanable        *bool   `json:"loanable"`
+		PurchaseCost    *float64 `json:"purchase_cost"`
+		CustomFields    map[string]interface{} `json:"custom_fields"` // Merged into existing values; null removes a key
 	}
 	
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -236,6 +284,8 @@ func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 		return nil, fmt.Errorf("invalid date format: %s, expected YYYY-MM-DD", dateStr)
 	}
 	
+	previousType := existingAsset.AssetType
+
 	// Update fields (only if provided in input)
 	if input.InternalID != "" {
 		existingAsset.InternalID = input.InternalID
@@ -256,11 +306,25 @@ func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 		existingAsset.SerialNumber = input.SerialNumber
 	}
 	if input.Status != "" {
+		// Retirement goes through the disposal workflow so it gets approved
+		if input.Status == "RETIRED" && existingAsset.Status != "RETIRED" {
+			http.Error(w, "Assets are retired through a disposal request: POST /api/v1/assets/{id}/disposal", http.StatusBadRequest)
+			return
+		}
 		existingAsset.Status = input.Status
 	}
 	if input.InUseBy != nil {
 		existingAsset.InUseBy = input.InUseBy
 	}
+	if input.Location != "" {
+		existingAsset.Location = input.Location
+	}
+	if input.Loanable != nil {
+		existingAsset.Loanable = *input.Loanable
+	}
+	if input.PurchaseCost != nil {
+		existingAsset.PurchaseCost = input.PurchaseCost
+	}
 	
 	// Handle date updates
 	if input.DatePurchased != "" {
@@ -290,6 +354,16 @@ func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 		existingAsset.NextServiceDate = nextServiceDate
 	}
 	
+	// Only the custom fields sent are validated; stored values stay as they are
+	if err := h.CustomFieldModel.ValidateChangesForAsset(existingAsset, input.CustomFields, existingAsset.AssetType != previousType); err != nil {
+		if _, ok := err.(*models.CustomFieldError); ok {
+			http.Error(w, "Custom fields: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
 	err = h.Model.Update(existingAsset)
 	if err != nil {
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
@@ -300,7 +374,8 @@ func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(existingAsset)
 }
 
-// DELETE /api/v1/assets/{id}
+// DELETE /api/v1/assets/{id} (soft delete, history is kept)
+// Optional body: {"reason": "..."}; recorded as a disposal that skipped approval
 func (h *AssetsHandler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -308,16 +383,46 @@ func (h *AssetsHandler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// The reason is optional so existing clients can keep sending no body
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		reason = "Deleted by admin"
+	}
 	
-	err = h.Model.Delete(id)
+	err = h.Model.Delete(id, int64(userID), reason)
 	if err != nil {
-		if err.Error() == "asset not found" {
+		switch err.Error() {
+		case "asset not found":
 			http.Error(w, "Asset not found", http.StatusNotFound)
-			return
+		case "a disposal request is already open for this asset", "asset is still on loan":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Database error", http.StatusInternalServerError)
 		}
-		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+
+	// The asset record is kept for history, but its photos and documents go
+	attachments, err := h.AttachmentModel.DeleteForAsset(id)
+	if err != nil {
+		fmt.Printf("Failed to delete attachments for asset %d: %v\n", id, err)
+	} else {
+		removeAttachmentFiles(h.FileStorage, attachments)
+	}
 	
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
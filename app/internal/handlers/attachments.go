@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+// maxAttachmentSize is the largest file accepted by the upload endpoints
+const maxAttachmentSize = 10 << 20 // 10 MB
+
+// allowedAttachmentTypes maps the sniffed content type of an upload to the
+// extension it is stored under. Anything else is rejected.
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+type AttachmentsHandler struct {
+	AttachmentModel *models.AttachmentModel
+	AssetsModel     *models.AssetsModel
+	ServiceModel    *models.AssetServiceModel
+	Storage         services.FileStorage
+}
+
+func NewAttachmentsHandler(db *sql.DB, storage services.FileStorage) *AttachmentsHandler {
+	return &AttachmentsHandler{
+		AttachmentModel: models.NewAttachmentModel(db),
+		AssetsModel:     models.NewAssetsModel(db),
+		ServiceModel:    models.NewAssetServiceModel(db),
+		Storage:         storage,
+	}
+}
+
+// attachmentIDFromPath extracts the attachment ID from /api/v1/files/{id}/...
+func attachmentIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/files/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// removeAttachmentFiles deletes the stored files of attachments whose
+// records are already gone. Failures are logged, not returned, since the
+// records cannot be brought back.
+func removeAttachmentFiles(storage services.FileStorage, attachments []models.Attachment) {
+	for _, a := range attachments {
+		if err := storage.Delete(a.StorageKey); err != nil {
+			fmt.Printf("Failed to remove attachment file %s: %v\n", a.StorageKey, err)
+		}
+		if a.ThumbnailKey != "" {
+			if err := storage.Delete(a.ThumbnailKey); err != nil {
+				fmt.Printf("Failed to remove thumbnail %s: %v\n", a.ThumbnailKey, err)
+			}
+		}
+	}
+}
+
+// newStorageKey builds a random, unguessable storage key under prefix
+func newStorageKey(prefix, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "/" + hex.EncodeToString(b) + ext, nil
+}
+
+// upload reads the "file" part of a multipart request, checks its size and
+// sniffed type, stores it (plus a thumbnail for images) under prefix and
+// records it against the asset or service log already set on attachment
+func (h *AttachmentsHandler) upload(w http.ResponseWriter, r *http.Request, attachment *models.Attachment, prefix string) {
+	// Leave headroom for the multipart envelope; the file itself is checked below
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "File too large (max 10 MB)", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "A file is required in the 'file' field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAttachmentSize {
+		http.Error(w, "File too large (max 10 MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Trust the bytes, not the client's Content-Type header
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err == io.EOF {
+		http.Error(w, "File is empty", http.StatusBadRequest)
+		return
+	} else if err != nil && err != io.ErrUnexpectedEOF {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+	head = head[:n]
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	ext, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		http.Error(w, "Unsupported file type: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key, err := newStorageKey(prefix, ext)
+	if err != nil {
+		http.Error(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	size, err := h.Storage.Save(key, io.MultiReader(bytes.NewReader(head), file))
+	if err != nil {
+		http.Error(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	// A thumbnail is a nicety: an image that fails to decode is still stored
+	thumbnailKey := ""
+	if services.ThumbnailableTypes[contentType] {
+		if _, err := file.Seek(0, io.SeekStart); err == nil {
+			thumb, err := services.MakeThumbnail(file)
+			if err != nil {
+				fmt.Printf("Failed to generate thumbnail for %s: %v\n", key, err)
+			} else {
+				thumbnailKey = strings.TrimSuffix(key, ext) + "_thumb.jpg"
+				if _, err := h.Storage.Save(thumbnailKey, bytes.NewReader(thumb)); err != nil {
+					fmt.Printf("Failed to store thumbnail for %s: %v\n", key, err)
+					thumbnailKey = ""
+				}
+			}
+		}
+	}
+
+	attachment.FileName = filepath.Base(header.Filename)
+	attachment.ContentType = contentType
+	attachment.SizeBytes = size
+	attachment.StorageKey = key
+	attachment.ThumbnailKey = thumbnailKey
+	attachment.UploadedBy = currentUserID(r)
+
+	if err := h.AttachmentModel.Insert(attachment); err != nil {
+		removeAttachmentFiles(h.Storage, []models.Attachment{*attachment})
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// POST /api/v1/assets/{id}/files (multipart, field "file")
+func (h *AttachmentsHandler) UploadAssetFile(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.AssetsModel.GetByID(assetID)
+	if err != nil {
+		if err.Error() == "asset not found" {
+			http.Error(w, "Asset not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if asset.DeletedAt != nil {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+
+	h.upload(w, r, &models.Attachment{AssetID: &assetID}, fmt.Sprintf("assets/%d", assetID))
+}
+
+// GET /api/v1/assets/{id}/files
+func (h *AttachmentsHandler) GetAssetFiles(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.AttachmentModel.GetByAsset(assetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// POST /api/v1/service-logs/{id}/files (multipart, field "file")
+func (h *AttachmentsHandler) UploadServiceLogFile(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/service-logs/")
+	logID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid service log ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.ServiceModel.GetByID(logID); err != nil {
+		if err.Error() == "service log not found" {
+			http.Error(w, "Service log not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	h.upload(w, r, &models.Attachment{ServiceLogID: &logID}, fmt.Sprintf("service-logs/%d", logID))
+}
+
+// GET /api/v1/service-logs/{id}/files
+func (h *AttachmentsHandler) GetServiceLogFiles(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/service-logs/")
+	logID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid service log ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.AttachmentModel.GetByServiceLog(logID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// serveFile streams a stored file with the given content type and disposition
+func (h *AttachmentsHandler) serveFile(w http.ResponseWriter, key, contentType, disposition, fileName string) {
+	f, err := h.Storage.Open(key)
+	if err != nil {
+		if err.Error() == "file not found" {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	io.Copy(w, f)
+}
+
+// GET /api/v1/files/{id}
+// Images and PDFs are shown inline, everything else is downloaded
+func (h *AttachmentsHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	id, err := attachmentIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.AttachmentModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") || attachment.ContentType == "application/pdf" {
+		disposition = "inline"
+	}
+
+	h.serveFile(w, attachment.StorageKey, attachment.ContentType, disposition, attachment.FileName)
+}
+
+// GET /api/v1/files/{id}/thumbnail
+func (h *AttachmentsHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	id, err := attachmentIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.AttachmentModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if attachment.ThumbnailKey == "" {
+		http.Error(w, "No thumbnail for this file", http.StatusNotFound)
+		return
+	}
+
+	h.serveFile(w, attachment.ThumbnailKey, "image/jpeg", "inline", "thumb_"+attachment.FileName+".jpg")
+}
+
+// DELETE /api/v1/files/{id}
+func (h *AttachmentsHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	id, err := attachmentIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.AttachmentModel.Delete(id)
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	removeAttachmentFiles(h.Storage, []models.Attachment{*attachment})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type CSATHandler struct {
+	CSATModel *models.CSATModel
+	JWTSecret string // Signs the survey links sent by email
+}
+
+func NewCSATHandler(db *sql.DB, jwtSecret string) *CSATHandler {
+	return &CSATHandler{
+		CSATModel: models.NewCSATModel(db),
+		JWTSecret: jwtSecret,
+	}
+}
+
+// csatErrorStatus maps model errors to HTTP status codes
+func csatErrorStatus(err error) int {
+	var csatErr *models.CSATError
+	if errors.As(err, &csatErr) {
+		return http.StatusBadRequest
+	}
+
+	switch err.Error() {
+	case "survey not found":
+		return http.StatusNotFound
+	case "invalid survey token":
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// csatResponseInput is the body of a survey response
+type csatResponseInput struct {
+	Token   string  `json:"token"` // Only for responses without a login
+	Rating  int     `json:"rating"`
+	Comment *string `json:"comment"`
+}
+
+// GET /api/v1/csat/pending
+// The current user's unanswered surveys
+func (h *CSATHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	surveys, err := h.CSATModel.GetPendingForUser(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(surveys)
+}
+
+// POST /api/v1/csat/{id}/respond
+// Body: {"rating": 5, "comment": "Fixed within the hour"}
+func (h *CSATHandler) Respond(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/csat/")
+	surveyID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+		return
+	}
+
+	var input csatResponseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.CSATModel.Respond(surveyID, userID, input.Rating, input.Comment)
+	if err != nil {
+		http.Error(w, err.Error(), csatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(survey)
+}
+
+// respondWithToken records a response for the survey a signed token names
+func (h *CSATHandler) respondWithToken(token string, rating int, comment *string) (*models.CSATSurvey, error) {
+	surveyID, err := services.VerifySurveyToken(token, h.JWTSecret)
+	if err != nil {
+		return nil, errors.New("invalid survey token")
+	}
+
+	survey, err := h.CSATModel.GetByID(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	return h.CSATModel.Respond(survey.ID, survey.UserID, rating, comment)
+}
+
+// GET /api/v1/csat/respond?token=...&rating=4
+// The link from the survey email; no login needed. It only shows a form
+// confirming the rating, since mail scanners fetch every link in a message:
+// the rating is recorded when the form is posted.
+func (h *CSATHandler) RespondFromLink(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	rating, err := strconv.Atoi(r.URL.Query().Get("rating"))
+	if err != nil || rating < 1 || rating > 5 {
+		csatPage(w, http.StatusBadRequest, "Invalid rating", "The rating in this link is not valid.")
+		return
+	}
+
+	surveyID, err := services.VerifySurveyToken(token, h.JWTSecret)
+	if err != nil {
+		csatPage(w, http.StatusForbidden, "We could not record your rating", "invalid survey token")
+		return
+	}
+	survey, err := h.CSATModel.GetByID(surveyID)
+	if err != nil {
+		csatPage(w, csatErrorStatus(err), "We could not record your rating", err.Error())
+		return
+	}
+
+	csatConfirmPage(w, survey, token, rating)
+}
+
+// POST /api/v1/csat/respond
+// Body: {"token": "...", "rating": 4, "comment": "..."}; no login needed.
+// Also takes the form from the email link's confirmation page.
+func (h *CSATHandler) RespondWithToken(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		h.respondFromForm(w, r)
+		return
+	}
+
+	var input csatResponseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.respondWithToken(input.Token, input.Rating, input.Comment)
+	if err != nil {
+		http.Error(w, err.Error(), csatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(survey)
+}
+
+// respondFromForm records a response posted from the confirmation page
+func (h *CSATHandler) respondFromForm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		csatPage(w, http.StatusBadRequest, "We could not record your rating", "The form could not be read.")
+		return
+	}
+	rating, err := strconv.Atoi(r.PostForm.Get("rating"))
+	if err != nil {
+		csatPage(w, http.StatusBadRequest, "Invalid rating", "Please choose a rating from 1 to 5.")
+		return
+	}
+	var comment *string
+	if c := strings.TrimSpace(r.PostForm.Get("comment")); c != "" {
+		comment = &c
+	}
+
+	survey, err := h.respondWithToken(r.PostForm.Get("token"), rating, comment)
+	if err != nil {
+		csatPage(w, csatErrorStatus(err), "We could not record your rating", err.Error())
+		return
+	}
+
+	csatPage(w, http.StatusOK, "Thank you for your feedback",
+		fmt.Sprintf("You rated ticket %s %d out of 5. You can change your rating or add a comment in the app.",
+			survey.TicketNum, rating))
+}
+
+// csatConfirmPage writes the form that confirms a rating picked in the
+// survey email
+func csatConfirmPage(w http.ResponseWriter, survey *models.CSATSurvey, token string, rating int) {
+	labels := []string{"Very poor", "Poor", "Okay", "Good", "Excellent"}
+	var options strings.Builder
+	for i, label := range labels {
+		checked := ""
+		if i+1 == rating {
+			checked = " checked"
+		}
+		fmt.Fprintf(&options, `        <label><input type="radio" name="rating" value="%d"%s> %d - %s</label><br>
+`, i+1, checked, i+1, label)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Rate ticket %s</title></head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; padding: 20px;">
+    <h2>How did we do on ticket %s?</h2>
+    <p>%s</p>
+    <form method="post" action="/api/v1/csat/respond">
+        <input type="hidden" name="token" value="%s">
+%s        <p><textarea name="comment" rows="4" cols="50" placeholder="Anything you'd like to add? (optional)"></textarea></p>
+        <button type="submit">Send rating</button>
+    </form>
+</body>
+</html>
+`, html.EscapeString(survey.TicketNum), html.EscapeString(survey.TicketNum), html.EscapeString(survey.TicketTitle),
+		html.EscapeString(token), options.String())
+}
+
+// csatPage writes the small HTML page shown after clicking an email link
+func csatPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>%s</title></head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; padding: 20px;">
+    <h2>%s</h2>
+    <p>%s</p>
+</body>
+</html>
+`, html.EscapeString(title), html.EscapeString(title), html.EscapeString(message))
+}
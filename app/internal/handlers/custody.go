@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type CustodyHandler struct {
+	CustodyModel        *models.CustodyModel
+	NotificationService *services.NotificationService
+}
+
+func NewCustodyHandler(db *sql.DB) *CustodyHandler {
+	return &CustodyHandler{
+		CustodyModel:        models.NewCustodyModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// GET /api/v1/assets/{id}/custody
+func (h *CustodyHandler) GetAssetCustody(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.CustodyModel.GetByAsset(assetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// GET /api/v1/custody/pending
+func (h *CustodyHandler) GetMyPendingHandovers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	records, err := h.CustodyModel.GetPendingForUser(int64(userID))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// POST /api/v1/custody/{id}/accept
+func (h *CustodyHandler) AcceptHandover(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/custody/")
+	id, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid custody ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Condition string `json:"condition"`
+		Notes     string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsValidCustodyCondition(input.Condition) {
+		http.Error(w, "Invalid condition. Must be one of: new, good, fair, poor, damaged", http.StatusBadRequest)
+		return
+	}
+
+	// Only the recipient can acknowledge a handover
+	custody, err := h.CustodyModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "custody record not found" {
+			http.Error(w, "Custody record not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if custody.UserID != int64(userID) {
+		http.Error(w, "Only the recipient can acknowledge this handover", http.StatusForbidden)
+		return
+	}
+
+	err = h.CustodyModel.Accept(id, int64(userID), input.Condition, input.Notes)
+	if err != nil {
+		if err.Error() == "custody record not found or not pending" {
+			http.Error(w, "Handover is not awaiting acknowledgement", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	custody, err = h.CustodyModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Handover acknowledged",
+		"custody": custody,
+	})
+}
+
+// GET /api/v1/custody/overdue
+func (h *CustodyHandler) GetOverdueHandovers(w http.ResponseWriter, r *http.Request) {
+	records, err := h.CustodyModel.GetOverdue()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// POST /api/v1/custody/overdue/remind
+func (h *CustodyHandler) RemindOverdueHandovers(w http.ResponseWriter, r *http.Request) {
+	records, err := h.CustodyModel.GetOverdue()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.NotificationService.NotifyCustodyOverdue(records); err != nil {
+		http.Error(w, "Failed to send reminders: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Reminders sent",
+		"overdue":   len(records),
+		"handovers": records,
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type CustomFieldsHandler struct {
+	CustomFieldModel *models.CustomFieldModel
+}
+
+func NewCustomFieldsHandler(db *sql.DB) *CustomFieldsHandler {
+	return &CustomFieldsHandler{
+		CustomFieldModel: models.NewCustomFieldModel(db),
+	}
+}
+
+// customFieldIDFromPath extracts the field ID from /api/v1/custom-fields/{id}
+func customFieldIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/custom-fields/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// GET /api/v1/custom-fields?asset_type=PC
+func (h *CustomFieldsHandler) ListCustomFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.CustomFieldModel.GetByAssetType(r.URL.Query().Get("asset_type"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields)
+}
+
+// POST /api/v1/custom-fields
+func (h *CustomFieldsHandler) CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	var field models.CustomField
+
+	if err := json.NewDecoder(r.Body).Decode(&field); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := field.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomFieldModel.Insert(&field); err != nil {
+		if err.Error() == "custom field key already exists for this asset type" {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(field)
+}
+
+// PUT /api/v1/custom-fields/{id}
+func (h *CustomFieldsHandler) UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	id, err := customFieldIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid custom field ID", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.CustomFieldModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "custom field not found" {
+			http.Error(w, "Custom field not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var input struct {
+		Label     string   `json:"label"`
+		FieldType string   `json:"field_type"`
+		Required  *bool    `json:"required"`
+		Unique    *bool    `json:"unique"`
+		Options   []string `json:"options"`
+		SortOrder *int     `json:"sort_order"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// asset_type and key are fixed: existing values are stored under them
+	if input.Label != "" {
+		existing.Label = input.Label
+	}
+	if input.FieldType != "" {
+		existing.FieldType = input.FieldType
+	}
+	if input.Required != nil {
+		existing.Required = *input.Required
+	}
+	if input.Unique != nil {
+		existing.Unique = *input.Unique
+	}
+	if input.Options != nil {
+		existing.Options = input.Options
+	}
+	if input.SortOrder != nil {
+		existing.SortOrder = *input.SortOrder
+	}
+
+	if err := existing.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomFieldModel.Update(existing); err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existing)
+}
+
+// DELETE /api/v1/custom-fields/{id}
+func (h *CustomFieldsHandler) DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	id, err := customFieldIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid custom field ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomFieldModel.Delete(id); err != nil {
+		if err.Error() == "custom field not found" {
+			http.Error(w, "Custom field not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
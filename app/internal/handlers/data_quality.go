@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type DataQualityHandler struct {
+	DataQualityModel *models.DataQualityModel
+}
+
+func NewDataQualityHandler(db *sql.DB) *DataQualityHandler {
+	return &DataQualityHandler{
+		DataQualityModel: models.NewDataQualityModel(db),
+	}
+}
+
+// dataQualityErrorStatus maps model errors to HTTP status codes
+func dataQualityErrorStatus(err error) int {
+	switch err.Error() {
+	case "unknown data-quality check", "this check has no automatic fix":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/v1/assets/data-quality?check=duplicate_serial&severity=error
+// Runs the data-quality checks over live assets. The summary lists every
+// check with its finding count, so a clean check shows up as zero.
+func (h *DataQualityHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	severity := q.Get("severity")
+	if severity != "" && severity != "error" && severity != "warning" {
+		http.Error(w, "severity must be error or warning", http.StatusBadRequest)
+		return
+	}
+
+	findings, err := h.DataQualityModel.GetFindings(q.Get("check"))
+	if err != nil {
+		http.Error(w, err.Error(), dataQualityErrorStatus(err))
+		return
+	}
+
+	type checkSummary struct {
+		models.DataQualityCheck
+		Findings int `json:"findings"`
+		Fixable  int `json:"fixable"`
+	}
+
+	summary := []checkSummary{}
+	byCheck := map[string]*checkSummary{}
+	for _, c := range models.DataQualityChecks() {
+		if (q.Get("check") != "" && c.Name != q.Get("check")) || (severity != "" && c.Severity != severity) {
+			continue
+		}
+		summary = append(summary, checkSummary{DataQualityCheck: c})
+	}
+	for i := range summary {
+		byCheck[summary[i].Name] = &summary[i]
+	}
+
+	filtered := []models.DataQualityFinding{}
+	for _, f := range findings {
+		s, ok := byCheck[f.Check]
+		if !ok {
+			continue
+		}
+		s.Findings++
+		if f.Fixable {
+			s.Fixable++
+		}
+		filtered = append(filtered, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":    len(filtered),
+		"checks":   summary,
+		"findings": filtered,
+	})
+}
+
+// POST /api/v1/assets/data-quality/fix
+// Applies the automatic fix for one check, either to the listed assets or to
+// every fixable finding when asset_ids is omitted. Only checks with a safe,
+// unambiguous correction have a fix.
+func (h *DataQualityHandler) ApplyFix(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Check    string  `json:"check"`
+		AssetIDs []int64 `json:"asset_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Check == "" {
+		http.Error(w, "check is required", http.StatusBadRequest)
+		return
+	}
+
+	fixed, err := h.DataQualityModel.Fix(input.Check, input.AssetIDs)
+	if err != nil {
+		http.Error(w, err.Error(), dataQualityErrorStatus(err))
+		return
+	}
+
+	// Report requested assets that were left alone so the caller can follow up
+	skipped := []int64{}
+	done := map[int64]bool{}
+	for _, id := range fixed {
+		done[id] = true
+	}
+	for _, id := range input.AssetIDs {
+		if !done[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"check":   input.Check,
+		"fixed":   fixed,
+		"skipped": skipped,
+	})
+}
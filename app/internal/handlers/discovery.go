@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+// maxDiscoverySnapshotSize caps an uploaded snapshot
+const maxDiscoverySnapshotSize = 20 << 20
+
+type DiscoveryHandler struct {
+	DiscoveryModel *models.DiscoveryModel
+}
+
+func NewDiscoveryHandler(db *sql.DB) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		DiscoveryModel: models.NewDiscoveryModel(db),
+	}
+}
+
+// discoveredDeviceIDFromPath extracts the device ID from /api/v1/discovery/devices/{id}/...
+func discoveredDeviceIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/discovery/devices/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// discoveryErrorStatus maps model errors to HTTP status codes
+func discoveryErrorStatus(err error) int {
+	switch err.Error() {
+	case "discovered device not found":
+		return http.StatusNotFound
+	case "discovered device or asset not found":
+		return http.StatusBadRequest
+	case "discovered device not found or linked to an asset":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// csvDiscoveryColumns maps the header names scanners commonly export onto
+// snapshot fields. Any other column is passed through as a custom field.
+var csvDiscoveryColumns = map[string]string{
+	"hostname": "hostname", "host": "hostname", "name": "hostname", "computer_name": "hostname",
+	"mac": "mac_address", "mac_address": "mac_address", "macaddress": "mac_address",
+	"serial": "serial_number", "serial_number": "serial_number", "serialnumber": "serial_number",
+	"os": "os", "operating_system": "os",
+	"ip": "ip_address", "ip_address": "ip_address", "ipaddress": "ip_address",
+	"last_seen": "last_seen", "lastseen": "last_seen",
+}
+
+// parseLastSeen accepts RFC 3339, "YYYY-MM-DD HH:MM:SS" or a plain date
+func parseLastSeen(value string) (*time.Time, error) {
+	for _, format := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(format, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid last_seen %q", value)
+}
+
+// parseDiscoveryCSV reads a scanner export with a header row
+func parseDiscoveryCSV(r io.Reader) ([]models.DiscoverySnapshot, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV must start with a header row")
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if mapped, ok := csvDiscoveryColumns[name]; ok {
+			columns[i] = mapped
+		} else {
+			columns[i] = name
+		}
+	}
+
+	snapshots := []models.DiscoverySnapshot{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		var s models.DiscoverySnapshot
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i >= len(columns) || value == "" {
+				continue
+			}
+			switch columns[i] {
+			case "hostname":
+				s.Hostname = value
+			case "mac_address":
+				s.MACAddress = value
+			case "serial_number":
+				s.SerialNumber = value
+			case "os":
+				s.OS = value
+			case "ip_address":
+				s.IPAddress = value
+			case "last_seen":
+				if s.LastSeen, err = parseLastSeen(value); err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+			default:
+				if s.Fields == nil {
+					s.Fields = map[string]interface{}{}
+				}
+				s.Fields[columns[i]] = value
+			}
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+// POST /api/v1/discovery/import?source=nmap
+// Accepts a JSON snapshot {"source": "...", "devices": [...]} from an endpoint
+// agent, or a CSV scanner export sent as text/csv.
+func (h *DiscoveryHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDiscoverySnapshotSize)
+
+	imp := &models.DiscoveryImport{
+		Source:     r.URL.Query().Get("source"),
+		ImportedBy: currentUserID(r),
+	}
+
+	var snapshots []models.DiscoverySnapshot
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		var err error
+		snapshots, err = parseDiscoveryCSV(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var input struct {
+			Source  string                     `json:"source"`
+			Devices []models.DiscoverySnapshot `json:"devices"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if input.Source != "" {
+			imp.Source = input.Source
+		}
+		snapshots = input.Devices
+	}
+
+	if len(snapshots) == 0 {
+		http.Error(w, "Snapshot contains no devices", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DiscoveryModel.Import(imp, snapshots); err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(imp)
+}
+
+// GET /api/v1/discovery/imports
+func (h *DiscoveryHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	imports, err := h.DiscoveryModel.GetImports(50)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imports)
+}
+
+// GET /api/v1/discovery/devices?status=candidate
+func (h *DiscoveryHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "matched" && status != "candidate" && status != "ignored" {
+		http.Error(w, "status must be matched, candidate or ignored", http.StatusBadRequest)
+		return
+	}
+
+	devices, err := h.DiscoveryModel.GetDevices(status)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(devices)
+}
+
+// GET /api/v1/discovery/devices/{id}
+func (h *DiscoveryHandler) GetDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := discoveredDeviceIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid device ID", http.StatusBadRequest)
+		return
+	}
+
+	device, err := h.DiscoveryModel.GetDevice(id)
+	if err != nil {
+		http.Error(w, err.Error(), discoveryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
+
+// POST /api/v1/discovery/devices/{id}/link
+// Links a candidate to an existing asset; later imports keep the link
+func (h *DiscoveryHandler) LinkDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := discoveredDeviceIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid device ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		AssetID int64 `json:"asset_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.AssetID <= 0 {
+		http.Error(w, "asset_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DiscoveryModel.Link(id, input.AssetID); err != nil {
+		http.Error(w, err.Error(), discoveryErrorStatus(err))
+		return
+	}
+
+	device, err := h.DiscoveryModel.GetDevice(id)
+	if err != nil {
+		http.Error(w, err.Error(), discoveryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
+
+// POST /api/v1/discovery/devices/{id}/ignore
+func (h *DiscoveryHandler) IgnoreDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := discoveredDeviceIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid device ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DiscoveryModel.Ignore(id); err != nil {
+		http.Error(w, err.Error(), discoveryErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/discovery/stale?days=30&asset_type=PC
+// Assets discovery has not seen in the last N days, or has never seen
+func (h *DiscoveryHandler) GetStale(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	days := 30
+	if daysStr := q.Get("days"); daysStr != "" {
+		n, err := strconv.Atoi(daysStr)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	assetType := q.Get("asset_type")
+	if assetType == "" {
+		assetType = "PC"
+	}
+
+	assets, err := h.DiscoveryModel.GetStale(assetType, days)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	neverSeen := 0
+	for _, a := range assets {
+		if a.LastSeen == nil {
+			neverSeen++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":       days,
+		"asset_type": assetType,
+		"count":      len(assets),
+		"never_seen": neverSeen,
+		"assets":     assets,
+	})
+}
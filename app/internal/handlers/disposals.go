@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type DisposalsHandler struct {
+	DisposalModel       *models.DisposalModel
+	NotificationService *services.NotificationService
+}
+
+func NewDisposalsHandler(db *sql.DB) *DisposalsHandler {
+	return &DisposalsHandler{
+		DisposalModel:       models.NewDisposalModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// disposalIDFromPath extracts the disposal ID from /api/v1/disposals/{id}/...
+func disposalIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/disposals/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// disposalErrorStatus maps model errors to HTTP status codes
+func disposalErrorStatus(err error) int {
+	switch err.Error() {
+	case "disposal not found", "asset not found":
+		return http.StatusNotFound
+	case "a disposal request is already open for this asset",
+		"disposal not found or not awaiting approval",
+		"disposal not found or already closed",
+		"disposal must be approved before it can be completed",
+		"data wipe must be confirmed before disposing of a PC",
+		"asset is still on loan":
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// POST /api/v1/assets/{id}/disposal
+func (h *DisposalsHandler) RequestDisposal(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(input.Reason) == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	requestedBy := int64(userID)
+	disposal := &models.AssetDisposal{
+		AssetID:     assetID,
+		Reason:      input.Reason,
+		RequestedBy: &requestedBy,
+	}
+
+	if err := h.DisposalModel.Insert(disposal); err != nil {
+		http.Error(w, err.Error(), disposalErrorStatus(err))
+		return
+	}
+
+	disposal, err = h.DisposalModel.GetByID(disposal.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyDisposalRequested(disposal); err != nil {
+			fmt.Printf("Failed to send disposal notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(disposal)
+}
+
+// GET /api/v1/disposals
+func (h *DisposalsHandler) ListDisposals(w http.ResponseWriter, r *http.Request) {
+	disposals, err := h.DisposalModel.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disposals)
+}
+
+// GET /api/v1/disposals/{id}
+func (h *DisposalsHandler) GetDisposal(w http.ResponseWriter, r *http.Request) {
+	id, err := disposalIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid disposal ID", http.StatusBadRequest)
+		return
+	}
+
+	disposal, err := h.DisposalModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), disposalErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disposal)
+}
+
+// POST /api/v1/disposals/{id}/approve
+func (h *DisposalsHandler) ApproveDisposal(w http.ResponseWriter, r *http.Request) {
+	h.reviewDisposal(w, r, true)
+}
+
+// POST /api/v1/disposals/{id}/reject
+func (h *DisposalsHandler) RejectDisposal(w http.ResponseWriter, r *http.Request) {
+	h.reviewDisposal(w, r, false)
+}
+
+func (h *DisposalsHandler) reviewDisposal(w http.ResponseWriter, r *http.Request, approved bool) {
+	id, err := disposalIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid disposal ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if approved {
+		err = h.DisposalModel.Approve(id, int64(userID), input.Notes)
+	} else {
+		err = h.DisposalModel.Reject(id, int64(userID), input.Notes)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), disposalErrorStatus(err))
+		return
+	}
+
+	disposal, err := h.DisposalModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyDisposalReviewed(disposal, approved); err != nil {
+			fmt.Printf("Failed to send disposal notification: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disposal)
+}
+
+// POST /api/v1/disposals/{id}/data-wipe
+func (h *DisposalsHandler) ConfirmDataWipe(w http.ResponseWriter, r *http.Request) {
+	id, err := disposalIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid disposal ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Method string `json:"method"` // e.g. "DBAN 3-pass", "drive shredded"
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(input.Method) == "" {
+		http.Error(w, "Wipe method is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DisposalModel.ConfirmDataWipe(id, int64(userID), input.Method); err != nil {
+		http.Error(w, err.Error(), disposalErrorStatus(err))
+		return
+	}
+
+	disposal, err := h.DisposalModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disposal)
+}
+
+// POST /api/v1/disposals/{id}/complete
+func (h *DisposalsHandler) CompleteDisposal(w http.ResponseWriter, r *http.Request) {
+	id, err := disposalIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid disposal ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		DisposalMethod string `json:"disposal_method"`
+		CertificateRef string `json:"certificate_ref"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsValidDisposalMethod(input.DisposalMethod) {
+		http.Error(w, "Invalid disposal method. Must be one of: recycle, donate, sell", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DisposalModel.Complete(id, int64(userID), input.DisposalMethod, input.CertificateRef); err != nil {
+		http.Error(w, err.Error(), disposalErrorStatus(err))
+		return
+	}
+
+	disposal, err := h.DisposalModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disposal)
+}
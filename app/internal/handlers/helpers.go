@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 )
 
 type envelope map[string]interface{}
@@ -24,3 +26,15 @@ func (a *ApplicationDependencies) writeJSON(w http.ResponseWriter, status int, d
 	w.Write(js)
 	return nil
 }
+
+// parseOptionalDate parses a YYYY-MM-DD date; an empty string gives nil
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %s, expected YYYY-MM-DD", value)
+	}
+	return &t, nil
+}
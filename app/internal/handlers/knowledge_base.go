@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type KnowledgeBaseHandler struct {
+	KBModel     *models.KnowledgeBaseModel
+	TicketModel *models.TicketModel
+}
+
+func NewKnowledgeBaseHandler(db *sql.DB) *KnowledgeBaseHandler {
+	return &KnowledgeBaseHandler{
+		KBModel:     models.NewKnowledgeBaseModel(db),
+		TicketModel: models.NewTicketModel(db),
+	}
+}
+
+// kbErrorStatus maps model errors to HTTP status codes
+func kbErrorStatus(err error) int {
+	var kbErr *models.KBError
+	if errors.As(err, &kbErr) {
+		return http.StatusBadRequest
+	}
+
+	switch err.Error() {
+	case "article not found", "category not found", "version not found", "ticket not found", "article link not found":
+		return http.StatusNotFound
+	case "category already exists":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// kbPathSegment returns segment n (from 0) of the path after prefix, e.g.
+// the article ID in /api/v1/kb/articles/{id}/versions
+func kbPathSegment(path, prefix string, n int) (int64, error) {
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if n >= len(parts) {
+		return 0, errors.New("invalid path")
+	}
+	return strconv.ParseInt(parts[n], 10, 64)
+}
+
+// kbFilters reads the reader's role and list filters from the request
+func kbFilters(r *http.Request, roleID int64) (models.KBFilters, error) {
+	filters := models.KBFilters{
+		RoleID:        roleID,
+		IncludeDrafts: models.CanManageKnowledgeBase(roleID),
+		TicketType:    r.URL.Query().Get("ticket_type"),
+		Limit:         20,
+	}
+
+	if categoryID := r.URL.Query().Get("category_id"); categoryID != "" {
+		id, err := strconv.ParseInt(categoryID, 10, 64)
+		if err != nil {
+			return filters, &models.KBError{Message: "invalid category_id"}
+		}
+		filters.CategoryID = &id
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		if limit > 100 {
+			limit = 100
+		}
+		filters.Limit = limit
+	}
+	if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && offset > 0 {
+		filters.Offset = offset
+	}
+	return filters, nil
+}
+
+// GET /api/v1/kb/categories
+func (h *KnowledgeBaseHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	_, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	categories, err := h.KBModel.GetCategories(roleID, models.CanManageKnowledgeBase(roleID))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// POST /api/v1/kb/categories
+// Body: {"name": "Email", "description": "Outlook and mailbox issues"}
+func (h *KnowledgeBaseHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.KBCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.KBModel.InsertCategory(&category); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// PUT /api/v1/kb/categories/{id}
+func (h *KnowledgeBaseHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/categories/", 0)
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var category models.KBCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	category.ID = id
+
+	if err := h.KBModel.UpdateCategory(&category); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DELETE /api/v1/kb/categories/{id}
+// Articles in the category become uncategorised
+func (h *KnowledgeBaseHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/categories/", 0)
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.KBModel.DeleteCategory(id); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/kb/articles?q=outlook&category_id=2&ticket_type=it_help&limit=20&offset=0
+// Lists articles, or searches them when q is given. Editors also see drafts.
+func (h *KnowledgeBaseHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
+	_, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	filters, err := kbFilters(r, roleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var articles []models.KBArticleSummary
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		articles, err = h.KBModel.SearchArticles(q, filters)
+	} else {
+		articles, err = h.KBModel.ListArticles(filters)
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(articles)
+}
+
+// GET /api/v1/kb/articles/suggest?title=Outlook+keeps+crashing&type=it_help
+// Published articles that may answer a ticket before it is raised
+func (h *KnowledgeBaseHandler) SuggestArticles(w http.ResponseWriter, r *http.Request) {
+	_, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	articles, err := h.KBModel.SuggestArticles(r.URL.Query().Get("title"), r.URL.Query().Get("type"), roleID, 5)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(articles)
+}
+
+// GET /api/v1/kb/articles/{id}
+func (h *KnowledgeBaseHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
+	_, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 0)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+
+	article, err := h.KBModel.GetArticle(id, roleID, models.CanManageKnowledgeBase(roleID))
+	if err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(article)
+}
+
+// kbArticleInput is the body of a create or update
+type kbArticleInput struct {
+	CategoryID  *int64 `json:"category_id"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	TicketType  string `json:"ticket_type"`
+	Visibility  string `json:"visibility"`
+	IsPublished bool   `json:"is_published"`
+}
+
+// POST /api/v1/kb/articles
+// Body: {"category_id": 2, "title": "Fixing Outlook crashes", "body": "## Steps\n...",
+//        "ticket_type": "it_help", "visibility": "everyone", "is_published": true}
+func (h *KnowledgeBaseHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input kbArticleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	article := &models.KBArticle{
+		CategoryID:  input.CategoryID,
+		Title:       input.Title,
+		Body:        input.Body,
+		TicketType:  input.TicketType,
+		Visibility:  input.Visibility,
+		IsPublished: input.IsPublished,
+		CreatedBy:   &userID,
+	}
+	if err := h.KBModel.InsertArticle(article); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(article)
+}
+
+// PUT /api/v1/kb/articles/{id}
+// A changed title or body is saved as a new version
+func (h *KnowledgeBaseHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 0)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+
+	var input kbArticleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	article := &models.KBArticle{
+		ID:          id,
+		CategoryID:  input.CategoryID,
+		Title:       input.Title,
+		Body:        input.Body,
+		TicketType:  input.TicketType,
+		Visibility:  input.Visibility,
+		IsPublished: input.IsPublished,
+	}
+	if err := h.KBModel.UpdateArticle(article, userID); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(article)
+}
+
+// DELETE /api/v1/kb/articles/{id}
+func (h *KnowledgeBaseHandler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 0)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.KBModel.DeleteArticle(id); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/kb/articles/{id}/versions
+func (h *KnowledgeBaseHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 0)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.KBModel.GetVersions(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// POST /api/v1/kb/articles/{id}/versions/{version}/restore
+// Saves the old version's title and body as a new version
+func (h *KnowledgeBaseHandler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 0)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+	version, err := kbPathSegment(r.URL.Path, "/api/v1/kb/articles/", 2)
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	article, err := h.KBModel.RestoreVersion(id, int(version), userID)
+	if err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(article)
+}
+
+// GET /api/v1/tickets/{id}/articles
+func (h *KnowledgeBaseHandler) GetTicketArticles(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	articles, err := h.KBModel.GetTicketArticles(ticketID, roleID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(articles)
+}
+
+// POST /api/v1/tickets/{id}/articles
+// Body: {"article_id": 4}
+func (h *KnowledgeBaseHandler) LinkTicketArticle(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	var input struct {
+		ArticleID int64 `json:"article_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Users can only link articles they can read
+	if _, err := h.KBModel.GetArticle(input.ArticleID, roleID, models.CanManageKnowledgeBase(roleID)); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	if err := h.KBModel.LinkTicket(input.ArticleID, ticketID, &userID); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	articles, err := h.KBModel.GetTicketArticles(ticketID, roleID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(articles)
+}
+
+// DELETE /api/v1/tickets/{id}/articles/{articleID}
+func (h *KnowledgeBaseHandler) UnlinkTicketArticle(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	articleID, err := kbPathSegment(r.URL.Path, "/api/v1/tickets/", 2)
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	// Users can only unlink articles they can read
+	if _, err := h.KBModel.GetArticle(articleID, roleID, models.CanManageKnowledgeBase(roleID)); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	if err := h.KBModel.UnlinkTicket(articleID, ticketID); err != nil {
+		http.Error(w, err.Error(), kbErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
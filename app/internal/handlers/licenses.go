@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type LicensesHandler struct {
+	LicenseModel        *models.LicenseModel
+	NotificationService *services.NotificationService
+}
+
+func NewLicensesHandler(db *sql.DB) *LicensesHandler {
+	return &LicensesHandler{
+		LicenseModel:        models.NewLicenseModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// defaultRenewalWindowDays is how far ahead renewals are reported when no
+// days parameter is given
+const defaultRenewalWindowDays = 30
+
+// licenseIDFromPath extracts the license ID from /api/v1/licenses/{id}/...
+func licenseIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/licenses/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// licenseErrorStatus maps model errors to HTTP status codes
+func licenseErrorStatus(err error) int {
+	switch err.Error() {
+	case "license not found", "asset not found", "user not found",
+		"allocation not found or already released":
+		return http.StatusNotFound
+	case "no free seats on this license",
+		"license is already allocated to this user or asset":
+		return http.StatusConflict
+	case "allocate to either a user or an asset",
+		"licenses can only be allocated to PC assets":
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// maskLicenseKeys hides license keys from anyone outside IT
+func maskLicenseKeys(r *http.Request, licenses ...*models.License) {
+	roleID, _ := r.Context().Value(middleware.ContextRoleID).(int)
+	if roleID == 1 || roleID == 2 {
+		return
+	}
+	for _, l := range licenses {
+		if l.LicenseKey != "" {
+			l.LicenseKey = "********"
+		}
+	}
+}
+
+// writeLicenses masks keys and encodes a license list
+func writeLicenses(w http.ResponseWriter, r *http.Request, licenses []models.License) {
+	for i := range licenses {
+		maskLicenseKeys(r, &licenses[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(licenses)
+}
+
+type licenseInput struct {
+	Product     *string `json:"product"`
+	Vendor      *string `json:"vendor"`
+	Seats       *int    `json:"seats"`
+	LicenseKey  *string `json:"license_key"`
+	Term        *string `json:"term"`
+	StartDate   *string `json:"start_date"`
+	RenewalDate *string `json:"renewal_date"`
+	Notes       *string `json:"notes"`
+}
+
+// apply copies the fields that were sent onto l
+func (in *licenseInput) apply(l *models.License) error {
+	if in.Product != nil {
+		l.Product = strings.TrimSpace(*in.Product)
+	}
+	if in.Vendor != nil {
+		l.Vendor = *in.Vendor
+	}
+	if in.Seats != nil {
+		l.Seats = *in.Seats
+	}
+	if in.LicenseKey != nil {
+		l.LicenseKey = *in.LicenseKey
+	}
+	if in.Term != nil {
+		l.Term = *in.Term
+	}
+	if in.StartDate != nil {
+		d, err := parseOptionalDate(*in.StartDate)
+		if err != nil {
+			return fmt.Errorf("start_date: %v", err)
+		}
+		l.StartDate = d
+	}
+	if in.RenewalDate != nil {
+		d, err := parseOptionalDate(*in.RenewalDate)
+		if err != nil {
+			return fmt.Errorf("renewal_date: %v", err)
+		}
+		l.RenewalDate = d
+	}
+	if in.Notes != nil {
+		l.Notes = *in.Notes
+	}
+
+	if l.Product == "" {
+		return errors.New("product is required")
+	}
+	if l.Seats < 0 {
+		return errors.New("seats cannot be negative")
+	}
+	if !models.IsValidLicenseTerm(l.Term) {
+		return errors.New("Invalid term. Must be one of: perpetual, monthly, annual")
+	}
+	return nil
+}
+
+// GET /api/v1/licenses?vendor=
+func (h *LicensesHandler) ListLicenses(w http.ResponseWriter, r *http.Request) {
+	licenses, err := h.LicenseModel.GetAll(r.URL.Query().Get("vendor"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLicenses(w, r, licenses)
+}
+
+// POST /api/v1/licenses
+func (h *LicensesHandler) CreateLicense(w http.ResponseWriter, r *http.Request) {
+	var input licenseInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	license := &models.License{Term: "annual"}
+	if err := input.apply(license); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.LicenseModel.Insert(license); err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(license)
+}
+
+// GET /api/v1/licenses/{id}
+func (h *LicensesHandler) GetLicense(w http.ResponseWriter, r *http.Request) {
+	id, err := licenseIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid license ID", http.StatusBadRequest)
+		return
+	}
+
+	license, err := h.LicenseModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	maskLicenseKeys(r, license)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(license)
+}
+
+// PUT /api/v1/licenses/{id}
+func (h *LicensesHandler) UpdateLicense(w http.ResponseWriter, r *http.Request) {
+	id, err := licenseIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid license ID", http.StatusBadRequest)
+		return
+	}
+
+	license, err := h.LicenseModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	var input licenseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := input.apply(license); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.LicenseModel.Update(license); err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	license.OverAllocated = license.SeatsUsed > license.Seats
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(license)
+}
+
+// DELETE /api/v1/licenses/{id}
+func (h *LicensesHandler) DeleteLicense(w http.ResponseWriter, r *http.Request) {
+	id, err := licenseIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid license ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.LicenseModel.Delete(id); err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/licenses/over-allocated
+func (h *LicensesHandler) GetOverAllocated(w http.ResponseWriter, r *http.Request) {
+	licenses, err := h.LicenseModel.GetOverAllocated()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLicenses(w, r, licenses)
+}
+
+// renewalWindow reads the days query parameter
+func renewalWindow(r *http.Request) (int, error) {
+	days := defaultRenewalWindowDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 {
+			return 0, errors.New("Invalid days parameter")
+		}
+		days = d
+	}
+	return days, nil
+}
+
+// GET /api/v1/licenses/renewals?days=30
+func (h *LicensesHandler) GetRenewals(w http.ResponseWriter, r *http.Request) {
+	days, err := renewalWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	licenses, err := h.LicenseModel.GetRenewalsDue(days)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLicenses(w, r, licenses)
+}
+
+// POST /api/v1/licenses/renewals/notify?days=30
+func (h *LicensesHandler) NotifyRenewals(w http.ResponseWriter, r *http.Request) {
+	days, err := renewalWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// One reminder per license per renewal date
+	licenses, err := h.LicenseModel.GetRenewalsToNotify(days)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.NotificationService.NotifyLicenseRenewals(licenses); err != nil {
+		http.Error(w, "Failed to send notifications: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, 0, len(licenses))
+	for _, l := range licenses {
+		ids = append(ids, l.ID)
+	}
+	if err := h.LicenseModel.MarkRenewalNotified(ids); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Renewal notifications sent",
+		"notified": len(licenses),
+	})
+}
+
+// GET /api/v1/licenses/{id}/allocations?include_released=true
+func (h *LicensesHandler) GetAllocations(w http.ResponseWriter, r *http.Request) {
+	id, err := licenseIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid license ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.LicenseModel.GetByID(id); err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	includeReleased := r.URL.Query().Get("include_released") == "true"
+	allocations, err := h.LicenseModel.GetAllocations(id, includeReleased)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(allocations)
+}
+
+// POST /api/v1/licenses/{id}/allocations
+func (h *LicensesHandler) AllocateSeat(w http.ResponseWriter, r *http.Request) {
+	id, err := licenseIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid license ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		UserID  *int64 `json:"user_id"`
+		AssetID *int64 `json:"asset_id"`
+		Force   bool   `json:"force"` // Allocate even when all seats are taken
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allocation := &models.LicenseAllocation{
+		LicenseID:   id,
+		UserID:      input.UserID,
+		AssetID:     input.AssetID,
+		AllocatedBy: currentUserID(r),
+	}
+
+	if err := h.LicenseModel.Allocate(allocation, input.Force); err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(allocation)
+}
+
+// POST /api/v1/license-allocations/{id}/release
+func (h *LicensesHandler) ReleaseSeat(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/license-allocations/")
+	id, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid allocation ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Reason == "" {
+		input.Reason = "released"
+	}
+
+	if err := h.LicenseModel.Release(id, input.Reason); err != nil {
+		http.Error(w, err.Error(), licenseErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Seat released",
+	})
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type MaintenanceHandler struct {
+	MaintenanceModel    *models.MaintenanceModel
+	TicketScheduler     *services.MaintenanceTicketScheduler
+	NotificationService *services.NotificationService
+}
+
+func NewMaintenanceHandler(db *sql.DB) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		MaintenanceModel:    models.NewMaintenanceModel(db),
+		TicketScheduler:     services.NewMaintenanceTicketScheduler(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// maintenancePlanIDFromPath extracts the plan ID from /api/v1/maintenance/plans/{id}
+func maintenancePlanIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/maintenance/plans/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// maintenanceErrorStatus maps model errors to HTTP status codes
+func maintenanceErrorStatus(err error) int {
+	switch err.Error() {
+	case "maintenance plan not found":
+		return http.StatusNotFound
+	case "a plan applies to either an asset_type or an asset_id",
+		"asset or assignee not found":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// validateMaintenancePlan checks the schedule settings shared by create and update
+func validateMaintenancePlan(p *models.MaintenancePlan) string {
+	if strings.TrimSpace(p.Name) == "" {
+		return "Name is required"
+	}
+	if p.IntervalDays <= 0 {
+		return "interval_days must be positive"
+	}
+	if p.LeadDays < 0 {
+		return "lead_days cannot be negative"
+	}
+	if p.LeadDays >= p.IntervalDays {
+		return "lead_days must be shorter than interval_days"
+	}
+	return ""
+}
+
+// GET /api/v1/maintenance/plans?asset_type=UPS&asset_id=12
+func (h *MaintenanceHandler) ListPlans(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var assetID *int64
+	if idStr := q.Get("asset_id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid asset_id", http.StatusBadRequest)
+			return
+		}
+		assetID = &id
+	}
+
+	plans, err := h.MaintenanceModel.GetPlans(q.Get("asset_type"), assetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+// POST /api/v1/maintenance/plans
+func (h *MaintenanceHandler) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name         string  `json:"name"`
+		AssetType    *string `json:"asset_type"`
+		AssetID      *int64  `json:"asset_id"`
+		ServiceType  string  `json:"service_type"`
+		IntervalDays int     `json:"interval_days"`
+		LeadDays     *int    `json:"lead_days"`
+		AssignTo     *int64  `json:"assign_to"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.AssetType != nil && strings.TrimSpace(*input.AssetType) == "" {
+		input.AssetType = nil
+	}
+
+	plan := &models.MaintenancePlan{
+		Name:         input.Name,
+		AssetType:    input.AssetType,
+		AssetID:      input.AssetID,
+		ServiceType:  strings.ToUpper(strings.TrimSpace(input.ServiceType)),
+		IntervalDays: input.IntervalDays,
+		LeadDays:     7,
+		AssignTo:     input.AssignTo,
+		Active:       true,
+		CreatedBy:    currentUserID(r),
+	}
+	if plan.ServiceType == "" {
+		plan.ServiceType = "MAINTENANCE"
+	}
+	if input.LeadDays != nil {
+		plan.LeadDays = *input.LeadDays
+	}
+
+	if msg := validateMaintenancePlan(plan); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.MaintenanceModel.InsertPlan(plan); err != nil {
+		http.Error(w, err.Error(), maintenanceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GET /api/v1/maintenance/plans/{id}
+func (h *MaintenanceHandler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	id, err := maintenancePlanIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid maintenance plan ID", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.MaintenanceModel.GetPlan(id)
+	if err != nil {
+		http.Error(w, err.Error(), maintenanceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// PUT /api/v1/maintenance/plans/{id}
+func (h *MaintenanceHandler) UpdatePlan(w http.ResponseWriter, r *http.Request) {
+	id, err := maintenancePlanIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid maintenance plan ID", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.MaintenanceModel.GetPlan(id)
+	if err != nil {
+		http.Error(w, err.Error(), maintenanceErrorStatus(err))
+		return
+	}
+
+	var input struct {
+		Name         *string `json:"name"`
+		ServiceType  *string `json:"service_type"`
+		IntervalDays *int    `json:"interval_days"`
+		LeadDays     *int    `json:"lead_days"`
+		AssignTo     *int64  `json:"assign_to"`
+		Active       *bool   `json:"active"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Name != nil {
+		plan.Name = *input.Name
+	}
+	if input.ServiceType != nil {
+		plan.ServiceType = strings.ToUpper(strings.TrimSpace(*input.ServiceType))
+		if plan.ServiceType == "" {
+			http.Error(w, "service_type cannot be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.IntervalDays != nil {
+		plan.IntervalDays = *input.IntervalDays
+	}
+	if input.LeadDays != nil {
+		plan.LeadDays = *input.LeadDays
+	}
+	if input.AssignTo != nil {
+		plan.AssignTo = input.AssignTo
+	}
+	if input.Active != nil {
+		plan.Active = *input.Active
+	}
+
+	if msg := validateMaintenancePlan(plan); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.MaintenanceModel.UpdatePlan(plan); err != nil {
+		http.Error(w, err.Error(), maintenanceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// DELETE /api/v1/maintenance/plans/{id}
+func (h *MaintenanceHandler) DeletePlan(w http.ResponseWriter, r *http.Request) {
+	id, err := maintenancePlanIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid maintenance plan ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.MaintenanceModel.DeletePlan(id); err != nil {
+		http.Error(w, err.Error(), maintenanceErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/maintenance/calendar?weeks=4
+// Maintenance due over the coming weeks, plus anything overdue
+func (h *MaintenanceHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	weeks := 4
+	if weeksStr := r.URL.Query().Get("weeks"); weeksStr != "" {
+		n, err := strconv.Atoi(weeksStr)
+		if err != nil || n <= 0 || n > 52 {
+			http.Error(w, "weeks must be between 1 and 52", http.StatusBadRequest)
+			return
+		}
+		weeks = n
+	}
+
+	items, err := h.MaintenanceModel.GetCalendar(weeks * 7)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Group by due date for the calendar view
+	byDate := map[string][]models.MaintenanceDue{}
+	overdue := 0
+	for _, item := range items {
+		date := item.DueDate.Format("2006-01-02")
+		byDate[date] = append(byDate[date], item)
+		if item.Overdue {
+			overdue++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"weeks":   weeks,
+		"count":   len(items),
+		"overdue": overdue,
+		"items":   items,
+		"by_date": byDate,
+	})
+}
+
+// POST /api/v1/maintenance/generate-tickets
+// Raises a ticket for each asset entering its plan's lead window now rather
+// than waiting for the scheduler. Safe to repeat: each due date gets one ticket.
+func (h *MaintenanceHandler) GenerateTickets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	createdBy := int64(userID)
+
+	tickets, err := h.TicketScheduler.GenerateDue(&createdBy)
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		for _, ticket := range tickets {
+			if err := h.NotificationService.NotifyTicketCreated(ticket); err != nil {
+				fmt.Printf("Failed to send maintenance ticket notifications: %v\n", err)
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"created": len(tickets),
+		"tickets": tickets,
+	})
+}
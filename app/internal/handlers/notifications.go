@@ -65,6 +65,21 @@ func (h *NotificationsHandler) GetNotificationTypes(w http.ResponseWriter, r *ht
 		"ticket_updated", 
 		"asset_created",
 		"user_created",
+		"custody_pending",
+		"custody_overdue",
+		"loan_overdue",
+		"disposal_requested",
+		"disposal_reviewed",
+		"license_renewal",
+		"low_stock",
+		"purchase_requested",
+		"purchase_reviewed",
+		"transfer_requested",
+		"transfer_completed",
+		"transfer_rejected",
+		"ticket_comment",
+		"ticket_mention",
+		"csat_survey",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type PurchasingHandler struct {
+	PurchasingModel     *models.PurchasingModel
+	NotificationService *services.NotificationService
+}
+
+func NewPurchasingHandler(db *sql.DB) *PurchasingHandler {
+	return &PurchasingHandler{
+		PurchasingModel:     models.NewPurchasingModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// purchaseRequestIDFromPath extracts the request ID from /api/v1/purchase-requests/{id}/...
+func purchaseRequestIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/purchase-requests/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// purchaseOrderIDFromPath extracts the order ID from /api/v1/purchase-orders/{id}/...
+func purchaseOrderIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/purchase-orders/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// purchasingErrorStatus maps model errors to HTTP status codes
+func purchasingErrorStatus(err error) int {
+	if _, ok := err.(*models.CustomFieldError); ok {
+		return http.StatusBadRequest
+	}
+	msg := err.Error()
+	switch msg {
+	case "purchase request not found", "purchase order not found":
+		return http.StatusNotFound
+	case "purchase request not found or not pending",
+		"purchase request not found or not approved",
+		"purchase order not found or closed",
+		"purchase order is not awaiting delivery",
+		"only open purchase orders with nothing received can be cancelled",
+		"PO number already exists":
+		return http.StatusConflict
+	case "a purchase order needs at least one line",
+		"nothing to receive",
+		"internal_id is required for every received unit":
+		return http.StatusBadRequest
+	}
+	if strings.HasPrefix(msg, "asset with internal ID") || strings.HasPrefix(msg, "PO line") {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// GET /api/v1/purchase-requests?status=pending&mine=true
+func (h *PurchasingHandler) ListRequests(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var requestedBy *int64
+	if q.Get("mine") == "true" {
+		requestedBy = currentUserID(r)
+	}
+
+	requests, err := h.PurchasingModel.GetRequests(q.Get("status"), requestedBy)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// POST /api/v1/purchase-requests
+func (h *PurchasingHandler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title         string   `json:"title"`
+		Justification string   `json:"justification"`
+		AssetType     string   `json:"asset_type"`
+		Quantity      int      `json:"quantity"`
+		EstimatedCost *float64 `json:"estimated_cost"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(input.Title) == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+	if input.Quantity < 0 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	request := &models.PurchaseRequest{
+		Title:         input.Title,
+		Justification: input.Justification,
+		AssetType:     input.AssetType,
+		Quantity:      input.Quantity,
+		EstimatedCost: input.EstimatedCost,
+		RequestedBy:   currentUserID(r),
+	}
+
+	if err := h.PurchasingModel.InsertRequest(request); err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	request, err := h.PurchasingModel.GetRequest(request.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyPurchaseRequested(request); err != nil {
+			fmt.Printf("Failed to send purchase request notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(request)
+}
+
+// GET /api/v1/purchase-requests/{id}
+func (h *PurchasingHandler) GetRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseRequestIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase request ID", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.PurchasingModel.GetRequest(id)
+	if err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// POST /api/v1/purchase-requests/{id}/approve
+func (h *PurchasingHandler) ApproveRequest(w http.ResponseWriter, r *http.Request) {
+	h.reviewRequest(w, r, true)
+}
+
+// POST /api/v1/purchase-requests/{id}/reject
+func (h *PurchasingHandler) RejectRequest(w http.ResponseWriter, r *http.Request) {
+	h.reviewRequest(w, r, false)
+}
+
+func (h *PurchasingHandler) reviewRequest(w http.ResponseWriter, r *http.Request, approved bool) {
+	id, err := purchaseRequestIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase request ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.PurchasingModel.ReviewRequest(id, int64(userID), approved, input.Notes); err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	request, err := h.PurchasingModel.GetRequest(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyPurchaseReviewed(request); err != nil {
+			fmt.Printf("Failed to send purchase review notification: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// GET /api/v1/purchase-orders?status=open&vendor=
+func (h *PurchasingHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	orders, err := h.PurchasingModel.GetOrders(q.Get("status"), q.Get("vendor"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+// POST /api/v1/purchase-orders
+func (h *PurchasingHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PONumber          string                     `json:"po_number"` // Optional; generated when empty
+		PurchaseRequestID *int64                     `json:"purchase_request_id"`
+		Vendor            string                     `json:"vendor"`
+		OrderDate         string                     `json:"order_date"`
+		ExpectedDelivery  string                     `json:"expected_delivery"`
+		Notes             string                     `json:"notes"`
+		Lines             []models.PurchaseOrderLine `json:"lines"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(input.Vendor) == "" {
+		http.Error(w, "Vendor is required", http.StatusBadRequest)
+		return
+	}
+
+	for _, line := range input.Lines {
+		if line.AssetType == "" {
+			http.Error(w, "Every line needs an asset_type", http.StatusBadRequest)
+			return
+		}
+		if line.Quantity <= 0 {
+			http.Error(w, "Every line needs a positive quantity", http.StatusBadRequest)
+			return
+		}
+		if line.UnitCost < 0 {
+			http.Error(w, "Unit cost cannot be negative", http.StatusBadRequest)
+			return
+		}
+	}
+
+	orderDate, err := parseOptionalDate(input.OrderDate)
+	if err != nil {
+		http.Error(w, "OrderDate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedDelivery, err := parseOptionalDate(input.ExpectedDelivery)
+	if err != nil {
+		http.Error(w, "ExpectedDelivery: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order := &models.PurchaseOrder{
+		PONumber:          input.PONumber,
+		PurchaseRequestID: input.PurchaseRequestID,
+		Vendor:            input.Vendor,
+		ExpectedDelivery:  expectedDelivery,
+		Notes:             input.Notes,
+		CreatedBy:         currentUserID(r),
+		Lines:             input.Lines,
+	}
+	if orderDate != nil {
+		order.OrderDate = *orderDate
+	}
+
+	if err := h.PurchasingModel.InsertOrder(order); err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GET /api/v1/purchase-orders/{id}
+func (h *PurchasingHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.PurchasingModel.GetOrder(id)
+	if err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// PUT /api/v1/purchase-orders/{id}
+func (h *PurchasingHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.PurchasingModel.GetOrder(id)
+	if err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	var input struct {
+		Vendor           *string `json:"vendor"`
+		ExpectedDelivery *string `json:"expected_delivery"`
+		Notes            *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Vendor != nil {
+		if strings.TrimSpace(*input.Vendor) == "" {
+			http.Error(w, "Vendor cannot be empty", http.StatusBadRequest)
+			return
+		}
+		order.Vendor = *input.Vendor
+	}
+	if input.ExpectedDelivery != nil {
+		expectedDelivery, err := parseOptionalDate(*input.ExpectedDelivery)
+		if err != nil {
+			http.Error(w, "ExpectedDelivery: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		order.ExpectedDelivery = expectedDelivery
+	}
+	if input.Notes != nil {
+		order.Notes = *input.Notes
+	}
+
+	if err := h.PurchasingModel.UpdateOrder(order); err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// POST /api/v1/purchase-orders/{id}/cancel
+func (h *PurchasingHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.PurchasingModel.CancelOrder(id); err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Purchase order cancelled",
+	})
+}
+
+// POST /api/v1/purchase-orders/{id}/receive
+// Body: {"location": "Store Room", "units": [{"line_id": 1, "internal_id": "AM-M010", "serial_number": "...", "custom_fields": {...}}]}
+func (h *PurchasingHandler) ReceiveGoods(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Location string                    `json:"location"`
+		Units    []models.GoodsReceiptUnit `json:"units"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assets, err := h.PurchasingModel.Receive(id, input.Units, input.Location)
+	if err != nil {
+		http.Error(w, err.Error(), purchasingErrorStatus(err))
+		return
+	}
+
+	order, err := h.PurchasingModel.GetOrder(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"purchase_order": order,
+		"assets":         assets,
+	})
+}
+
+// GET /api/v1/purchase-orders/{id}/assets
+func (h *PurchasingHandler) GetOrderAssets(w http.ResponseWriter, r *http.Request) {
+	id, err := purchaseOrderIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	assets, err := h.PurchasingModel.GetReceivedAssets(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assets)
+}
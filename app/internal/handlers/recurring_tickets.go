@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type RecurringTicketsHandler struct {
+	RecurringModel *models.RecurringTicketModel
+	UsersModel     *models.UsersModel
+	AssetsModel    *models.AssetsModel
+}
+
+func NewRecurringTicketsHandler(db *sql.DB) *RecurringTicketsHandler {
+	return &RecurringTicketsHandler{
+		RecurringModel: models.NewRecurringTicketModel(db),
+		UsersModel:     models.NewUsersModel(db),
+		AssetsModel:    models.NewAssetsModel(db),
+	}
+}
+
+// recurringTicketErrorStatus maps model errors to HTTP status codes
+func recurringTicketErrorStatus(err error) int {
+	var recurringErr *models.RecurringTicketError
+	if errors.As(err, &recurringErr) {
+		return http.StatusBadRequest
+	}
+
+	if err.Error() == "recurring ticket not found" {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// recurringTicketIDFromPath extracts the ID from /api/v1/recurring-tickets/{id}/...
+func recurringTicketIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/recurring-tickets/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// recurringTicketInput is the body of a create or update
+type recurringTicketInput struct {
+	Name        string     `json:"name"`
+	Schedule    string     `json:"schedule"`
+	Timezone    string     `json:"timezone"`
+	StartsAt    *time.Time `json:"starts_at"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Type        string     `json:"type"`
+	Priority    string     `json:"priority"`
+	AssignedTo  *int64     `json:"assigned_to"`
+	AssetID     *int64     `json:"asset_id"`
+	IsInternal  bool       `json:"is_internal"`
+	IsPaused    bool       `json:"is_paused"` // Only on create
+}
+
+// validateTemplateRefs checks the template's assignee and asset exist
+func (h *RecurringTicketsHandler) validateTemplateRefs(input *recurringTicketInput) error {
+	if input.AssignedTo != nil {
+		if _, err := h.UsersModel.GetByID(*input.AssignedTo); err != nil {
+			return &models.RecurringTicketError{Message: "Assigned user not found"}
+		}
+	}
+	if input.AssetID != nil {
+		if _, err := h.AssetsModel.GetByID(*input.AssetID); err != nil {
+			return &models.RecurringTicketError{Message: "Asset not found"}
+		}
+	}
+	return nil
+}
+
+// GET /api/v1/recurring-tickets
+func (h *RecurringTicketsHandler) ListRecurringTickets(w http.ResponseWriter, r *http.Request) {
+	definitions, err := h.RecurringModel.GetAll()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definitions)
+}
+
+// GET /api/v1/recurring-tickets/{id}
+// Includes the next five occurrences
+func (h *RecurringTicketsHandler) GetRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.RecurringModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	upcoming, err := definition.Upcoming(time.Now(), 5)
+	if err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recurring_ticket": definition,
+		"upcoming":         upcoming,
+	})
+}
+
+// POST /api/v1/recurring-tickets
+// Body: {"name": "Monthly UPS check", "schedule": "0 9 1 * *", "timezone": "America/Belize",
+//        "title": "UPS check", "type": "maintenance", "priority": "normal", "assigned_to": 4, "asset_id": 12}
+func (h *RecurringTicketsHandler) CreateRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input recurringTicketInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateTemplateRefs(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	definition := &models.RecurringTicket{
+		Name:        input.Name,
+		Schedule:    input.Schedule,
+		Timezone:    input.Timezone,
+		Title:       input.Title,
+		Description: input.Description,
+		Type:        input.Type,
+		Priority:    input.Priority,
+		AssignedTo:  input.AssignedTo,
+		AssetID:     input.AssetID,
+		IsInternal:  input.IsInternal,
+		IsPaused:    input.IsPaused,
+		CreatedBy:   &userID,
+	}
+	if input.StartsAt != nil {
+		definition.StartsAt = *input.StartsAt
+	}
+
+	if err := h.RecurringModel.Insert(definition); err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// PUT /api/v1/recurring-tickets/{id}
+// Reschedules the next run from now; starts_at is kept when left out
+func (h *RecurringTicketsHandler) UpdateRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.RecurringModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	var input recurringTicketInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateTemplateRefs(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	definition := &models.RecurringTicket{
+		ID:          id,
+		Name:        input.Name,
+		Schedule:    input.Schedule,
+		Timezone:    input.Timezone,
+		StartsAt:    existing.StartsAt,
+		Title:       input.Title,
+		Description: input.Description,
+		Type:        input.Type,
+		Priority:    input.Priority,
+		AssignedTo:  input.AssignedTo,
+		AssetID:     input.AssetID,
+		IsInternal:  input.IsInternal,
+	}
+	if input.StartsAt != nil {
+		definition.StartsAt = *input.StartsAt
+	}
+
+	if err := h.RecurringModel.Update(definition); err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// DELETE /api/v1/recurring-tickets/{id}
+// Tickets already raised are kept
+func (h *RecurringTicketsHandler) DeleteRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.RecurringModel.Delete(id); err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/recurring-tickets/{id}/pause
+func (h *RecurringTicketsHandler) PauseRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.RecurringModel.Pause(id)
+	if err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// POST /api/v1/recurring-tickets/{id}/resume
+// Picks up from the next occurrence; those missed while paused are skipped
+func (h *RecurringTicketsHandler) ResumeRecurringTicket(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	definition, err := h.RecurringModel.Resume(id)
+	if err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definition)
+}
+
+// GET /api/v1/recurring-tickets/{id}/runs?limit=50
+// Occurrences and the tickets raised for them, most recent first
+func (h *RecurringTicketsHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	id, err := recurringTicketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid recurring ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	if _, err := h.RecurringModel.GetByID(id); err != nil {
+		http.Error(w, err.Error(), recurringTicketErrorStatus(err))
+		return
+	}
+
+	runs, err := h.RecurringModel.GetRuns(id, limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
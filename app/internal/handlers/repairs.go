@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type RepairsHandler struct {
+	RepairModel *models.RepairModel
+}
+
+func NewRepairsHandler(db *sql.DB) *RepairsHandler {
+	return &RepairsHandler{
+		RepairModel: models.NewRepairModel(db),
+	}
+}
+
+// repairIDFromPath extracts the repair ID from /api/v1/repairs/{id}/...
+func repairIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/repairs/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// repairErrorStatus maps model errors to HTTP status codes
+func repairErrorStatus(err error) int {
+	switch err.Error() {
+	case "repair not found", "asset not found":
+		return http.StatusNotFound
+	case "asset already has an active repair",
+		"repair not found or already shipped",
+		"repair not found or not shipped",
+		"asset is still on loan":
+		return http.StatusConflict
+	case "outcome must be one of: repaired, replaced, written_off",
+		"new_serial_number is required for a replacement":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/v1/repairs?status=shipped
+func (h *RepairsHandler) ListRepairs(w http.ResponseWriter, r *http.Request) {
+	repairs, err := h.RepairModel.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repairs)
+}
+
+// GET /api/v1/repairs/overdue?days=14
+// Assets still with the vendor after the given number of days, or past their expected return
+func (h *RepairsHandler) GetOverdueRepairs(w http.ResponseWriter, r *http.Request) {
+	days := 14
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	repairs, err := h.RepairModel.GetOutstanding(days)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":    days,
+		"count":   len(repairs),
+		"repairs": repairs,
+	})
+}
+
+// GET /api/v1/assets/{id}/repairs
+func (h *RepairsHandler) GetAssetRepairs(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	repairs, err := h.RepairModel.GetByAsset(assetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repairs)
+}
+
+// POST /api/v1/assets/{id}/repairs
+func (h *RepairsHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Vendor           string `json:"vendor"`
+		RMANumber        string `json:"rma_number"`
+		FaultDescription string `json:"fault_description"`
+		ExpectedReturn   string `json:"expected_return"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(input.Vendor) == "" {
+		http.Error(w, "Vendor is required", http.StatusBadRequest)
+		return
+	}
+
+	expectedReturn, err := parseOptionalDate(input.ExpectedReturn)
+	if err != nil {
+		http.Error(w, "ExpectedReturn: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repair := &models.AssetRepair{
+		AssetID:          assetID,
+		Vendor:           input.Vendor,
+		RMANumber:        input.RMANumber,
+		FaultDescription: input.FaultDescription,
+		ExpectedReturn:   expectedReturn,
+		CreatedBy:        currentUserID(r),
+	}
+
+	if err := h.RepairModel.Insert(repair); err != nil {
+		http.Error(w, err.Error(), repairErrorStatus(err))
+		return
+	}
+
+	repair, err = h.RepairModel.GetByID(repair.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(repair)
+}
+
+// GET /api/v1/repairs/{id}
+func (h *RepairsHandler) GetRepair(w http.ResponseWriter, r *http.Request) {
+	id, err := repairIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid repair ID", http.StatusBadRequest)
+		return
+	}
+
+	repair, err := h.RepairModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), repairErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repair)
+}
+
+// POST /api/v1/repairs/{id}/ship
+// Moves the asset to REPAIR and takes it off its current user
+func (h *RepairsHandler) ShipRepair(w http.ResponseWriter, r *http.Request) {
+	id, err := repairIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid repair ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		RMANumber      string `json:"rma_number"`
+		ShippedAt      string `json:"shipped_at"`
+		ExpectedReturn string `json:"expected_return"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shippedAt, err := parseOptionalDate(input.ShippedAt)
+	if err != nil {
+		http.Error(w, "ShippedAt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if shippedAt == nil {
+		now := time.Now()
+		shippedAt = &now
+	}
+
+	expectedReturn, err := parseOptionalDate(input.ExpectedReturn)
+	if err != nil {
+		http.Error(w, "ExpectedReturn: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.RepairModel.Ship(id, int64(userID), input.RMANumber, *shippedAt, expectedReturn); err != nil {
+		http.Error(w, err.Error(), repairErrorStatus(err))
+		return
+	}
+
+	repair, err := h.RepairModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repair)
+}
+
+// POST /api/v1/repairs/{id}/return
+// Body: {"outcome": "replaced", "new_serial_number": "...", "repair_cost": 0, "returned_at": "2025-03-01"}
+func (h *RepairsHandler) ReturnRepair(w http.ResponseWriter, r *http.Request) {
+	id, err := repairIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid repair ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Outcome         string   `json:"outcome"`
+		ReturnedAt      string   `json:"returned_at"`
+		RepairCost      *float64 `json:"repair_cost"`
+		NewSerialNumber string   `json:"new_serial_number"`
+		Notes           string   `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.RepairCost != nil && *input.RepairCost < 0 {
+		http.Error(w, "Repair cost cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	returnedAt, err := parseOptionalDate(input.ReturnedAt)
+	if err != nil {
+		http.Error(w, "ReturnedAt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ret := models.RepairReturn{
+		Outcome:         input.Outcome,
+		ReturnedAt:      time.Now(),
+		RepairCost:      input.RepairCost,
+		NewSerialNumber: strings.TrimSpace(input.NewSerialNumber),
+		Notes:           input.Notes,
+		ReceivedBy:      currentUserID(r),
+	}
+	if returnedAt != nil {
+		ret.ReturnedAt = *returnedAt
+	}
+
+	if err := h.RepairModel.Return(id, ret); err != nil {
+		http.Error(w, err.Error(), repairErrorStatus(err))
+		return
+	}
+
+	repair, err := h.RepairModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repair)
+}
+
+// POST /api/v1/repairs/{id}/cancel
+func (h *RepairsHandler) CancelRepair(w http.ResponseWriter, r *http.Request) {
+	id, err := repairIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid repair ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.RepairModel.Cancel(id); err != nil {
+		http.Error(w, err.Error(), repairErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Repair cancelled",
+	})
+}
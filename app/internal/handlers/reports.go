@@ -6,16 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
 )
 
 type ReportsHandler struct {
-	DB *sql.DB
+	DB         *sql.DB
+	RolesModel *models.RolesModel
 }
 
 func NewReportsHandler(db *sql.DB) *ReportsHandler {
-	return &ReportsHandler{DB: db}
+	return &ReportsHandler{
+		DB:         db,
+		RolesModel: models.NewRolesModel(db),
+	}
 }
 
 // ReportFilter represents the filter criteria for reports
@@ -84,6 +92,10 @@ func (h *ReportsHandler) GetReportTypes(w http.ResponseWriter, r *http.Request)
 		"user_activity",
 		"system_metrics",
 		"performance_report",
+		"consumption",
+		"tco",
+		"time",
+		"csat",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -92,6 +104,702 @@ func (h *ReportsHandler) GetReportTypes(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GET /api/v1/reports/consumption?start_date=2025-01-01&end_date=2025-12-31&category=&format=csv
+// Consumables used per month and item, from stock-out movements
+func (h *ReportsHandler) GetConsumptionReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("format") == "csv" && !h.canExport(w, r) {
+		return
+	}
+
+	// Default to the last twelve full and current months
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -11, 0)
+	endDate := now
+
+	if v := q.Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		startDate = t
+	}
+	if v := q.Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		endDate = t.AddDate(0, 0, 1) // inclusive of the whole end day
+	}
+
+	if endDate.Before(startDate) {
+		http.Error(w, "End date cannot be before start date", http.StatusBadRequest)
+		return
+	}
+
+	consumption, err := h.getConsumption(startDate, endDate, q.Get("category"))
+	if err != nil {
+		fmt.Printf("Error getting consumption report: %v\n", err)
+		http.Error(w, "Failed to generate consumption report", http.StatusInternalServerError)
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		csv := "Month,SKU,Item,Category,Unit,Quantity Used,Tickets,Service Logs\n"
+		for _, row := range consumption {
+			csv += fmt.Sprintf("%s,%s,%s,%s,%s,%v,%v,%v\n",
+				row["month"], csvField(row["sku"].(string)), csvField(row["name"].(string)),
+				csvField(row["category"].(string)), row["unit"], row["quantity_used"],
+				row["tickets"], row["service_logs"])
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=consumption.csv")
+		w.Write([]byte(csv))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"start_date":  startDate.Format("2006-01-02"),
+		"end_date":    endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+		"consumption": consumption,
+	})
+}
+
+// Get consumables used per month and item
+func (h *ReportsHandler) getConsumption(startDate, endDate time.Time, category string) ([]map[string]interface{}, error) {
+	query := `
+		SELECT
+			TO_CHAR(DATE_TRUNC('month', mv.created_at), 'YYYY-MM') as month,
+			i.sku,
+			i.name,
+			i.category,
+			i.unit,
+			SUM(mv.quantity) as quantity_used,
+			COUNT(DISTINCT mv.ticket_id) as tickets,
+			COUNT(DISTINCT mv.service_log_id) as service_logs
+		FROM stock_movements mv
+		JOIN stock_items i ON i.id = mv.item_id
+		WHERE mv.movement_type = 'out'
+		AND mv.created_at >= $1 AND mv.created_at < $2
+		AND ($3 = '' OR i.category = $3)
+		GROUP BY DATE_TRUNC('month', mv.created_at), i.id, i.sku, i.name, i.category, i.unit
+		ORDER BY DATE_TRUNC('month', mv.created_at), i.category, i.name
+	`
+
+	rows, err := h.DB.Query(query, startDate, endDate, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumption := []map[string]interface{}{}
+	for rows.Next() {
+		var month, sku, name, itemCategory, unit string
+		var quantityUsed, tickets, serviceLogs int
+
+		err := rows.Scan(&month, &sku, &name, &itemCategory, &unit,
+			&quantityUsed, &tickets, &serviceLogs)
+		if err != nil {
+			return nil, err
+		}
+
+		consumption = append(consumption, map[string]interface{}{
+			"month":         month,
+			"sku":           sku,
+			"name":          name,
+			"category":      itemCategory,
+			"unit":          unit,
+			"quantity_used": quantityUsed,
+			"tickets":       tickets,
+			"service_logs":  serviceLogs,
+		})
+	}
+
+	return consumption, rows.Err()
+}
+
+// assetTCO is one asset's lifetime cost. Maintenance is everything spent
+// after purchase; labor is costed at the rate given to the report.
+type assetTCO struct {
+	AssetID          int64    `json:"asset_id"`
+	InternalID       string   `json:"internal_id"`
+	AssetType        string   `json:"asset_type"`
+	Manufacturer     string   `json:"manufacturer"`
+	Model            string   `json:"model"`
+	Status           string   `json:"status"`
+	DatePurchased    *string  `json:"date_purchased"`
+	PurchaseCost     float64  `json:"purchase_cost"`
+	ServiceCount     int      `json:"service_count"`
+	LaborMinutes     int      `json:"labor_minutes"`
+	LaborCost        float64  `json:"labor_cost"`
+	PartsCost        float64  `json:"parts_cost"`
+	ExternalCost     float64  `json:"external_cost"`
+	RepairCost       float64  `json:"repair_cost"` // External repairs (RMAs)
+	MaintenanceCost  float64  `json:"maintenance_cost"`
+	TotalCost        float64  `json:"total_cost"`
+	MaintenanceRatio *float64 `json:"maintenance_ratio"` // Maintenance cost / purchase cost
+}
+
+// assetTypeTCO rolls assetTCO up per asset type
+type assetTypeTCO struct {
+	AssetType              string  `json:"asset_type"`
+	Assets                 int     `json:"assets"`
+	PurchaseCost           float64 `json:"purchase_cost"`
+	LaborCost              float64 `json:"labor_cost"`
+	PartsCost              float64 `json:"parts_cost"`
+	ExternalCost           float64 `json:"external_cost"`
+	RepairCost             float64 `json:"repair_cost"`
+	MaintenanceCost        float64 `json:"maintenance_cost"`
+	TotalCost              float64 `json:"total_cost"`
+	AvgTotalPerAsset       float64 `json:"avg_total_per_asset"`
+	AvgMaintenancePerAsset float64 `json:"avg_maintenance_per_asset"`
+}
+
+// GET /api/v1/reports/tco?group_by=asset|asset_type&asset_type=PC&labor_rate=35&format=csv
+// Total cost of ownership: purchase plus service labor, parts, external and
+// RMA repair costs. Per-asset rows are sorted by maintenance ratio so assets
+// that are cheaper to replace than to keep repairing come first.
+func (h *ReportsHandler) GetTCOReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("format") == "csv" && !h.canExport(w, r) {
+		return
+	}
+
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = "asset"
+	}
+	if groupBy != "asset" && groupBy != "asset_type" {
+		http.Error(w, "group_by must be asset or asset_type", http.StatusBadRequest)
+		return
+	}
+
+	laborRate := 0.0
+	if v := q.Get("labor_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			http.Error(w, "Invalid labor_rate, expected a non-negative hourly rate", http.StatusBadRequest)
+			return
+		}
+		laborRate = rate
+	}
+
+	assets, err := h.getAssetTCO(q.Get("asset_type"), laborRate)
+	if err != nil {
+		fmt.Printf("Error getting TCO report: %v\n", err)
+		http.Error(w, "Failed to generate TCO report", http.StatusInternalServerError)
+		return
+	}
+
+	if groupBy == "asset_type" {
+		types := rollUpTCOByType(assets)
+
+		if q.Get("format") == "csv" {
+			csv := "Asset Type,Assets,Purchase,Labor,Parts,External,Repairs,Maintenance,Total,Avg Total per Asset,Avg Maintenance per Asset\n"
+			for _, t := range types {
+				csv += fmt.Sprintf("%s,%d,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f\n",
+					csvField(t.AssetType), t.Assets, t.PurchaseCost, t.LaborCost, t.PartsCost,
+					t.ExternalCost, t.RepairCost, t.MaintenanceCost, t.TotalCost,
+					t.AvgTotalPerAsset, t.AvgMaintenancePerAsset)
+			}
+
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename=tco_by_type.csv")
+			w.Write([]byte(csv))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"group_by":   groupBy,
+			"labor_rate": laborRate,
+			"tco":        types,
+		})
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		csv := "Internal ID,Asset Type,Manufacturer,Model,Status,Purchased,Purchase,Services,Labor Minutes,Labor,Parts,External,Repairs,Maintenance,Total,Maintenance Ratio\n"
+		for _, a := range assets {
+			purchased, ratio := "", ""
+			if a.DatePurchased != nil {
+				purchased = *a.DatePurchased
+			}
+			if a.MaintenanceRatio != nil {
+				ratio = fmt.Sprintf("%.2f", *a.MaintenanceRatio)
+			}
+			csv += fmt.Sprintf("%s,%s,%s,%s,%s,%s,%.2f,%d,%d,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%s\n",
+				csvField(a.InternalID), csvField(a.AssetType), csvField(a.Manufacturer), csvField(a.Model),
+				a.Status, purchased, a.PurchaseCost, a.ServiceCount, a.LaborMinutes, a.LaborCost,
+				a.PartsCost, a.ExternalCost, a.RepairCost, a.MaintenanceCost, a.TotalCost, ratio)
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=tco.csv")
+		w.Write([]byte(csv))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group_by":   groupBy,
+		"labor_rate": laborRate,
+		"tco":        assets,
+	})
+}
+
+// Get lifetime costs per live asset
+func (h *ReportsHandler) getAssetTCO(assetType string, laborRate float64) ([]assetTCO, error) {
+	query := `
+		SELECT
+			a.id, a.internal_id, a.asset_type, COALESCE(a.manufacturer, ''), COALESCE(a.model, ''),
+			a.status, TO_CHAR(a.date_purchased, 'YYYY-MM-DD'),
+			COALESCE(a.purchase_cost, 0),
+			COALESCE(svc.service_count, 0),
+			COALESCE(svc.labor_minutes, 0),
+			COALESCE(svc.external_cost, 0),
+			COALESCE(parts.parts_cost, 0),
+			COALESCE(rma.repair_cost, 0)
+		FROM assets a
+		LEFT JOIN (
+			SELECT asset_id, COUNT(*) AS service_count, SUM(labor_minutes) AS labor_minutes,
+			       SUM(external_cost) AS external_cost
+			FROM asset_service
+			GROUP BY asset_id
+		) svc ON svc.asset_id = a.id
+		LEFT JOIN (
+			SELECT s.asset_id, SUM(mv.quantity * mv.unit_cost) AS parts_cost
+			FROM stock_movements mv
+			JOIN asset_service s ON s.id = mv.service_log_id
+			WHERE mv.movement_type = 'out'
+			GROUP BY s.asset_id
+		) parts ON parts.asset_id = a.id
+		LEFT JOIN (
+			SELECT asset_id, SUM(repair_cost) AS repair_cost
+			FROM asset_repairs
+			GROUP BY asset_id
+		) rma ON rma.asset_id = a.id
+		WHERE a.deleted_at IS NULL
+		AND ($1 = '' OR a.asset_type = $1)
+	`
+
+	rows, err := h.DB.Query(query, assetType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []assetTCO{}
+	for rows.Next() {
+		var a assetTCO
+		err := rows.Scan(&a.AssetID, &a.InternalID, &a.AssetType, &a.Manufacturer, &a.Model,
+			&a.Status, &a.DatePurchased, &a.PurchaseCost, &a.ServiceCount, &a.LaborMinutes,
+			&a.ExternalCost, &a.PartsCost, &a.RepairCost)
+		if err != nil {
+			return nil, err
+		}
+
+		a.LaborCost = float64(a.LaborMinutes) / 60 * laborRate
+		a.MaintenanceCost = a.LaborCost + a.PartsCost + a.ExternalCost + a.RepairCost
+		a.TotalCost = a.PurchaseCost + a.MaintenanceCost
+		if a.PurchaseCost > 0 {
+			ratio := a.MaintenanceCost / a.PurchaseCost
+			a.MaintenanceRatio = &ratio
+		}
+		assets = append(assets, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Worst value for money first; assets without a purchase cost sort by spend
+	sort.SliceStable(assets, func(i, j int) bool {
+		ri, rj := assets[i].MaintenanceRatio, assets[j].MaintenanceRatio
+		if ri != nil && rj != nil && *ri != *rj {
+			return *ri > *rj
+		}
+		if (ri == nil) != (rj == nil) {
+			return ri != nil
+		}
+		return assets[i].MaintenanceCost > assets[j].MaintenanceCost
+	})
+
+	return assets, nil
+}
+
+// rollUpTCOByType totals per-asset costs by asset type, most expensive type first
+func rollUpTCOByType(assets []assetTCO) []assetTypeTCO {
+	byType := map[string]*assetTypeTCO{}
+	types := []*assetTypeTCO{}
+	for _, a := range assets {
+		t, ok := byType[a.AssetType]
+		if !ok {
+			t = &assetTypeTCO{AssetType: a.AssetType}
+			byType[a.AssetType] = t
+			types = append(types, t)
+		}
+		t.Assets++
+		t.PurchaseCost += a.PurchaseCost
+		t.LaborCost += a.LaborCost
+		t.PartsCost += a.PartsCost
+		t.ExternalCost += a.ExternalCost
+		t.RepairCost += a.RepairCost
+		t.MaintenanceCost += a.MaintenanceCost
+		t.TotalCost += a.TotalCost
+	}
+
+	result := make([]assetTypeTCO, 0, len(types))
+	for _, t := range types {
+		t.AvgTotalPerAsset = t.TotalCost / float64(t.Assets)
+		t.AvgMaintenancePerAsset = t.MaintenanceCost / float64(t.Assets)
+		result = append(result, *t)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].TotalCost > result[j].TotalCost
+	})
+
+	return result
+}
+
+// timeReportGroups maps the time report's group_by values to the column
+// worklogs are grouped on
+var timeReportGroups = map[string]string{
+	"technician":  "COALESCE(u.full_name, u.username, 'Unknown')",
+	"ticket_type": "t.type",
+	"campaign":    "COALESCE(NULLIF(t.campaign, ''), '(none)')",
+}
+
+// timeSpentRow is the logged time for one group
+type timeSpentRow struct {
+	Group           string  `json:"group"`
+	Minutes         int     `json:"minutes"`
+	Hours           float64 `json:"hours"`
+	BillableMinutes int     `json:"billable_minutes"`
+	InternalMinutes int     `json:"internal_minutes"`
+	Tickets         int     `json:"tickets"`
+	Entries         int     `json:"entries"`
+}
+
+// GET /api/v1/reports/time?group_by=technician|ticket_type|campaign&start_date=2025-06-01&end_date=2025-06-30&ticket_type=&campaign=&user_id=&format=csv
+// Time logged on tickets, by work date. Defaults to the current month by technician.
+func (h *ReportsHandler) GetTimeReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("format") == "csv" && !h.canExport(w, r) {
+		return
+	}
+
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = "technician"
+	}
+	if _, ok := timeReportGroups[groupBy]; !ok {
+		http.Error(w, "group_by must be technician, ticket_type or campaign", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if v := q.Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		startDate = t
+	}
+	if v := q.Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		endDate = t
+	}
+
+	if endDate.Before(startDate) {
+		http.Error(w, "End date cannot be before start date", http.StatusBadRequest)
+		return
+	}
+
+	var userID *int64
+	if v := q.Get("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		userID = &id
+	}
+
+	rows, err := h.getTimeSpent(groupBy, startDate, endDate, q.Get("ticket_type"), q.Get("campaign"), userID)
+	if err != nil {
+		fmt.Printf("Error getting time report: %v\n", err)
+		http.Error(w, "Failed to generate time report", http.StatusInternalServerError)
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		csv := "Group,Minutes,Hours,Billable Minutes,Internal Minutes,Tickets,Entries\n"
+		for _, row := range rows {
+			csv += fmt.Sprintf("%s,%d,%.2f,%d,%d,%d,%d\n",
+				csvField(row.Group), row.Minutes, row.Hours, row.BillableMinutes,
+				row.InternalMinutes, row.Tickets, row.Entries)
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=time_by_%s.csv", groupBy))
+		w.Write([]byte(csv))
+		return
+	}
+
+	totalMinutes := 0
+	for _, row := range rows {
+		totalMinutes += row.Minutes
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group_by":      groupBy,
+		"start_date":    startDate.Format("2006-01-02"),
+		"end_date":      endDate.Format("2006-01-02"),
+		"total_minutes": totalMinutes,
+		"time":          rows,
+	})
+}
+
+// Get logged time per group between two work dates, inclusive
+func (h *ReportsHandler) getTimeSpent(groupBy string, startDate, endDate time.Time, ticketType, campaign string, userID *int64) ([]timeSpentRow, error) {
+	group := timeReportGroups[groupBy]
+	query := `
+		SELECT
+			` + group + ` as grp,
+			SUM(wl.minutes),
+			COALESCE(SUM(wl.minutes) FILTER (WHERE wl.billable), 0),
+			COALESCE(SUM(wl.minutes) FILTER (WHERE NOT wl.billable), 0),
+			COUNT(DISTINCT wl.ticket_id),
+			COUNT(*)
+		FROM ticket_worklogs wl
+		JOIN tickets t ON t.id = wl.ticket_id
+		LEFT JOIN users u ON u.id = wl.user_id
+		WHERE wl.work_date >= $1 AND wl.work_date <= $2
+		AND ($3 = '' OR t.type = $3)
+		AND ($4 = '' OR t.campaign = $4)
+		AND ($5::BIGINT IS NULL OR wl.user_id = $5)
+		GROUP BY ` + group + `
+		ORDER BY SUM(wl.minutes) DESC, grp
+	`
+
+	rows, err := h.DB.Query(query, startDate, endDate, ticketType, campaign, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []timeSpentRow{}
+	for rows.Next() {
+		var row timeSpentRow
+		err := rows.Scan(&row.Group, &row.Minutes, &row.BillableMinutes, &row.InternalMinutes,
+			&row.Tickets, &row.Entries)
+		if err != nil {
+			return nil, err
+		}
+		row.Hours = float64(row.Minutes) / 60
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// csatReportGroups maps the CSAT report's group_by values to the column
+// surveys are grouped on
+var csatReportGroups = map[string]string{
+	"technician":  "COALESCE(tech.full_name, tech.username, 'Unassigned')",
+	"ticket_type": "t.type",
+}
+
+// csatRow is satisfaction for one group. CSAT is the share of responses
+// rated 4 or 5.
+type csatRow struct {
+	Group        string   `json:"group"`
+	Sent         int      `json:"sent"`
+	Responses    int      `json:"responses"`
+	ResponseRate float64  `json:"response_rate"` // Percent of surveys answered
+	AvgRating    *float64 `json:"avg_rating"`    // nil with no responses
+	CSATPercent  *float64 `json:"csat_percent"`
+	Ratings      [5]int   `json:"ratings"` // Count of each rating, 1 to 5
+}
+
+// GET /api/v1/reports/csat?group_by=technician|ticket_type&start_date=2025-01-01&end_date=2025-06-30&format=csv
+// Satisfaction survey results for tickets closed by verification, by when the
+// survey was sent. Defaults to the last 90 days by technician.
+func (h *ReportsHandler) GetCSATReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("format") == "csv" && !h.canExport(w, r) {
+		return
+	}
+
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = "technician"
+	}
+	if _, ok := csatReportGroups[groupBy]; !ok {
+		http.Error(w, "group_by must be technician or ticket_type", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -90)
+	endDate := now
+
+	if v := q.Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		startDate = t
+	}
+	if v := q.Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		endDate = t.AddDate(0, 0, 1) // inclusive of the whole end day
+	}
+
+	if endDate.Before(startDate) {
+		http.Error(w, "End date cannot be before start date", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.getCSAT(groupBy, startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error getting CSAT report: %v\n", err)
+		http.Error(w, "Failed to generate CSAT report", http.StatusInternalServerError)
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		csv := "Group,Sent,Responses,Response Rate,Avg Rating,CSAT %,1,2,3,4,5\n"
+		for _, row := range rows {
+			avg, pct := "", ""
+			if row.AvgRating != nil {
+				avg = fmt.Sprintf("%.2f", *row.AvgRating)
+			}
+			if row.CSATPercent != nil {
+				pct = fmt.Sprintf("%.1f", *row.CSATPercent)
+			}
+			csv += fmt.Sprintf("%s,%d,%d,%.1f,%s,%s,%d,%d,%d,%d,%d\n",
+				csvField(row.Group), row.Sent, row.Responses, row.ResponseRate, avg, pct,
+				row.Ratings[0], row.Ratings[1], row.Ratings[2], row.Ratings[3], row.Ratings[4])
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=csat_by_%s.csv", groupBy))
+		w.Write([]byte(csv))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group_by":   groupBy,
+		"start_date": startDate.Format("2006-01-02"),
+		"end_date":   endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+		"csat":       rows,
+	})
+}
+
+// Get survey results per group for surveys sent in a date range
+func (h *ReportsHandler) getCSAT(groupBy string, startDate, endDate time.Time) ([]csatRow, error) {
+	group := csatReportGroups[groupBy]
+	query := `
+		SELECT
+			` + group + ` as grp,
+			COUNT(*),
+			COUNT(s.rating),
+			AVG(s.rating)::FLOAT8,
+			COUNT(*) FILTER (WHERE s.rating = 1),
+			COUNT(*) FILTER (WHERE s.rating = 2),
+			COUNT(*) FILTER (WHERE s.rating = 3),
+			COUNT(*) FILTER (WHERE s.rating = 4),
+			COUNT(*) FILTER (WHERE s.rating = 5)
+		FROM ticket_csat_surveys s
+		JOIN tickets t ON t.id = s.ticket_id
+		LEFT JOIN users tech ON tech.id = s.technician_id
+		WHERE s.sent_at >= $1 AND s.sent_at < $2
+		GROUP BY ` + group + `
+		ORDER BY grp
+	`
+
+	rows, err := h.DB.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []csatRow{}
+	for rows.Next() {
+		var row csatRow
+		var avg sql.NullFloat64
+		err := rows.Scan(&row.Group, &row.Sent, &row.Responses, &avg,
+			&row.Ratings[0], &row.Ratings[1], &row.Ratings[2], &row.Ratings[3], &row.Ratings[4])
+		if err != nil {
+			return nil, err
+		}
+
+		if row.Sent > 0 {
+			row.ResponseRate = float64(row.Responses) / float64(row.Sent) * 100
+		}
+		if avg.Valid {
+			row.AvgRating = &avg.Float64
+		}
+		if row.Responses > 0 {
+			pct := float64(row.Ratings[3]+row.Ratings[4]) / float64(row.Responses) * 100
+			row.CSATPercent = &pct
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// canExport checks reports:export for format=csv on a reports:read route. It
+// writes the error response and returns false when the role may not export.
+func (h *ReportsHandler) canExport(w http.ResponseWriter, r *http.Request) bool {
+	_, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return false
+	}
+
+	allowed, err := h.RolesModel.HasPermission(roleID, "reports:export")
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "Forbidden: You cannot export reports", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// csvField quotes a value if it contains a comma, quote or newline
+func csvField(value string) string {
+	if strings.ContainsAny(value, ",\"\n") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
 // Helper method to get comprehensive analytics
 func (h *ReportsHandler) getComprehensiveAnalytics(filter ReportFilter) (map[string]interface{}, error) {
 	analytics := make(map[string]interface{})
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type ReservationsHandler struct {
+	ReservationModel    *models.ReservationModel
+	AssetsModel         *models.AssetsModel
+	CustodyModel        *models.CustodyModel
+	NotificationService *services.NotificationService
+}
+
+func NewReservationsHandler(db *sql.DB) *ReservationsHandler {
+	return &ReservationsHandler{
+		ReservationModel:    models.NewReservationModel(db),
+		AssetsModel:         models.NewAssetsModel(db),
+		CustodyModel:        models.NewCustodyModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// parseReservationTime accepts a full timestamp or a plain date
+func parseReservationTime(value string) (time.Time, error) {
+	formats := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s, expected YYYY-MM-DD or RFC3339", value)
+}
+
+// reservationIDFromPath extracts the reservation ID from /api/v1/reservations/{id}/...
+func reservationIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/reservations/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// reservationErrorStatus maps model errors to HTTP status codes
+func reservationErrorStatus(err error) int {
+	switch err.Error() {
+	case "reservation not found", "asset not found":
+		return http.StatusNotFound
+	case "reservation conflicts with an existing reservation", "asset is still on loan",
+		"reservation not found or not reserved", "reservation not found or not checked out":
+		return http.StatusConflict
+	case "asset is not loanable", "asset is not available for loan",
+		"reservation must end after it starts", "due date must be in the future":
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// loadOwnReservation fetches a reservation the caller may act on: their own,
+// or any reservation for Admin and IT staff
+func (h *ReservationsHandler) loadOwnReservation(w http.ResponseWriter, r *http.Request) (*models.AssetReservation, bool) {
+	id, err := reservationIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+	roleID, _ := r.Context().Value(middleware.ContextRoleID).(int)
+
+	res, err := h.ReservationModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return nil, false
+	}
+
+	if roleID != 1 && roleID != 2 && res.UserID != int64(userID) { // 1=Admin, 2=IT Staff
+		http.Error(w, "Forbidden - not your reservation", http.StatusForbidden)
+		return nil, false
+	}
+
+	return res, true
+}
+
+// GET /api/v1/reservations
+func (h *ReservationsHandler) ListReservations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	roleID, _ := r.Context().Value(middleware.ContextRoleID).(int)
+
+	filter := models.ReservationFilter{
+		Status: r.URL.Query().Get("status"),
+	}
+
+	if assetIDStr := r.URL.Query().Get("asset_id"); assetIDStr != "" {
+		if assetID, err := strconv.ParseInt(assetIDStr, 10, 64); err == nil {
+			filter.AssetID = &assetID
+		}
+	}
+
+	// Admin and IT see everyone's reservations, others only their own
+	if roleID == 1 || roleID == 2 {
+		if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+			if filterUserID, err := strconv.ParseInt(userIDStr, 10, 64); err == nil {
+				filter.UserID = &filterUserID
+			}
+		}
+	} else {
+		ownID := int64(userID)
+		filter.UserID = &ownID
+	}
+
+	reservations, err := h.ReservationModel.GetAll(filter)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reservations)
+}
+
+// POST /api/v1/reservations
+func (h *ReservationsHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	roleID, _ := r.Context().Value(middleware.ContextRoleID).(int)
+
+	var input struct {
+		AssetID  int64  `json:"asset_id"`
+		UserID   *int64 `json:"user_id"` // Borrower, defaults to the caller
+		StartsAt string `json:"starts_at"`
+		EndsAt   string `json:"ends_at"`
+		Purpose  string `json:"purpose"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.AssetID == 0 || input.StartsAt == "" || input.EndsAt == "" {
+		http.Error(w, "asset_id, starts_at and ends_at are required", http.StatusBadRequest)
+		return
+	}
+
+	startsAt, err := parseReservationTime(input.StartsAt)
+	if err != nil {
+		http.Error(w, "StartsAt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	endsAt, err := parseReservationTime(input.EndsAt)
+	if err != nil {
+		http.Error(w, "EndsAt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reservedBy := int64(userID)
+	borrower := reservedBy
+	if input.UserID != nil && *input.UserID != borrower {
+		// Only Admin and IT can book on someone else's behalf
+		if roleID != 1 && roleID != 2 {
+			http.Error(w, "Forbidden - you can only reserve assets for yourself", http.StatusForbidden)
+			return
+		}
+		borrower = *input.UserID
+	}
+
+	res := &models.AssetReservation{
+		AssetID:    input.AssetID,
+		UserID:     borrower,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		Purpose:    input.Purpose,
+		ReservedBy: &reservedBy,
+	}
+
+	if err := h.ReservationModel.Insert(res); err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/v1/reservations/{id}
+func (h *ReservationsHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadOwnReservation(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// POST /api/v1/reservations/{id}/cancel
+func (h *ReservationsHandler) CancelReservation(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadOwnReservation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ReservationModel.Cancel(res.ID); err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Reservation cancelled"})
+}
+
+// POST /api/v1/reservations/{id}/checkout
+func (h *ReservationsHandler) CheckOutLoan(w http.ResponseWriter, r *http.Request) {
+	id, err := reservationIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		DueAt string `json:"due_at"` // Defaults to the end of the reservation
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.ReservationModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return
+	}
+
+	dueAt := res.EndsAt
+	if input.DueAt != "" {
+		dueAt, err = parseReservationTime(input.DueAt)
+		if err != nil {
+			http.Error(w, "DueAt: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Loans go through the same handover acknowledgement as assignments
+	custody, err := h.ReservationModel.CheckOut(id, dueAt, currentUserID(r))
+	if err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return
+	}
+
+	res, err = h.ReservationModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if asset, err := h.AssetsModel.GetByID(res.AssetID); err == nil {
+		go func() {
+			if err := h.NotificationService.NotifyCustodyPending(custody, asset); err != nil {
+				fmt.Printf("Failed to send custody notification: %v\n", err)
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Asset checked out",
+		"reservation": res,
+		"custody":     custody,
+	})
+}
+
+// POST /api/v1/reservations/{id}/return
+func (h *ReservationsHandler) ReturnLoan(w http.ResponseWriter, r *http.Request) {
+	id, err := reservationIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Condition string `json:"condition"`
+		Notes     string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Condition != "" && !models.IsValidCustodyCondition(input.Condition) {
+		http.Error(w, "Invalid condition. Must be one of: new, good, fair, poor, damaged", http.StatusBadRequest)
+		return
+	}
+
+	custodyID, err := h.ReservationModel.Return(id, models.CustodyCheckIn{
+		ReceivedBy: currentUserID(r),
+		Condition:  input.Condition,
+		Notes:      input.Notes,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), reservationErrorStatus(err))
+		return
+	}
+
+	res, err := h.ReservationModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var custody *models.AssetCustody
+	if custodyID != 0 {
+		custody, err = h.CustodyModel.GetByID(custodyID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Asset returned",
+		"reservation": res,
+		"custody":     custody,
+	})
+}
+
+// GET /api/v1/reservations/overdue
+func (h *ReservationsHandler) GetOverdueLoans(w http.ResponseWriter, r *http.Request) {
+	loans, err := h.ReservationModel.GetOverdue()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loans)
+}
+
+// POST /api/v1/reservations/overdue/notify
+func (h *ReservationsHandler) NotifyOverdueLoans(w http.ResponseWriter, r *http.Request) {
+	// At most one reminder per loan per day
+	loans, err := h.ReservationModel.GetOverdueToNotify()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.NotificationService.NotifyLoansOverdue(loans); err != nil {
+		http.Error(w, "Failed to send notifications: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, 0, len(loans))
+	for _, loan := range loans {
+		ids = append(ids, loan.ID)
+	}
+	if err := h.ReservationModel.MarkOverdueNotified(ids); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Overdue notifications sent",
+		"notified": len(loans),
+	})
+}
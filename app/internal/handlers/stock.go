@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type StockHandler struct {
+	StockModel          *models.StockModel
+	NotificationService *services.NotificationService
+}
+
+func NewStockHandler(db *sql.DB) *StockHandler {
+	return &StockHandler{
+		StockModel:          models.NewStockModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// stockItemIDFromPath extracts the item ID from /api/v1/stock/items/{id}/...
+func stockItemIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/stock/items/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// stockErrorStatus maps model errors to HTTP status codes
+func stockErrorStatus(err error) int {
+	switch err.Error() {
+	case "stock item not found", "ticket or service log not found":
+		return http.StatusNotFound
+	case "SKU already exists", "insufficient stock at this location", "stock item has movement history":
+		return http.StatusConflict
+	case "quantity must be positive", "stock-out must be linked to a ticket or service log":
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// notifyLowStock sends a low-stock notification for an item that has just hit its
+// reorder threshold
+func (h *StockHandler) notifyLowStock(itemID int64) {
+	notifyLowStock(h.StockModel, h.NotificationService, itemID)
+}
+
+// notifyLowStock is shared with handlers that take stock out on the side,
+// such as service logs recording the parts they used
+func notifyLowStock(stockModel *models.StockModel, notificationService *services.NotificationService, itemID int64) {
+	go func() {
+		item, err := stockModel.GetItem(itemID)
+		if err != nil {
+			fmt.Printf("Failed to load stock item for notification: %v\n", err)
+			return
+		}
+		if err := notificationService.NotifyLowStock(item); err != nil {
+			fmt.Printf("Failed to send low stock notifications: %v\n", err)
+		}
+	}()
+}
+
+// GET /api/v1/stock/items?category=&low=true
+func (h *StockHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	items, err := h.StockModel.GetItems(q.Get("category"), q.Get("low") == "true")
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// GET /api/v1/stock/low
+func (h *StockHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	items, err := h.StockModel.GetItems("", true)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// POST /api/v1/stock/items
+func (h *StockHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	var item models.StockItem
+
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(item.SKU) == "" || strings.TrimSpace(item.Name) == "" {
+		http.Error(w, "SKU and name are required", http.StatusBadRequest)
+		return
+	}
+	if item.ReorderThreshold < 0 || item.ReorderQuantity < 0 {
+		http.Error(w, "Reorder threshold and quantity cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if item.Unit == "" {
+		item.Unit = "each"
+	}
+	item.TotalQuantity = 0
+
+	if err := h.StockModel.InsertItem(&item); err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// GET /api/v1/stock/items/{id}
+func (h *StockHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.StockModel.GetItem(id)
+	if err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// PUT /api/v1/stock/items/{id}
+func (h *StockHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.StockModel.GetItem(id)
+	if err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	var input struct {
+		SKU              *string `json:"sku"`
+		Name             *string `json:"name"`
+		Category         *string `json:"category"`
+		Unit             *string `json:"unit"`
+		ReorderThreshold *int    `json:"reorder_threshold"`
+		ReorderQuantity  *int    `json:"reorder_quantity"`
+		Notes            *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.SKU != nil {
+		item.SKU = *input.SKU
+	}
+	if input.Name != nil {
+		item.Name = *input.Name
+	}
+	if input.Category != nil {
+		item.Category = *input.Category
+	}
+	if input.Unit != nil {
+		item.Unit = *input.Unit
+	}
+	if input.ReorderThreshold != nil {
+		item.ReorderThreshold = *input.ReorderThreshold
+	}
+	if input.ReorderQuantity != nil {
+		item.ReorderQuantity = *input.ReorderQuantity
+	}
+	if input.Notes != nil {
+		item.Notes = *input.Notes
+	}
+
+	if strings.TrimSpace(item.SKU) == "" || strings.TrimSpace(item.Name) == "" {
+		http.Error(w, "SKU and name are required", http.StatusBadRequest)
+		return
+	}
+	if item.ReorderThreshold < 0 || item.ReorderQuantity < 0 {
+		http.Error(w, "Reorder threshold and quantity cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.StockModel.UpdateItem(item); err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// DELETE /api/v1/stock/items/{id}
+func (h *StockHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.StockModel.DeleteItem(id); err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/stock/items/{id}/movements
+func (h *StockHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	movements, err := h.StockModel.GetMovements(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+// POST /api/v1/stock/items/{id}/in
+func (h *StockHandler) StockIn(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Location  string   `json:"location"`
+		Quantity  int      `json:"quantity"`
+		UnitCost  *float64 `json:"unit_cost"`
+		Reference string   `json:"reference"` // Invoice or order number
+		Notes     string   `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	movement := &models.StockMovement{
+		ItemID:      id,
+		Location:    input.Location,
+		Quantity:    input.Quantity,
+		UnitCost:    input.UnitCost,
+		Reference:   input.Reference,
+		PerformedBy: currentUserID(r),
+		Notes:       input.Notes,
+	}
+
+	if err := h.StockModel.StockIn(movement); err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// POST /api/v1/stock/items/{id}/out
+func (h *StockHandler) StockOut(w http.ResponseWriter, r *http.Request) {
+	id, err := stockItemIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stock item ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Location     string `json:"location"`
+		Quantity     int    `json:"quantity"`
+		TicketID     *int64 `json:"ticket_id"`
+		ServiceLogID *int64 `json:"service_log_id"`
+		Notes        string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	movement := &models.StockMovement{
+		ItemID:       id,
+		Location:     input.Location,
+		Quantity:     input.Quantity,
+		TicketID:     input.TicketID,
+		ServiceLogID: input.ServiceLogID,
+		PerformedBy:  currentUserID(r),
+		Notes:        input.Notes,
+	}
+
+	lowStock, err := h.StockModel.StockOut(movement)
+	if err != nil {
+		http.Error(w, err.Error(), stockErrorStatus(err))
+		return
+	}
+
+	if lowStock {
+		h.notifyLowStock(id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
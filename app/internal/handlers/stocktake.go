@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type StocktakeHandler struct {
+	StocktakeModel      *models.StocktakeModel
+	AssetsModel         *models.AssetsModel
+	NotificationService *services.NotificationService
+}
+
+func NewStocktakeHandler(db *sql.DB) *StocktakeHandler {
+	return &StocktakeHandler{
+		StocktakeModel:      models.NewStocktakeModel(db),
+		AssetsModel:         models.NewAssetsModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// stocktakeIDFromPath extracts the session ID from /api/v1/stocktakes/{id}/...
+func stocktakeIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/stocktakes/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// GET /api/v1/stocktakes
+func (h *StocktakeHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	sessions, err := h.StocktakeModel.GetAll(status)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// POST /api/v1/stocktakes
+func (h *StocktakeHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Name      string `json:"name"`
+		Location  string `json:"location"`
+		AssetType string `json:"asset_type"`
+		Notes     string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if input.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if input.Location == "" && input.AssetType == "" {
+		http.Error(w, "A stocktake must be scoped to a location or an asset type", http.StatusBadRequest)
+		return
+	}
+
+	startedBy := int64(userID)
+	session := &models.StocktakeSession{
+		Name:      input.Name,
+		Location:  input.Location,
+		AssetType: input.AssetType,
+		Notes:     input.Notes,
+		StartedBy: &startedBy,
+	}
+
+	err := h.StocktakeModel.Insert(session)
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// GET /api/v1/stocktakes/{id}
+func (h *StocktakeHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.StocktakeModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "stocktake session not found" {
+			http.Error(w, "Stocktake session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// POST /api/v1/stocktakes/{id}/scans
+func (h *StocktakeHandler) RecordScan(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Code           string `json:"code"` // internal_id or serial_number
+		Location       string `json:"location"`
+		ObservedUserID *int64 `json:"observed_user_id"`
+		Notes          string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	input.Code = strings.TrimSpace(input.Code)
+	if input.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	scannedBy := int64(userID)
+	scan := &models.StocktakeScan{
+		SessionID:      id,
+		ScannedCode:    input.Code,
+		Location:       input.Location,
+		ObservedUserID: input.ObservedUserID,
+		ScannedBy:      &scannedBy,
+		Notes:          input.Notes,
+	}
+
+	err = h.StocktakeModel.InsertScan(scan)
+	if err != nil {
+		if err.Error() == "stocktake session not found or closed" {
+			http.Error(w, "Stocktake session not found or closed", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scan":    scan,
+		"matched": scan.AssetID != nil,
+	})
+}
+
+// GET /api/v1/stocktakes/{id}/scans
+func (h *StocktakeHandler) GetScans(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	scans, err := h.StocktakeModel.GetScans(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+// GET /api/v1/stocktakes/{id}/reconciliation
+func (h *StocktakeHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := h.StocktakeModel.Reconcile(id)
+	if err != nil {
+		if err.Error() == "stocktake session not found" {
+			http.Error(w, "Stocktake session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// POST /api/v1/stocktakes/{id}/close
+func (h *StocktakeHandler) CloseSession(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	rec, err := h.StocktakeModel.Reconcile(id)
+	if err != nil {
+		if err.Error() == "stocktake session not found" {
+			http.Error(w, "Stocktake session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.StocktakeModel.Close(id, int64(userID), rec)
+	if err != nil {
+		if err.Error() == "stocktake session not found or already closed" {
+			http.Error(w, "Stocktake session is already closed", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session, err := h.StocktakeModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Stocktake session closed successfully",
+		"session":        session,
+		"reconciliation": rec,
+	})
+}
+
+// POST /api/v1/stocktakes/{id}/apply
+// Applies all the corrections or, if any cannot be applied, none of them.
+// Corrections to assets changed since the stocktake, or already applied, fail
+// with 409.
+func (h *StocktakeHandler) ApplyCorrections(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Corrections []struct {
+			AssetID int64  `json:"asset_id"`
+			Action  string `json:"action"` // update_location, update_assignee
+		} `json:"corrections"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(input.Corrections) == 0 {
+		http.Error(w, "At least one correction is required", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := h.StocktakeModel.Reconcile(id)
+	if err != nil {
+		if err.Error() == "stocktake session not found" {
+			http.Error(w, "Stocktake session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	findFinding := func(findings []models.StocktakeFinding, assetID int64) *models.StocktakeFinding {
+		for i := range findings {
+			if findings[i].AssetID != nil && *findings[i].AssetID == assetID {
+				return &findings[i]
+			}
+		}
+		return nil
+	}
+
+	// Every correction must match a finding; they are then applied together
+	appliedBy := int64(userID)
+	corrections := make([]models.StocktakeCorrection, 0, len(input.Corrections))
+	for _, c := range input.Corrections {
+		var finding *models.StocktakeFinding
+		switch c.Action {
+		case "update_location":
+			finding = findFinding(rec.WrongLocation, c.AssetID)
+		case "update_assignee":
+			finding = findFinding(rec.WrongAssignee, c.AssetID)
+		default:
+			http.Error(w, fmt.Sprintf("Asset %d: unknown action %s", c.AssetID, c.Action), http.StatusBadRequest)
+			return
+		}
+
+		if finding == nil {
+			http.Error(w, fmt.Sprintf("Asset %d: no matching finding in reconciliation", c.AssetID), http.StatusBadRequest)
+			return
+		}
+
+		correction := models.StocktakeCorrection{
+			SessionID: id,
+			AssetID:   finding.AssetID,
+			Action:    c.Action,
+			AppliedBy: &appliedBy,
+		}
+
+		if c.Action == "update_location" {
+			correction.OldValue = finding.ExpectedLocation
+			correction.NewValue = finding.ScannedLocation
+		} else {
+			if finding.ExpectedUserID != nil {
+				correction.OldValue = strconv.FormatInt(*finding.ExpectedUserID, 10)
+			}
+			correction.NewValue = strconv.FormatInt(*finding.ObservedUserID, 10)
+		}
+		corrections = append(corrections, correction)
+	}
+
+	handovers, err := h.StocktakeModel.ApplyCorrections(corrections)
+	if err != nil {
+		if _, ok := err.(*models.StocktakeCorrectionError); ok {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Reassigned assets go through the same handover acknowledgement as assignments
+	go func() {
+		for _, custody := range handovers {
+			asset, err := h.AssetsModel.GetByID(custody.AssetID)
+			if err != nil {
+				fmt.Printf("Failed to load asset %d for custody notification: %v\n", custody.AssetID, err)
+				continue
+			}
+			if err := h.NotificationService.NotifyCustodyPending(custody, asset); err != nil {
+				fmt.Printf("Failed to send custody notification: %v\n", err)
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"applied": corrections,
+	})
+}
+
+// GET /api/v1/stocktakes/{id}/report
+func (h *StocktakeHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	id, err := stocktakeIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid stocktake ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.StocktakeModel.GetByID(id)
+	if err != nil {
+		if err.Error() == "stocktake session not found" {
+			http.Error(w, "Stocktake session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if session.Status != "closed" {
+		http.Error(w, "Stocktake session must be closed before a report is available", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := h.StocktakeModel.Reconcile(id)
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	corrections, err := h.StocktakeModel.GetCorrections(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session":        session,
+		"reconciliation": rec,
+		"corrections":    corrections,
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+// ticketBulkErrorStatus maps bulk update errors to HTTP status codes
+func ticketBulkErrorStatus(err error) int {
+	var bulkErr *models.TicketBulkError
+	var tagErr *models.TicketTagError
+	if errors.As(err, &bulkErr) || errors.As(err, &tagErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// POST /api/v1/tickets/bulk
+// Body: {"ticket_ids": [12, 13], "changes": {"status": "closed"}}
+//    or {"filter": {"status": "resolved", "assigned_to": 7}, "changes": {"assigned_to": 4, "add_tags": ["handover"]}}
+// Changes: status, priority, assigned_to, add_tags, remove_tags, close.
+// Each ticket is checked as if updated on its own and reported in "results";
+// with "all_or_nothing": true a single skipped ticket rolls back the rest.
+func (h *TicketsHandler) BulkUpdateTickets(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		TicketIDs    []int64                   `json:"ticket_ids"`
+		Filter       *models.TicketViewFilters `json:"filter"`
+		Changes      models.TicketBulkChanges  `json:"changes"`
+		AllOrNothing bool                      `json:"all_or_nothing"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if (len(input.TicketIDs) > 0) == (input.Filter != nil) {
+		http.Error(w, "Give either ticket_ids or filter", http.StatusBadRequest)
+		return
+	}
+
+	ids := input.TicketIDs
+	if input.Filter != nil {
+		f := input.Filter
+		if f.Status == "" && f.Type == "" && f.Priority == "" && f.AssignedTo == nil && !f.AssignedToMe &&
+			f.CreatedBy == nil && !f.CreatedByMe && len(f.Tags) == 0 {
+			http.Error(w, "Filter must have at least one condition", http.StatusBadRequest)
+			return
+		}
+
+		filters := f.TicketFilters(userID, "oldest")
+		if roleID > 2 { // Staff, agents and viewers only reach their own tickets
+			filters.CreatedBy = &userID
+		}
+		filters.Limit = models.MaxBulkTickets + 1
+
+		tickets, err := h.TicketModel.GetAll(filters)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if len(tickets) > models.MaxBulkTickets {
+			http.Error(w, fmt.Sprintf("Filter matches more than %d tickets", models.MaxBulkTickets), http.StatusBadRequest)
+			return
+		}
+		if len(tickets) == 0 {
+			http.Error(w, "Filter matches no tickets", http.StatusBadRequest)
+			return
+		}
+		ids = make([]int64, len(tickets))
+		for i, ticket := range tickets {
+			ids[i] = ticket.ID
+		}
+	}
+
+	if input.Changes.AssignedTo != nil {
+		canAssign, err := h.RolesModel.HasPermission(roleID, "tickets:assign")
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !canAssign {
+			http.Error(w, "Forbidden: You cannot assign tickets", http.StatusForbidden)
+			return
+		}
+		if _, err := h.UsersModel.GetByID(*input.Changes.AssignedTo); err != nil {
+			http.Error(w, "Assigned user not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.TicketModel.BulkUpdate(ids, input.Changes, userID, roleID, input.AllOrNothing)
+	if err != nil {
+		http.Error(w, err.Error(), ticketBulkErrorStatus(err))
+		return
+	}
+
+	summary := map[string]int{}
+	var updated []*models.Ticket
+	for _, result := range results {
+		summary[result.Result]++
+		if result.After != nil {
+			updated = append(updated, result.After)
+		}
+	}
+
+	// One notification and one email per recipient for the whole batch
+	if len(updated) > 0 {
+		go func() {
+			if err := h.NotificationService.NotifyTicketsBulkUpdated(updated, userID); err != nil {
+				fmt.Printf("Failed to send notifications: %v\n", err)
+			}
+			h.sendBulkUpdateEmails(results, userID)
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"summary": summary,
+		"results": results,
+	})
+}
+
+// sendBulkUpdateEmails sends each person affected by a bulk update a single
+// digest: new assignees hear about tickets given to them, creators and
+// assignees about status changes, as sendStatusUpdateEmails does per ticket
+func (h *TicketsHandler) sendBulkUpdateEmails(results []models.TicketBulkResult, updaterID int64) {
+	updatedBy := "IT Support"
+	if updater, err := h.UsersModel.GetByID(updaterID); err == nil {
+		updatedBy = updater.Username
+	}
+
+	var recipients []int64
+	lines := map[int64][]string{}
+	add := func(userID *int64, line string) {
+		if userID == nil || *userID == updaterID {
+			return
+		}
+		if _, ok := lines[*userID]; !ok {
+			recipients = append(recipients, *userID)
+		}
+		lines[*userID] = append(lines[*userID], line)
+	}
+
+	for _, result := range results {
+		before, after := result.Before, result.After
+		if after == nil {
+			continue
+		}
+		label := fmt.Sprintf("%s: %s", after.TicketNum, after.Title)
+
+		if after.AssignedTo != nil && (before.AssignedTo == nil || *before.AssignedTo != *after.AssignedTo) {
+			add(after.AssignedTo, label+" - assigned to you")
+		}
+		if before.Status != after.Status {
+			line := fmt.Sprintf("%s - status changed from %s to %s", label, before.Status, after.Status)
+			add(after.CreatedBy, line)
+			if after.AssignedTo != nil && (after.CreatedBy == nil || *after.AssignedTo != *after.CreatedBy) {
+				add(after.AssignedTo, line)
+			}
+		}
+	}
+
+	for _, userID := range recipients {
+		user, err := h.UsersModel.GetByID(userID)
+		if err != nil || user.Email == "" {
+			continue
+		}
+		if err := h.EmailService.SendTicketBulkUpdateEmail(user.Email, updatedBy, lines[userID]); err != nil {
+			fmt.Printf("Failed to send bulk update email: %v\n", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,14 +16,18 @@ import (
 type TicketCommentsHandler struct {
 	CommentModel *models.TicketCommentModel
 	TicketModel  *models.TicketModel
+	WatcherModel *models.TicketWatcherModel
 	EmailService  *services.EmailService
+	NotificationService *services.NotificationService
 }
 
 func NewTicketCommentsHandler(db *sql.DB, emailService *services.EmailService) *TicketCommentsHandler {
 	return &TicketCommentsHandler{
 		CommentModel: models.NewTicketCommentModel(db),
 		TicketModel:  models.NewTicketModel(db),
+		WatcherModel: models.NewTicketWatcherModel(db),
 		EmailService: emailService, // FIXED: Use the parameter
+		NotificationService: services.NewNotificationService(db),
 	}
 }
 
@@ -51,7 +56,7 @@ func (h *TicketCommentsHandler) CreateComment(w http.ResponseWriter, r *http.Req
 	}
 
 	// Verify ticket exists
-	_, err = h.TicketModel.GetByID(ticketID)
+	ticket, err := h.TicketModel.GetByID(ticketID)
 	if err != nil {
 		if err.Error() == "ticket not found" {
 			http.Error(w, "Ticket not found", http.StatusNotFound)
@@ -90,9 +95,18 @@ func (h *TicketCommentsHandler) CreateComment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Mentioned users start watching the ticket
+	mentioned := h.watchMentions(comment, "")
+
 	//send email notification to ticket watchers
 	go h.sendCommentNotification(comment, ticketID)
 
+	go func() {
+		if err := h.NotificationService.NotifyTicketComment(ticket, comment, mentioned); err != nil {
+			fmt.Printf("Failed to send comment notifications: %v\n", err)
+		}
+	}()
+
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(comment)
@@ -123,7 +137,7 @@ func (h *TicketCommentsHandler) GetComments(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Only IT staff and admins can see internal comments
-	showInternal := (roleID == 1 || roleID == 2) // Admin or IT staff
+	showInternal := models.CanViewInternalComments(int64(roleID))
 
 	comments, err := h.CommentModel.GetByTicketID(ticketID, showInternal)
 	if err != nil {
@@ -188,6 +202,7 @@ func (h *TicketCommentsHandler) UpdateComment(w http.ResponseWriter, r *http.Req
 	}
 
 	// Update comment
+	previousComment := existingComment.Comment
 	existingComment.Comment = input.Comment
 	existingComment.IsInternal = input.IsInternal
 
@@ -197,6 +212,20 @@ func (h *TicketCommentsHandler) UpdateComment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Only users newly mentioned by the edit are notified
+	if mentioned := h.watchMentions(existingComment, previousComment); len(mentioned) > 0 {
+		go func() {
+			ticket, err := h.TicketModel.GetByID(existingComment.TicketID)
+			if err != nil {
+				fmt.Printf("Failed to send mention notifications: %v\n", err)
+				return
+			}
+			if err := h.NotificationService.NotifyTicketMentioned(ticket, existingComment, mentioned); err != nil {
+				fmt.Printf("Failed to send mention notifications: %v\n", err)
+			}
+		}()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingComment)
 }
@@ -247,7 +276,49 @@ func (h *TicketCommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// sendCommentNotification sends email notifications to ticket watchers about a new comment
+// watchMentions adds the users @mentioned in a comment as watchers of its
+// ticket and returns them. Mentions already in previous, the text of an edited
+// comment before the edit, are left alone. Users who cannot read internal
+// comments are not picked up from an internal one.
+func (h *TicketCommentsHandler) watchMentions(comment *models.TicketComment, previous string) []models.User {
+	alreadyMentioned := map[string]bool{}
+	for _, username := range models.ExtractMentions(previous) {
+		alreadyMentioned[strings.ToLower(username)] = true
+	}
+
+	var usernames []string
+	for _, username := range models.ExtractMentions(comment.Comment) {
+		if !alreadyMentioned[strings.ToLower(username)] {
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := h.WatcherModel.ResolveMentions(usernames)
+	if err != nil {
+		fmt.Printf("Failed to resolve mentions: %v\n", err)
+		return nil
+	}
+
+	var mentioned []models.User
+	for _, user := range users {
+		if comment.IsInternal && !models.CanViewInternalComments(user.RoleID) {
+			continue
+		}
+		if err := h.WatcherModel.Add(comment.TicketID, user.ID, "mention", comment.AuthorID); err != nil {
+			fmt.Printf("Failed to add mentioned watcher: %v\n", err)
+			continue
+		}
+		mentioned = append(mentioned, user)
+	}
+
+	return mentioned
+}
+
+// sendCommentNotification sends email notifications to the ticket's creator,
+// assignee and watchers about a new comment
 func (h *TicketCommentsHandler) sendCommentNotification(comment *models.TicketComment, ticketID int64) {
 	// Get ticket details
 	ticket, err := h.TicketModel.GetByID(ticketID)
@@ -264,45 +335,31 @@ func (h *TicketCommentsHandler) sendCommentNotification(comment *models.TicketCo
 		).Scan(&authorUsername)
 	}
 
-	// Notify ticket creator (if different from comment author)
-	if ticket.CreatedBy != nil && (comment.AuthorID == nil || *ticket.CreatedBy != *comment.AuthorID) {
-		var creatorEmail string
-		err := h.TicketModel.DB.QueryRow(
-			"SELECT email FROM users WHERE id = $1", 
-			ticket.CreatedBy,
-		).Scan(&creatorEmail)
-		
-		if err == nil && creatorEmail != "" {
-			h.EmailService.SendTicketCommentEmail(
-				creatorEmail,
-				ticket.TicketNum,
-				ticket.Title,
-				comment.Comment,
-				authorUsername,
-			)
-		}
+	recipients, err := h.WatcherModel.GetRecipients(ticketID)
+	if err != nil {
+		return
 	}
 
-	// Notify assigned user (if different from comment author and creator)
-	if ticket.AssignedTo != nil && 
-	   (comment.AuthorID == nil || *ticket.AssignedTo != *comment.AuthorID) &&
-	   (ticket.CreatedBy == nil || *ticket.AssignedTo != *ticket.CreatedBy) {
-		
-		var assigneeEmail string
-		err := h.TicketModel.DB.QueryRow(
-			"SELECT email FROM users WHERE id = $1", 
-			ticket.AssignedTo,
-		).Scan(&assigneeEmail)
-		
-		if err == nil && assigneeEmail != "" {
-			h.EmailService.SendTicketCommentEmail(
-				assigneeEmail,
-				ticket.TicketNum,
-				ticket.Title,
-				comment.Comment,
-				authorUsername,
-			)
+	for _, user := range recipients {
+		// Skip the comment author
+		if comment.AuthorID != nil && user.ID == *comment.AuthorID {
+			continue
 		}
+		// Internal comments only go to users who can read them
+		if comment.IsInternal && !models.CanViewInternalComments(user.RoleID) {
+			continue
+		}
+		if user.Email == "" {
+			continue
+		}
+
+		h.EmailService.SendTicketCommentEmail(
+			user.Email,
+			ticket.TicketNum,
+			ticket.Title,
+			comment.Comment,
+			authorUsername,
+		)
 	}
 }
 
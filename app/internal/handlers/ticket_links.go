@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type TicketLinksHandler struct {
+	TicketModel         *models.TicketModel
+	NotificationService *services.NotificationService
+}
+
+func NewTicketLinksHandler(db *sql.DB) *TicketLinksHandler {
+	return &TicketLinksHandler{
+		TicketModel:         models.NewTicketModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// ticketRelationErrorStatus maps model errors to HTTP status codes
+func ticketRelationErrorStatus(err error) int {
+	var relationErr *models.TicketRelationError
+	if errors.As(err, &relationErr) {
+		return http.StatusConflict
+	}
+
+	switch err.Error() {
+	case "ticket not found", "link not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/v1/tickets/{id}/links
+func (h *TicketLinksHandler) GetLinks(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	links, err := h.TicketModel.GetLinks(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// POST /api/v1/tickets/{id}/links
+// Body: {"ticket_id": 42, "relation": "blocked_by"}
+func (h *TicketLinksHandler) AddLink(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		TicketID int64  `json:"ticket_id"`
+		Relation string `json:"relation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.TicketID <= 0 {
+		http.Error(w, "ticket_id is required", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidTicketRelation(input.Relation) {
+		http.Error(w, "relation must be duplicates, duplicated_by, blocks, blocked_by, relates_to, parent_of or child_of", http.StatusBadRequest)
+		return
+	}
+
+	// A link changes both tickets, e.g. child_of stops the parent closing
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) ||
+		!checkTicketAccess(w, h.TicketModel, input.TicketID, userID, roleID) {
+		return
+	}
+
+	if _, err := h.TicketModel.AddLink(ticketID, input.TicketID, input.Relation, currentUserID(r)); err != nil {
+		http.Error(w, err.Error(), ticketRelationErrorStatus(err))
+		return
+	}
+
+	links, err := h.TicketModel.GetLinks(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(links)
+}
+
+// DELETE /api/v1/tickets/{id}/links/{linkID}
+func (h *TicketLinksHandler) RemoveLink(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	linkID, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	links, err := h.TicketModel.GetLinks(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var otherID int64
+	for _, link := range links {
+		if link.ID == linkID {
+			otherID = link.TicketID
+		}
+	}
+	if otherID == 0 {
+		http.Error(w, "link not found", http.StatusNotFound)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, otherID, userID, roleID) {
+		return
+	}
+
+	if err := h.TicketModel.RemoveLink(ticketID, linkID); err != nil {
+		http.Error(w, err.Error(), ticketRelationErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/tickets/{id}/merge
+// Body: {"duplicate_ids": [12, 13, 14]}
+// Folds the duplicates into this ticket and closes them
+func (h *TicketLinksHandler) MergeTickets(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		DuplicateIDs []int64 `json:"duplicate_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(input.DuplicateIDs) == 0 {
+		http.Error(w, "duplicate_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	mergedBy := currentUserID(r)
+	if err := h.TicketModel.Merge(ticketID, input.DuplicateIDs, mergedBy); err != nil {
+		http.Error(w, err.Error(), ticketRelationErrorStatus(err))
+		return
+	}
+
+	ticket, err := h.TicketModel.GetByID(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Watchers moved over from the duplicates hear about the merge too
+	go func() {
+		var updater int64
+		if mergedBy != nil {
+			updater = *mergedBy
+		}
+		if err := h.NotificationService.NotifyTicketUpdated(ticket, updater, "merged"); err != nil {
+			fmt.Printf("Failed to send notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ticket)
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type TicketMacrosHandler struct {
+	MacroModel  *models.TicketMacroModel
+	TicketModel *models.TicketModel
+	Comments    *TicketCommentsHandler // Posts the comment and notifies like a normal one
+}
+
+func NewTicketMacrosHandler(db *sql.DB, emailService *services.EmailService) *TicketMacrosHandler {
+	return &TicketMacrosHandler{
+		MacroModel:  models.NewTicketMacroModel(db),
+		TicketModel: models.NewTicketModel(db),
+		Comments:    NewTicketCommentsHandler(db, emailService),
+	}
+}
+
+// macroErrorStatus maps model errors to HTTP status codes
+func macroErrorStatus(err error) int {
+	var macroErr *models.TicketMacroError
+	if errors.As(err, &macroErr) {
+		return http.StatusBadRequest
+	}
+	var statusErr *models.TicketStatusError
+	if errors.As(err, &statusErr) {
+		return http.StatusBadRequest
+	}
+	var relationErr *models.TicketRelationError
+	if errors.As(err, &relationErr) {
+		return http.StatusConflict
+	}
+
+	switch err.Error() {
+	case "macro not found", "ticket not found":
+		return http.StatusNotFound
+	case "a macro with this name already exists":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// macroIDFromPath extracts the macro ID from /api/v1/ticket-macros/{id} or
+// /api/v1/tickets/{id}/macros/{macroID}/render
+func macroIDFromPath(path string) (int64, error) {
+	if rest, ok := strings.CutPrefix(path, "/api/v1/ticket-macros/"); ok {
+		return strconv.ParseInt(strings.Split(rest, "/")[0], 10, 64)
+	}
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 {
+		return 0, errors.New("invalid path")
+	}
+	return strconv.ParseInt(parts[len(parts)-2], 10, 64)
+}
+
+// macroInput is the body of a create or update
+type macroInput struct {
+	Name        string `json:"name"`
+	Body        string `json:"body"`
+	IsInternal  bool   `json:"is_internal"`
+	SetStatus   string `json:"set_status"`
+	SetPriority string `json:"set_priority"`
+	IsShared    bool   `json:"is_shared"`
+}
+
+// GET /api/v1/ticket-macros
+// The user's own macros followed by shared ones
+func (h *TicketMacrosHandler) ListMacros(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macros, err := h.MacroModel.GetVisible(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"macros":       macros,
+		"placeholders": models.MacroPlaceholders,
+	})
+}
+
+// GET /api/v1/ticket-macros/{id}
+func (h *TicketMacrosHandler) GetMacro(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macroID, err := macroIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid macro ID", http.StatusBadRequest)
+		return
+	}
+
+	macro, err := h.MacroModel.GetByID(macroID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(macro)
+}
+
+// POST /api/v1/ticket-macros
+// Body: {"name": "Password reset done", "body": "Hi {{requester_name}}, ...",
+//        "set_status": "resolved", "set_priority": "", "is_internal": false, "is_shared": true}
+func (h *TicketMacrosHandler) CreateMacro(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input macroInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macro := &models.TicketMacro{
+		UserID:      userID,
+		Name:        input.Name,
+		Body:        input.Body,
+		IsInternal:  input.IsInternal,
+		SetStatus:   input.SetStatus,
+		SetPriority: input.SetPriority,
+		IsShared:    input.IsShared,
+	}
+	if err := h.MacroModel.Insert(macro); err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(macro)
+}
+
+// PUT /api/v1/ticket-macros/{id}
+// Owners edit their own macros; admins can edit any
+func (h *TicketMacrosHandler) UpdateMacro(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macroID, err := macroIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid macro ID", http.StatusBadRequest)
+		return
+	}
+
+	var input macroInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macro := &models.TicketMacro{
+		ID:          macroID,
+		Name:        input.Name,
+		Body:        input.Body,
+		IsInternal:  input.IsInternal,
+		SetStatus:   input.SetStatus,
+		SetPriority: input.SetPriority,
+		IsShared:    input.IsShared,
+	}
+	if err := h.MacroModel.Update(macro, userID, roleID == 1); err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(macro)
+}
+
+// DELETE /api/v1/ticket-macros/{id}
+// Owners delete their own macros; admins can delete any
+func (h *TicketMacrosHandler) DeleteMacro(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macroID, err := macroIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid macro ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.MacroModel.Delete(macroID, userID, roleID == 1); err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadMacroAndTicket loads the macro and ticket named by
+// /api/v1/tickets/{id}/macros/{macroID}/...
+func (h *TicketMacrosHandler) loadMacroAndTicket(r *http.Request, userID int64) (*models.TicketMacro, *models.Ticket, error) {
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		return nil, nil, &models.TicketMacroError{Message: "invalid ticket ID"}
+	}
+	macroID, err := macroIDFromPath(r.URL.Path)
+	if err != nil {
+		return nil, nil, &models.TicketMacroError{Message: "invalid macro ID"}
+	}
+
+	macro, err := h.MacroModel.GetByID(macroID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ticket, err := h.TicketModel.GetByID(ticketID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return macro, ticket, nil
+}
+
+// GET /api/v1/tickets/{id}/macros/{macroID}/render
+// Previews the macro's comment for the ticket without posting it
+func (h *TicketMacrosHandler) RenderMacro(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macro, ticket, err := h.loadMacroAndTicket(r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"macro_id":     macro.ID,
+		"ticket_id":    ticket.ID,
+		"comment":      models.RenderMacro(macro.Body, ticket),
+		"is_internal":  macro.IsInternal,
+		"set_status":   macro.SetStatus,
+		"set_priority": macro.SetPriority,
+	})
+}
+
+// POST /api/v1/tickets/{id}/macros/{macroID}/apply
+// Body (optional): {"comment": "edited text"} to post an edited rendering
+// Applies the macro's status and priority, then posts its comment
+func (h *TicketMacrosHandler) ApplyMacro(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	macro, ticket, err := h.loadMacroAndTicket(r, userID)
+	if err != nil {
+		http.Error(w, err.Error(), macroErrorStatus(err))
+		return
+	}
+	if reason := models.TicketEditDenied(ticket, userID, roleID); reason != "" {
+		http.Error(w, "Forbidden: "+reason, http.StatusForbidden)
+		return
+	}
+
+	var input struct {
+		Comment *string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	text := models.RenderMacro(macro.Body, ticket)
+	if input.Comment != nil {
+		text = strings.TrimSpace(*input.Comment)
+	}
+	if text == "" {
+		http.Error(w, "Comment is required", http.StatusBadRequest)
+		return
+	}
+
+	// The status, priority and comment are saved together, so a ticket that
+	// cannot be closed doesn't get a comment saying it was. The status goes
+	// through the same validation as UpdateTicketStatus.
+	tx, err := h.TicketModel.DB.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if macro.SetStatus != "" && macro.SetStatus != ticket.Status {
+		completion := ticket.Completion
+		if macro.SetStatus == "resolved" || macro.SetStatus == "closed" {
+			completion = 100
+		}
+		if err := h.TicketModel.UpdateStatusTx(tx, ticket.ID, macro.SetStatus, completion, ticket.AssignedTo); err != nil {
+			http.Error(w, err.Error(), macroErrorStatus(err))
+			return
+		}
+	}
+	if macro.SetPriority != "" && macro.SetPriority != ticket.Priority {
+		if err := h.TicketModel.UpdatePriorityTx(tx, ticket.ID, macro.SetPriority); err != nil {
+			http.Error(w, err.Error(), macroErrorStatus(err))
+			return
+		}
+	}
+
+	comment := &models.TicketComment{
+		TicketID:   ticket.ID,
+		AuthorID:   &userID,
+		Comment:    text,
+		IsInternal: macro.IsInternal,
+	}
+	if err := h.Comments.CommentModel.InsertTx(tx, comment); err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	mentioned := h.Comments.watchMentions(comment, "")
+	go h.Comments.sendCommentNotification(comment, ticket.ID)
+	go func() {
+		if err := h.Comments.NotificationService.NotifyTicketComment(ticket, comment, mentioned); err != nil {
+			fmt.Printf("Failed to send comment notifications: %v\n", err)
+		}
+	}()
+
+	updatedTicket, err := h.TicketModel.GetByID(ticket.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"comment": comment,
+		"ticket":  updatedTicket,
+	})
+}
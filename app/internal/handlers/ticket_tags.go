@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type TicketTagsHandler struct {
+	TagModel    *models.TicketTagModel
+	TicketModel *models.TicketModel
+}
+
+func NewTicketTagsHandler(db *sql.DB) *TicketTagsHandler {
+	return &TicketTagsHandler{
+		TagModel:    models.NewTicketTagModel(db),
+		TicketModel: models.NewTicketModel(db),
+	}
+}
+
+// tagIDFromPath extracts the tag ID from /api/v1/ticket-tags/{id}
+func tagIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/ticket-tags/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// tagErrorStatus maps model errors to HTTP status codes
+func tagErrorStatus(err error) int {
+	var tagErr *models.TicketTagError
+	if errors.As(err, &tagErr) {
+		return http.StatusBadRequest
+	}
+
+	switch err.Error() {
+	case "tag not found", "ticket not found":
+		return http.StatusNotFound
+	case "tag already exists":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/v1/ticket-tags?include_inactive=true
+func (h *TicketTagsHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("include_inactive") == "true"
+
+	tags, err := h.TagModel.GetAll(includeInactive)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// POST /api/v1/ticket-tags
+func (h *TicketTagsHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.TicketTag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TagModel.Insert(&tag); err != nil {
+		http.Error(w, err.Error(), tagErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// PUT /api/v1/ticket-tags/{id}
+func (h *TicketTagsHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := tagIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	tag := models.TicketTag{IsActive: true}
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag.ID = id
+
+	if err := h.TagModel.Update(&tag); err != nil {
+		http.Error(w, err.Error(), tagErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// DELETE /api/v1/ticket-tags/{id}
+// Removes the tag from every ticket; retire it with is_active=false to keep history
+func (h *TicketTagsHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := tagIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TagModel.Delete(id); err != nil {
+		http.Error(w, err.Error(), tagErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /api/v1/tickets/{id}/tags
+// Body: {"tags": ["headsets", "outage"]} replaces the ticket's tags
+func (h *TicketTagsHandler) SetTicketTags(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := h.TagModel.SetTicketTags(ticketID, input.Tags, &userID)
+	if err != nil {
+		http.Error(w, err.Error(), tagErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticket_id": ticketID,
+		"tags":      tags,
+	})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type TicketViewsHandler struct {
+	ViewModel *models.TicketViewModel
+}
+
+func NewTicketViewsHandler(db *sql.DB) *TicketViewsHandler {
+	return &TicketViewsHandler{
+		ViewModel: models.NewTicketViewModel(db),
+	}
+}
+
+// viewIDFromPath extracts the view ID from /api/v1/tickets/views/{id}
+func viewIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/tickets/views/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// viewErrorStatus maps model errors to HTTP status codes
+func viewErrorStatus(err error) int {
+	switch err.Error() {
+	case "view not found":
+		return http.StatusNotFound
+	case "a view with this name already exists":
+		return http.StatusConflict
+	case "view name is required", "unknown sort":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// viewUser returns the current user's ID and role from the request context
+func viewUser(r *http.Request) (int64, int64, bool) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		return 0, 0, false
+	}
+	roleID, ok := r.Context().Value(middleware.ContextRoleID).(int)
+	if !ok {
+		return 0, 0, false
+	}
+	return int64(userID), int64(roleID), true
+}
+
+// GET /api/v1/tickets/views
+// The user's own views, then views shared with their role
+func (h *TicketViewsHandler) ListViews(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	views, err := h.ViewModel.GetVisible(userID, roleID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+// GET /api/v1/tickets/views/{id}
+// Open the view's tickets with GET /api/v1/tickets?view={id}
+func (h *TicketViewsHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := viewIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid view ID", http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.ViewModel.GetByID(id, userID, roleID)
+	if err != nil {
+		http.Error(w, err.Error(), viewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// ticketViewInput is the body of a create or update
+type ticketViewInput struct {
+	Name         string                   `json:"name"`
+	Filters      models.TicketViewFilters `json:"filters"`
+	Sort         string                   `json:"sort"`
+	SharedRoleID *int64                   `json:"shared_role_id"` // Optional, share with everyone in this role
+}
+
+// POST /api/v1/tickets/views
+func (h *TicketViewsHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var input ticketViewInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view := &models.TicketView{
+		UserID:       userID,
+		Name:         input.Name,
+		Filters:      input.Filters,
+		Sort:         input.Sort,
+		SharedRoleID: input.SharedRoleID,
+	}
+	if err := h.ViewModel.Insert(view); err != nil {
+		http.Error(w, err.Error(), viewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(view)
+}
+
+// PUT /api/v1/tickets/views/{id}
+func (h *TicketViewsHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := viewIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid view ID", http.StatusBadRequest)
+		return
+	}
+
+	var input ticketViewInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view := &models.TicketView{
+		ID:           id,
+		UserID:       userID,
+		Name:         input.Name,
+		Filters:      input.Filters,
+		Sort:         input.Sort,
+		SharedRoleID: input.SharedRoleID,
+	}
+	if err := h.ViewModel.Update(view); err != nil {
+		http.Error(w, err.Error(), viewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// DELETE /api/v1/tickets/views/{id}
+func (h *TicketViewsHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := viewIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid view ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ViewModel.Delete(id, userID); err != nil {
+		http.Error(w, err.Error(), viewErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
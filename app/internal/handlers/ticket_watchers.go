@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type TicketWatchersHandler struct {
+	WatcherModel *models.TicketWatcherModel
+	TicketModel  *models.TicketModel
+}
+
+func NewTicketWatchersHandler(db *sql.DB) *TicketWatchersHandler {
+	return &TicketWatchersHandler{
+		WatcherModel: models.NewTicketWatcherModel(db),
+		TicketModel:  models.NewTicketModel(db),
+	}
+}
+
+// ticketIDFromPath extracts the ticket ID from /api/v1/tickets/{id}/...
+func ticketIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/tickets/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// checkTicketAccess applies UpdateTicket's per-ticket rule before an action on
+// one ticket. It writes the error response and returns false when the user
+// may not.
+func checkTicketAccess(w http.ResponseWriter, tickets *models.TicketModel, ticketID, userID, roleID int64) bool {
+	ticket, err := tickets.GetByID(ticketID)
+	if err != nil {
+		if err.Error() == "ticket not found" {
+			http.Error(w, "Ticket not found", http.StatusNotFound)
+			return false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+
+	if reason := models.TicketEditDenied(ticket, userID, roleID); reason != "" {
+		http.Error(w, "Forbidden: "+reason, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// watcherErrorStatus maps model errors to HTTP status codes
+func watcherErrorStatus(err error) int {
+	switch err.Error() {
+	case "ticket not found", "watcher not found":
+		return http.StatusNotFound
+	case "user not found or inactive":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /api/v1/tickets/{id}/watchers
+func (h *TicketWatchersHandler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	watchers, err := h.WatcherModel.GetByTicket(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(watchers)
+}
+
+// POST /api/v1/tickets/{id}/watchers
+// Adds another user as a watcher, e.g. CCs a team lead after the ticket was raised
+func (h *TicketWatchersHandler) AddWatcher(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	var input struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.UserID <= 0 {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.WatcherModel.Add(ticketID, input.UserID, "cc", &userID); err != nil {
+		http.Error(w, err.Error(), watcherErrorStatus(err))
+		return
+	}
+
+	h.GetWatchers(w, r)
+}
+
+// DELETE /api/v1/tickets/{id}/watchers/{userID}
+func (h *TicketWatchersHandler) RemoveWatcher(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	watcherID, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.WatcherModel.Remove(ticketID, watcherID); err != nil {
+		http.Error(w, err.Error(), watcherErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/tickets/{id}/watch
+func (h *TicketWatchersHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	if err := h.WatcherModel.Add(ticketID, userID, "manual", &userID); err != nil {
+		http.Error(w, err.Error(), watcherErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/tickets/{id}/watch
+func (h *TicketWatchersHandler) Unwatch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.WatcherModel.Remove(ticketID, int64(userID)); err != nil {
+		http.Error(w, err.Error(), watcherErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
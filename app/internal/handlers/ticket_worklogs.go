@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+type TicketWorklogsHandler struct {
+	WorklogModel *models.TicketWorklogModel
+	TicketModel  *models.TicketModel
+}
+
+func NewTicketWorklogsHandler(db *sql.DB) *TicketWorklogsHandler {
+	return &TicketWorklogsHandler{
+		WorklogModel: models.NewTicketWorklogModel(db),
+		TicketModel:  models.NewTicketModel(db),
+	}
+}
+
+// worklogErrorStatus maps model errors to HTTP status codes
+func worklogErrorStatus(err error) int {
+	var worklogErr *models.WorklogError
+	if errors.As(err, &worklogErr) {
+		return http.StatusBadRequest
+	}
+
+	switch err.Error() {
+	case "ticket not found", "worklog not found", "no timer running on this ticket":
+		return http.StatusNotFound
+	case "a timer is already running":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// worklogIDFromPath extracts the worklog ID from /api/v1/tickets/{id}/worklogs/{worklogID}
+func worklogIDFromPath(path string) (int64, error) {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	return strconv.ParseInt(parts[len(parts)-1], 10, 64)
+}
+
+// worklogInput is the body of a create or update
+type worklogInput struct {
+	Minutes  int    `json:"minutes"`
+	WorkDate string `json:"work_date"` // YYYY-MM-DD, defaults to today
+	Note     string `json:"note"`
+	Billable bool   `json:"billable"`
+}
+
+// GET /api/v1/tickets/{id}/worklogs
+func (h *TicketWorklogsHandler) GetWorklogs(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	worklogs, err := h.WorklogModel.GetByTicket(ticketID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(worklogs)
+}
+
+// POST /api/v1/tickets/{id}/worklogs
+// Body: {"minutes": 45, "work_date": "2025-06-02", "note": "Reimaged PC", "billable": true}
+func (h *TicketWorklogsHandler) AddWorklog(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	var input worklogInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	worklog := &models.TicketWorklog{
+		TicketID: ticketID,
+		UserID:   &userID,
+		Minutes:  input.Minutes,
+		WorkDate: input.WorkDate,
+		Note:     input.Note,
+		Billable: input.Billable,
+	}
+	if err := h.WorklogModel.Insert(worklog); err != nil {
+		http.Error(w, err.Error(), worklogErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(worklog)
+}
+
+// PUT /api/v1/tickets/{id}/worklogs/{worklogID}
+// Users edit their own entries; admins can edit any
+func (h *TicketWorklogsHandler) UpdateWorklog(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	worklogID, err := worklogIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid worklog ID", http.StatusBadRequest)
+		return
+	}
+
+	var input worklogInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	worklog := &models.TicketWorklog{
+		ID:       worklogID,
+		TicketID: ticketID,
+		Minutes:  input.Minutes,
+		WorkDate: input.WorkDate,
+		Note:     input.Note,
+		Billable: input.Billable,
+	}
+	if err := h.WorklogModel.Update(worklog, userID, roleID == 1); err != nil {
+		http.Error(w, err.Error(), worklogErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(worklog)
+}
+
+// DELETE /api/v1/tickets/{id}/worklogs/{worklogID}
+// Users delete their own entries; admins can delete any
+func (h *TicketWorklogsHandler) DeleteWorklog(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	worklogID, err := worklogIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid worklog ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.WorklogModel.Delete(worklogID, ticketID, userID, roleID == 1); err != nil {
+		http.Error(w, err.Error(), worklogErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/tickets/timer
+// The current user's running timer, or null
+func (h *TicketWorklogsHandler) GetRunningTimer(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	timer, err := h.WorklogModel.GetRunningTimer(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timer)
+}
+
+// POST /api/v1/tickets/{id}/timer/start
+// A user can only run one timer at a time
+func (h *TicketWorklogsHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, roleID, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	if !checkTicketAccess(w, h.TicketModel, ticketID, userID, roleID) {
+		return
+	}
+
+	timer, err := h.WorklogModel.StartTimer(ticketID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), worklogErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(timer)
+}
+
+// POST /api/v1/tickets/{id}/timer/stop
+// Body (optional): {"note": "Replaced headset", "billable": true}
+// Stops the timer and logs the elapsed time as a worklog
+func (h *TicketWorklogsHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, _, ok := viewUser(r)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticketID, err := ticketIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Note     string `json:"note"`
+		Billable bool   `json:"billable"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	worklog, err := h.WorklogModel.StopTimer(ticketID, userID, input.Note, input.Billable)
+	if err != nil {
+		http.Error(w, err.Error(), worklogErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(worklog)
+}
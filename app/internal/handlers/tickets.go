@@ -4,6 +4,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,6 +19,11 @@ type TicketsHandler struct {
 	TicketModel *models.TicketModel
 	UsersModel  *models.UsersModel
 	AssetsModel *models.AssetsModel
+	WatcherModel *models.TicketWatcherModel
+	ViewModel   *models.TicketViewModel
+	CSATModel   *models.CSATModel
+	KBModel     *models.KnowledgeBaseModel
+	RolesModel  *models.RolesModel
 	EmailService *services.EmailService
 	NotificationService  *services.NotificationService
 }
@@ -27,12 +33,18 @@ func NewTicketsHandler(db *sql.DB, emailService *services.EmailService) *Tickets
 		TicketModel: models.NewTicketModel(db),
 		UsersModel:  models.NewUsersModel(db),
 		AssetsModel: models.NewAssetsModel(db),
+		WatcherModel: models.NewTicketWatcherModel(db),
+		ViewModel:   models.NewTicketViewModel(db),
+		CSATModel:   models.NewCSATModel(db),
+		KBModel:     models.NewKnowledgeBaseModel(db),
+		RolesModel:  models.NewRolesModel(db),
 		NotificationService: services.NewNotificationService(db),
 		EmailService: emailService, // FIXED: Use the parameter
 	}
 }
 
-// GET /api/v1/tickets
+// GET /api/v1/tickets?view=3&tags=headsets,outage&sort=priority
+// A saved view supplies the starting filters; other parameters override them.
 func (h *TicketsHandler) ListTickets(w http.ResponseWriter, r *http.Request) {
 	// Get current user from context (set by auth middleware)
 	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
@@ -54,10 +66,43 @@ func (h *TicketsHandler) ListTickets(w http.ResponseWriter, r *http.Request) {
 	assignedToStr := r.URL.Query().Get("assigned_to")
 	createdByStr := r.URL.Query().Get("created_by")
 
-	filters := models.TicketFilters{
-		Status:   status,
-		Type:     typeFilter,
-		Priority: priority,
+	filters := models.TicketFilters{}
+	if viewStr := r.URL.Query().Get("view"); viewStr != "" {
+		viewID, err := strconv.ParseInt(viewStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid view", http.StatusBadRequest)
+			return
+		}
+		view, err := h.ViewModel.GetByID(viewID, int64(userID), int64(roleID))
+		if err != nil {
+			if err.Error() == "view not found" {
+				http.Error(w, "View not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		filters = view.Filters.TicketFilters(int64(userID), view.Sort)
+	}
+
+	if status != "" {
+		filters.Status = status
+	}
+	if typeFilter != "" {
+		filters.Type = typeFilter
+	}
+	if priority != "" {
+		filters.Priority = priority
+	}
+	if tags := r.URL.Query().Get("tags"); tags != "" {
+		filters.Tags = strings.Split(tags, ",")
+	}
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		if _, ok := models.TicketSortOptions[sort]; !ok {
+			http.Error(w, "Invalid sort", http.StatusBadRequest)
+			return
+		}
+		filters.Sort = sort
 	}
 
 	// Handle role-based filtering
@@ -65,7 +110,7 @@ func (h *TicketsHandler) ListTickets(w http.ResponseWriter, r *http.Request) {
 	case 1: // Admin - can see all tickets
 		// No additional filters
 	case 2: // IT Staff - can see assigned tickets and all open tickets
-		if assignedToStr == "" {
+		if assignedToStr == "" && filters.AssignedTo == nil {
 			// IT staff sees tickets assigned to them OR unassigned tickets
 			filters.AssignedTo = &[]int64{int64(userID), 0}[0] // This needs refinement
 		}
@@ -118,6 +163,79 @@ func (h *TicketsHandler) ListTickets(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tickets)
 }
 
+// GET /api/v1/tickets/search?q=printer jam&limit=20&offset=0
+// Full-text search over ticket number, title, description and comments.
+// Internal comments are only searched for admins and IT staff.
+func (h *TicketsHandler) SearchTickets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	roleID, ok := r.Context().Value(middleware.ContextRoleID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "Search query q is required", http.StatusBadRequest)
+		return
+	}
+
+	filters := models.TicketFilters{
+		Status:   r.URL.Query().Get("status"),
+		Type:     r.URL.Query().Get("type"),
+		Priority: r.URL.Query().Get("priority"),
+		Limit:    20,
+	}
+
+	// Same visibility as ListTickets
+	switch roleID {
+	case 2: // IT Staff - tickets assigned to them
+		assignedTo := int64(userID)
+		filters.AssignedTo = &assignedTo
+	case 3: // Staff/Team Leads - tickets they created
+		createdBy := int64(userID)
+		filters.CreatedBy = &createdBy
+	case 4, 5: // Agents and viewers - only tickets they created
+		agentView := int64(userID)
+		filters.AgentView = &agentView
+	}
+
+	// Parse pagination
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters.Limit = limit
+		}
+	}
+	if filters.Limit > 100 {
+		filters.Limit = 100
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			filters.Offset = offset
+		}
+	}
+
+	includeInternal := models.CanViewInternalComments(int64(roleID))
+	results, total, err := h.TicketModel.Search(q, includeInternal, filters)
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"results": results,
+		"total":   total,
+		"limit":   filters.Limit,
+		"offset":  filters.Offset,
+	})
+}
+
 // GET /api/v1/tickets/{id}
 func (h *TicketsHandler) GetTicket(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/tickets/")
@@ -157,6 +275,8 @@ func (h *TicketsHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		Priority    string `json:"priority"`
 		AssetID     *int64 `json:"asset_id"`
 		IsInternal  bool   `json:"is_internal"`
+		Campaign    string `json:"campaign"`
+		CCUserIDs   []int64 `json:"cc_user_ids"` // Users to add as watchers, e.g. team leads
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -187,6 +307,14 @@ func (h *TicketsHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Validate CC'd users exist
+	for _, ccID := range input.CCUserIDs {
+		if _, err := h.UsersModel.GetByID(ccID); err != nil {
+			http.Error(w, fmt.Sprintf("CC user %d not found", ccID), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Set default priority if not provided
 	if input.Priority == "" {
 		input.Priority = "normal"
@@ -203,6 +331,7 @@ func (h *TicketsHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		CreatedBy:   &createdBy,
 		AssetID:     input.AssetID,
 		IsInternal:  input.IsInternal,
+		Campaign:    strings.TrimSpace(input.Campaign),
 	}
 
 	err := h.TicketModel.Insert(ticket)
@@ -211,6 +340,16 @@ func (h *TicketsHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// CC'd users watch the ticket from the start, so they are notified of its creation
+	for _, ccID := range input.CCUserIDs {
+		if ccID == createdBy {
+			continue
+		}
+		if err := h.WatcherModel.Add(ticket.ID, ccID, "cc", &createdBy); err != nil {
+			fmt.Printf("Failed to CC user %d on ticket %d: %v\n", ccID, ticket.ID, err)
+		}
+	}
+
 	// FIXED: Send notifications for new ticket
 	go func() {
 		if err := h.NotificationService.NotifyTicketCreated(ticket); err != nil {
@@ -218,6 +357,14 @@ func (h *TicketsHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Suggest articles so the requester can try a fix while IT picks it up
+	roleID, _ := r.Context().Value(middleware.ContextRoleID).(int)
+	suggested, err := h.KBModel.SuggestArticles(ticket.Title, ticket.Type, int64(roleID), 5)
+	if err != nil {
+		fmt.Printf("Failed to suggest articles for ticket %d: %v\n", ticket.ID, err)
+	}
+	ticket.SuggestedArticles = suggested
+
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ticket)
@@ -278,6 +425,7 @@ func (h *TicketsHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
         Priority    string `json:"priority"`
         AssetID     *int64 `json:"asset_id"`
         IsInternal  bool   `json:"is_internal"`
+        Campaign    *string `json:"campaign"` // Empty string clears it
     }
 
     if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -308,6 +456,9 @@ func (h *TicketsHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
         existingTicket.AssetID = input.AssetID
     }
     existingTicket.IsInternal = input.IsInternal
+    if input.Campaign != nil {
+        existingTicket.Campaign = strings.TrimSpace(*input.Campaign)
+    }
 
     err = h.TicketModel.Update(existingTicket)
     if err != nil {
@@ -446,19 +597,9 @@ func (h *TicketsHandler) UpdateTicketStatus(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Validate status transition
-	validStatuses := map[string]bool{
-		"open": true, "received": true, "in_progress": true, 
-		"resolved": true, "closed": true,
-	}
-	if !validStatuses[input.Status] {
-		http.Error(w, "Invalid status", http.StatusBadRequest)
-		return
-	}
-
-	// Validate completion percentage
-	if input.Completion < 0 || input.Completion > 100 {
-		http.Error(w, "Completion must be between 0 and 100", http.StatusBadRequest)
+	// Validate status transition and completion percentage
+	if err := models.ValidateStatusChange(input.Status, input.Completion); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -482,6 +623,11 @@ func (h *TicketsHandler) UpdateTicketStatus(w http.ResponseWriter, r *http.Reque
 			http.Error(w, "Ticket not found", http.StatusNotFound)
 			return
 		}
+		var relationErr *models.TicketRelationError
+		if errors.As(err, &relationErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -803,6 +949,28 @@ func (h *TicketsHandler) RequestVerification(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+// sendCSATSurvey opens a satisfaction survey for a closed ticket and sends it
+// to the creator in-app and by email
+func (h *TicketsHandler) sendCSATSurvey(ticket *models.Ticket) error {
+	survey, err := h.CSATModel.Create(ticket.ID)
+	if err != nil {
+		return err
+	}
+	if survey == nil {
+		return nil // Already surveyed, or no one to ask
+	}
+
+	if err := h.NotificationService.NotifyCSATSurvey(ticket, survey); err != nil {
+		return err
+	}
+
+	user, err := h.UsersModel.GetByID(survey.UserID)
+	if err != nil {
+		return err
+	}
+	return h.EmailService.SendCSATSurveyEmail(user.Email, ticket.TicketNum, ticket.Title, survey.TechnicianName, survey.ID)
+}
+
 // POST /api/v1/tickets/{id}/verify
 func (h *TicketsHandler) VerifyTicket(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/tickets/")
@@ -868,6 +1036,11 @@ func (h *TicketsHandler) VerifyTicket(w http.ResponseWriter, r *http.Request) {
 	// Verify ticket
 	err = h.TicketModel.VerifyTicket(id, int64(userID), input.Approved, input.Notes, roleID)
 	if err != nil {
+		var relationErr *models.TicketRelationError
+		if errors.As(err, &relationErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -886,6 +1059,15 @@ func (h *TicketsHandler) VerifyTicket(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Approval closes the ticket, so ask its creator how it went
+	if input.Approved {
+		go func() {
+			if err := h.sendCSATSurvey(updatedTicket); err != nil {
+				fmt.Printf("Failed to send CSAT survey: %v\n", err)
+			}
+		}()
+	}
+
 	action := "approved"
 	if !input.Approved {
 		action = "rejected"
@@ -941,6 +1123,11 @@ func (h *TicketsHandler) SkipVerification(w http.ResponseWriter, r *http.Request
 	// Skip verification
 	err = h.TicketModel.SkipVerification(id)
 	if err != nil {
+		var relationErr *models.TicketRelationError
+		if errors.As(err, &relationErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"victortillett.net/internal-inventory-tracker/internal/middleware"
+	"victortillett.net/internal-inventory-tracker/internal/models"
+	"victortillett.net/internal-inventory-tracker/internal/services"
+)
+
+type TransfersHandler struct {
+	TransferModel       *models.TransferModel
+	NotificationService *services.NotificationService
+}
+
+func NewTransfersHandler(db *sql.DB) *TransfersHandler {
+	return &TransfersHandler{
+		TransferModel:       models.NewTransferModel(db),
+		NotificationService: services.NewNotificationService(db),
+	}
+}
+
+// transferIDFromPath extracts the transfer ID from /api/v1/asset-transfers/{id}/...
+func transferIDFromPath(path string) (int64, error) {
+	path = strings.TrimPrefix(path, "/api/v1/asset-transfers/")
+	return strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+}
+
+// transferErrorStatus maps model errors to HTTP status codes
+func transferErrorStatus(err error) int {
+	var transferErr *models.TransferError
+	if errors.As(err, &transferErr) {
+		return http.StatusConflict
+	}
+
+	switch err.Error() {
+	case "transfer not found":
+		return http.StatusNotFound
+	case "transfer not found or not pending":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// POST /api/v1/asset-transfers
+// Moves assets from the user who has them to another user. With
+// require_approval the transfer waits for a team lead; otherwise it happens
+// straight away.
+func (h *TransfersHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AssetIDs               []int64 `json:"asset_ids"`
+		FromUserID             int64   `json:"from_user_id"`
+		ToUserID               int64   `json:"to_user_id"`
+		Reason                 string  `json:"reason"`
+		RequireApproval        bool    `json:"require_approval"`
+		AcknowledgeWithinHours int     `json:"acknowledge_within_hours"` // Optional, defaults to 48
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(input.AssetIDs) == 0 {
+		http.Error(w, "asset_ids is required", http.StatusBadRequest)
+		return
+	}
+	if input.FromUserID <= 0 || input.ToUserID <= 0 {
+		http.Error(w, "from_user_id and to_user_id are required", http.StatusBadRequest)
+		return
+	}
+	if input.AcknowledgeWithinHours < 0 {
+		http.Error(w, "acknowledge_within_hours cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	transfer := &models.AssetTransfer{
+		FromUserID:       input.FromUserID,
+		ToUserID:         input.ToUserID,
+		Reason:           strings.TrimSpace(input.Reason),
+		RequiresApproval: input.RequireApproval,
+		AcknowledgeHours: int(models.DefaultAcknowledgeWindow.Hours()),
+		RequestedBy:      currentUserID(r),
+	}
+	if input.AcknowledgeWithinHours > 0 {
+		transfer.AcknowledgeHours = input.AcknowledgeWithinHours
+	}
+	for _, assetID := range input.AssetIDs {
+		transfer.Items = append(transfer.Items, models.AssetTransferItem{AssetID: assetID})
+	}
+
+	if err := h.TransferModel.Insert(transfer); err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	saved, err := h.TransferModel.GetByID(transfer.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		notify := h.NotificationService.NotifyTransferCompleted
+		if saved.Status == "pending" {
+			notify = h.NotificationService.NotifyTransferRequested
+		}
+		if err := notify(saved); err != nil {
+			fmt.Printf("Failed to send transfer notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// GET /api/v1/asset-transfers?status=pending&user_id=5
+func (h *TransfersHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var userID *int64
+	if idStr := q.Get("user_id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		userID = &id
+	}
+
+	transfers, err := h.TransferModel.GetAll(q.Get("status"), userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// GET /api/v1/asset-transfers/{id}
+func (h *TransfersHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := transferIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.TransferModel.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// decodeReviewNotes reads the optional {"notes": "..."} body of a review
+func decodeReviewNotes(r *http.Request) (string, error) {
+	var input struct {
+		Notes string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(input.Notes), nil
+}
+
+// POST /api/v1/asset-transfers/{id}/approve
+func (h *TransfersHandler) ApproveTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := transferIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	notes, err := decodeReviewNotes(r)
+	if err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.TransferModel.Approve(id, int64(userID), notes)
+	if err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyTransferCompleted(transfer); err != nil {
+			fmt.Printf("Failed to send transfer notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// POST /api/v1/asset-transfers/{id}/reject
+func (h *TransfersHandler) RejectTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.ContextUserID).(int)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := transferIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	notes, err := decodeReviewNotes(r)
+	if err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if notes == "" {
+		http.Error(w, "Notes are required when rejecting a transfer", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TransferModel.Reject(id, int64(userID), notes); err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	transfer, err := h.TransferModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.NotificationService.NotifyTransferRejected(transfer); err != nil {
+			fmt.Printf("Failed to send transfer notifications: %v\n", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// POST /api/v1/asset-transfers/{id}/cancel
+func (h *TransfersHandler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := transferIDFromPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TransferModel.Cancel(id); err != nil {
+		http.Error(w, err.Error(), transferErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/assets/{id}/transfers
+func (h *TransfersHandler) GetAssetTransfers(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/assets/")
+	assetID, err := strconv.ParseInt(strings.Split(path, "/")[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := h.TransferModel.GetByAsset(assetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
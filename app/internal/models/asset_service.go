@@ -4,6 +4,7 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -15,7 +16,15 @@ type AssetServiceLog struct {
 	ServiceType      string     `json:"service_type"`      // MAINTENANCE, REPAIR, UPGRADE, etc.
 	NextServiceDate  *time.Time `json:"next_service_date"` // When next service is due
 	Notes            string     `json:"notes"`             // Service details
+	LaborMinutes     int        `json:"labor_minutes"`     // Time spent on the job
+	ExternalCost     *float64   `json:"external_cost"`     // Contractor or vendor invoice, excluding stock parts
+	Technician       string     `json:"technician"`        // Who did the work when not a user, e.g. a contractor
 	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Parts taken from stock for this service; each is a stock-out movement
+	Parts     []StockMovement `json:"parts,omitempty"`
+	PartsCost float64         `json:"parts_cost"` // Parts valued at their average purchase cost
 }
 
 type AssetServiceModel struct {
@@ -26,17 +35,56 @@ func NewAssetServiceModel(db *sql.DB) *AssetServiceModel {
 	return &AssetServiceModel{DB: db}
 }
 
-// Insert a new service log
-func (m *AssetServiceModel) Insert(log *AssetServiceLog) error {
+const serviceLogColumns = `
+			s.id, s.asset_id, s.performed_by, s.performed_at, s.service_type,
+			s.next_service_date, COALESCE(s.notes, ''), s.labor_minutes, s.external_cost,
+			s.technician, s.created_at, s.updated_at,
+			(SELECT COALESCE(SUM(mv.quantity * mv.unit_cost), 0) FROM stock_movements mv
+			 WHERE mv.service_log_id = s.id AND mv.movement_type = 'out') AS parts_cost`
+
+func scanServiceLog(row rowScanner) (*AssetServiceLog, error) {
+	var log AssetServiceLog
+	err := row.Scan(
+		&log.ID,
+		&log.AssetID,
+		&log.PerformedBy,
+		&log.PerformedAt,
+		&log.ServiceType,
+		&log.NextServiceDate,
+		&log.Notes,
+		&log.LaborMinutes,
+		&log.ExternalCost,
+		&log.Technician,
+		&log.CreatedAt,
+		&log.UpdatedAt,
+		&log.PartsCost,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// Insert a new service log. Parts on the log are taken out of stock in the
+// same transaction, so a log is never saved without the stock it used. It
+// returns the IDs of stock items that have just dropped to their reorder
+// threshold.
+func (m *AssetServiceModel) Insert(log *AssetServiceLog) ([]int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO asset_service (
-			asset_id, performed_by, performed_at, service_type, 
-			next_service_date, notes
-		) VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
+			asset_id, performed_by, performed_at, service_type,
+			next_service_date, notes, labor_minutes, external_cost, technician
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
 	`
-	
-	err := m.DB.QueryRow(
+
+	err = tx.QueryRow(
 		query,
 		log.AssetID,
 		log.PerformedBy,
@@ -44,89 +92,257 @@ func (m *AssetServiceModel) Insert(log *AssetServiceLog) error {
 		log.ServiceType,
 		log.NextServiceDate,
 		log.Notes,
-	).Scan(&log.ID, &log.CreatedAt)
-	
-	return err
+		log.LaborMinutes,
+		log.ExternalCost,
+		log.Technician,
+	).Scan(&log.ID, &log.CreatedAt, &log.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	lowStock := []int64{}
+	log.PartsCost = 0
+	for i := range log.Parts {
+		part := &log.Parts[i]
+		part.ServiceLogID = &log.ID
+		part.PerformedBy = log.PerformedBy
+
+		// Value the part at what it cost to buy so the log carries its true cost
+		part.UnitCost, err = averageStockCostTx(tx, part.ItemID)
+		if err != nil {
+			return nil, err
+		}
+
+		low, err := stockOutTx(tx, part)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", part.ItemID, err)
+		}
+		if low {
+			lowStock = append(lowStock, part.ItemID)
+		}
+		if part.UnitCost != nil {
+			log.PartsCost += *part.UnitCost * float64(part.Quantity)
+		}
+	}
+
+	return lowStock, tx.Commit()
 }
 
 // Get service logs for a specific asset
 func (m *AssetServiceModel) GetByAssetID(assetID int64) ([]AssetServiceLog, error) {
 	query := `
-		SELECT 
-			id, asset_id, performed_by, performed_at, service_type,
-			next_service_date, notes, created_at
-		FROM asset_service 
-		WHERE asset_id = $1
-		ORDER BY performed_at DESC
+		SELECT ` + serviceLogColumns + `
+		FROM asset_service s
+		WHERE s.asset_id = $1
+		ORDER BY s.performed_at DESC
 	`
-	
+
 	rows, err := m.DB.Query(query, assetID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
+
 	var logs []AssetServiceLog
 	for rows.Next() {
-		var log AssetServiceLog
-		err := rows.Scan(
-			&log.ID,
-			&log.AssetID,
-			&log.PerformedBy,
-			&log.PerformedAt,
-			&log.ServiceType,
-			&log.NextServiceDate,
-			&log.Notes,
-			&log.CreatedAt,
-		)
+		log, err := scanServiceLog(rows)
 		if err != nil {
 			return nil, err
 		}
-		logs = append(logs, log)
+		logs = append(logs, *log)
 	}
-	
+
 	return logs, nil
 }
 
-// Get service log by ID
+// Get service log by ID, with the parts used
 func (m *AssetServiceModel) GetByID(id int64) (*AssetServiceLog, error) {
-	var log AssetServiceLog
 	query := `
-		SELECT 
-			id, asset_id, performed_by, performed_at, service_type,
-			next_service_date, notes, created_at
-		FROM asset_service 
-		WHERE id = $1
+		SELECT ` + serviceLogColumns + `
+		FROM asset_service s
+		WHERE s.id = $1
 	`
-	
-	err := m.DB.QueryRow(query, id).Scan(
-		&log.ID,
-		&log.AssetID,
-		&log.PerformedBy,
-		&log.PerformedAt,
-		&log.ServiceType,
-		&log.NextServiceDate,
-		&log.Notes,
-		&log.CreatedAt,
-	)
-	
+
+	log, err := scanServiceLog(m.DB.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("service log not found")
 	} else if err != nil {
 		return nil, err
 	}
-	
-	return &log, nil
+
+	log.Parts, err = m.getParts(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// getParts returns the stock taken out for a service log
+func (m *AssetServiceModel) getParts(serviceLogID int64) ([]StockMovement, error) {
+	query := `
+		SELECT mv.id, mv.item_id, mv.location, mv.movement_type, mv.quantity,
+		       mv.unit_cost, mv.reference, mv.ticket_id, mv.service_log_id,
+		       mv.performed_by, mv.notes, mv.created_at, i.name
+		FROM stock_movements mv
+		JOIN stock_items i ON i.id = mv.item_id
+		WHERE mv.service_log_id = $1 AND mv.movement_type = 'out'
+		ORDER BY mv.id
+	`
+
+	rows, err := m.DB.Query(query, serviceLogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := []StockMovement{}
+	for rows.Next() {
+		var mv StockMovement
+		err := rows.Scan(
+			&mv.ID,
+			&mv.ItemID,
+			&mv.Location,
+			&mv.MovementType,
+			&mv.Quantity,
+			&mv.UnitCost,
+			&mv.Reference,
+			&mv.TicketID,
+			&mv.ServiceLogID,
+			&mv.PerformedBy,
+			&mv.Notes,
+			&mv.CreatedAt,
+			&mv.ItemName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, mv)
+	}
+
+	return parts, rows.Err()
+}
+
+// syncAssetServiceDates resets an asset's service dates from its latest log
+const syncAssetServiceDates = `
+		UPDATE assets
+		SET last_service_date = (SELECT MAX(performed_at) FROM asset_service WHERE asset_id = $1),
+		    next_service_date = (SELECT next_service_date FROM asset_service
+		                         WHERE asset_id = $1 ORDER BY performed_at DESC LIMIT 1),
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+
+// Update a service log's details and re-sync the asset's service dates.
+// Parts are stock movements and are not edited here; delete and re-create
+// the log to change them.
+func (m *AssetServiceModel) Update(log *AssetServiceLog) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE asset_service
+		SET performed_by = $1, performed_at = $2, service_type = $3, next_service_date = $4,
+		    notes = $5, labor_minutes = $6, external_cost = $7, technician = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		log.PerformedBy,
+		log.PerformedAt,
+		log.ServiceType,
+		log.NextServiceDate,
+		log.Notes,
+		log.LaborMinutes,
+		log.ExternalCost,
+		log.Technician,
+		log.ID,
+	).Scan(&log.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("service log not found")
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(syncAssetServiceDates, log.AssetID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete a service log. Parts it used go back into stock where they were
+// taken from, and the asset's service dates fall back to its latest
+// remaining log.
+func (m *AssetServiceModel) Delete(id int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	err = tx.QueryRow(`SELECT asset_id FROM asset_service WHERE id = $1 FOR UPDATE`, id).Scan(&assetID)
+	if err == sql.ErrNoRows {
+		return errors.New("service log not found")
+	} else if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT item_id, location, quantity, unit_cost
+		FROM stock_movements
+		WHERE service_log_id = $1 AND movement_type = 'out'
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	var returns []StockMovement
+	for rows.Next() {
+		var mv StockMovement
+		if err := rows.Scan(&mv.ItemID, &mv.Location, &mv.Quantity, &mv.UnitCost); err != nil {
+			rows.Close()
+			return err
+		}
+		returns = append(returns, mv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range returns {
+		returns[i].Reference = fmt.Sprintf("Returned from deleted service log %d", id)
+		if err := stockInTx(tx, &returns[i]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM asset_service WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(syncAssetServiceDates, assetID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Update asset's last_service_date when service is performed
 func (m *AssetServiceModel) UpdateAssetServiceDate(assetID int64, serviceDate time.Time, nextServiceDate *time.Time) error {
 	query := `
-		UPDATE assets 
+		UPDATE assets
 		SET last_service_date = $1, next_service_date = $2, updated_at = NOW()
 		WHERE id = $3
 	`
-	
+
 	_, err := m.DB.Exec(query, serviceDate, nextServiceDate, assetID)
 	return err
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@
 package models
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -23,6 +24,12 @@ func setupAssetServiceTest(t *testing.T) (*AssetServiceModel, sqlmock.Sqlmock, f
 	return model, mock, teardown
 }
 
+var serviceLogRowColumns = []string{
+	"id", "asset_id", "performed_by", "performed_at", "service_type",
+	"next_service_date", "notes", "labor_minutes", "external_cost",
+	"technician", "created_at", "updated_at", "parts_cost",
+}
+
 func TestAssetServiceModel_Insert(t *testing.T) {
 	model, mock, teardown := setupAssetServiceTest(t)
 	defer teardown()
@@ -41,6 +48,7 @@ func TestAssetServiceModel_Insert(t *testing.T) {
 	}
 
 	t.Run("successful insert", func(t *testing.T) {
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO asset_service`).
 			WithArgs(
 				serviceLog.AssetID,
@@ -49,23 +57,100 @@ func TestAssetServiceModel_Insert(t *testing.T) {
 				serviceLog.ServiceType,
 				serviceLog.NextServiceDate,
 				serviceLog.Notes,
+				0,
+				nil,
+				"",
 			).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
-				AddRow(1, now))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(1, now, now))
+		mock.ExpectCommit()
 
-		err := model.Insert(serviceLog)
+		lowStock, err := model.Insert(serviceLog)
 		assert.NoError(t, err)
+		assert.Empty(t, lowStock)
 		assert.Equal(t, int64(1), serviceLog.ID)
 		assert.Equal(t, now, serviceLog.CreatedAt)
 	})
 
+	t.Run("parts are taken from stock at average cost", func(t *testing.T) {
+		withParts := &AssetServiceLog{
+			AssetID:      1,
+			PerformedBy:  &userID,
+			PerformedAt:  now,
+			ServiceType:  "REPAIR",
+			LaborMinutes: 45,
+			Technician:   "Onsite contractor",
+			Parts:        []StockMovement{{ItemID: 7, Location: "Main store", Quantity: 2}},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO asset_service`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(2, now, now))
+		mock.ExpectQuery(`SELECT ROUND`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.5))
+		mock.ExpectQuery(`SELECT reorder_threshold, low_stock_notified FROM stock_items`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold", "low_stock_notified"}).AddRow(3, false))
+		mock.ExpectExec(`UPDATE stock_levels SET quantity = quantity - \$3`).
+			WithArgs(int64(7), "Main store", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO stock_movements`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, now))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(quantity\), 0\) FROM stock_levels`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(3))
+		mock.ExpectExec(`UPDATE stock_items SET low_stock_notified = true`).
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		lowStock, err := model.Insert(withParts)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{7}, lowStock)
+		assert.Equal(t, int64(2), *withParts.Parts[0].ServiceLogID)
+		assert.Equal(t, 25.0, withParts.PartsCost)
+	})
+
+	t.Run("insufficient stock rolls back the log", func(t *testing.T) {
+		withParts := &AssetServiceLog{
+			AssetID:     1,
+			PerformedAt: now,
+			ServiceType: "REPAIR",
+			Parts:       []StockMovement{{ItemID: 7, Location: "Main store", Quantity: 50}},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO asset_service`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(3, now, now))
+		mock.ExpectQuery(`SELECT ROUND`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(nil))
+		mock.ExpectQuery(`SELECT reorder_threshold, low_stock_notified FROM stock_items`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold", "low_stock_notified"}).AddRow(3, false))
+		mock.ExpectExec(`UPDATE stock_levels SET quantity = quantity - \$3`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := model.Insert(withParts)
+		assert.Error(t, err)
+		assert.Equal(t, "insufficient stock at this location", errors.Unwrap(err).Error())
+	})
+
 	t.Run("database error", func(t *testing.T) {
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO asset_service`).
 			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
 
-		err := model.Insert(serviceLog)
+		_, err := model.Insert(serviceLog)
 		assert.Error(t, err)
 	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestAssetServiceModel_GetByAssetID(t *testing.T) {
@@ -79,15 +164,12 @@ func TestAssetServiceModel_GetByAssetID(t *testing.T) {
 	t.Run("successful retrieval", func(t *testing.T) {
 		mock.ExpectQuery(`SELECT`).
 			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{
-				"id", "asset_id", "performed_by", "performed_at", "service_type",
-				"next_service_date", "notes", "created_at",
-			}).AddRow(
+			WillReturnRows(sqlmock.NewRows(serviceLogRowColumns).AddRow(
 				1, 1, &userID, now, "MAINTENANCE",
-				&nextService, "Routine maintenance", now,
+				&nextService, "Routine maintenance", 30, nil, "", now, now, 0,
 			).AddRow(
 				2, 1, &userID, now.AddDate(0, -6, 0), "REPAIR",
-				nil, "Fixed hardware issue", now.AddDate(0, -6, 0),
+				nil, "Fixed hardware issue", 90, 150.0, "Vendor", now.AddDate(0, -6, 0), now.AddDate(0, -6, 0), 42.5,
 			))
 
 		logs, err := model.GetByAssetID(1)
@@ -95,6 +177,8 @@ func TestAssetServiceModel_GetByAssetID(t *testing.T) {
 		assert.Len(t, logs, 2)
 		assert.Equal(t, "MAINTENANCE", logs[0].ServiceType)
 		assert.Equal(t, "REPAIR", logs[1].ServiceType)
+		assert.Equal(t, 150.0, *logs[1].ExternalCost)
+		assert.Equal(t, 42.5, logs[1].PartsCost)
 	})
 
 	t.Run("database error", func(t *testing.T) {
@@ -119,13 +203,16 @@ func TestAssetServiceModel_GetByID(t *testing.T) {
 	t.Run("successful retrieval", func(t *testing.T) {
 		mock.ExpectQuery(`SELECT`).
 			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{
-				"id", "asset_id", "performed_by", "performed_at", "service_type",
-				"next_service_date", "notes", "created_at",
-			}).AddRow(
+			WillReturnRows(sqlmock.NewRows(serviceLogRowColumns).AddRow(
 				1, 1, &userID, now, "MAINTENANCE",
-				&nextService, "Routine maintenance", now,
+				&nextService, "Routine maintenance", 30, nil, "", now, now, 0,
 			))
+		mock.ExpectQuery(`FROM stock_movements mv`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "item_id", "location", "movement_type", "quantity", "unit_cost", "reference",
+				"ticket_id", "service_log_id", "performed_by", "notes", "created_at", "name",
+			}))
 
 		log, err := model.GetByID(1)
 		assert.NoError(t, err)
@@ -136,10 +223,7 @@ func TestAssetServiceModel_GetByID(t *testing.T) {
 	t.Run("service log not found", func(t *testing.T) {
 		mock.ExpectQuery(`SELECT`).
 			WithArgs(int64(999)).
-			WillReturnRows(sqlmock.NewRows([]string{
-				"id", "asset_id", "performed_by", "performed_at", "service_type",
-				"next_service_date", "notes", "created_at",
-			}))
+			WillReturnRows(sqlmock.NewRows(serviceLogRowColumns))
 
 		log, err := model.GetByID(999)
 		assert.Error(t, err)
@@ -148,6 +232,61 @@ func TestAssetServiceModel_GetByID(t *testing.T) {
 	})
 }
 
+func TestAssetServiceModel_Delete(t *testing.T) {
+	model, mock, teardown := setupAssetServiceTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("parts go back into stock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT asset_id FROM asset_service`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}).AddRow(1))
+		mock.ExpectQuery(`FROM stock_movements`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"item_id", "location", "quantity", "unit_cost"}).
+				AddRow(7, "Main store", 2, 12.5))
+		mock.ExpectQuery(`SELECT reorder_threshold FROM stock_items`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold"}).AddRow(3))
+		mock.ExpectExec(`INSERT INTO stock_levels`).
+			WithArgs(int64(7), "Main store", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO stock_movements`).
+			WithArgs(int64(7), "Main store", "in", 2, sqlmock.AnyArg(), "Returned from deleted service log 4",
+				nil, nil, nil, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(31, now))
+		mock.ExpectExec(`UPDATE stock_items SET low_stock_notified = false`).
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM asset_service`).
+			WithArgs(int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE assets`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Delete(4)
+		assert.NoError(t, err)
+	})
+
+	t.Run("service log not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT asset_id FROM asset_service`).
+			WithArgs(int64(999)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}))
+		mock.ExpectRollback()
+
+		err := model.Delete(999)
+		assert.Error(t, err)
+		assert.Equal(t, "service log not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAssetServiceModel_UpdateAssetServiceDate(t *testing.T) {
 	model, mock, teardown := setupAssetServiceTest(t)
 	defer teardown()
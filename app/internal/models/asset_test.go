@@ -54,6 +54,11 @@ func TestAssetsModel_Insert(t *testing.T) {
 				asset.DatePurchased,
 				asset.LastServiceDate,
 				asset.NextServiceDate,
+				asset.Location,
+				asset.Loanable,
+				"{}",
+				asset.PurchaseCost,
+				asset.PurchaseOrderLineID,
 			).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 				AddRow(1, now, now))
@@ -93,6 +98,7 @@ func TestAssetsModel_GetByID(t *testing.T) {
 			Status:        "IN_USE",
 			InUseBy:       int64Ptr(2),
 			DatePurchased: &purchaseDate,
+			Location:      "Floor 2",
 			CreatedAt:     now,
 			UpdatedAt:     now,
 		}
@@ -103,7 +109,7 @@ func TestAssetsModel_GetByID(t *testing.T) {
 				"id", "internal_id", "asset_type", "manufacturer", "model", 
 				"model_number", "serial_number", "status", "in_use_by", 
 				"date_purchased", "last_service_date", "next_service_date", 
-				"created_at", "updated_at",
+				"location", "loanable", "deleted_at", "custom_fields", "purchase_cost", "purchase_order_line_id", "created_at", "updated_at",
 			}).AddRow(
 				expectedAsset.ID,
 				expectedAsset.InternalID,
@@ -117,6 +123,12 @@ func TestAssetsModel_GetByID(t *testing.T) {
 				expectedAsset.DatePurchased,
 				expectedAsset.LastServiceDate,
 				expectedAsset.NextServiceDate,
+				expectedAsset.Location,
+				expectedAsset.Loanable,
+				expectedAsset.DeletedAt,
+				[]byte(`{"cpu": "i7-9700", "ram_gb": 16}`),
+				nil,
+				nil,
 				expectedAsset.CreatedAt,
 				expectedAsset.UpdatedAt,
 			))
@@ -125,6 +137,8 @@ func TestAssetsModel_GetByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, expectedAsset.ID, asset.ID)
 		assert.Equal(t, expectedAsset.InternalID, asset.InternalID)
+		assert.Equal(t, "i7-9700", asset.CustomFields["cpu"])
+		assert.Equal(t, float64(16), asset.CustomFields["ram_gb"])
 		assert.Equal(t, expectedAsset.AssetType, asset.AssetType)
 	})
 
@@ -162,15 +176,15 @@ func TestAssetsModel_GetAll(t *testing.T) {
 				"id", "internal_id", "asset_type", "manufacturer", "model", 
 				"model_number", "serial_number", "status", "in_use_by", 
 				"date_purchased", "last_service_date", "next_service_date", 
-				"created_at", "updated_at",
+				"location", "loanable", "deleted_at", "custom_fields", "purchase_cost", "purchase_order_line_id", "created_at", "updated_at",
 			}).AddRow(
 				1, "DPA-PC001", "PC", "Dell", "OptiPlex 7070", 
 				"OP7070", "ABC123456", "IN_USE", int64(2),
-				now, nil, nil, now, now,
+				now, nil, nil, "", false, nil, []byte(`{}`), nil, nil, now, now,
 			).AddRow(
 				2, "AM-M001", "Monitor", "Viewsonic", "VX3276", 
 				"VX3276", "DEF789012", "IN_STORAGE", nil,
-				now, nil, nil, now, now,
+				now, nil, nil, "", false, nil, []byte(`{}`), nil, nil, now, now,
 			))
 
 		assets, err := model.GetAll()
@@ -194,11 +208,11 @@ func TestAssetsModel_GetAll(t *testing.T) {
 				"id", "internal_id", "asset_type", "manufacturer", "model", 
 				"model_number", "serial_number", "status", "in_use_by", 
 				"date_purchased", "last_service_date", "next_service_date", 
-				"created_at", "updated_at",
+				"location", "loanable", "deleted_at", "custom_fields", "purchase_cost", "purchase_order_line_id", "created_at", "updated_at",
 			}).AddRow(
 				1, "DPA-PC001", "PC", "Dell", "OptiPlex 7070", 
 				"OP7070", "ABC123456", "IN_USE", &userID,
-				now, nil, nil, now, now,
+				now, nil, nil, "", false, nil, []byte(`{}`), nil, nil, now, now,
 			))
 
 		assets, err := model.GetAll(filters...)
@@ -256,6 +270,58 @@ func TestAssetsModel_AssignAsset(t *testing.T) {
 	})
 }
 
+func TestAssetsModel_AssignWithCustody(t *testing.T) {
+	model, mock, teardown := setupAssetTest(t)
+	defer teardown()
+
+	now := time.Now()
+	assignedBy := int64(1)
+
+	t.Run("asset and handover saved together", func(t *testing.T) {
+		custody := &AssetCustody{AssetID: 5, UserID: 7, AssignedBy: &assignedBy, AcknowledgeBy: now.Add(DefaultAcknowledgeWindow)}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`UPDATE assets`).
+			WithArgs(int64(7), int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO asset_custody`).
+			WithArgs(int64(5), int64(7), &assignedBy, custody.AcknowledgeBy).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "assigned_at"}).AddRow(3, "pending", now))
+		mock.ExpectCommit()
+
+		err := model.AssignWithCustody(custody)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), custody.ID)
+	})
+
+	t.Run("failed handover undoes the assignment", func(t *testing.T) {
+		custody := &AssetCustody{AssetID: 5, UserID: 7, AcknowledgeBy: now}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`UPDATE assets`).
+			WithArgs(int64(7), int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs(int64(5)).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err := model.AssignWithCustody(custody)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAssetsModel_GetAssetStats(t *testing.T) {
 	model, mock, teardown := setupAssetTest(t)
 	defer teardown()
@@ -282,4 +348,67 @@ func TestAssetsModel_GetAssetStats(t *testing.T) {
 // Helper function
 func int64Ptr(i int64) *int64 {
 	return &i
-}
\ No newline at end of file
+}
+
+func TestAssetsModel_Delete(t *testing.T) {
+	model, mock, teardown := setupAssetTest(t)
+	defer teardown()
+
+	t.Run("recorded as a disposal and seats released", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM assets a\s+WHERE a.id = \$1 AND a.deleted_at IS NULL`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists", "open"}).AddRow(true, false))
+		mock.ExpectExec(`INSERT INTO asset_disposals`).
+			WithArgs(int64(1), "Water damage", int64(2)).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		userID := int64(2)
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE assets SET status = \$1, in_use_by = NULL`).
+			WithArgs("RETIRED", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("", "Disposed", &userID, int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec(`UPDATE license_allocations SET released_at = NOW\(\), release_reason = \$1`).
+			WithArgs("asset disposed", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`UPDATE asset_reservations SET status = 'cancelled'`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE assets SET deleted_at = NOW\(\)`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Delete(1, 2, "Water damage")
+		assert.NoError(t, err)
+	})
+
+	t.Run("disposal already requested", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM assets a`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists", "open"}).AddRow(true, true))
+		mock.ExpectRollback()
+
+		err := model.Delete(1, 2, "Water damage")
+		assert.EqualError(t, err, "a disposal request is already open for this asset")
+	})
+
+	t.Run("asset not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM assets a`).
+			WithArgs(int64(999)).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := model.Delete(999, 2, "Lost")
+		assert.Error(t, err)
+		assert.Equal(t, "asset not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
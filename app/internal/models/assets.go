@@ -2,7 +2,9 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"sort"
 	"time"
 	"strconv"
 	"strings"
@@ -21,6 +23,12 @@ type Asset struct {
 	DatePurchased   *time.Time `json:"date_purchased"`   // Purchase date
 	LastServiceDate *time.Time `json:"last_service_date"` // Last service date
 	NextServiceDate *time.Time `json:"next_service_date"` // Next service date
+	Location        string     `json:"location"`         // Site/room where the asset is kept
+	Loanable        bool       `json:"loanable"`         // Part of the loaner pool
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // Set once the asset has been disposed of
+	CustomFields    map[string]interface{} `json:"custom_fields"` // Values for the asset type's custom field schema
+	PurchaseCost    *float64   `json:"purchase_cost"`    // Unit price paid
+	PurchaseOrderLineID *int64 `json:"purchase_order_line_id"` // PO line the asset was received against
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
@@ -33,18 +41,100 @@ func NewAssetsModel(db *sql.DB) *AssetsModel {
 	return &AssetsModel{DB: db}
 }
 
+// assetColumns is the column list shared by every asset SELECT, in scanAsset order
+const assetColumns = `
+			id, internal_id, asset_type, manufacturer, model, model_number,
+			serial_number, status, in_use_by, date_purchased, last_service_date,
+			next_service_date, location, loanable, deleted_at, custom_fields,
+			purchase_cost, purchase_order_line_id, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAsset reads one asset row selected with assetColumns
+func scanAsset(row rowScanner) (*Asset, error) {
+	var asset Asset
+	var customFields []byte
+	err := row.Scan(
+		&asset.ID,
+		&asset.InternalID,
+		&asset.AssetType,
+		&asset.Manufacturer,
+		&asset.Model,
+		&asset.ModelNumber,
+		&asset.SerialNumber,
+		&asset.Status,
+		&asset.InUseBy,
+		&asset.DatePurchased,
+		&asset.LastServiceDate,
+		&asset.NextServiceDate,
+		&asset.Location,
+		&asset.Loanable,
+		&asset.DeletedAt,
+		&customFields,
+		&asset.PurchaseCost,
+		&asset.PurchaseOrderLineID,
+		&asset.CreatedAt,
+		&asset.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	asset.CustomFields = map[string]interface{}{}
+	if len(customFields) > 0 {
+		if err := json.Unmarshal(customFields, &asset.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+	return &asset, nil
+}
+
+// customFieldsJSON encodes custom field values for the JSONB column
+func customFieldsJSON(fields map[string]interface{}) (string, error) {
+	if len(fields) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 // Insert a new asset
 func (m *AssetsModel) Insert(asset *Asset) error {
+	return insertAsset(m.DB, asset)
+}
+
+// createAssetTx validates a new asset's custom fields against its type's
+// schema and inserts it, as part of the caller's transaction, for assets
+// created other than through the assets API
+func createAssetTx(tx *sql.Tx, asset *Asset) error {
+	if err := validateAssetCustomFields(tx, asset); err != nil {
+		return err
+	}
+	return insertAsset(tx, asset)
+}
+
+func insertAsset(db dbtx, asset *Asset) error {
 	query := `
 		INSERT INTO assets (
 			internal_id, asset_type, manufacturer, model, model_number, 
 			serial_number, status, in_use_by, date_purchased, 
-			last_service_date, next_service_date
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			last_service_date, next_service_date, location, loanable, custom_fields,
+			purchase_cost, purchase_order_line_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at, updated_at
 	`
 	
-	err := m.DB.QueryRow(
+	customFields, err := customFieldsJSON(asset.CustomFields)
+	if err != nil {
+		return err
+	}
+	
+	err = db.QueryRow(
 		query,
 		asset.InternalID,
 		asset.AssetType,
@@ -57,6 +147,11 @@ func (m *AssetsModel) Insert(asset *Asset) error {
 		asset.DatePurchased,
 		asset.LastServiceDate,
 		asset.NextServiceDate,
+		asset.Location,
+		asset.Loanable,
+		customFields,
+		asset.PurchaseCost,
+		asset.PurchaseOrderLineID,
 	).Scan(&asset.ID, &asset.CreatedAt, &asset.UpdatedAt)
 	
 	return err
@@ -64,51 +159,28 @@ func (m *AssetsModel) Insert(asset *Asset) error {
 
 // Get asset by ID
 func (m *AssetsModel) GetByID(id int64) (*Asset, error) {
-	var asset Asset
 	query := `
-		SELECT 
-			id, internal_id, asset_type, manufacturer, model, model_number,
-			serial_number, status, in_use_by, date_purchased, last_service_date,
-			next_service_date, created_at, updated_at
+		SELECT `+assetColumns+`
 		FROM assets 
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	
-	err := m.DB.QueryRow(query, id).Scan(
-		&asset.ID,
-		&asset.InternalID,
-		&asset.AssetType,
-		&asset.Manufacturer,
-		&asset.Model,
-		&asset.ModelNumber,
-		&asset.SerialNumber,
-		&asset.Status,
-		&asset.InUseBy,
-		&asset.DatePurchased,
-		&asset.LastServiceDate,
-		&asset.NextServiceDate,
-		&asset.CreatedAt,
-		&asset.UpdatedAt,
-	)
-	
+	asset, err := scanAsset(m.DB.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("asset not found")
 	} else if err != nil {
 		return nil, err
 	}
 	
-	return &asset, nil
+	return asset, nil
 }
 
 // Get all assets with optional filtering
 func (m *AssetsModel) GetAll(filters ...AssetFilter) ([]Asset, error) {
 	query := `
-		SELECT 
-			id, internal_id, asset_type, manufacturer, model, model_number,
-			serial_number, status, in_use_by, date_purchased, last_service_date,
-			next_service_date, created_at, updated_at
+		SELECT `+assetColumns+`
 		FROM assets 
-		WHERE 1=1
+		WHERE deleted_at IS NULL
 	`
 	args := []interface{}{}
 	argPos := 1
@@ -130,6 +202,16 @@ func (m *AssetsModel) GetAll(filters ...AssetFilter) ([]Asset, error) {
 			args = append(args, *filter.InUseBy)
 			argPos++
 		}
+		if filter.Location != "" {
+			query += " AND location = $" + string(rune(argPos+'0'))
+			args = append(args, filter.Location)
+			argPos++
+		}
+		if filter.Loanable != nil {
+			query += " AND loanable = $" + string(rune(argPos+'0'))
+			args = append(args, *filter.Loanable)
+			argPos++
+		}
 	}
 	
 	query += " ORDER BY internal_id"
@@ -142,27 +224,11 @@ func (m *AssetsModel) GetAll(filters ...AssetFilter) ([]Asset, error) {
 	
 	var assets []Asset
 	for rows.Next() {
-		var asset Asset
-		err := rows.Scan(
-			&asset.ID,
-			&asset.InternalID,
-			&asset.AssetType,
-			&asset.Manufacturer,
-			&asset.Model,
-			&asset.ModelNumber,
-			&asset.SerialNumber,
-			&asset.Status,
-			&asset.InUseBy,
-			&asset.DatePurchased,
-			&asset.LastServiceDate,
-			&asset.NextServiceDate,
-			&asset.CreatedAt,
-			&asset.UpdatedAt,
-		)
+		asset, err := scanAsset(rows)
 		if err != nil {
 			return nil, err
 		}
-		assets = append(assets, asset)
+		assets = append(assets, *asset)
 	}
 	
 	return assets, nil
@@ -177,12 +243,18 @@ func (m *AssetsModel) Update(asset *Asset) error {
 			model = $4, model_number = $5, serial_number = $6, 
 			status = $7, in_use_by = $8, date_purchased = $9, 
 			last_service_date = $10, next_service_date = $11,
-			updated_at = NOW()
-		WHERE id = $12
+			location = $12, loanable = $13, custom_fields = $14,
+			purchase_cost = $15, updated_at = NOW()
+		WHERE id = $16 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 	
-	err := m.DB.QueryRow(
+	customFields, err := customFieldsJSON(asset.CustomFields)
+	if err != nil {
+		return err
+	}
+	
+	err = m.DB.QueryRow(
 		query,
 		asset.InternalID,
 		asset.AssetType,
@@ -195,6 +267,10 @@ func (m *AssetsModel) Update(asset *Asset) error {
 		asset.DatePurchased,
 		asset.LastServiceDate,
 		asset.NextServiceDate,
+		asset.Location,
+		asset.Loanable,
+		customFields,
+		asset.PurchaseCost,
 		asset.ID,
 	).Scan(&asset.UpdatedAt)
 	
@@ -204,33 +280,86 @@ func (m *AssetsModel) Update(asset *Asset) error {
 	return err
 }
 
-// Delete an asset
-func (m *AssetsModel) Delete(id int64) error {
-	res, err := m.DB.Exec("DELETE FROM assets WHERE id = $1", id)
+// Delete disposes of an asset straight away, skipping approval. It is kept
+// for administrators and recorded as a completed disposal with their reason;
+// the asset is soft-deleted so its service history and reports survive.
+func (m *AssetsModel) Delete(id, userID int64, reason string) error {
+	tx, err := m.DB.Begin()
 	if err != nil {
 		return err
 	}
-	
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
+	defer tx.Rollback()
+
+	var exists, open bool
+	err = tx.QueryRow(`
+		SELECT true, EXISTS(SELECT 1 FROM asset_disposals WHERE asset_id = a.id AND status IN ('requested', 'approved'))
+		FROM assets a
+		WHERE a.id = $1 AND a.deleted_at IS NULL
+		FOR UPDATE OF a
+	`, id).Scan(&exists, &open)
+	if err == sql.ErrNoRows {
 		return errors.New("asset not found")
+	} else if err != nil {
+		return err
+	}
+	if open {
+		return errors.New("a disposal request is already open for this asset")
 	}
-	return nil
+
+	_, err = tx.Exec(`
+		INSERT INTO asset_disposals (asset_id, status, reason, requested_by, reviewed_by, reviewed_at,
+		                             review_notes, completed_by, completed_at)
+		VALUES ($1, 'completed', $2, $3, $3, NOW(), 'Deleted by an administrator', $3, NOW())
+	`, id, reason, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := disposeAssetTx(tx, id, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // AssetFilter for filtering assets
 type AssetFilter struct {
-	Type    string
-	Status  string
-	InUseBy *int64
+	Type     string
+	Status   string
+	InUseBy  *int64
+	Location string
+	Loanable *bool
 }
 
 
 // AssignAsset assigns an asset to a user
 func (m *AssetsModel) AssignAsset(assetID, userID int64) error {
+	return assignAsset(m.DB, assetID, userID)
+}
+
+// AssignWithCustody assigns custody.AssetID to custody.UserID and opens the
+// pending handover the recipient has to acknowledge, in one transaction
+func (m *AssetsModel) AssignWithCustody(custody *AssetCustody) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := assignAsset(tx, custody.AssetID, custody.UserID); err != nil {
+		return err
+	}
+	if err := openCustodyTx(tx, custody); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func assignAsset(db dbtx, assetID, userID int64) error {
 	// Verify user exists
 	var userExists bool
-	err := m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&userExists)
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&userExists)
 	if err != nil {
 		return err
 	}
@@ -241,20 +370,28 @@ func (m *AssetsModel) AssignAsset(assetID, userID int64) error {
 	query := `
 		UPDATE assets 
 		SET in_use_by = $1, status = 'IN_USE', updated_at = NOW()
-		WHERE id = $2 AND (status != 'RETIRED' AND status != 'REPAIR')
+		WHERE id = $2 AND (status != 'RETIRED' AND status != 'REPAIR') AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 	
 	var updatedAt time.Time
-	err = m.DB.QueryRow(query, userID, assetID).Scan(&updatedAt)
+	err = db.QueryRow(query, userID, assetID).Scan(&updatedAt)
 	if err == sql.ErrNoRows {
 		return errors.New("asset not found or cannot be assigned (might be retired or in repair)")
 	}
 	return err
 }
 
-// UnassignAsset removes user assignment from an asset
-func (m *AssetsModel) UnassignAsset(assetID int64) error {
+// UnassignAsset removes user assignment from an asset, frees any license
+// seats allocated to it and closes its open handover, in one transaction. It
+// returns the closed handover's ID, or 0 if the asset had none.
+func (m *AssetsModel) UnassignAsset(assetID int64, checkIn CustodyCheckIn) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE assets 
 		SET in_use_by = NULL, status = 'IN_STORAGE', updated_at = NOW()
@@ -263,7 +400,47 @@ func (m *AssetsModel) UnassignAsset(assetID int64) error {
 	`
 	
 	var updatedAt time.Time
-	err := m.DB.QueryRow(query, assetID).Scan(&updatedAt)
+	err = tx.QueryRow(query, assetID).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("asset not found")
+	} else if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE license_allocations SET released_at = NOW(), release_reason = 'asset unassigned'
+		WHERE asset_id = $1 AND released_at IS NULL
+	`, assetID)
+	if err != nil {
+		return 0, err
+	}
+
+	custodyID, err := checkInCustody(tx, assetID, checkIn)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return custodyID, nil
+}
+
+// UpdateLocation records where an asset physically is
+func (m *AssetsModel) UpdateLocation(assetID int64, location string) error {
+	return updateAssetLocation(m.DB, assetID, location)
+}
+
+func updateAssetLocation(db dbtx, assetID int64, location string) error {
+	query := `
+		UPDATE assets 
+		SET location = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+	
+	var updatedAt time.Time
+	err := db.QueryRow(query, location, assetID).Scan(&updatedAt)
 	if err == sql.ErrNoRows {
 		return errors.New("asset not found")
 	}
@@ -273,12 +450,9 @@ func (m *AssetsModel) UnassignAsset(assetID int64) error {
 // GetAssetsByUser gets all assets assigned to a specific user
 func (m *AssetsModel) GetAssetsByUser(userID int64) ([]Asset, error) {
 	query := `
-		SELECT 
-			id, internal_id, asset_type, manufacturer, model, model_number,
-			serial_number, status, in_use_by, date_purchased, last_service_date,
-			next_service_date, created_at, updated_at
+		SELECT `+assetColumns+`
 		FROM assets 
-		WHERE in_use_by = $1
+		WHERE in_use_by = $1 AND deleted_at IS NULL
 		ORDER BY asset_type, internal_id
 	`
 	
@@ -290,27 +464,11 @@ func (m *AssetsModel) GetAssetsByUser(userID int64) ([]Asset, error) {
 	
 	var assets []Asset
 	for rows.Next() {
-		var asset Asset
-		err := rows.Scan(
-			&asset.ID,
-			&asset.InternalID,
-			&asset.AssetType,
-			&asset.Manufacturer,
-			&asset.Model,
-			&asset.ModelNumber,
-			&asset.SerialNumber,
-			&asset.Status,
-			&asset.InUseBy,
-			&asset.DatePurchased,
-			&asset.LastServiceDate,
-			&asset.NextServiceDate,
-			&asset.CreatedAt,
-			&asset.UpdatedAt,
-		)
+		asset, err := scanAsset(rows)
 		if err != nil {
 			return nil, err
 		}
-		assets = append(assets, asset)
+		assets = append(assets, *asset)
 	}
 	
 	return assets, nil
@@ -319,12 +477,9 @@ func (m *AssetsModel) GetAssetsByUser(userID int64) ([]Asset, error) {
 // GetAvailableAssets gets assets that are not assigned to any user
 func (m *AssetsModel) GetAvailableAssets(assetType string) ([]Asset, error) {
 	query := `
-		SELECT 
-			id, internal_id, asset_type, manufacturer, model, model_number,
-			serial_number, status, in_use_by, date_purchased, last_service_date,
-			next_service_date, created_at, updated_at
+		SELECT `+assetColumns+`
 		FROM assets 
-		WHERE in_use_by IS NULL AND status = 'IN_STORAGE'
+		WHERE in_use_by IS NULL AND status = 'IN_STORAGE' AND deleted_at IS NULL
 	`
 	
 	args := []interface{}{}
@@ -343,27 +498,53 @@ func (m *AssetsModel) GetAvailableAssets(assetType string) ([]Asset, error) {
 	
 	var assets []Asset
 	for rows.Next() {
-		var asset Asset
-		err := rows.Scan(
-			&asset.ID,
-			&asset.InternalID,
-			&asset.AssetType,
-			&asset.Manufacturer,
-			&asset.Model,
-			&asset.ModelNumber,
-			&asset.SerialNumber,
-			&asset.Status,
-			&asset.InUseBy,
-			&asset.DatePurchased,
-			&asset.LastServiceDate,
-			&asset.NextServiceDate,
-			&asset.CreatedAt,
-			&asset.UpdatedAt,
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *asset)
+	}
+	
+	return assets, nil
+}
+
+// GetAvailableBetween gets loanable assets with no reservation or loan
+// overlapping the window [from, to)
+func (m *AssetsModel) GetAvailableBetween(assetType string, from, to time.Time) ([]Asset, error) {
+	query := `
+		SELECT `+assetColumns+`
+		FROM assets 
+		WHERE loanable = true AND status NOT IN ('RETIRED', 'REPAIR') AND deleted_at IS NULL
+		AND (in_use_by IS NULL OR EXISTS (
+			SELECT 1 FROM asset_reservations r WHERE r.asset_id = assets.id AND r.status = 'checked_out'
+		))
+		AND NOT EXISTS (
+			SELECT 1 FROM asset_reservations r
+			WHERE r.asset_id = assets.id AND `+reservationBlocks(1, 2)+`
 		)
+	`
+	
+	args := []interface{}{from, to}
+	if assetType != "" {
+		query += " AND asset_type = $3"
+		args = append(args, assetType)
+	}
+	
+	query += " ORDER BY asset_type, internal_id"
+	
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var assets []Asset
+	for rows.Next() {
+		asset, err := scanAsset(rows)
 		if err != nil {
 			return nil, err
 		}
-		assets = append(assets, asset)
+		assets = append(assets, *asset)
 	}
 	
 	return assets, nil
@@ -373,10 +554,7 @@ func (m *AssetsModel) GetAvailableAssets(assetType string) ([]Asset, error) {
 // SearchAssets performs advanced search across multiple fields
 func (m *AssetsModel) SearchAssets(query string, filters AssetSearchFilters) ([]Asset, error) {
 	baseQuery := `
-		SELECT 
-			id, internal_id, asset_type, manufacturer, model, model_number,
-			serial_number, status, in_use_by, date_purchased, last_service_date,
-			next_service_date, created_at, updated_at
+		SELECT `+assetColumns+`
 		FROM assets 
 		WHERE 1=1
 	`
@@ -384,6 +562,11 @@ func (m *AssetsModel) SearchAssets(query string, filters AssetSearchFilters) ([]
 	args := []interface{}{}
 	argPos := 1
 	
+	// Disposed assets only show up when asked for
+	if !filters.IncludeDisposed {
+		baseQuery += ` AND deleted_at IS NULL`
+	}
+	
 	// Text search across multiple fields
 	if query != "" {
 		searchTerm := "%" + strings.ToLower(query) + "%"
@@ -424,6 +607,27 @@ func (m *AssetsModel) SearchAssets(query string, filters AssetSearchFilters) ([]
 		argPos++
 	}
 	
+	if filters.Location != "" {
+		baseQuery += ` AND LOWER(location) = $` + strconv.Itoa(argPos)
+		args = append(args, strings.ToLower(filters.Location))
+		argPos++
+	}
+	
+	// Custom field filters, matched case-insensitively on the text value
+	customKeys := make([]string, 0, len(filters.CustomFields))
+	for key := range filters.CustomFields {
+		if IsValidCustomFieldKey(key) {
+			customKeys = append(customKeys, key)
+		}
+	}
+	sort.Strings(customKeys)
+	for _, key := range customKeys {
+		value := filters.CustomFields[key]
+		baseQuery += ` AND LOWER(custom_fields->>$` + strconv.Itoa(argPos) + `) = $` + strconv.Itoa(argPos+1)
+		args = append(args, key, strings.ToLower(value))
+		argPos += 2
+	}
+	
 	// Date range filters
 	if !filters.PurchasedAfter.IsZero() {
 		baseQuery += ` AND date_purchased >= $` + strconv.Itoa(argPos)
@@ -462,17 +666,27 @@ func (m *AssetsModel) SearchAssets(query string, filters AssetSearchFilters) ([]
 		"internal_id": true, "asset_type": true, "manufacturer": true, 
 		"model": true, "status": true, "date_purchased": true,
 		"last_service_date": true, "next_service_date": true, "created_at": true,
-	}
-	
-	if !validSortFields[sortField] {
-		sortField = "internal_id"
+		"location": true,
 	}
 	
 	if sortOrder != "ASC" && sortOrder != "DESC" {
 		sortOrder = "ASC"
 	}
 	
-	baseQuery += ` ORDER BY ` + sortField + ` ` + sortOrder
+	// cf.<key> sorts by a custom field: numbers numerically, everything else
+	// (including YYYY-MM-DD dates) by its text value
+	if key := strings.TrimPrefix(sortField, "cf."); key != sortField && IsValidCustomFieldKey(key) {
+		keyArg := `$` + strconv.Itoa(argPos)
+		args = append(args, key)
+		argPos++
+		baseQuery += ` ORDER BY CASE WHEN jsonb_typeof(custom_fields->` + keyArg + `) = 'number' THEN (custom_fields->>` + keyArg + `)::numeric END ` + sortOrder +
+			`, custom_fields->>` + keyArg + ` ` + sortOrder + `, internal_id`
+	} else {
+		if !validSortFields[sortField] {
+			sortField = "internal_id"
+		}
+		baseQuery += ` ORDER BY ` + sortField + ` ` + sortOrder
+	}
 	
 	// Pagination
 	if filters.Limit > 0 {
@@ -494,27 +708,11 @@ func (m *AssetsModel) SearchAssets(query string, filters AssetSearchFilters) ([]
 	
 	var assets []Asset
 	for rows.Next() {
-		var asset Asset
-		err := rows.Scan(
-			&asset.ID,
-			&asset.InternalID,
-			&asset.AssetType,
-			&asset.Manufacturer,
-			&asset.Model,
-			&asset.ModelNumber,
-			&asset.SerialNumber,
-			&asset.Status,
-			&asset.InUseBy,
-			&asset.DatePurchased,
-			&asset.LastServiceDate,
-			&asset.NextServiceDate,
-			&asset.CreatedAt,
-			&asset.UpdatedAt,
-		)
+		asset, err := scanAsset(rows)
 		if err != nil {
 			return nil, err
 		}
-		assets = append(assets, asset)
+		assets = append(assets, *asset)
 	}
 	
 	return assets, nil
@@ -559,11 +757,14 @@ type AssetSearchFilters struct {
 	Status          string
 	Manufacturer    string
 	InUseBy         *int64
+	Location        string
 	PurchasedAfter  time.Time
 	PurchasedBefore time.Time
 	NeedsService    bool
 	OverdueService  bool
-	SortBy          string
+	IncludeDisposed bool
+	CustomFields    map[string]string // custom field key -> value to match
+	SortBy          string            // column name or cf.<custom field key>
 	SortOrder       string
 	Limit           int
 	Offset          int
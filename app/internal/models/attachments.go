@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Attachment is an uploaded photo or document. It belongs to exactly one of
+// an asset or a service log; the file itself lives in FileStorage.
+type Attachment struct {
+	ID           int64     `json:"id"`
+	AssetID      *int64    `json:"asset_id"`
+	ServiceLogID *int64    `json:"service_log_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	UploadedBy   *int64    `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+func NewAttachmentModel(db *sql.DB) *AttachmentModel {
+	return &AttachmentModel{DB: db}
+}
+
+const attachmentColumns = `
+			id, asset_id, service_log_id, file_name, content_type, size_bytes,
+			storage_key, thumbnail_key, uploaded_by, created_at`
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	var a Attachment
+	err := row.Scan(
+		&a.ID,
+		&a.AssetID,
+		&a.ServiceLogID,
+		&a.FileName,
+		&a.ContentType,
+		&a.SizeBytes,
+		&a.StorageKey,
+		&a.ThumbnailKey,
+		&a.UploadedBy,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return &a, nil
+}
+
+func (m *AttachmentModel) queryAttachments(query string, args ...interface{}) ([]Attachment, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Insert records an uploaded file
+func (m *AttachmentModel) Insert(a *Attachment) error {
+	if (a.AssetID == nil) == (a.ServiceLogID == nil) {
+		return errors.New("attachment must belong to an asset or a service log")
+	}
+
+	query := `
+		INSERT INTO attachments (asset_id, service_log_id, file_name, content_type, size_bytes,
+		                         storage_key, thumbnail_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	err := m.DB.QueryRow(query, a.AssetID, a.ServiceLogID, a.FileName, a.ContentType, a.SizeBytes,
+		a.StorageKey, a.ThumbnailKey, a.UploadedBy).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return nil
+}
+
+// Get attachment by ID
+func (m *AttachmentModel) GetByID(id int64) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	a, err := scanAttachment(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("attachment not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetByAsset returns the files attached directly to an asset, newest first
+func (m *AttachmentModel) GetByAsset(assetID int64) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE asset_id = $1
+		ORDER BY created_at DESC`
+
+	return m.queryAttachments(query, assetID)
+}
+
+// GetByServiceLog returns the files attached to a service log, newest first
+func (m *AttachmentModel) GetByServiceLog(serviceLogID int64) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE service_log_id = $1
+		ORDER BY created_at DESC`
+
+	return m.queryAttachments(query, serviceLogID)
+}
+
+// Delete removes an attachment record and returns it so the caller can
+// remove the stored files
+func (m *AttachmentModel) Delete(id int64) (*Attachment, error) {
+	query := `DELETE FROM attachments WHERE id = $1 RETURNING ` + attachmentColumns
+
+	a, err := scanAttachment(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("attachment not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// DeleteForAsset removes every attachment on an asset and on its service
+// logs, returning them so the caller can remove the stored files
+func (m *AttachmentModel) DeleteForAsset(assetID int64) ([]Attachment, error) {
+	query := `
+		DELETE FROM attachments
+		WHERE asset_id = $1
+		OR service_log_id IN (SELECT id FROM asset_service WHERE asset_id = $1)
+		RETURNING ` + attachmentColumns
+
+	return m.queryAttachments(query, assetID)
+}
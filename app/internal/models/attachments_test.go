@@ -0,0 +1,88 @@
+// file: app/internal/models/attachments_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAttachmentTest(t *testing.T) (*AttachmentModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewAttachmentModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var attachmentRowColumns = []string{
+	"id", "asset_id", "service_log_id", "file_name", "content_type", "size_bytes",
+	"storage_key", "thumbnail_key", "uploaded_by", "created_at",
+}
+
+func TestAttachmentModel_Insert(t *testing.T) {
+	model, mock, teardown := setupAttachmentTest(t)
+	defer teardown()
+
+	assetID := int64(12)
+
+	t.Run("image with thumbnail", func(t *testing.T) {
+		a := &Attachment{
+			AssetID:      &assetID,
+			FileName:     "desk.jpg",
+			ContentType:  "image/jpeg",
+			SizeBytes:    2048,
+			StorageKey:   "assets/12/abc.jpg",
+			ThumbnailKey: "assets/12/abc_thumb.jpg",
+		}
+
+		mock.ExpectQuery(`INSERT INTO attachments`).
+			WithArgs(&assetID, nil, "desk.jpg", "image/jpeg", int64(2048), "assets/12/abc.jpg", "assets/12/abc_thumb.jpg", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+		err := model.Insert(a)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), a.ID)
+		assert.True(t, a.HasThumbnail)
+	})
+
+	t.Run("needs exactly one owner", func(t *testing.T) {
+		logID := int64(3)
+		err := model.Insert(&Attachment{AssetID: &assetID, ServiceLogID: &logID})
+		assert.Error(t, err)
+		assert.Equal(t, "attachment must belong to an asset or a service log", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttachmentModel_DeleteForAsset(t *testing.T) {
+	model, mock, teardown := setupAttachmentTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	mock.ExpectQuery(`DELETE FROM attachments`).
+		WithArgs(int64(12)).
+		WillReturnRows(sqlmock.NewRows(attachmentRowColumns).
+			AddRow(1, 12, nil, "desk.jpg", "image/jpeg", 2048, "assets/12/abc.jpg", "assets/12/abc_thumb.jpg", nil, now).
+			AddRow(2, nil, 5, "invoice.pdf", "application/pdf", 40960, "service-logs/5/def.pdf", "", nil, now))
+
+	attachments, err := model.DeleteForAsset(12)
+	assert.NoError(t, err)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "assets/12/abc_thumb.jpg", attachments[0].ThumbnailKey)
+	assert.True(t, attachments[0].HasThumbnail)
+	assert.Equal(t, int64(5), *attachments[1].ServiceLogID)
+	assert.False(t, attachments[1].HasThumbnail)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
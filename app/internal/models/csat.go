@@ -0,0 +1,181 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CSATSurvey asks a ticket's creator to rate how it was handled, 1 to 5
+type CSATSurvey struct {
+	ID           int64      `json:"id"`
+	TicketID     int64      `json:"ticket_id"`
+	UserID       int64      `json:"user_id"`
+	TechnicianID *int64     `json:"technician_id"`
+	Rating       *int       `json:"rating"` // nil until answered
+	Comment      string     `json:"comment"`
+	SentAt       time.Time  `json:"sent_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RespondedAt  *time.Time `json:"responded_at"`
+
+	// Joined fields
+	TicketNum      string `json:"ticket_num,omitempty"`
+	TicketTitle    string `json:"ticket_title,omitempty"`
+	TechnicianName string `json:"technician_name,omitempty"`
+}
+
+// CSATError is a survey response the client got wrong, e.g. a rating of 6
+type CSATError struct {
+	Message string
+}
+
+func (e *CSATError) Error() string {
+	return e.Message
+}
+
+type CSATModel struct {
+	DB *sql.DB
+}
+
+func NewCSATModel(db *sql.DB) *CSATModel {
+	return &CSATModel{DB: db}
+}
+
+const csatSurveyColumns = `
+			s.id, s.ticket_id, s.user_id, s.technician_id, s.rating, s.comment,
+			s.sent_at, s.expires_at, s.responded_at,
+			t.ticket_num, t.title, COALESCE(tech.full_name, tech.username, '')`
+
+const csatSurveyJoins = `
+		FROM ticket_csat_surveys s
+		JOIN tickets t ON t.id = s.ticket_id
+		LEFT JOIN users tech ON tech.id = s.technician_id`
+
+func scanCSATSurvey(row rowScanner) (*CSATSurvey, error) {
+	var s CSATSurvey
+	var technicianID sql.NullInt64
+	var rating sql.NullInt64
+	var respondedAt sql.NullTime
+	err := row.Scan(
+		&s.ID, &s.TicketID, &s.UserID, &technicianID, &rating, &s.Comment,
+		&s.SentAt, &s.ExpiresAt, &respondedAt,
+		&s.TicketNum, &s.TicketTitle, &s.TechnicianName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if technicianID.Valid {
+		s.TechnicianID = &technicianID.Int64
+	}
+	if rating.Valid {
+		r := int(rating.Int64)
+		s.Rating = &r
+	}
+	if respondedAt.Valid {
+		s.RespondedAt = &respondedAt.Time
+	}
+	return &s, nil
+}
+
+// Create opens a survey for a closed ticket, addressed to its creator. A ticket
+// gets one survey, so verifying a reopened ticket again returns nil, as does a
+// ticket whose creator is gone or inactive.
+func (m *CSATModel) Create(ticketID int64) (*CSATSurvey, error) {
+	var id int64
+	err := m.DB.QueryRow(`
+		INSERT INTO ticket_csat_surveys (ticket_id, user_id, technician_id)
+		SELECT t.id, t.created_by, t.assigned_to
+		FROM tickets t
+		JOIN users u ON u.id = t.created_by AND u.is_active = true
+		WHERE t.id = $1
+		ON CONFLICT (ticket_id) DO NOTHING
+		RETURNING id
+	`, ticketID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.GetByID(id)
+}
+
+// GetByID returns a survey
+func (m *CSATModel) GetByID(id int64) (*CSATSurvey, error) {
+	row := m.DB.QueryRow(`
+		SELECT`+csatSurveyColumns+csatSurveyJoins+`
+		WHERE s.id = $1
+	`, id)
+
+	s, err := scanCSATSurvey(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("survey not found")
+	}
+	return s, err
+}
+
+// GetPendingForUser lists the user's unanswered surveys that are still open
+func (m *CSATModel) GetPendingForUser(userID int64) ([]CSATSurvey, error) {
+	rows, err := m.DB.Query(`
+		SELECT`+csatSurveyColumns+csatSurveyJoins+`
+		WHERE s.user_id = $1 AND s.rating IS NULL AND s.expires_at > NOW()
+		ORDER BY s.sent_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	surveys := []CSATSurvey{}
+	for rows.Next() {
+		s, err := scanCSATSurvey(rows)
+		if err != nil {
+			return nil, err
+		}
+		surveys = append(surveys, *s)
+	}
+
+	return surveys, rows.Err()
+}
+
+// Respond records the user's rating and comment. Answers can be changed until
+// the survey expires, so a one-click rating from email can be followed by a
+// comment. A nil comment keeps the one already given.
+func (m *CSATModel) Respond(id, userID int64, rating int, comment *string) (*CSATSurvey, error) {
+	if rating < 1 || rating > 5 {
+		return nil, &CSATError{Message: "rating must be between 1 and 5"}
+	}
+	if comment != nil {
+		trimmed := strings.TrimSpace(*comment)
+		comment = &trimmed
+	}
+
+	result, err := m.DB.Exec(`
+		UPDATE ticket_csat_surveys
+		SET rating = $1, comment = COALESCE($2, comment), responded_at = NOW()
+		WHERE id = $3 AND user_id = $4 AND expires_at > NOW()
+	`, rating, comment, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		var expired bool
+		err := m.DB.QueryRow(
+			"SELECT expires_at <= NOW() FROM ticket_csat_surveys WHERE id = $1 AND user_id = $2",
+			id, userID,
+		).Scan(&expired)
+		if err == sql.ErrNoRows {
+			return nil, errors.New("survey not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			return nil, &CSATError{Message: "survey has expired"}
+		}
+	}
+
+	return m.GetByID(id)
+}
@@ -0,0 +1,124 @@
+// file: app/internal/models/csat_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCSATTest(t *testing.T) (*CSATModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewCSATModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var csatSurveyTestColumns = []string{
+	"id", "ticket_id", "user_id", "technician_id", "rating", "comment",
+	"sent_at", "expires_at", "responded_at",
+	"ticket_num", "title", "technician_name",
+}
+
+func TestCSATModel_Create(t *testing.T) {
+	model, mock, teardown := setupCSATTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("survey for the creator", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_csat_surveys`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(`FROM ticket_csat_surveys s`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows(csatSurveyTestColumns).
+				AddRow(3, 1, 12, 2, nil, "", now, now.AddDate(0, 0, 14), nil, "TCK-2025-0001", "Headset broken", "IT Staff"))
+
+		survey, err := model.Create(1)
+		require.NoError(t, err)
+		require.NotNil(t, survey)
+		assert.Equal(t, int64(12), survey.UserID)
+		assert.Nil(t, survey.Rating)
+		assert.Equal(t, "IT Staff", survey.TechnicianName)
+	})
+
+	t.Run("already surveyed", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_csat_surveys`).
+			WithArgs(int64(1)).
+			WillReturnError(sql.ErrNoRows)
+
+		survey, err := model.Create(1)
+		require.NoError(t, err)
+		assert.Nil(t, survey)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCSATModel_Respond(t *testing.T) {
+	model, mock, teardown := setupCSATTest(t)
+	defer teardown()
+
+	now := time.Now()
+	comment := " Fixed within the hour "
+
+	t.Run("success", func(t *testing.T) {
+		trimmed := "Fixed within the hour"
+		mock.ExpectExec(`UPDATE ticket_csat_surveys`).
+			WithArgs(5, &trimmed, int64(3), int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`FROM ticket_csat_surveys s`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows(csatSurveyTestColumns).
+				AddRow(3, 1, 12, 2, 5, trimmed, now, now.AddDate(0, 0, 14), now, "TCK-2025-0001", "Headset broken", "IT Staff"))
+
+		survey, err := model.Respond(3, 12, 5, &comment)
+		require.NoError(t, err)
+		require.NotNil(t, survey.Rating)
+		assert.Equal(t, 5, *survey.Rating)
+		assert.NotNil(t, survey.RespondedAt)
+	})
+
+	t.Run("rating out of range", func(t *testing.T) {
+		_, err := model.Respond(3, 12, 6, nil)
+		require.Error(t, err)
+		assert.IsType(t, &CSATError{}, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE ticket_csat_surveys`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT expires_at <= NOW\(\) FROM ticket_csat_surveys`).
+			WithArgs(int64(3), int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"expired"}).AddRow(true))
+
+		_, err := model.Respond(3, 12, 4, nil)
+		require.Error(t, err)
+		assert.Equal(t, "survey has expired", err.Error())
+	})
+
+	t.Run("someone else's survey", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE ticket_csat_surveys`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT expires_at <= NOW\(\) FROM ticket_csat_surveys`).
+			WithArgs(int64(3), int64(99)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := model.Respond(3, 99, 4, nil)
+		require.Error(t, err)
+		assert.Equal(t, "survey not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
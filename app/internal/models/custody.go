@@ -0,0 +1,251 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Custody records track each handover of an asset to a user. A record starts
+// as pending, becomes accepted once the recipient acknowledges it, and is
+// returned (or cancelled, if never accepted) when the asset comes back.
+type AssetCustody struct {
+	ID              int64      `json:"id"`
+	AssetID         int64      `json:"asset_id"`
+	UserID          int64      `json:"user_id"`
+	Status          string     `json:"status"` // pending, accepted, returned, cancelled
+	AssignedBy      *int64     `json:"assigned_by"`
+	AssignedAt      time.Time  `json:"assigned_at"`
+	AcknowledgeBy   time.Time  `json:"acknowledge_by"` // Deadline for the recipient to accept
+	AcceptedAt      *time.Time `json:"accepted_at"`
+	AcceptCondition string     `json:"accept_condition"`
+	AcceptNotes     string     `json:"accept_notes"`
+	ReturnedAt      *time.Time `json:"returned_at"`
+	ReturnCondition string     `json:"return_condition"`
+	ReturnNotes     string     `json:"return_notes"`
+	ReceivedBy      *int64     `json:"received_by"`
+	Overdue         bool       `json:"overdue"` // Pending past its acknowledge_by deadline
+
+	// Joined fields
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+	UserFullName    string `json:"user_full_name,omitempty"`
+}
+
+// DefaultAcknowledgeWindow is how long a recipient has to accept a handover
+const DefaultAcknowledgeWindow = 48 * time.Hour
+
+// custodyConditions are the condition grades accepted at check-out and check-in
+var custodyConditions = map[string]bool{
+	"new": true, "good": true, "fair": true, "poor": true, "damaged": true,
+}
+
+// IsValidCustodyCondition reports whether c is a known condition grade
+func IsValidCustodyCondition(c string) bool {
+	return custodyConditions[c]
+}
+
+type CustodyModel struct {
+	DB *sql.DB
+}
+
+func NewCustodyModel(db *sql.DB) *CustodyModel {
+	return &CustodyModel{DB: db}
+}
+
+const custodyColumns = `
+			c.id, c.asset_id, c.user_id, c.status, c.assigned_by, c.assigned_at,
+			c.acknowledge_by, c.accepted_at, c.accept_condition, c.accept_notes,
+			c.returned_at, c.return_condition, c.return_notes, c.received_by,
+			(c.status = 'pending' AND c.acknowledge_by < NOW()) AS overdue,
+			a.internal_id, u.full_name`
+
+const custodyJoins = `
+		FROM asset_custody c
+		JOIN assets a ON a.id = c.asset_id
+		JOIN users u ON u.id = c.user_id`
+
+func scanCustody(row rowScanner) (*AssetCustody, error) {
+	var c AssetCustody
+	err := row.Scan(
+		&c.ID,
+		&c.AssetID,
+		&c.UserID,
+		&c.Status,
+		&c.AssignedBy,
+		&c.AssignedAt,
+		&c.AcknowledgeBy,
+		&c.AcceptedAt,
+		&c.AcceptCondition,
+		&c.AcceptNotes,
+		&c.ReturnedAt,
+		&c.ReturnCondition,
+		&c.ReturnNotes,
+		&c.ReceivedBy,
+		&c.Overdue,
+		&c.AssetInternalID,
+		&c.UserFullName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (m *CustodyModel) queryCustody(query string, args ...interface{}) ([]AssetCustody, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AssetCustody
+	for rows.Next() {
+		c, err := scanCustody(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *c)
+	}
+
+	return records, rows.Err()
+}
+
+// Open starts a pending handover. Any handover still open for the asset is
+// closed first: pending ones are cancelled, accepted ones are marked returned.
+func (m *CustodyModel) Open(custody *AssetCustody) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := openCustodyTx(tx, custody); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// openCustodyTx starts a pending handover as part of the caller's transaction
+func openCustodyTx(tx *sql.Tx, custody *AssetCustody) error {
+	_, err := tx.Exec(`
+		UPDATE asset_custody
+		SET status = CASE WHEN status = 'pending' THEN 'cancelled' ELSE 'returned' END,
+		    returned_at = NOW()
+		WHERE asset_id = $1 AND status IN ('pending', 'accepted')
+	`, custody.AssetID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO asset_custody (asset_id, user_id, assigned_by, acknowledge_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, assigned_at
+	`, custody.AssetID, custody.UserID, custody.AssignedBy, custody.AcknowledgeBy).Scan(
+		&custody.ID, &custody.Status, &custody.AssignedAt,
+	)
+	return err
+}
+
+// Get custody record by ID
+func (m *CustodyModel) GetByID(id int64) (*AssetCustody, error) {
+	query := `SELECT ` + custodyColumns + custodyJoins + ` WHERE c.id = $1`
+
+	c, err := scanCustody(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("custody record not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Accept records the recipient's acknowledgement of a pending handover
+func (m *CustodyModel) Accept(id, userID int64, condition, notes string) error {
+	query := `
+		UPDATE asset_custody
+		SET status = 'accepted', accepted_at = NOW(), accept_condition = $1, accept_notes = $2
+		WHERE id = $3 AND user_id = $4 AND status = 'pending'
+	`
+
+	result, err := m.DB.Exec(query, condition, notes, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("custody record not found or not pending")
+	}
+
+	return nil
+}
+
+// CustodyCheckIn describes an asset coming back when its handover is closed
+type CustodyCheckIn struct {
+	ReceivedBy *int64
+	Condition  string
+	Notes      string
+}
+
+// CheckIn closes the open handover for an asset, recording its condition on return
+func (m *CustodyModel) CheckIn(assetID, receivedBy int64, condition, notes string) (*AssetCustody, error) {
+	id, err := checkInCustody(m.DB, assetID, CustodyCheckIn{ReceivedBy: &receivedBy, Condition: condition, Notes: notes})
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, errors.New("no open custody record")
+	}
+
+	return m.GetByID(id)
+}
+
+// checkInCustody closes the asset's open handover and returns its ID, or 0
+// when there is none (assets assigned before custody tracking have none)
+func checkInCustody(db dbtx, assetID int64, checkIn CustodyCheckIn) (int64, error) {
+	var id int64
+	err := db.QueryRow(`
+		UPDATE asset_custody
+		SET status = CASE WHEN status = 'pending' THEN 'cancelled' ELSE 'returned' END,
+		    returned_at = NOW(), return_condition = $1, return_notes = $2, received_by = $3
+		WHERE asset_id = $4 AND status IN ('pending', 'accepted')
+		RETURNING id
+	`, checkIn.Condition, checkIn.Notes, checkIn.ReceivedBy, assetID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetByAsset returns the custody history of an asset, newest first
+func (m *CustodyModel) GetByAsset(assetID int64) ([]AssetCustody, error) {
+	query := `SELECT ` + custodyColumns + custodyJoins + `
+		WHERE c.asset_id = $1
+		ORDER BY c.assigned_at DESC`
+
+	return m.queryCustody(query, assetID)
+}
+
+// GetPendingForUser returns handovers waiting for the user's acknowledgement
+func (m *CustodyModel) GetPendingForUser(userID int64) ([]AssetCustody, error) {
+	query := `SELECT ` + custodyColumns + custodyJoins + `
+		WHERE c.user_id = $1 AND c.status = 'pending'
+		ORDER BY c.acknowledge_by`
+
+	return m.queryCustody(query, userID)
+}
+
+// GetOverdue returns pending handovers whose acknowledgement deadline has passed
+func (m *CustodyModel) GetOverdue() ([]AssetCustody, error) {
+	query := `SELECT ` + custodyColumns + custodyJoins + `
+		WHERE c.status = 'pending' AND c.acknowledge_by < NOW()
+		ORDER BY c.acknowledge_by`
+
+	return m.queryCustody(query)
+}
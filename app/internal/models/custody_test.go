@@ -0,0 +1,94 @@
+// file: app/internal/models/custody_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCustodyTest(t *testing.T) (*CustodyModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewCustodyModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestCustodyModel_Open(t *testing.T) {
+	model, mock, teardown := setupCustodyTest(t)
+	defer teardown()
+
+	now := time.Now()
+	assignedBy := int64(2)
+	custody := &AssetCustody{
+		AssetID:       5,
+		UserID:        7,
+		AssignedBy:    &assignedBy,
+		AcknowledgeBy: now.Add(DefaultAcknowledgeWindow),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE asset_custody`).
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO asset_custody`).
+		WithArgs(int64(5), int64(7), &assignedBy, custody.AcknowledgeBy).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "assigned_at"}).
+			AddRow(1, "pending", now))
+	mock.ExpectCommit()
+
+	err := model.Open(custody)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), custody.ID)
+	assert.Equal(t, "pending", custody.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCustodyModel_Accept(t *testing.T) {
+	model, mock, teardown := setupCustodyTest(t)
+	defer teardown()
+
+	t.Run("successful accept", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs("good", "Minor scuff on left ear cup", int64(1), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := model.Accept(1, 7, "good", "Minor scuff on left ear cup")
+		assert.NoError(t, err)
+	})
+
+	t.Run("not pending", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs("good", "", int64(1), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.Accept(1, 7, "good", "")
+		assert.Error(t, err)
+		assert.Equal(t, "custody record not found or not pending", err.Error())
+	})
+}
+
+func TestCustodyModel_CheckIn(t *testing.T) {
+	model, mock, teardown := setupCustodyTest(t)
+	defer teardown()
+
+	t.Run("no open record", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("fair", "", int64(2), int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		custody, err := model.CheckIn(5, 2, "fair", "")
+		assert.Error(t, err)
+		assert.Nil(t, custody)
+		assert.Equal(t, "no open custody record", err.Error())
+	})
+}
@@ -0,0 +1,496 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomField is one admin-defined field in an asset type's schema
+type CustomField struct {
+	ID        int64     `json:"id"`
+	AssetType string    `json:"asset_type"` // PC, Monitor, UPS, etc.
+	Key       string    `json:"key"`        // Key in assets.custom_fields, e.g. ram_gb
+	Label     string    `json:"label"`      // Display name, e.g. "RAM (GB)"
+	FieldType string    `json:"field_type"` // text, number, date, enum, boolean
+	Required  bool      `json:"required"`
+	Unique    bool      `json:"unique"`  // Unique among assets of the same type
+	Options   []string  `json:"options"` // Allowed values for enum fields
+	SortOrder int       `json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var customFieldTypes = map[string]bool{
+	"text": true, "number": true, "date": true, "enum": true, "boolean": true,
+}
+
+// customFieldKeyPattern keeps keys safe to use in JSONB paths and query params
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// IsValidCustomFieldKey reports whether key is a well-formed custom field key
+func IsValidCustomFieldKey(key string) bool {
+	return customFieldKeyPattern.MatchString(key)
+}
+
+// Validate checks a field definition before it is saved
+func (f *CustomField) Validate() error {
+	if f.AssetType == "" || f.Label == "" {
+		return errors.New("asset_type and label are required")
+	}
+	if !IsValidCustomFieldKey(f.Key) {
+		return errors.New("key must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+	if !customFieldTypes[f.FieldType] {
+		return errors.New("field_type must be one of: text, number, date, enum, boolean")
+	}
+	if f.FieldType == "enum" && len(f.Options) == 0 {
+		return errors.New("enum fields need at least one option")
+	}
+	if f.FieldType != "enum" {
+		f.Options = []string{}
+	}
+	return nil
+}
+
+// normaliseCustomFieldValue checks one value against its field and returns it
+// normalised; ok is false for an empty value
+func normaliseCustomFieldValue(field CustomField, value interface{}) (interface{}, bool, error) {
+	if value == nil {
+		return nil, false, nil
+	}
+
+	switch field.FieldType {
+	case "text":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("%s must be text", field.Key)
+		}
+		if s == "" {
+			return nil, false, nil
+		}
+		return s, true, nil
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, true, nil
+		case int:
+			return float64(v), true, nil
+		case int64:
+			return float64(v), true, nil
+		case string:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s must be a number", field.Key)
+			}
+			return n, true, nil
+		default:
+			return nil, false, fmt.Errorf("%s must be a number", field.Key)
+		}
+	case "date":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("%s must be a date (YYYY-MM-DD)", field.Key)
+		}
+		var parsed time.Time
+		var err error
+		for _, format := range []string{"2006-01-02", time.RFC3339} {
+			if parsed, err = time.Parse(format, s); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%s must be a date (YYYY-MM-DD)", field.Key)
+		}
+		return parsed.Format("2006-01-02"), true, nil
+	case "enum":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("%s must be one of: %s", field.Key, strings.Join(field.Options, ", "))
+		}
+		allowed := false
+		for _, option := range field.Options {
+			if option == s {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, false, fmt.Errorf("%s must be one of: %s", field.Key, strings.Join(field.Options, ", "))
+		}
+		return s, true, nil
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, false, fmt.Errorf("%s must be true or false", field.Key)
+		}
+		return b, true, nil
+	}
+	return nil, false, nil
+}
+
+// ValidateCustomFieldValues checks values against an asset type's schema and
+// returns them normalised: numbers as float64, dates as YYYY-MM-DD.
+func ValidateCustomFieldValues(schema []CustomField, values map[string]interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]CustomField, len(schema))
+	for _, f := range schema {
+		fields[f.Key] = f
+	}
+
+	normalised := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field: %s", key)
+		}
+		v, ok, err := normaliseCustomFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			normalised[key] = v
+		}
+	}
+
+	for _, f := range schema {
+		if _, ok := normalised[f.Key]; f.Required && !ok {
+			return nil, fmt.Errorf("%s is required", f.Key)
+		}
+	}
+
+	return normalised, nil
+}
+
+// ValidateCustomFieldChanges checks only the values an update sends and merges
+// them into the stored ones; a nil value removes a key. Stored values are
+// kept as they are, so keys of deleted fields stay and a field made required
+// later only has to be filled in when it is changed.
+func ValidateCustomFieldChanges(schema []CustomField, current, changes map[string]interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]CustomField, len(schema))
+	for _, f := range schema {
+		fields[f.Key] = f
+	}
+
+	merged := make(map[string]interface{}, len(current)+len(changes))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range changes {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field: %s", key)
+		}
+		v, ok, err := normaliseCustomFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if field.Required {
+				return nil, fmt.Errorf("%s is required", key)
+			}
+			delete(merged, key)
+			continue
+		}
+		merged[key] = v
+	}
+
+	return merged, nil
+}
+
+// CustomFieldText renders a normalised value the way Postgres' ->> operator does
+func CustomFieldText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+type CustomFieldModel struct {
+	DB *sql.DB
+}
+
+func NewCustomFieldModel(db *sql.DB) *CustomFieldModel {
+	return &CustomFieldModel{DB: db}
+}
+
+const customFieldColumns = `
+			id, asset_type, key, label, field_type, required, is_unique,
+			options, sort_order, created_at, updated_at`
+
+func scanCustomField(row rowScanner) (*CustomField, error) {
+	var f CustomField
+	var options []byte
+	err := row.Scan(
+		&f.ID,
+		&f.AssetType,
+		&f.Key,
+		&f.Label,
+		&f.FieldType,
+		&f.Required,
+		&f.Unique,
+		&options,
+		&f.SortOrder,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	f.Options = []string{}
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &f.Options); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// Insert a new custom field definition
+func (m *CustomFieldModel) Insert(f *CustomField) error {
+	options, err := json.Marshal(f.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO asset_custom_fields (asset_type, key, label, field_type, required, is_unique, options, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	err = m.DB.QueryRow(
+		query,
+		f.AssetType,
+		f.Key,
+		f.Label,
+		f.FieldType,
+		f.Required,
+		f.Unique,
+		string(options),
+		f.SortOrder,
+	).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil && strings.Contains(err.Error(), "duplicate key") {
+		return errors.New("custom field key already exists for this asset type")
+	}
+
+	return err
+}
+
+// Get custom field by ID
+func (m *CustomFieldModel) GetByID(id int64) (*CustomField, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM asset_custom_fields WHERE id = $1`
+
+	f, err := scanCustomField(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("custom field not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// GetByAssetType returns the schema for an asset type; empty returns every schema
+func (m *CustomFieldModel) GetByAssetType(assetType string) ([]CustomField, error) {
+	return customFieldSchema(m.DB, assetType)
+}
+
+func customFieldSchema(db dbtx, assetType string) ([]CustomField, error) {
+	query := `
+		SELECT ` + customFieldColumns + `
+		FROM asset_custom_fields
+		WHERE ($1 = '' OR asset_type = $1)
+		ORDER BY asset_type, sort_order, key
+	`
+
+	rows, err := db.Query(query, assetType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []CustomField{}
+	for rows.Next() {
+		f, err := scanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, *f)
+	}
+
+	return fields, rows.Err()
+}
+
+// Update a custom field definition. The asset type and key are fixed once
+// created because existing asset values are stored under them.
+func (m *CustomFieldModel) Update(f *CustomField) error {
+	options, err := json.Marshal(f.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE asset_custom_fields
+		SET label = $1, field_type = $2, required = $3, is_unique = $4,
+		    options = $5, sort_order = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING updated_at
+	`
+
+	err = m.DB.QueryRow(
+		query,
+		f.Label,
+		f.FieldType,
+		f.Required,
+		f.Unique,
+		string(options),
+		f.SortOrder,
+		f.ID,
+	).Scan(&f.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("custom field not found")
+	}
+
+	return err
+}
+
+// Delete a custom field definition. Values already stored on assets are kept
+// but no longer validated or shown in the schema.
+func (m *CustomFieldModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM asset_custom_fields WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("custom field not found")
+	}
+
+	return nil
+}
+
+// ValueTaken reports whether another live asset of the type already has this
+// value for a unique custom field
+func (m *CustomFieldModel) ValueTaken(assetType, key string, value interface{}, excludeAssetID int64) (bool, error) {
+	return customFieldValueTaken(m.DB, assetType, key, value, excludeAssetID)
+}
+
+func customFieldValueTaken(db dbtx, assetType, key string, value interface{}, excludeAssetID int64) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM assets
+			WHERE asset_type = $1 AND custom_fields->>$2 = $3
+			AND id != $4 AND deleted_at IS NULL
+		)
+	`
+
+	var taken bool
+	err := db.QueryRow(query, assetType, key, CustomFieldText(value), excludeAssetID).Scan(&taken)
+	return taken, err
+}
+
+// CustomFieldError is a validation failure the client can fix, as opposed to a
+// database error
+type CustomFieldError struct {
+	Message string
+}
+
+func (e *CustomFieldError) Error() string {
+	return e.Message
+}
+
+// ValidateForAsset validates an asset's custom field values against its type's
+// schema, including unique checks, and stores the normalised values back on it
+func (m *CustomFieldModel) ValidateForAsset(asset *Asset) error {
+	return validateAssetCustomFields(m.DB, asset)
+}
+
+func validateAssetCustomFields(db dbtx, asset *Asset) error {
+	schema, err := customFieldSchema(db, asset.AssetType)
+	if err != nil {
+		return err
+	}
+
+	values, err := ValidateCustomFieldValues(schema, asset.CustomFields)
+	if err != nil {
+		return &CustomFieldError{Message: err.Error()}
+	}
+
+	if err := checkCustomFieldsUnique(db, asset, schema, values, nil); err != nil {
+		return err
+	}
+
+	asset.CustomFields = values
+	return nil
+}
+
+// ValidateChangesForAsset validates the custom field values an update sends
+// and merges them into the asset's stored values, which are not revalidated.
+// An asset moved to another type must fill in that type's required fields.
+func (m *CustomFieldModel) ValidateChangesForAsset(asset *Asset, changes map[string]interface{}, typeChanged bool) error {
+	schema, err := m.GetByAssetType(asset.AssetType)
+	if err != nil {
+		return err
+	}
+
+	values, err := ValidateCustomFieldChanges(schema, asset.CustomFields, changes)
+	if err != nil {
+		return &CustomFieldError{Message: err.Error()}
+	}
+	if typeChanged {
+		for _, f := range schema {
+			if _, ok := values[f.Key]; f.Required && !ok {
+				return &CustomFieldError{Message: fmt.Sprintf("%s is required", f.Key)}
+			}
+		}
+	}
+
+	// Moving type can clash with the new type's unique values; otherwise
+	// only the values being changed need checking
+	only := changes
+	if typeChanged {
+		only = nil
+	}
+	if err := checkCustomFieldsUnique(m.DB, asset, schema, values, only); err != nil {
+		return err
+	}
+
+	asset.CustomFields = values
+	return nil
+}
+
+// checkCustomFieldsUnique refuses values of unique fields another asset of the
+// same type already has. With only set, just those keys are checked.
+func checkCustomFieldsUnique(db dbtx, asset *Asset, schema []CustomField, values, only map[string]interface{}) error {
+	for _, f := range schema {
+		value, ok := values[f.Key]
+		if !f.Unique || !ok {
+			continue
+		}
+		if _, changed := only[f.Key]; only != nil && !changed {
+			continue
+		}
+		taken, err := customFieldValueTaken(db, asset.AssetType, f.Key, value, asset.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return &CustomFieldError{Message: fmt.Sprintf("%s must be unique: %s is already used by another %s", f.Key, CustomFieldText(value), asset.AssetType)}
+		}
+	}
+	return nil
+}
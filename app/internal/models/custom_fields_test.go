@@ -0,0 +1,175 @@
+// file: app/internal/models/custom_fields_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pcSchema() []CustomField {
+	return []CustomField{
+		{AssetType: "PC", Key: "hostname", FieldType: "text", Required: true, Unique: true},
+		{AssetType: "PC", Key: "ram_gb", FieldType: "number"},
+		{AssetType: "PC", Key: "os", FieldType: "enum", Options: []string{"Windows 10", "Windows 11", "Ubuntu"}},
+		{AssetType: "PC", Key: "warranty_end", FieldType: "date"},
+		{AssetType: "PC", Key: "encrypted", FieldType: "boolean"},
+	}
+}
+
+func TestValidateCustomFieldValues(t *testing.T) {
+	t.Run("valid values are normalised", func(t *testing.T) {
+		values, err := ValidateCustomFieldValues(pcSchema(), map[string]interface{}{
+			"hostname":     "DPA-PC001",
+			"ram_gb":       "16",
+			"os":           "Windows 11",
+			"warranty_end": "2026-03-01T00:00:00Z",
+			"encrypted":    true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, float64(16), values["ram_gb"])
+		assert.Equal(t, "2026-03-01", values["warranty_end"])
+		assert.Equal(t, true, values["encrypted"])
+	})
+
+	t.Run("missing required field", func(t *testing.T) {
+		_, err := ValidateCustomFieldValues(pcSchema(), map[string]interface{}{"ram_gb": 8.0})
+		assert.EqualError(t, err, "hostname is required")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := ValidateCustomFieldValues(pcSchema(), map[string]interface{}{
+			"hostname": "DPA-PC001",
+			"gpu":      "RTX",
+		})
+		assert.EqualError(t, err, "unknown custom field: gpu")
+	})
+
+	t.Run("invalid enum option", func(t *testing.T) {
+		_, err := ValidateCustomFieldValues(pcSchema(), map[string]interface{}{
+			"hostname": "DPA-PC001",
+			"os":       "macOS",
+		})
+		assert.EqualError(t, err, "os must be one of: Windows 10, Windows 11, Ubuntu")
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, err := ValidateCustomFieldValues(pcSchema(), map[string]interface{}{
+			"hostname":  "DPA-PC001",
+			"encrypted": "yes",
+		})
+		assert.EqualError(t, err, "encrypted must be true or false")
+	})
+}
+
+func TestCustomField_Validate(t *testing.T) {
+	f := &CustomField{AssetType: "PC", Key: "MAC Address", Label: "MAC", FieldType: "text"}
+	assert.Error(t, f.Validate())
+
+	f = &CustomField{AssetType: "Monitor", Key: "panel", Label: "Panel", FieldType: "enum"}
+	assert.EqualError(t, f.Validate(), "enum fields need at least one option")
+
+	f = &CustomField{AssetType: "Monitor", Key: "size_in", Label: "Size", FieldType: "number", Options: []string{"x"}}
+	assert.NoError(t, f.Validate())
+	assert.Empty(t, f.Options)
+}
+
+func TestAssetsModel_SearchAssets_CustomFields(t *testing.T) {
+	model, mock, teardown := setupAssetTest(t)
+	defer teardown()
+
+	filters := AssetSearchFilters{
+		AssetType:    "PC",
+		CustomFields: map[string]string{"os": "Windows 11"},
+		SortBy:       "cf.ram_gb",
+		SortOrder:    "DESC",
+	}
+
+	mock.ExpectQuery(`deleted_at IS NULL.*asset_type = \$1.*LOWER\(custom_fields->>\$2\) = \$3.*ORDER BY CASE WHEN jsonb_typeof\(custom_fields->\$4\) = 'number'`).
+		WithArgs("PC", "os", "windows 11", "ram_gb").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := model.SearchAssets("", filters)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateCustomFieldChanges(t *testing.T) {
+	// Stored before gpu was deleted from the schema and hostname made required
+	stored := map[string]interface{}{"gpu": "RTX", "ram_gb": 8.0}
+
+	t.Run("stale and missing fields are left alone", func(t *testing.T) {
+		values, err := ValidateCustomFieldChanges(pcSchema(), stored, map[string]interface{}{"ram_gb": "16"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"gpu": "RTX", "ram_gb": float64(16)}, values)
+		assert.Equal(t, 8.0, stored["ram_gb"])
+	})
+
+	t.Run("null removes a key", func(t *testing.T) {
+		values, err := ValidateCustomFieldChanges(pcSchema(), stored, map[string]interface{}{"ram_gb": nil})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"gpu": "RTX"}, values)
+	})
+
+	t.Run("sent keys are still checked", func(t *testing.T) {
+		_, err := ValidateCustomFieldChanges(pcSchema(), stored, map[string]interface{}{"gpu": "GTX"})
+		assert.EqualError(t, err, "unknown custom field: gpu")
+
+		_, err = ValidateCustomFieldChanges(pcSchema(), stored, map[string]interface{}{"hostname": ""})
+		assert.EqualError(t, err, "hostname is required")
+	})
+}
+
+var customFieldSchemaColumns = []string{
+	"id", "asset_type", "key", "label", "field_type", "required", "is_unique",
+	"options", "sort_order", "created_at", "updated_at",
+}
+
+func TestCustomFieldModel_ValidateChangesForAsset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	model := NewCustomFieldModel(db)
+
+	schemaRows := func() *sqlmock.Rows {
+		// hostname was made required after the asset was saved; gpu was deleted
+		return sqlmock.NewRows(customFieldSchemaColumns).
+			AddRow(1, "PC", "hostname", "Hostname", "text", true, true, []byte("[]"), 0, time.Now(), time.Now()).
+			AddRow(2, "PC", "ram_gb", "RAM (GB)", "number", false, false, []byte("[]"), 1, time.Now(), time.Now())
+	}
+
+	t.Run("update after schema changes", func(t *testing.T) {
+		asset := &Asset{ID: 5, AssetType: "PC", CustomFields: map[string]interface{}{"gpu": "RTX", "ram_gb": 8.0}}
+		mock.ExpectQuery(`FROM asset_custom_fields`).WithArgs("PC").WillReturnRows(schemaRows())
+
+		err := model.ValidateChangesForAsset(asset, map[string]interface{}{"ram_gb": 32}, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"gpu": "RTX", "ram_gb": float64(32)}, asset.CustomFields)
+	})
+
+	t.Run("moving type needs its required fields", func(t *testing.T) {
+		asset := &Asset{ID: 5, AssetType: "PC", CustomFields: map[string]interface{}{"ram_gb": 8.0}}
+		mock.ExpectQuery(`FROM asset_custom_fields`).WithArgs("PC").WillReturnRows(schemaRows())
+
+		err := model.ValidateChangesForAsset(asset, nil, true)
+		var fieldErr *CustomFieldError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.EqualError(t, err, "hostname is required")
+	})
+
+	t.Run("changed unique values are checked", func(t *testing.T) {
+		asset := &Asset{ID: 5, AssetType: "PC", CustomFields: map[string]interface{}{}}
+		mock.ExpectQuery(`FROM asset_custom_fields`).WithArgs("PC").WillReturnRows(schemaRows())
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs("PC", "hostname", "DPA-PC001", int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := model.ValidateChangesForAsset(asset, map[string]interface{}{"hostname": "DPA-PC001"}, false)
+		assert.EqualError(t, err, "hostname must be unique: DPA-PC001 is already used by another PC")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
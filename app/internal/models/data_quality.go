@@ -0,0 +1,293 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// DataQualityFinding is one asset record that fails a data-quality check
+type DataQualityFinding struct {
+	Check      string `json:"check"`
+	Severity   string `json:"severity"` // error, warning
+	AssetID    int64  `json:"asset_id"`
+	InternalID string `json:"internal_id"`
+	AssetType  string `json:"asset_type"`
+	Detail     string `json:"detail"`
+	Fixable    bool   `json:"fixable"` // The bulk fix for this check can correct it
+}
+
+// DataQualityCheck describes one check. Fix is empty when the right correction
+// needs a person to decide, e.g. which of two assets owns a serial number.
+type DataQualityCheck struct {
+	Name        string `json:"name"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	HasFix      bool   `json:"has_fix"`
+	FixAction   string `json:"fix_action,omitempty"`
+
+	// find selects id, internal_id, asset_type, detail, fixable for every
+	// failing live asset. fix corrects the fixable ones, limited to one asset
+	// when $1 is not NULL, and returns the IDs it changed. Both repeat the
+	// failing condition so a fix never touches a record that has since been
+	// corrected by hand.
+	find string
+	fix  string
+}
+
+// openHolder is the user an asset is with according to its open custody
+// record or checked-out loan
+const openHolder = `
+	COALESCE(
+		(SELECT c.user_id FROM asset_custody c WHERE c.asset_id = a.id AND c.status IN ('pending', 'accepted')),
+		(SELECT r.user_id FROM asset_reservations r WHERE r.asset_id = a.id AND r.status = 'checked_out'))`
+
+var dataQualityChecks = []DataQualityCheck{
+	{
+		Name:        "duplicate_serial",
+		Severity:    "error",
+		Description: "Serial number is shared with another asset",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       'Serial ' || a.serial_number || ' is also on ' ||
+			       (SELECT string_agg(o.internal_id, ', ' ORDER BY o.internal_id) FROM assets o
+			        WHERE o.deleted_at IS NULL AND o.id != a.id
+			        AND LOWER(TRIM(o.serial_number)) = LOWER(TRIM(a.serial_number))),
+			       false
+			FROM assets a
+			WHERE a.deleted_at IS NULL AND TRIM(COALESCE(a.serial_number, '')) != ''
+			AND EXISTS (SELECT 1 FROM assets o
+			            WHERE o.deleted_at IS NULL AND o.id != a.id
+			            AND LOWER(TRIM(o.serial_number)) = LOWER(TRIM(a.serial_number)))
+			ORDER BY LOWER(TRIM(a.serial_number)), a.internal_id`,
+	},
+	{
+		Name:        "in_use_without_user",
+		Severity:    "error",
+		Description: "Asset is IN_USE but not assigned to anyone",
+		HasFix:      true,
+		FixAction:   "Assign to the holder of its open custody record or loan",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       CASE WHEN ` + openHolder + ` IS NULL
+			            THEN 'IN_USE with no assignee and no open custody record or loan'
+			            ELSE 'IN_USE with no assignee; open custody or loan is with user ' || ` + openHolder + `
+			       END,
+			       ` + openHolder + ` IS NOT NULL
+			FROM assets a
+			WHERE a.deleted_at IS NULL AND a.status = 'IN_USE' AND a.in_use_by IS NULL
+			ORDER BY a.internal_id`,
+		fix: `
+			UPDATE assets a SET in_use_by = ` + openHolder + `, updated_at = NOW()
+			WHERE a.deleted_at IS NULL AND a.status = 'IN_USE' AND a.in_use_by IS NULL
+			AND ` + openHolder + ` IS NOT NULL
+			AND ($1::bigint IS NULL OR a.id = $1)
+			RETURNING a.id`,
+	},
+	{
+		Name:        "assigned_not_in_use",
+		Severity:    "error",
+		Description: "Asset is assigned to a user but its status is not IN_USE",
+		HasFix:      true,
+		FixAction:   "Clear the assignee when no custody record or loan is open",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       a.status || ' but assigned to ' || u.username ||
+			       CASE WHEN ` + openHolder + ` IS NOT NULL THEN ' with an open custody record or loan' ELSE '' END,
+			       ` + openHolder + ` IS NULL
+			FROM assets a
+			JOIN users u ON u.id = a.in_use_by
+			WHERE a.deleted_at IS NULL AND a.status != 'IN_USE'
+			ORDER BY a.internal_id`,
+		fix: `
+			UPDATE assets a SET in_use_by = NULL, updated_at = NOW()
+			WHERE a.deleted_at IS NULL AND a.status != 'IN_USE' AND a.in_use_by IS NOT NULL
+			AND ` + openHolder + ` IS NULL
+			AND ($1::bigint IS NULL OR a.id = $1)
+			RETURNING a.id`,
+	},
+	{
+		Name:        "assigned_to_inactive_user",
+		Severity:    "error",
+		Description: "Asset is assigned to a deactivated user and should be collected",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       'Assigned to inactive user ' || u.username, false
+			FROM assets a
+			JOIN users u ON u.id = a.in_use_by
+			WHERE a.deleted_at IS NULL AND NOT u.is_active
+			ORDER BY u.username, a.internal_id`,
+	},
+	{
+		Name:        "service_dates_out_of_sync",
+		Severity:    "warning",
+		Description: "Last service date does not match the asset's service log",
+		HasFix:      true,
+		FixAction:   "Set the last service date from the latest service log",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       'Last service date is ' || COALESCE(TO_CHAR(a.last_service_date, 'YYYY-MM-DD'), 'empty') ||
+			       ' but the latest service log is ' || TO_CHAR(s.last_service, 'YYYY-MM-DD'),
+			       true
+			FROM assets a
+			JOIN (SELECT asset_id, MAX(performed_at)::date AS last_service
+			      FROM asset_service GROUP BY asset_id) s ON s.asset_id = a.id
+			WHERE a.deleted_at IS NULL AND a.last_service_date IS DISTINCT FROM s.last_service
+			ORDER BY a.internal_id`,
+		fix: `
+			UPDATE assets a SET last_service_date = s.last_service, updated_at = NOW()
+			FROM (SELECT asset_id, MAX(performed_at)::date AS last_service
+			      FROM asset_service GROUP BY asset_id) s
+			WHERE s.asset_id = a.id AND a.deleted_at IS NULL
+			AND a.last_service_date IS DISTINCT FROM s.last_service
+			AND ($1::bigint IS NULL OR a.id = $1)
+			RETURNING a.id`,
+	},
+	{
+		Name:        "service_overdue",
+		Severity:    "warning",
+		Description: "Next service date has passed",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       'Next service was due ' || TO_CHAR(a.next_service_date, 'YYYY-MM-DD'), false
+			FROM assets a
+			WHERE a.deleted_at IS NULL AND a.status != 'RETIRED'
+			AND a.next_service_date < CURRENT_DATE
+			ORDER BY a.next_service_date, a.internal_id`,
+	},
+	{
+		Name:        "service_date_in_future",
+		Severity:    "warning",
+		Description: "Last service date is in the future, or the next service is not after it",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       CASE WHEN a.last_service_date > CURRENT_DATE
+			            THEN 'Last service date ' || TO_CHAR(a.last_service_date, 'YYYY-MM-DD') || ' is in the future'
+			            ELSE 'Next service ' || TO_CHAR(a.next_service_date, 'YYYY-MM-DD') ||
+			                 ' is not after the last service ' || TO_CHAR(a.last_service_date, 'YYYY-MM-DD')
+			       END,
+			       false
+			FROM assets a
+			WHERE a.deleted_at IS NULL
+			AND (a.last_service_date > CURRENT_DATE OR a.next_service_date <= a.last_service_date)
+			ORDER BY a.internal_id`,
+	},
+	{
+		Name:        "purchased_after_created",
+		Severity:    "warning",
+		Description: "Purchase date is later than the date the asset was recorded",
+		find: `
+			SELECT a.id, a.internal_id, a.asset_type,
+			       'Purchased ' || TO_CHAR(a.date_purchased, 'YYYY-MM-DD') ||
+			       ' but recorded ' || TO_CHAR(a.created_at, 'YYYY-MM-DD'),
+			       false
+			FROM assets a
+			WHERE a.deleted_at IS NULL AND a.date_purchased > a.created_at::date
+			ORDER BY a.internal_id`,
+	},
+}
+
+// DataQualityChecks returns every check the engine runs, in report order
+func DataQualityChecks() []DataQualityCheck {
+	return dataQualityChecks
+}
+
+func findDataQualityCheck(name string) (*DataQualityCheck, error) {
+	for i := range dataQualityChecks {
+		if dataQualityChecks[i].Name == name {
+			return &dataQualityChecks[i], nil
+		}
+	}
+	return nil, errors.New("unknown data-quality check")
+}
+
+type DataQualityModel struct {
+	DB *sql.DB
+}
+
+func NewDataQualityModel(db *sql.DB) *DataQualityModel {
+	return &DataQualityModel{DB: db}
+}
+
+// GetFindings runs one check, or all of them when check is empty
+func (m *DataQualityModel) GetFindings(check string) ([]DataQualityFinding, error) {
+	checks := dataQualityChecks
+	if check != "" {
+		c, err := findDataQualityCheck(check)
+		if err != nil {
+			return nil, err
+		}
+		checks = []DataQualityCheck{*c}
+	}
+
+	findings := []DataQualityFinding{}
+	for _, c := range checks {
+		rows, err := m.DB.Query(c.find)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			f := DataQualityFinding{Check: c.Name, Severity: c.Severity}
+			if err := rows.Scan(&f.AssetID, &f.InternalID, &f.AssetType, &f.Detail, &f.Fixable); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			findings = append(findings, f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return findings, nil
+}
+
+// Fix applies a check's bulk fix to the given assets, or to every fixable
+// finding when assetIDs is empty. Assets that no longer fail the check, or
+// that need a manual decision, are skipped. It returns the IDs it changed.
+func (m *DataQualityModel) Fix(check string, assetIDs []int64) ([]int64, error) {
+	c, err := findDataQualityCheck(check)
+	if err != nil {
+		return nil, err
+	}
+	if c.fix == "" {
+		return nil, errors.New("this check has no automatic fix")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	targets := []*int64{nil}
+	if len(assetIDs) > 0 {
+		targets = make([]*int64, len(assetIDs))
+		for i := range assetIDs {
+			targets[i] = &assetIDs[i]
+		}
+	}
+
+	fixed := []int64{}
+	for _, target := range targets {
+		rows, err := tx.Query(c.fix, target)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			fixed = append(fixed, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return fixed, tx.Commit()
+}
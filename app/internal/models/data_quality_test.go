@@ -0,0 +1,108 @@
+// file: app/internal/models/data_quality_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDataQualityTest(t *testing.T) (*DataQualityModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewDataQualityModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var findingRowColumns = []string{"id", "internal_id", "asset_type", "detail", "fixable"}
+
+func TestDataQualityModel_GetFindings(t *testing.T) {
+	model, mock, teardown := setupDataQualityTest(t)
+	defer teardown()
+
+	t.Run("single check", func(t *testing.T) {
+		mock.ExpectQuery(`LOWER\(TRIM\(o.serial_number\)\)`).
+			WillReturnRows(sqlmock.NewRows(findingRowColumns).
+				AddRow(1, "DPA-PC001", "PC", "Serial ABC123 is also on DPA-PC007", false).
+				AddRow(7, "DPA-PC007", "PC", "Serial ABC123 is also on DPA-PC001", false))
+
+		findings, err := model.GetFindings("duplicate_serial")
+		assert.NoError(t, err)
+		require.Len(t, findings, 2)
+		assert.Equal(t, "duplicate_serial", findings[0].Check)
+		assert.Equal(t, "error", findings[0].Severity)
+		assert.False(t, findings[1].Fixable)
+	})
+
+	t.Run("all checks run in order", func(t *testing.T) {
+		for _, c := range DataQualityChecks() {
+			rows := sqlmock.NewRows(findingRowColumns)
+			if c.Name == "in_use_without_user" {
+				rows.AddRow(3, "DPA-PC003", "PC", "IN_USE with no assignee", true)
+			}
+			mock.ExpectQuery(`SELECT a.id, a.internal_id, a.asset_type`).WillReturnRows(rows)
+		}
+
+		findings, err := model.GetFindings("")
+		assert.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "in_use_without_user", findings[0].Check)
+		assert.True(t, findings[0].Fixable)
+	})
+
+	t.Run("unknown check", func(t *testing.T) {
+		_, err := model.GetFindings("bogus")
+		assert.Error(t, err)
+		assert.Equal(t, "unknown data-quality check", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDataQualityModel_Fix(t *testing.T) {
+	model, mock, teardown := setupDataQualityTest(t)
+	defer teardown()
+
+	t.Run("fix all", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE assets a SET in_use_by = NULL`).
+			WithArgs(nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(9))
+		mock.ExpectCommit()
+
+		fixed, err := model.Fix("assigned_not_in_use", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{4, 9}, fixed)
+	})
+
+	t.Run("fix selected assets", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE assets a SET last_service_date`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery(`UPDATE assets a SET last_service_date`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		fixed, err := model.Fix("service_dates_out_of_sync", []int64{4, 5})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{4}, fixed)
+	})
+
+	t.Run("check without a fix", func(t *testing.T) {
+		_, err := model.Fix("duplicate_serial", nil)
+		assert.Error(t, err)
+		assert.Equal(t, "this check has no automatic fix", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
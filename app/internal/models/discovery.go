@@ -0,0 +1,601 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DiscoverySnapshot is one device as reported by an endpoint agent or a
+// network scanner
+type DiscoverySnapshot struct {
+	Hostname     string                 `json:"hostname"`
+	MACAddress   string                 `json:"mac_address"`
+	SerialNumber string                 `json:"serial_number"`
+	OS           string                 `json:"os"`
+	IPAddress    string                 `json:"ip_address"`
+	LastSeen     *time.Time             `json:"last_seen"` // Defaults to the import time
+	Fields       map[string]interface{} `json:"fields"`    // Extra hardware details, e.g. cpu, ram_gb
+}
+
+// DiscoveredDevice is a device seen on the network. Matched devices point at
+// their asset; the rest are candidates to be added as assets or ignored.
+type DiscoveredDevice struct {
+	ID           int64     `json:"id"`
+	Hostname     string    `json:"hostname"`
+	MACAddress   string    `json:"mac_address"`
+	SerialNumber string    `json:"serial_number"`
+	OS           string    `json:"os"`
+	IPAddress    string    `json:"ip_address"`
+	AssetID      *int64    `json:"asset_id"`
+	Status       string    `json:"status"` // matched, candidate, ignored
+	Notes        string    `json:"notes"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	LastImportID *int64    `json:"last_import_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Joined fields
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+}
+
+// DiscoveryImport records one snapshot ingestion
+type DiscoveryImport struct {
+	ID             int64     `json:"id"`
+	Source         string    `json:"source"`
+	DeviceCount    int       `json:"device_count"`
+	MatchedCount   int       `json:"matched_count"`
+	UpdatedCount   int       `json:"updated_count"`
+	CandidateCount int       `json:"candidate_count"`
+	ImportedBy     *int64    `json:"imported_by"`
+	ImportedAt     time.Time `json:"imported_at"`
+
+	Results []DiscoveryResult `json:"results,omitempty"`
+}
+
+// DiscoveryResult is what an import did with one snapshot row
+type DiscoveryResult struct {
+	Row      int    `json:"row"` // 1-based position in the snapshot
+	DeviceID int64  `json:"device_id"`
+	AssetID  *int64 `json:"asset_id"`
+	Status   string `json:"status"`  // matched, candidate, ignored, skipped
+	Updated  bool   `json:"updated"` // The asset's custom fields changed
+	Notes    string `json:"notes,omitempty"`
+}
+
+// StaleAsset is an asset that discovery has not seen recently, or ever
+type StaleAsset struct {
+	AssetID    int64      `json:"asset_id"`
+	InternalID string     `json:"internal_id"`
+	AssetType  string     `json:"asset_type"`
+	Status     string     `json:"status"`
+	InUseBy    *int64     `json:"in_use_by"`
+	Location   string     `json:"location"`
+	Hostname   string     `json:"hostname"`
+	LastSeen   *time.Time `json:"last_seen"` // nil if never seen
+}
+
+// NormalizeMAC returns a MAC address as aa:bb:cc:dd:ee:ff, accepting any
+// separator, or "" if it is not a MAC address
+func NormalizeMAC(mac string) string {
+	var hex []byte
+	for _, c := range strings.ToLower(mac) {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f':
+			hex = append(hex, byte(c))
+		case c == ':' || c == '-' || c == '.' || c == ' ':
+		default:
+			return ""
+		}
+	}
+	if len(hex) != 12 {
+		return ""
+	}
+
+	parts := make([]string, 6)
+	for i := range parts {
+		parts[i] = string(hex[i*2 : i*2+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// discoveryFieldValues maps a snapshot onto custom field keys. Only keys in
+// the asset type's schema are applied.
+func discoveryFieldValues(s *DiscoverySnapshot) map[string]interface{} {
+	values := map[string]interface{}{}
+	for key, value := range s.Fields {
+		values[key] = value
+	}
+	for key, value := range map[string]string{
+		"hostname":    s.Hostname,
+		"mac_address": s.MACAddress,
+		"os":          s.OS,
+		"ip_address":  s.IPAddress,
+	} {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	if s.LastSeen != nil {
+		values["last_seen"] = s.LastSeen.Format("2006-01-02")
+	}
+	return values
+}
+
+type DiscoveryModel struct {
+	DB *sql.DB
+}
+
+func NewDiscoveryModel(db *sql.DB) *DiscoveryModel {
+	return &DiscoveryModel{DB: db}
+}
+
+const discoveredDeviceColumns = `
+			d.id, d.hostname, d.mac_address, d.serial_number, d.os, d.ip_address,
+			d.asset_id, d.status, d.notes, d.first_seen, d.last_seen, d.last_import_id,
+			d.created_at, d.updated_at, COALESCE(a.internal_id, '')`
+
+const discoveredDeviceJoins = `
+		FROM discovered_devices d
+		LEFT JOIN assets a ON a.id = d.asset_id`
+
+func scanDiscoveredDevice(row rowScanner) (*DiscoveredDevice, error) {
+	var d DiscoveredDevice
+	err := row.Scan(
+		&d.ID,
+		&d.Hostname,
+		&d.MACAddress,
+		&d.SerialNumber,
+		&d.OS,
+		&d.IPAddress,
+		&d.AssetID,
+		&d.Status,
+		&d.Notes,
+		&d.FirstSeen,
+		&d.LastSeen,
+		&d.LastImportID,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&d.AssetInternalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// matchAssetTx finds the live asset a device belongs to: by serial number
+// first, then by MAC address. A serial or MAC shared by several assets is
+// not matched; the note says why.
+func matchAssetTx(tx *sql.Tx, serial, mac string) (*int64, string, error) {
+	lookups := []struct {
+		value string
+		query string
+	}{
+		{serial, `SELECT id FROM assets WHERE deleted_at IS NULL AND LOWER(TRIM(serial_number)) = LOWER($1)`},
+		{strings.ReplaceAll(mac, ":", ""), `
+			SELECT id FROM assets
+			WHERE deleted_at IS NULL
+			AND regexp_replace(LOWER(custom_fields->>'mac_address'), '[^0-9a-f]', '', 'g') = $1`},
+	}
+
+	for i, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+
+		rows, err := tx.Query(lookup.query, lookup.value)
+		if err != nil {
+			return nil, "", err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, "", err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, "", err
+		}
+
+		if len(ids) == 1 {
+			return &ids[0], "", nil
+		}
+		if len(ids) > 1 {
+			field := "Serial number"
+			if i == 1 {
+				field = "MAC address"
+			}
+			return nil, fmt.Sprintf("%s matches %d assets", field, len(ids)), nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// applyDiscoveryFieldsTx merges a snapshot into an asset's custom fields. Keys
+// outside the asset type's schema are ignored. It reports whether anything
+// changed; a value that fails validation leaves the asset untouched and is
+// returned as a note.
+func applyDiscoveryFieldsTx(tx *sql.Tx, assetID int64, s *DiscoverySnapshot, schemas map[string][]CustomField) (bool, string, error) {
+	var assetType string
+	var raw []byte
+	err := tx.QueryRow(`SELECT asset_type, custom_fields FROM assets WHERE id = $1 FOR UPDATE`, assetID).Scan(&assetType, &raw)
+	if err != nil {
+		return false, "", err
+	}
+
+	current := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &current); err != nil {
+			return false, "", err
+		}
+	}
+
+	schema := schemas[assetType]
+	known := map[string]CustomField{}
+	for _, f := range schema {
+		known[f.Key] = f
+	}
+
+	// Values left over from a field since removed from the schema are kept
+	// as they are rather than failing validation
+	merged := make(map[string]interface{}, len(current))
+	for key, value := range current {
+		if _, ok := known[key]; ok {
+			merged[key] = value
+		}
+	}
+	applied := 0
+	for key, value := range discoveryFieldValues(s) {
+		if _, ok := known[key]; ok {
+			merged[key] = value
+			applied++
+		}
+	}
+	if applied == 0 {
+		return false, "", nil
+	}
+
+	values, err := ValidateCustomFieldValues(schema, merged)
+	if err != nil {
+		return false, "Custom fields not updated: " + err.Error(), nil
+	}
+	for key, value := range current {
+		if _, ok := known[key]; !ok {
+			values[key] = value
+		}
+	}
+
+	before, _ := json.Marshal(current)
+	after, err := json.Marshal(values)
+	if err != nil {
+		return false, "", err
+	}
+	if string(before) == string(after) {
+		return false, "", nil
+	}
+
+	for key, value := range values {
+		if !known[key].Unique || CustomFieldText(value) == CustomFieldText(current[key]) {
+			continue
+		}
+		var taken bool
+		err := tx.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM assets
+				WHERE asset_type = $1 AND custom_fields->>$2 = $3
+				AND id != $4 AND deleted_at IS NULL
+			)
+		`, assetType, key, CustomFieldText(value), assetID).Scan(&taken)
+		if err != nil {
+			return false, "", err
+		}
+		if taken {
+			return false, fmt.Sprintf("Custom fields not updated: %s %s is already used by another %s", key, CustomFieldText(value), assetType), nil
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE assets SET custom_fields = $1, updated_at = NOW() WHERE id = $2`, after, assetID)
+	if err != nil {
+		return false, "", err
+	}
+
+	return true, "", nil
+}
+
+// Import ingests a snapshot in one transaction. Each device is matched to an
+// asset by serial or MAC and the asset's hardware custom fields are refreshed;
+// devices with no match are kept as candidates. A device seen before keeps
+// its manual link or ignored status.
+func (m *DiscoveryModel) Import(imp *DiscoveryImport, snapshots []DiscoverySnapshot) error {
+	schemaList, err := NewCustomFieldModel(m.DB).GetByAssetType("")
+	if err != nil {
+		return err
+	}
+	schemas := map[string][]CustomField{}
+	for _, f := range schemaList {
+		schemas[f.AssetType] = append(schemas[f.AssetType], f)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO discovery_imports (source, imported_by) VALUES ($1, $2)
+		RETURNING id, imported_at
+	`, imp.Source, imp.ImportedBy).Scan(&imp.ID, &imp.ImportedAt)
+	if err != nil {
+		return err
+	}
+
+	imp.DeviceCount = len(snapshots)
+	imp.Results = make([]DiscoveryResult, 0, len(snapshots))
+	for i := range snapshots {
+		s := &snapshots[i]
+		s.MACAddress = NormalizeMAC(s.MACAddress)
+		s.SerialNumber = strings.TrimSpace(s.SerialNumber)
+		if s.LastSeen == nil {
+			seen := imp.ImportedAt
+			s.LastSeen = &seen
+		}
+
+		result := DiscoveryResult{Row: i + 1}
+		if s.SerialNumber == "" && s.MACAddress == "" {
+			result.Status = "skipped"
+			result.Notes = "Device has no serial number or valid MAC address"
+			imp.Results = append(imp.Results, result)
+			continue
+		}
+
+		// The device row seen before, preferring one already linked to an asset
+		var deviceID int64
+		var prevStatus string
+		var prevAssetID *int64
+		err := tx.QueryRow(`
+			SELECT id, status, asset_id FROM discovered_devices
+			WHERE ($1 != '' AND LOWER(serial_number) = LOWER($1)) OR ($2 != '' AND mac_address = $2)
+			ORDER BY asset_id IS NULL, id
+			LIMIT 1
+			FOR UPDATE
+		`, s.SerialNumber, s.MACAddress).Scan(&deviceID, &prevStatus, &prevAssetID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		assetID, note, err := matchAssetTx(tx, s.SerialNumber, s.MACAddress)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case assetID != nil:
+			result.Status = "matched"
+		case prevAssetID != nil:
+			assetID = prevAssetID
+			result.Status = "matched"
+		case prevStatus == "ignored":
+			result.Status = "ignored"
+		default:
+			result.Status = "candidate"
+		}
+
+		if assetID != nil {
+			updated, fieldNote, err := applyDiscoveryFieldsTx(tx, *assetID, s, schemas)
+			if err != nil {
+				return err
+			}
+			result.Updated = updated
+			note = fieldNote
+			imp.MatchedCount++
+			if updated {
+				imp.UpdatedCount++
+			}
+		} else if result.Status == "candidate" {
+			imp.CandidateCount++
+		}
+		result.AssetID = assetID
+		result.Notes = note
+
+		if deviceID != 0 {
+			_, err = tx.Exec(`
+				UPDATE discovered_devices
+				SET hostname = COALESCE(NULLIF($1, ''), hostname),
+				    mac_address = COALESCE(NULLIF($2, ''), mac_address),
+				    serial_number = COALESCE(NULLIF($3, ''), serial_number),
+				    os = COALESCE(NULLIF($4, ''), os),
+				    ip_address = COALESCE(NULLIF($5, ''), ip_address),
+				    last_seen = GREATEST(last_seen, $6),
+				    asset_id = $7, status = $8, notes = $9, last_import_id = $10, updated_at = NOW()
+				WHERE id = $11
+			`, s.Hostname, s.MACAddress, s.SerialNumber, s.OS, s.IPAddress, *s.LastSeen,
+				assetID, result.Status, note, imp.ID, deviceID)
+		} else {
+			err = tx.QueryRow(`
+				INSERT INTO discovered_devices (hostname, mac_address, serial_number, os, ip_address,
+				                                asset_id, status, notes, first_seen, last_seen, last_import_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9, $10)
+				RETURNING id
+			`, s.Hostname, s.MACAddress, s.SerialNumber, s.OS, s.IPAddress,
+				assetID, result.Status, note, *s.LastSeen, imp.ID).Scan(&deviceID)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.DeviceID = deviceID
+		imp.Results = append(imp.Results, result)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE discovery_imports
+		SET device_count = $1, matched_count = $2, updated_count = $3, candidate_count = $4
+		WHERE id = $5
+	`, imp.DeviceCount, imp.MatchedCount, imp.UpdatedCount, imp.CandidateCount, imp.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetImports lists past imports, newest first
+func (m *DiscoveryModel) GetImports(limit int) ([]DiscoveryImport, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, source, device_count, matched_count, updated_count, candidate_count,
+		       imported_by, imported_at
+		FROM discovery_imports
+		ORDER BY imported_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []DiscoveryImport{}
+	for rows.Next() {
+		var imp DiscoveryImport
+		err := rows.Scan(&imp.ID, &imp.Source, &imp.DeviceCount, &imp.MatchedCount,
+			&imp.UpdatedCount, &imp.CandidateCount, &imp.ImportedBy, &imp.ImportedAt)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+
+	return imports, rows.Err()
+}
+
+// GetDevices lists discovered devices, optionally by status, most recently seen first
+func (m *DiscoveryModel) GetDevices(status string) ([]DiscoveredDevice, error) {
+	query := `SELECT ` + discoveredDeviceColumns + discoveredDeviceJoins + `
+		WHERE ($1 = '' OR d.status = $1)
+		ORDER BY d.last_seen DESC, d.id`
+
+	rows, err := m.DB.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []DiscoveredDevice{}
+	for rows.Next() {
+		d, err := scanDiscoveredDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *d)
+	}
+
+	return devices, rows.Err()
+}
+
+// Get discovered device by ID
+func (m *DiscoveryModel) GetDevice(id int64) (*DiscoveredDevice, error) {
+	query := `SELECT ` + discoveredDeviceColumns + discoveredDeviceJoins + ` WHERE d.id = $1`
+
+	d, err := scanDiscoveredDevice(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("discovered device not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Link attaches a device to an asset by hand, e.g. a candidate that turned
+// out to be an asset recorded without its serial. Later imports keep the link.
+func (m *DiscoveryModel) Link(id, assetID int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE discovered_devices
+		SET asset_id = $1, status = 'matched', notes = '', updated_at = NOW()
+		WHERE id = $2
+		AND EXISTS (SELECT 1 FROM assets WHERE id = $1 AND deleted_at IS NULL)
+	`, assetID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("discovered device or asset not found")
+	}
+
+	return nil
+}
+
+// Ignore marks an unmatched device as not an asset, e.g. a phone or printer,
+// so it stops showing up as a candidate
+func (m *DiscoveryModel) Ignore(id int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE discovered_devices SET status = 'ignored', updated_at = NOW()
+		WHERE id = $1 AND asset_id IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("discovered device not found or linked to an asset")
+	}
+
+	return nil
+}
+
+// GetStale returns live, non-retired assets of a type that discovery has not
+// seen in the last days days, including those it has never seen, oldest first
+func (m *DiscoveryModel) GetStale(assetType string, days int) ([]StaleAsset, error) {
+	query := `
+		SELECT a.id, a.internal_id, a.asset_type, a.status, a.in_use_by, a.location,
+		       COALESCE(NULLIF(seen.hostname, ''), a.custom_fields->>'hostname', ''), seen.last_seen
+		FROM assets a
+		LEFT JOIN LATERAL (
+			SELECT d.hostname, d.last_seen FROM discovered_devices d
+			WHERE d.asset_id = a.id
+			ORDER BY d.last_seen DESC
+			LIMIT 1
+		) seen ON true
+		WHERE a.deleted_at IS NULL AND a.status != 'RETIRED' AND a.asset_type = $1
+		AND (seen.last_seen IS NULL OR seen.last_seen < NOW() - make_interval(days => $2))
+		ORDER BY seen.last_seen NULLS FIRST, a.internal_id
+	`
+
+	rows, err := m.DB.Query(query, assetType, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []StaleAsset{}
+	for rows.Next() {
+		var s StaleAsset
+		err := rows.Scan(&s.AssetID, &s.InternalID, &s.AssetType, &s.Status, &s.InUseBy,
+			&s.Location, &s.Hostname, &s.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, s)
+	}
+
+	return assets, rows.Err()
+}
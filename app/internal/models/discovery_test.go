@@ -0,0 +1,143 @@
+// file: app/internal/models/discovery_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDiscoveryTest(t *testing.T) (*DiscoveryModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewDiscoveryModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	assert.Equal(t, "aa:bb:cc:00:11:22", NormalizeMAC("AA-BB-CC-00-11-22"))
+	assert.Equal(t, "aa:bb:cc:00:11:22", NormalizeMAC("aabb.cc00.1122"))
+	assert.Equal(t, "aa:bb:cc:00:11:22", NormalizeMAC("aa:bb:cc:00:11:22"))
+	assert.Equal(t, "", NormalizeMAC("aa:bb:cc:00:11"))
+	assert.Equal(t, "", NormalizeMAC("zz:bb:cc:00:11:22"))
+	assert.Equal(t, "", NormalizeMAC(""))
+}
+
+func TestDiscoveryModel_Import(t *testing.T) {
+	model, mock, teardown := setupDiscoveryTest(t)
+	defer teardown()
+
+	now := time.Now()
+	seen := now.Add(-time.Hour)
+
+	schemaColumns := []string{
+		"id", "asset_type", "key", "label", "field_type", "required", "is_unique",
+		"options", "sort_order", "created_at", "updated_at",
+	}
+
+	mock.ExpectQuery(`FROM asset_custom_fields`).
+		WithArgs("").
+		WillReturnRows(sqlmock.NewRows(schemaColumns).
+			AddRow(1, "PC", "hostname", "Hostname", "text", false, false, []byte(`[]`), 0, now, now).
+			AddRow(2, "PC", "ram_gb", "RAM (GB)", "number", false, false, []byte(`[]`), 1, now, now))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO discovery_imports`).
+		WithArgs("agent", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "imported_at"}).AddRow(5, now))
+
+	// Known PC, matched by serial; hostname and RAM change
+	mock.ExpectQuery(`SELECT id, status, asset_id FROM discovered_devices`).
+		WithArgs("SN-001", "aa:bb:cc:00:11:22").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "asset_id"}))
+	mock.ExpectQuery(`LOWER\(TRIM\(serial_number\)\) = LOWER\(\$1\)`).
+		WithArgs("SN-001").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectQuery(`SELECT asset_type, custom_fields FROM assets`).
+		WithArgs(int64(12)).
+		WillReturnRows(sqlmock.NewRows([]string{"asset_type", "custom_fields"}).
+			AddRow("PC", []byte(`{"hostname":"old-name","ram_gb":8}`)))
+	mock.ExpectExec(`UPDATE assets SET custom_fields`).
+		WithArgs([]byte(`{"hostname":"ws-012","ram_gb":16}`), int64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO discovered_devices`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+
+	// Unknown device seen before: stays a candidate
+	mock.ExpectQuery(`SELECT id, status, asset_id FROM discovered_devices`).
+		WithArgs("", "de:ad:be:ef:00:01").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "asset_id"}).AddRow(41, "candidate", nil))
+	mock.ExpectQuery(`regexp_replace`).
+		WithArgs("deadbeef0001").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`UPDATE discovered_devices`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(`UPDATE discovery_imports`).
+		WithArgs(3, 1, 1, 1, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	imp := &DiscoveryImport{Source: "agent"}
+	err := model.Import(imp, []DiscoverySnapshot{
+		{
+			Hostname:     "ws-012",
+			MACAddress:   "AA-BB-CC-00-11-22",
+			SerialNumber: " SN-001 ",
+			OS:           "Windows 11",
+			LastSeen:     &seen,
+			Fields:       map[string]interface{}{"ram_gb": 16.0, "gpu": "ignored"},
+		},
+		{Hostname: "printer-2", MACAddress: "de:ad:be:ef:00:01"},
+		{Hostname: "no-ids"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(5), imp.ID)
+	assert.Equal(t, 1, imp.MatchedCount)
+	assert.Equal(t, 1, imp.UpdatedCount)
+	assert.Equal(t, 1, imp.CandidateCount)
+	require.Len(t, imp.Results, 3)
+	assert.Equal(t, "matched", imp.Results[0].Status)
+	assert.True(t, imp.Results[0].Updated)
+	assert.Equal(t, int64(40), imp.Results[0].DeviceID)
+	assert.Equal(t, "candidate", imp.Results[1].Status)
+	assert.Equal(t, int64(41), imp.Results[1].DeviceID)
+	assert.Equal(t, "skipped", imp.Results[2].Status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDiscoveryModel_Ignore(t *testing.T) {
+	model, mock, teardown := setupDiscoveryTest(t)
+	defer teardown()
+
+	t.Run("candidate ignored", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE discovered_devices SET status = 'ignored'`).
+			WithArgs(int64(41)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.Ignore(41))
+	})
+
+	t.Run("linked device", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE discovered_devices SET status = 'ignored'`).
+			WithArgs(int64(40)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.Ignore(40)
+		assert.Error(t, err)
+		assert.Equal(t, "discovered device not found or linked to an asset", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,355 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AssetDisposal is a request to retire and dispose of an asset. It moves from
+// requested to approved (or rejected) and finally to completed, at which point
+// the asset is soft-deleted.
+type AssetDisposal struct {
+	ID                  int64      `json:"id"`
+	AssetID             int64      `json:"asset_id"`
+	Status              string     `json:"status"` // requested, approved, rejected, completed
+	Reason              string     `json:"reason"`
+	RequestedBy         *int64     `json:"requested_by"`
+	RequestedAt         time.Time  `json:"requested_at"`
+	ReviewedBy          *int64     `json:"reviewed_by"`
+	ReviewedAt          *time.Time `json:"reviewed_at"`
+	ReviewNotes         string     `json:"review_notes"`
+	DataWipeConfirmed   bool       `json:"data_wipe_confirmed"`
+	DataWipeMethod      string     `json:"data_wipe_method"`
+	DataWipeConfirmedBy *int64     `json:"data_wipe_confirmed_by"`
+	DataWipeConfirmedAt *time.Time `json:"data_wipe_confirmed_at"`
+	DisposalMethod      string     `json:"disposal_method"` // recycle, donate, sell
+	CertificateRef      string     `json:"certificate_ref"`
+	CompletedBy         *int64     `json:"completed_by"`
+	CompletedAt         *time.Time `json:"completed_at"`
+
+	// Joined fields
+	AssetInternalID  string `json:"asset_internal_id,omitempty"`
+	AssetType        string `json:"asset_type,omitempty"`
+	DataWipeRequired bool   `json:"data_wipe_required"`
+}
+
+// disposalMethods are the accepted ways of getting rid of an asset
+var disposalMethods = map[string]bool{
+	"recycle": true, "donate": true, "sell": true,
+}
+
+// IsValidDisposalMethod reports whether method is a known disposal method
+func IsValidDisposalMethod(method string) bool {
+	return disposalMethods[method]
+}
+
+// RequiresDataWipe reports whether assets of this type hold data that must be
+// wiped before they leave the building
+func RequiresDataWipe(assetType string) bool {
+	return assetType == "PC"
+}
+
+type DisposalModel struct {
+	DB *sql.DB
+}
+
+func NewDisposalModel(db *sql.DB) *DisposalModel {
+	return &DisposalModel{DB: db}
+}
+
+const disposalColumns = `
+			d.id, d.asset_id, d.status, d.reason, d.requested_by, d.requested_at,
+			d.reviewed_by, d.reviewed_at, d.review_notes, d.data_wipe_confirmed,
+			d.data_wipe_method, d.data_wipe_confirmed_by, d.data_wipe_confirmed_at,
+			d.disposal_method, d.certificate_ref, d.completed_by, d.completed_at,
+			a.internal_id, a.asset_type`
+
+func scanDisposal(row rowScanner) (*AssetDisposal, error) {
+	var d AssetDisposal
+	err := row.Scan(
+		&d.ID,
+		&d.AssetID,
+		&d.Status,
+		&d.Reason,
+		&d.RequestedBy,
+		&d.RequestedAt,
+		&d.ReviewedBy,
+		&d.ReviewedAt,
+		&d.ReviewNotes,
+		&d.DataWipeConfirmed,
+		&d.DataWipeMethod,
+		&d.DataWipeConfirmedBy,
+		&d.DataWipeConfirmedAt,
+		&d.DisposalMethod,
+		&d.CertificateRef,
+		&d.CompletedBy,
+		&d.CompletedAt,
+		&d.AssetInternalID,
+		&d.AssetType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.DataWipeRequired = RequiresDataWipe(d.AssetType)
+	return &d, nil
+}
+
+// Insert opens a disposal request for an asset that has not been disposed of
+func (m *DisposalModel) Insert(d *AssetDisposal) error {
+	query := `
+		INSERT INTO asset_disposals (asset_id, reason, requested_by)
+		SELECT id, $2, $3 FROM assets WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, status, requested_at
+	`
+
+	err := m.DB.QueryRow(query, d.AssetID, d.Reason, d.RequestedBy).Scan(&d.ID, &d.Status, &d.RequestedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("asset not found")
+	} else if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("a disposal request is already open for this asset")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get disposal by ID
+func (m *DisposalModel) GetByID(id int64) (*AssetDisposal, error) {
+	query := `
+		SELECT ` + disposalColumns + `
+		FROM asset_disposals d
+		JOIN assets a ON a.id = d.asset_id
+		WHERE d.id = $1
+	`
+
+	d, err := scanDisposal(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("disposal not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// GetAll returns disposals, optionally filtered by status, newest first
+func (m *DisposalModel) GetAll(status string) ([]AssetDisposal, error) {
+	query := `
+		SELECT ` + disposalColumns + `
+		FROM asset_disposals d
+		JOIN assets a ON a.id = d.asset_id
+		WHERE ($1 = '' OR d.status = $1)
+		ORDER BY d.requested_at DESC
+	`
+
+	rows, err := m.DB.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disposals []AssetDisposal
+	for rows.Next() {
+		d, err := scanDisposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		disposals = append(disposals, *d)
+	}
+
+	return disposals, rows.Err()
+}
+
+// Approve a requested disposal. The asset is taken out of service straight
+// away so it can no longer be handed out while awaiting disposal.
+func (m *DisposalModel) Approve(id, reviewerID int64, notes string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	err = tx.QueryRow(`
+		UPDATE asset_disposals
+		SET status = 'approved', reviewed_by = $1, reviewed_at = NOW(), review_notes = $2
+		WHERE id = $3 AND status = 'requested'
+		RETURNING asset_id
+	`, reviewerID, notes, id).Scan(&assetID)
+	if err == sql.ErrNoRows {
+		return errors.New("disposal not found or not awaiting approval")
+	} else if err != nil {
+		return err
+	}
+
+	err = withdrawAssetTx(tx, assetID, "RETIRED", "asset retired", CustodyCheckIn{
+		ReceivedBy: &reviewerID,
+		Notes:      "Retired for disposal",
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reject a requested disposal
+func (m *DisposalModel) Reject(id, reviewerID int64, notes string) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_disposals
+		SET status = 'rejected', reviewed_by = $1, reviewed_at = NOW(), review_notes = $2
+		WHERE id = $3 AND status = 'requested'
+	`, reviewerID, notes, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("disposal not found or not awaiting approval")
+	}
+
+	return nil
+}
+
+// ConfirmDataWipe records that the asset's storage has been wiped
+func (m *DisposalModel) ConfirmDataWipe(id, userID int64, method string) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_disposals
+		SET data_wipe_confirmed = true, data_wipe_method = $1,
+		    data_wipe_confirmed_by = $2, data_wipe_confirmed_at = NOW()
+		WHERE id = $3 AND status IN ('requested', 'approved')
+	`, method, userID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("disposal not found or already closed")
+	}
+
+	return nil
+}
+
+// Complete records how an approved asset was disposed of and soft-deletes it
+func (m *DisposalModel) Complete(id, userID int64, method, certificateRef string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	var status, assetType string
+	var wiped bool
+	err = tx.QueryRow(`
+		SELECT d.asset_id, d.status, d.data_wipe_confirmed, a.asset_type
+		FROM asset_disposals d
+		JOIN assets a ON a.id = d.asset_id
+		WHERE d.id = $1
+		FOR UPDATE OF d
+	`, id).Scan(&assetID, &status, &wiped, &assetType)
+	if err == sql.ErrNoRows {
+		return errors.New("disposal not found")
+	} else if err != nil {
+		return err
+	}
+
+	if status != "approved" {
+		return errors.New("disposal must be approved before it can be completed")
+	}
+	if RequiresDataWipe(assetType) && !wiped {
+		return errors.New("data wipe must be confirmed before disposing of a PC")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_disposals
+		SET status = 'completed', disposal_method = $1, certificate_ref = $2,
+		    completed_by = $3, completed_at = NOW()
+		WHERE id = $4
+	`, method, certificateRef, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := disposeAssetTx(tx, assetID, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// disposeAssetTx takes a disposed asset out of service and soft-deletes it
+func disposeAssetTx(tx *sql.Tx, assetID, userID int64) error {
+	err := withdrawAssetTx(tx, assetID, "RETIRED", "asset disposed", CustodyCheckIn{
+		ReceivedBy: &userID,
+		Notes:      "Disposed",
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE assets SET deleted_at = NOW() WHERE id = $1`, assetID)
+	return err
+}
+
+// withdrawAssetTx moves an asset to status (RETIRED or REPAIR) as part of the
+// caller's transaction. It is taken off its user, its open handover is closed
+// and the license seats and bookings it held are freed. An asset out on loan
+// has to come back first.
+func withdrawAssetTx(tx *sql.Tx, assetID int64, status, releaseReason string, checkIn CustodyCheckIn) error {
+	var onLoan bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM asset_reservations WHERE asset_id = $1 AND status = 'checked_out')
+	`, assetID).Scan(&onLoan)
+	if err != nil {
+		return err
+	}
+	if onLoan {
+		return errors.New("asset is still on loan")
+	}
+
+	result, err := tx.Exec(`
+		UPDATE assets SET status = $1, in_use_by = NULL, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+	`, status, assetID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("asset not found")
+	}
+
+	if _, err := checkInCustody(tx, assetID, checkIn); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE license_allocations SET released_at = NOW(), release_reason = $1
+		WHERE asset_id = $2 AND released_at IS NULL
+	`, releaseReason, assetID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_reservations SET status = 'cancelled'
+		WHERE asset_id = $1 AND status = 'reserved'
+	`, assetID)
+	return err
+}
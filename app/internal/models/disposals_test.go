@@ -0,0 +1,168 @@
+// file: app/internal/models/disposals_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDisposalTest(t *testing.T) (*DisposalModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewDisposalModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestDisposalModel_Complete(t *testing.T) {
+	model, mock, teardown := setupDisposalTest(t)
+	defer teardown()
+
+	disposalRow := func(status string, wiped bool, assetType string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"asset_id", "status", "data_wipe_confirmed", "asset_type"}).
+			AddRow(int64(5), status, wiped, assetType)
+	}
+
+	t.Run("PC without data wipe", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT d.asset_id`).
+			WithArgs(int64(1)).
+			WillReturnRows(disposalRow("approved", false, "PC"))
+		mock.ExpectRollback()
+
+		err := model.Complete(1, 2, "recycle", "CERT-001")
+		assert.Error(t, err)
+		assert.Equal(t, "data wipe must be confirmed before disposing of a PC", err.Error())
+	})
+
+	t.Run("not approved", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT d.asset_id`).
+			WithArgs(int64(1)).
+			WillReturnRows(disposalRow("requested", true, "PC"))
+		mock.ExpectRollback()
+
+		err := model.Complete(1, 2, "recycle", "CERT-001")
+		assert.Error(t, err)
+		assert.Equal(t, "disposal must be approved before it can be completed", err.Error())
+	})
+
+	t.Run("monitor soft-deleted on completion", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT d.asset_id`).
+			WithArgs(int64(1)).
+			WillReturnRows(disposalRow("approved", false, "Monitor"))
+		mock.ExpectExec(`UPDATE asset_disposals`).
+			WithArgs("donate", "DON-2024-17", int64(2), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE assets SET status = \$1, in_use_by = NULL`).
+			WithArgs("RETIRED", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs("asset disposed", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE asset_reservations SET status = 'cancelled'`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE assets SET deleted_at = NOW\(\)`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Complete(1, 2, "donate", "DON-2024-17")
+		assert.NoError(t, err)
+	})
+
+	t.Run("asset still on loan", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT d.asset_id`).
+			WithArgs(int64(1)).
+			WillReturnRows(disposalRow("approved", false, "Monitor"))
+		mock.ExpectExec(`UPDATE asset_disposals`).
+			WithArgs("donate", "DON-2024-17", int64(2), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := model.Complete(1, 2, "donate", "DON-2024-17")
+		assert.EqualError(t, err, "asset is still on loan")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisposalModel_Approve(t *testing.T) {
+	model, mock, teardown := setupDisposalTest(t)
+	defer teardown()
+
+	t.Run("takes the asset out of service", func(t *testing.T) {
+		reviewerID := int64(2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_disposals`).
+			WithArgs(int64(2), "End of life", int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}).AddRow(int64(5)))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE assets SET status = \$1, in_use_by = NULL`).
+			WithArgs("RETIRED", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("", "Retired for disposal", &reviewerID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs("asset retired", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE asset_reservations SET status = 'cancelled'`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := model.Approve(1, 2, "End of life")
+		assert.NoError(t, err)
+	})
+
+	t.Run("asset still on loan", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_disposals`).
+			WithArgs(int64(2), "", int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}).AddRow(int64(5)))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := model.Approve(1, 2, "")
+		assert.EqualError(t, err, "asset is still on loan")
+	})
+
+	t.Run("already reviewed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_disposals`).
+			WithArgs(int64(2), "", int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}))
+		mock.ExpectRollback()
+
+		err := model.Approve(1, 2, "")
+		assert.Error(t, err)
+		assert.Equal(t, "disposal not found or not awaiting approval", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,684 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KBCategory groups knowledge base articles
+type KBCategory struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	ArticleCount int       `json:"article_count"` // Articles the reader can see
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// KBArticle is a markdown knowledge base article. Title and Body hold the
+// latest version; earlier ones are kept as KBArticleVersions.
+type KBArticle struct {
+	ID          int64     `json:"id"`
+	CategoryID  *int64    `json:"category_id"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`        // Markdown
+	TicketType  string    `json:"ticket_type"` // Ticket type the article helps with, empty for any
+	Visibility  string    `json:"visibility"`  // everyone, staff, it
+	IsPublished bool      `json:"is_published"`
+	Version     int       `json:"version"`
+	CreatedBy   *int64    `json:"created_by"`
+	UpdatedBy   *int64    `json:"updated_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Joined fields
+	CategoryName string `json:"category_name,omitempty"`
+	AuthorName   string `json:"author_name,omitempty"`
+}
+
+// KBArticleVersion is one saved version of an article
+type KBArticleVersion struct {
+	ID        int64     `json:"id"`
+	ArticleID int64     `json:"article_id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	EditedBy  *int64    `json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Joined fields
+	EditorName string `json:"editor_name,omitempty"`
+}
+
+// KBArticleSummary is an article in a list, search result or suggestion.
+// Snippet highlights the matching text between <mark> tags when searching.
+type KBArticleSummary struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	CategoryID   *int64    `json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	TicketType   string    `json:"ticket_type"`
+	Visibility   string    `json:"visibility"`
+	IsPublished  bool      `json:"is_published"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Snippet      string    `json:"snippet,omitempty"`
+	Rank         float64   `json:"rank,omitempty"`
+}
+
+// KBFilters narrows an article list. RoleID decides which visibilities the
+// reader can see; IncludeDrafts adds unpublished articles for editors.
+type KBFilters struct {
+	CategoryID    *int64
+	TicketType    string
+	RoleID        int64
+	IncludeDrafts bool
+	Limit         int
+	Offset        int
+}
+
+// KBError is an article or category the client got wrong, e.g. an empty title
+type KBError struct {
+	Message string
+}
+
+func (e *KBError) Error() string {
+	return e.Message
+}
+
+// CanManageKnowledgeBase reports whether a role writes and publishes articles
+// and so can see drafts: admin and IT
+func CanManageKnowledgeBase(roleID int64) bool {
+	return roleID == 1 || roleID == 2
+}
+
+// kbAccessLevel is the most restricted visibility a role can read:
+// 0 everyone, 1 staff, 2 it
+func kbAccessLevel(roleID int64) int {
+	switch roleID {
+	case 1, 2:
+		return 2
+	case 3:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// kbVisibleClause limits articles a to those the access level in parameter
+// $n can read, and to published ones unless drafts are included
+func kbVisibleClause(n int, includeDrafts bool) string {
+	clause := fmt.Sprintf(
+		" AND (CASE a.visibility WHEN 'everyone' THEN 0 WHEN 'staff' THEN 1 ELSE 2 END) <= $%d", n)
+	if !includeDrafts {
+		clause += " AND a.is_published"
+	}
+	return clause
+}
+
+var kbVisibilities = map[string]bool{"everyone": true, "staff": true, "it": true}
+
+// validateKBArticle checks an article before it is saved
+func validateKBArticle(a *KBArticle) error {
+	a.Title = strings.TrimSpace(a.Title)
+	a.Body = strings.TrimSpace(a.Body)
+	a.TicketType = strings.TrimSpace(a.TicketType)
+	if a.Title == "" {
+		return &KBError{Message: "title is required"}
+	}
+	if a.Body == "" {
+		return &KBError{Message: "body is required"}
+	}
+	if a.Visibility == "" {
+		a.Visibility = "everyone"
+	}
+	if !kbVisibilities[a.Visibility] {
+		return &KBError{Message: "visibility must be everyone, staff or it"}
+	}
+	return nil
+}
+
+type KnowledgeBaseModel struct {
+	DB *sql.DB
+}
+
+func NewKnowledgeBaseModel(db *sql.DB) *KnowledgeBaseModel {
+	return &KnowledgeBaseModel{DB: db}
+}
+
+// GetCategories lists categories with the number of articles the reader can see
+func (m *KnowledgeBaseModel) GetCategories(roleID int64, includeDrafts bool) ([]KBCategory, error) {
+	rows, err := m.DB.Query(`
+		SELECT c.id, c.name, c.description, c.created_at,
+			(SELECT COUNT(*) FROM kb_articles a WHERE a.category_id = c.id`+kbVisibleClause(1, includeDrafts)+`)
+		FROM kb_categories c
+		ORDER BY c.name
+	`, kbAccessLevel(roleID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []KBCategory{}
+	for rows.Next() {
+		var c KBCategory
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.ArticleCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// InsertCategory adds a category
+func (m *KnowledgeBaseModel) InsertCategory(c *KBCategory) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return &KBError{Message: "category name is required"}
+	}
+
+	err := m.DB.QueryRow(`
+		INSERT INTO kb_categories (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at
+	`, c.Name, strings.TrimSpace(c.Description)).Scan(&c.ID, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("category already exists")
+	}
+	return err
+}
+
+// UpdateCategory renames or redescribes a category
+func (m *KnowledgeBaseModel) UpdateCategory(c *KBCategory) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return &KBError{Message: "category name is required"}
+	}
+
+	var exists bool
+	err := m.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM kb_categories WHERE name = $1 AND id <> $2)",
+		c.Name, c.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("category already exists")
+	}
+
+	err = m.DB.QueryRow(`
+		UPDATE kb_categories SET name = $1, description = $2
+		WHERE id = $3
+		RETURNING created_at
+	`, c.Name, strings.TrimSpace(c.Description), c.ID).Scan(&c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("category not found")
+	}
+	return err
+}
+
+// DeleteCategory removes a category; its articles become uncategorised
+func (m *KnowledgeBaseModel) DeleteCategory(id int64) error {
+	result, err := m.DB.Exec("DELETE FROM kb_categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("category not found")
+	}
+	return nil
+}
+
+// checkCategory returns an error for a category ID that doesn't exist
+func (m *KnowledgeBaseModel) checkCategory(categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+	var exists bool
+	err := m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM kb_categories WHERE id = $1)", *categoryID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &KBError{Message: "category not found"}
+	}
+	return nil
+}
+
+const kbArticleColumns = `
+			a.id, a.category_id, a.title, a.body, a.ticket_type, a.visibility, a.is_published,
+			a.version, a.created_by, a.updated_by, a.created_at, a.updated_at,
+			COALESCE(c.name, ''), COALESCE(u.full_name, u.username, '')`
+
+const kbArticleJoins = `
+		FROM kb_articles a
+		LEFT JOIN kb_categories c ON c.id = a.category_id
+		LEFT JOIN users u ON u.id = a.created_by`
+
+func scanKBArticle(row rowScanner) (*KBArticle, error) {
+	var a KBArticle
+	var categoryID, createdBy, updatedBy sql.NullInt64
+	err := row.Scan(
+		&a.ID, &categoryID, &a.Title, &a.Body, &a.TicketType, &a.Visibility, &a.IsPublished,
+		&a.Version, &createdBy, &updatedBy, &a.CreatedAt, &a.UpdatedAt,
+		&a.CategoryName, &a.AuthorName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if categoryID.Valid {
+		a.CategoryID = &categoryID.Int64
+	}
+	if createdBy.Valid {
+		a.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		a.UpdatedBy = &updatedBy.Int64
+	}
+	return &a, nil
+}
+
+// InsertArticle saves a new article as version 1
+func (m *KnowledgeBaseModel) InsertArticle(a *KBArticle) error {
+	if err := validateKBArticle(a); err != nil {
+		return err
+	}
+	if err := m.checkCategory(a.CategoryID); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	a.Version = 1
+	a.UpdatedBy = a.CreatedBy
+	err = tx.QueryRow(`
+		INSERT INTO kb_articles (category_id, title, body, ticket_type, visibility, is_published, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id, created_at, updated_at
+	`, a.CategoryID, a.Title, a.Body, a.TicketType, a.Visibility, a.IsPublished, a.CreatedBy,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO kb_article_versions (article_id, version, title, body, edited_by)
+		VALUES ($1, 1, $2, $3, $4)
+	`, a.ID, a.Title, a.Body, a.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateArticle saves an edit. A changed title or body becomes a new
+// version; settings such as category or visibility change in place.
+func (m *KnowledgeBaseModel) UpdateArticle(a *KBArticle, userID int64) error {
+	if err := validateKBArticle(a); err != nil {
+		return err
+	}
+	if err := m.checkCategory(a.CategoryID); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var changed bool
+	err = tx.QueryRow(`
+		UPDATE kb_articles a
+		SET category_id = $1, title = $2, body = $3, ticket_type = $4, visibility = $5,
+			is_published = $6, updated_by = $7, updated_at = NOW(),
+			version = CASE WHEN old.title <> $2 OR old.body <> $3 THEN old.version + 1 ELSE old.version END
+		FROM (SELECT id, title, body, version FROM kb_articles WHERE id = $8 FOR UPDATE) old
+		WHERE a.id = old.id
+		RETURNING a.version, a.version <> old.version, a.created_by, a.created_at, a.updated_at
+	`, a.CategoryID, a.Title, a.Body, a.TicketType, a.Visibility, a.IsPublished, userID, a.ID,
+	).Scan(&a.Version, &changed, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("article not found")
+	}
+	if err != nil {
+		return err
+	}
+	a.UpdatedBy = &userID
+
+	if changed {
+		_, err = tx.Exec(`
+			INSERT INTO kb_article_versions (article_id, version, title, body, edited_by)
+			VALUES ($1, $2, $3, $4, $5)
+		`, a.ID, a.Version, a.Title, a.Body, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteArticle removes an article with its versions and ticket links
+func (m *KnowledgeBaseModel) DeleteArticle(id int64) error {
+	result, err := m.DB.Exec("DELETE FROM kb_articles WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("article not found")
+	}
+	return nil
+}
+
+// GetArticle returns an article the role can read
+func (m *KnowledgeBaseModel) GetArticle(id, roleID int64, includeDrafts bool) (*KBArticle, error) {
+	row := m.DB.QueryRow(`
+		SELECT`+kbArticleColumns+kbArticleJoins+`
+		WHERE a.id = $1`+kbVisibleClause(2, includeDrafts),
+		id, kbAccessLevel(roleID))
+
+	a, err := scanKBArticle(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("article not found")
+	}
+	return a, err
+}
+
+const kbSummaryColumns = `
+			a.id, a.title, a.category_id, COALESCE(c.name, ''), a.ticket_type, a.visibility,
+			a.is_published, a.updated_at`
+
+func scanKBSummary(row rowScanner, extra ...interface{}) (*KBArticleSummary, error) {
+	var s KBArticleSummary
+	var categoryID sql.NullInt64
+	dest := []interface{}{
+		&s.ID, &s.Title, &categoryID, &s.CategoryName, &s.TicketType, &s.Visibility,
+		&s.IsPublished, &s.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if categoryID.Valid {
+		s.CategoryID = &categoryID.Int64
+	}
+	return &s, nil
+}
+
+// ListArticles lists articles the reader can see, most recently updated first
+func (m *KnowledgeBaseModel) ListArticles(filters KBFilters) ([]KBArticleSummary, error) {
+	query := `
+		SELECT` + kbSummaryColumns + `
+		FROM kb_articles a
+		LEFT JOIN kb_categories c ON c.id = a.category_id
+		WHERE TRUE` + kbVisibleClause(1, filters.IncludeDrafts)
+
+	args := []interface{}{kbAccessLevel(filters.RoleID)}
+	argPos := 2
+
+	if filters.CategoryID != nil {
+		query += fmt.Sprintf(" AND a.category_id = $%d", argPos)
+		args = append(args, *filters.CategoryID)
+		argPos++
+	}
+
+	if filters.TicketType != "" {
+		query += fmt.Sprintf(" AND a.ticket_type = $%d", argPos)
+		args = append(args, filters.TicketType)
+		argPos++
+	}
+
+	query += " ORDER BY a.updated_at DESC"
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filters.Limit)
+		argPos++
+
+		if filters.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET $%d", argPos)
+			args = append(args, filters.Offset)
+		}
+	}
+
+	return m.querySummaries(false, query, args...)
+}
+
+// querySummaries runs a query selecting kbSummaryColumns, followed by rank
+// and snippet when ranked is set
+func (m *KnowledgeBaseModel) querySummaries(ranked bool, query string, args ...interface{}) ([]KBArticleSummary, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := []KBArticleSummary{}
+	for rows.Next() {
+		var s *KBArticleSummary
+		if ranked {
+			var rank float64
+			var snippet string
+			s, err = scanKBSummary(rows, &rank, &snippet)
+			if s != nil {
+				s.Rank, s.Snippet = rank, snippet
+			}
+		} else {
+			s, err = scanKBSummary(rows)
+		}
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, *s)
+	}
+
+	return articles, rows.Err()
+}
+
+const kbHeadlineOptions = `'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2'`
+
+// SearchArticles finds articles the reader can see whose title or body match
+// q, best match first
+func (m *KnowledgeBaseModel) SearchArticles(q string, filters KBFilters) ([]KBArticleSummary, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return []KBArticleSummary{}, nil
+	}
+
+	query := `
+		WITH search AS (SELECT websearch_to_tsquery('english', $1) AS query)
+		SELECT` + kbSummaryColumns + `,
+			ts_rank(a.search_vector, search.query) AS rank,
+			ts_headline('english', a.body, search.query, ` + kbHeadlineOptions + `)
+		FROM kb_articles a
+		CROSS JOIN search
+		LEFT JOIN kb_categories c ON c.id = a.category_id
+		WHERE a.search_vector @@ search.query` + kbVisibleClause(2, filters.IncludeDrafts)
+
+	args := []interface{}{q, kbAccessLevel(filters.RoleID)}
+	argPos := 3
+
+	if filters.CategoryID != nil {
+		query += fmt.Sprintf(" AND a.category_id = $%d", argPos)
+		args = append(args, *filters.CategoryID)
+		argPos++
+	}
+
+	if filters.TicketType != "" {
+		query += fmt.Sprintf(" AND a.ticket_type = $%d", argPos)
+		args = append(args, filters.TicketType)
+		argPos++
+	}
+
+	query += " ORDER BY rank DESC, a.updated_at DESC"
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filters.Limit)
+	}
+
+	return m.querySummaries(true, query, args...)
+}
+
+var kbWordPattern = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// suggestionQuery turns a ticket title into a tsquery matching any of its
+// words, so "Outlook keeps crashing" finds an article about Outlook
+func suggestionQuery(title string) string {
+	words := kbWordPattern.FindAllString(strings.ToLower(title), -1)
+	return strings.Join(words, " | ")
+}
+
+// SuggestArticles finds published articles that may answer a ticket with this
+// title and type: articles matching any word of the title, ranked higher when
+// they are written for the ticket type, or articles for the type when the
+// title matches nothing
+func (m *KnowledgeBaseModel) SuggestArticles(title, ticketType string, roleID int64, limit int) ([]KBArticleSummary, error) {
+	terms := suggestionQuery(title)
+	ticketType = strings.TrimSpace(ticketType)
+	if terms == "" && ticketType == "" {
+		return []KBArticleSummary{}, nil
+	}
+
+	return m.querySummaries(true, `
+		WITH search AS (SELECT to_tsquery('english', $1) AS query)
+		SELECT`+kbSummaryColumns+`,
+			ts_rank(a.search_vector, search.query)
+				+ CASE WHEN a.ticket_type <> '' AND a.ticket_type = $2 THEN 0.5 ELSE 0 END AS rank,
+			ts_headline('english', a.body, search.query, `+kbHeadlineOptions+`)
+		FROM kb_articles a
+		CROSS JOIN search
+		LEFT JOIN kb_categories c ON c.id = a.category_id
+		WHERE (a.search_vector @@ search.query OR (a.ticket_type <> '' AND a.ticket_type = $2))`+
+		kbVisibleClause(3, false)+`
+		ORDER BY rank DESC, a.updated_at DESC
+		LIMIT $4
+	`, terms, ticketType, kbAccessLevel(roleID), limit)
+}
+
+// GetVersions lists an article's saved versions, newest first
+func (m *KnowledgeBaseModel) GetVersions(articleID int64) ([]KBArticleVersion, error) {
+	rows, err := m.DB.Query(`
+		SELECT v.id, v.article_id, v.version, v.title, v.body, v.edited_by, v.created_at,
+			COALESCE(u.full_name, u.username, '')
+		FROM kb_article_versions v
+		LEFT JOIN users u ON u.id = v.edited_by
+		WHERE v.article_id = $1
+		ORDER BY v.version DESC
+	`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []KBArticleVersion{}
+	for rows.Next() {
+		var v KBArticleVersion
+		var editedBy sql.NullInt64
+		if err := rows.Scan(&v.ID, &v.ArticleID, &v.Version, &v.Title, &v.Body, &editedBy, &v.CreatedAt, &v.EditorName); err != nil {
+			return nil, err
+		}
+		if editedBy.Valid {
+			v.EditedBy = &editedBy.Int64
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// RestoreVersion makes an earlier version's title and body current again,
+// saved as a new version so the history is kept
+func (m *KnowledgeBaseModel) RestoreVersion(articleID int64, version int, userID int64) (*KBArticle, error) {
+	var title, body string
+	err := m.DB.QueryRow(
+		"SELECT title, body FROM kb_article_versions WHERE article_id = $1 AND version = $2",
+		articleID, version,
+	).Scan(&title, &body)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("version not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Editors restore, so drafts and every visibility are in reach
+	a, err := m.GetArticle(articleID, 1, true)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Title, a.Body = title, body
+	if err := m.UpdateArticle(a, userID); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// LinkTicket links an article to a ticket; linking twice is not an error
+func (m *KnowledgeBaseModel) LinkTicket(articleID, ticketID int64, linkedBy *int64) error {
+	var ticketExists, articleExists bool
+	err := m.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1),
+			EXISTS(SELECT 1 FROM kb_articles WHERE id = $2)
+	`, ticketID, articleID).Scan(&ticketExists, &articleExists)
+	if err != nil {
+		return err
+	}
+	if !ticketExists {
+		return errors.New("ticket not found")
+	}
+	if !articleExists {
+		return errors.New("article not found")
+	}
+
+	_, err = m.DB.Exec(`
+		INSERT INTO kb_article_tickets (article_id, ticket_id, linked_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (article_id, ticket_id) DO NOTHING
+	`, articleID, ticketID, linkedBy)
+	return err
+}
+
+// UnlinkTicket removes an article's link to a ticket
+func (m *KnowledgeBaseModel) UnlinkTicket(articleID, ticketID int64) error {
+	result, err := m.DB.Exec(
+		"DELETE FROM kb_article_tickets WHERE article_id = $1 AND ticket_id = $2",
+		articleID, ticketID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("article link not found")
+	}
+	return nil
+}
+
+// GetTicketArticles lists the articles linked to a ticket that the reader can see
+func (m *KnowledgeBaseModel) GetTicketArticles(ticketID, roleID int64) ([]KBArticleSummary, error) {
+	return m.querySummaries(false, `
+		SELECT`+kbSummaryColumns+`
+		FROM kb_article_tickets l
+		JOIN kb_articles a ON a.id = l.article_id
+		LEFT JOIN kb_categories c ON c.id = a.category_id
+		WHERE l.ticket_id = $1`+kbVisibleClause(2, CanManageKnowledgeBase(roleID))+`
+		ORDER BY l.created_at
+	`, ticketID, kbAccessLevel(roleID))
+}
@@ -0,0 +1,218 @@
+// file: app/internal/models/knowledge_base_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupKnowledgeBaseTest(t *testing.T) (*KnowledgeBaseModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewKnowledgeBaseModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var kbArticleTestColumns = []string{
+	"id", "category_id", "title", "body", "ticket_type", "visibility", "is_published",
+	"version", "created_by", "updated_by", "created_at", "updated_at",
+	"category_name", "author_name",
+}
+
+var kbSummaryTestColumns = []string{
+	"id", "title", "category_id", "category_name", "ticket_type", "visibility",
+	"is_published", "updated_at",
+}
+
+func TestKBAccessLevel(t *testing.T) {
+	assert.Equal(t, 2, kbAccessLevel(1))
+	assert.Equal(t, 2, kbAccessLevel(2))
+	assert.Equal(t, 1, kbAccessLevel(3))
+	assert.Equal(t, 0, kbAccessLevel(4))
+	assert.Equal(t, 0, kbAccessLevel(5))
+}
+
+func TestSuggestionQuery(t *testing.T) {
+	assert.Equal(t, "outlook | keeps | crashing", suggestionQuery("Outlook keeps crashing!"))
+	assert.Equal(t, "vpn | error | 809 | can | t | connect", suggestionQuery("VPN error 809 (can't connect)"))
+	assert.Equal(t, "", suggestionQuery("?!"))
+}
+
+func TestKnowledgeBaseModel_InsertArticle(t *testing.T) {
+	model, mock, teardown := setupKnowledgeBaseTest(t)
+	defer teardown()
+
+	now := time.Now()
+	author := int64(2)
+
+	t.Run("saved as version 1", func(t *testing.T) {
+		article := &KBArticle{Title: " Fixing Outlook crashes ", Body: "## Steps", TicketType: "it_help", CreatedBy: &author}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO kb_articles`).
+			WithArgs(nil, "Fixing Outlook crashes", "## Steps", "it_help", "everyone", false, &author).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, now, now))
+		mock.ExpectExec(`INSERT INTO kb_article_versions`).
+			WithArgs(int64(4), "Fixing Outlook crashes", "## Steps", &author).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, model.InsertArticle(article))
+		assert.Equal(t, int64(4), article.ID)
+		assert.Equal(t, 1, article.Version)
+		assert.Equal(t, "everyone", article.Visibility)
+	})
+
+	t.Run("invalid visibility", func(t *testing.T) {
+		err := model.InsertArticle(&KBArticle{Title: "VPN", Body: "Steps", Visibility: "managers"})
+		var kbErr *KBError
+		require.ErrorAs(t, err, &kbErr)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		category := int64(9)
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM kb_categories`).
+			WithArgs(category).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := model.InsertArticle(&KBArticle{CategoryID: &category, Title: "VPN", Body: "Steps"})
+		assert.EqualError(t, err, "category not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKnowledgeBaseModel_UpdateArticle(t *testing.T) {
+	model, mock, teardown := setupKnowledgeBaseTest(t)
+	defer teardown()
+
+	now := time.Now()
+	author := int64(2)
+
+	t.Run("body change saves a new version", func(t *testing.T) {
+		article := &KBArticle{ID: 4, Title: "Fixing Outlook crashes", Body: "## New steps", IsPublished: true}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE kb_articles a`).
+			WithArgs(nil, "Fixing Outlook crashes", "## New steps", "", "everyone", true, int64(3), int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"version", "changed", "created_by", "created_at", "updated_at"}).
+				AddRow(2, true, author, now, now))
+		mock.ExpectExec(`INSERT INTO kb_article_versions`).
+			WithArgs(int64(4), 2, "Fixing Outlook crashes", "## New steps", int64(3)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, model.UpdateArticle(article, 3))
+		assert.Equal(t, 2, article.Version)
+		assert.Equal(t, int64(3), *article.UpdatedBy)
+	})
+
+	t.Run("publishing only keeps the version", func(t *testing.T) {
+		article := &KBArticle{ID: 4, Title: "Fixing Outlook crashes", Body: "## New steps", IsPublished: true}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE kb_articles a`).
+			WillReturnRows(sqlmock.NewRows([]string{"version", "changed", "created_by", "created_at", "updated_at"}).
+				AddRow(2, false, author, now, now))
+		mock.ExpectCommit()
+
+		require.NoError(t, model.UpdateArticle(article, 3))
+		assert.Equal(t, 2, article.Version)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE kb_articles a`).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := model.UpdateArticle(&KBArticle{ID: 99, Title: "VPN", Body: "Steps"}, 3)
+		assert.EqualError(t, err, "article not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKnowledgeBaseModel_GetArticle(t *testing.T) {
+	model, mock, teardown := setupKnowledgeBaseTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("agent reads a published article", func(t *testing.T) {
+		mock.ExpectQuery(`FROM kb_articles a(.|\n)*AND a.is_published`).
+			WithArgs(int64(4), 0).
+			WillReturnRows(sqlmock.NewRows(kbArticleTestColumns).
+				AddRow(4, 1, "Fixing Outlook crashes", "## Steps", "it_help", "everyone", true,
+					2, 2, 2, now, now, "Email", "IT Staff"))
+
+		article, err := model.GetArticle(4, 4, false)
+		require.NoError(t, err)
+		assert.Equal(t, "Email", article.CategoryName)
+		assert.Equal(t, int64(1), *article.CategoryID)
+	})
+
+	t.Run("hidden from the role", func(t *testing.T) {
+		mock.ExpectQuery(`FROM kb_articles a`).
+			WithArgs(int64(5), 0).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := model.GetArticle(5, 5, false)
+		assert.EqualError(t, err, "article not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKnowledgeBaseModel_SuggestArticles(t *testing.T) {
+	model, mock, teardown := setupKnowledgeBaseTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("matches title words and type", func(t *testing.T) {
+		mock.ExpectQuery(`to_tsquery`).
+			WithArgs("outlook | crashing", "it_help", 1, 5).
+			WillReturnRows(sqlmock.NewRows(append(kbSummaryTestColumns, "rank", "snippet")).
+				AddRow(4, "Fixing Outlook crashes", 1, "Email", "it_help", "everyone", true, now, 0.6, "<mark>Outlook</mark> crashes"))
+
+		articles, err := model.SuggestArticles("Outlook crashing", "it_help", 3, 5)
+		require.NoError(t, err)
+		require.Len(t, articles, 1)
+		assert.Equal(t, 0.6, articles[0].Rank)
+		assert.Equal(t, "<mark>Outlook</mark> crashes", articles[0].Snippet)
+	})
+
+	t.Run("nothing to match", func(t *testing.T) {
+		articles, err := model.SuggestArticles("??", "", 3, 5)
+		require.NoError(t, err)
+		assert.Empty(t, articles)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKnowledgeBaseModel_RestoreVersion(t *testing.T) {
+	model, mock, teardown := setupKnowledgeBaseTest(t)
+	defer teardown()
+
+	mock.ExpectQuery(`SELECT title, body FROM kb_article_versions`).
+		WithArgs(int64(4), 7).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := model.RestoreVersion(4, 7, 3)
+	assert.EqualError(t, err, "version not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
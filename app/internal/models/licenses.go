@@ -0,0 +1,395 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// License is a software license or subscription with a fixed number of seats
+type License struct {
+	ID          int64      `json:"id"`
+	Product     string     `json:"product"`
+	Vendor      string     `json:"vendor"`
+	Seats       int        `json:"seats"`
+	LicenseKey  string     `json:"license_key"`
+	Term        string     `json:"term"` // perpetual, monthly, annual
+	StartDate   *time.Time `json:"start_date"`
+	RenewalDate *time.Time `json:"renewal_date"`
+	Notes       string     `json:"notes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Computed fields
+	SeatsUsed     int  `json:"seats_used"`
+	OverAllocated bool `json:"over_allocated"` // More active allocations than seats
+}
+
+// LicenseAllocation is one seat of a license held by a user or a PC
+type LicenseAllocation struct {
+	ID            int64      `json:"id"`
+	LicenseID     int64      `json:"license_id"`
+	UserID        *int64     `json:"user_id"`
+	AssetID       *int64     `json:"asset_id"`
+	AllocatedBy   *int64     `json:"allocated_by"`
+	AllocatedAt   time.Time  `json:"allocated_at"`
+	ReleasedAt    *time.Time `json:"released_at"`
+	ReleaseReason string     `json:"release_reason"`
+
+	// Joined fields
+	Product         string `json:"product,omitempty"`
+	UserFullName    string `json:"user_full_name,omitempty"`
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+}
+
+var licenseTerms = map[string]bool{
+	"perpetual": true, "monthly": true, "annual": true,
+}
+
+// IsValidLicenseTerm reports whether term is a known license term
+func IsValidLicenseTerm(term string) bool {
+	return licenseTerms[term]
+}
+
+type LicenseModel struct {
+	DB *sql.DB
+}
+
+func NewLicenseModel(db *sql.DB) *LicenseModel {
+	return &LicenseModel{DB: db}
+}
+
+const licenseColumns = `
+			l.id, l.product, l.vendor, l.seats, l.license_key, l.term,
+			l.start_date, l.renewal_date, l.notes, l.created_at, l.updated_at,
+			(SELECT COUNT(*) FROM license_allocations la
+			 WHERE la.license_id = l.id AND la.released_at IS NULL) AS seats_used`
+
+func scanLicense(row rowScanner) (*License, error) {
+	var l License
+	err := row.Scan(
+		&l.ID,
+		&l.Product,
+		&l.Vendor,
+		&l.Seats,
+		&l.LicenseKey,
+		&l.Term,
+		&l.StartDate,
+		&l.RenewalDate,
+		&l.Notes,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+		&l.SeatsUsed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	l.OverAllocated = l.SeatsUsed > l.Seats
+	return &l, nil
+}
+
+func (m *LicenseModel) queryLicenses(query string, args ...interface{}) ([]License, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	licenses := []License{}
+	for rows.Next() {
+		l, err := scanLicense(rows)
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, *l)
+	}
+
+	return licenses, rows.Err()
+}
+
+// Insert a new license
+func (m *LicenseModel) Insert(l *License) error {
+	query := `
+		INSERT INTO licenses (product, vendor, seats, license_key, term, start_date, renewal_date, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	return m.DB.QueryRow(
+		query,
+		l.Product,
+		l.Vendor,
+		l.Seats,
+		l.LicenseKey,
+		l.Term,
+		l.StartDate,
+		l.RenewalDate,
+		l.Notes,
+	).Scan(&l.ID, &l.CreatedAt, &l.UpdatedAt)
+}
+
+// Get license by ID
+func (m *LicenseModel) GetByID(id int64) (*License, error) {
+	query := `SELECT ` + licenseColumns + ` FROM licenses l WHERE l.id = $1`
+
+	l, err := scanLicense(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("license not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetAll returns licenses, optionally filtered by vendor
+func (m *LicenseModel) GetAll(vendor string) ([]License, error) {
+	query := `
+		SELECT ` + licenseColumns + `
+		FROM licenses l
+		WHERE ($1 = '' OR l.vendor = $1)
+		ORDER BY l.product, l.id
+	`
+
+	return m.queryLicenses(query, vendor)
+}
+
+// GetOverAllocated returns licenses with more active allocations than seats
+func (m *LicenseModel) GetOverAllocated() ([]License, error) {
+	query := `
+		SELECT * FROM (
+			SELECT ` + licenseColumns + ` FROM licenses l
+		) counted
+		WHERE seats_used > seats
+		ORDER BY product, id
+	`
+
+	return m.queryLicenses(query)
+}
+
+// GetRenewalsDue returns non-perpetual licenses renewing within the next days
+// days, including any whose renewal date has already passed
+func (m *LicenseModel) GetRenewalsDue(days int) ([]License, error) {
+	query := `
+		SELECT ` + licenseColumns + `
+		FROM licenses l
+		WHERE l.term != 'perpetual' AND l.renewal_date IS NOT NULL
+		AND l.renewal_date <= CURRENT_DATE + $1::int
+		ORDER BY l.renewal_date, l.id
+	`
+
+	return m.queryLicenses(query, days)
+}
+
+// GetRenewalsToNotify is GetRenewalsDue limited to licenses that have not yet
+// been reminded about their current renewal date
+func (m *LicenseModel) GetRenewalsToNotify(days int) ([]License, error) {
+	query := `
+		SELECT ` + licenseColumns + `
+		FROM licenses l
+		WHERE l.term != 'perpetual' AND l.renewal_date IS NOT NULL
+		AND l.renewal_date <= CURRENT_DATE + $1::int
+		AND l.renewal_notified_for IS DISTINCT FROM l.renewal_date
+		ORDER BY l.renewal_date, l.id
+	`
+
+	return m.queryLicenses(query, days)
+}
+
+// MarkRenewalNotified records that reminders went out for these licenses'
+// current renewal dates. Moving a renewal date re-arms the reminder.
+func (m *LicenseModel) MarkRenewalNotified(ids []int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE licenses SET renewal_notified_for = renewal_date WHERE id = $1`, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Update a license. Lowering seats below the number in use is allowed and
+// shows up as over-allocation.
+func (m *LicenseModel) Update(l *License) error {
+	query := `
+		UPDATE licenses
+		SET product = $1, vendor = $2, seats = $3, license_key = $4, term = $5,
+		    start_date = $6, renewal_date = $7, notes = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+
+	err := m.DB.QueryRow(
+		query,
+		l.Product,
+		l.Vendor,
+		l.Seats,
+		l.LicenseKey,
+		l.Term,
+		l.StartDate,
+		l.RenewalDate,
+		l.Notes,
+		l.ID,
+	).Scan(&l.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("license not found")
+	}
+
+	return err
+}
+
+// Delete a license along with its allocation history
+func (m *LicenseModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM licenses WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("license not found")
+	}
+
+	return nil
+}
+
+// Allocate a seat to a user or a PC. The license row is locked so concurrent
+// allocations can't both take the last seat. force allows going over the seat
+// count, e.g. while a true-up order is pending.
+func (m *LicenseModel) Allocate(a *LicenseAllocation, force bool) error {
+	if (a.UserID == nil) == (a.AssetID == nil) {
+		return errors.New("allocate to either a user or an asset")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var seats, used int
+	err = tx.QueryRow(`SELECT seats FROM licenses WHERE id = $1 FOR UPDATE`, a.LicenseID).Scan(&seats)
+	if err == sql.ErrNoRows {
+		return errors.New("license not found")
+	} else if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM license_allocations
+		WHERE license_id = $1 AND released_at IS NULL
+	`, a.LicenseID).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used >= seats && !force {
+		return errors.New("no free seats on this license")
+	}
+
+	if a.AssetID != nil {
+		var assetType string
+		err = tx.QueryRow(`SELECT asset_type FROM assets WHERE id = $1 AND deleted_at IS NULL`, *a.AssetID).Scan(&assetType)
+		if err == sql.ErrNoRows {
+			return errors.New("asset not found")
+		} else if err != nil {
+			return err
+		}
+		if assetType != "PC" {
+			return errors.New("licenses can only be allocated to PC assets")
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO license_allocations (license_id, user_id, asset_id, allocated_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, allocated_at
+	`, a.LicenseID, a.UserID, a.AssetID, a.AllocatedBy).Scan(&a.ID, &a.AllocatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("license is already allocated to this user or asset")
+		}
+		if strings.Contains(err.Error(), "foreign key") {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Release frees an allocated seat
+func (m *LicenseModel) Release(allocationID int64, reason string) error {
+	result, err := m.DB.Exec(`
+		UPDATE license_allocations SET released_at = NOW(), release_reason = $1
+		WHERE id = $2 AND released_at IS NULL
+	`, reason, allocationID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("allocation not found or already released")
+	}
+
+	return nil
+}
+
+// GetAllocations returns a license's active allocations, or its full history
+// when includeReleased is set
+func (m *LicenseModel) GetAllocations(licenseID int64, includeReleased bool) ([]LicenseAllocation, error) {
+	query := `
+		SELECT la.id, la.license_id, la.user_id, la.asset_id, la.allocated_by,
+		       la.allocated_at, la.released_at, la.release_reason,
+		       l.product, COALESCE(u.full_name, ''), COALESCE(a.internal_id, '')
+		FROM license_allocations la
+		JOIN licenses l ON l.id = la.license_id
+		LEFT JOIN users u ON u.id = la.user_id
+		LEFT JOIN assets a ON a.id = la.asset_id
+		WHERE la.license_id = $1 AND ($2 OR la.released_at IS NULL)
+		ORDER BY la.allocated_at DESC
+	`
+
+	rows, err := m.DB.Query(query, licenseID, includeReleased)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := []LicenseAllocation{}
+	for rows.Next() {
+		var a LicenseAllocation
+		err := rows.Scan(
+			&a.ID,
+			&a.LicenseID,
+			&a.UserID,
+			&a.AssetID,
+			&a.AllocatedBy,
+			&a.AllocatedAt,
+			&a.ReleasedAt,
+			&a.ReleaseReason,
+			&a.Product,
+			&a.UserFullName,
+			&a.AssetInternalID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, a)
+	}
+
+	return allocations, rows.Err()
+}
@@ -0,0 +1,157 @@
+// file: app/internal/models/licenses_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLicenseTest(t *testing.T) (*LicenseModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewLicenseModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestLicenseModel_Allocate(t *testing.T) {
+	model, mock, teardown := setupLicenseTest(t)
+	defer teardown()
+
+	now := time.Now()
+	allocatedBy := int64(1)
+
+	t.Run("successful allocation to a PC", func(t *testing.T) {
+		assetID := int64(12)
+		alloc := &LicenseAllocation{LicenseID: 3, AssetID: &assetID, AllocatedBy: &allocatedBy}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT seats FROM licenses`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"seats"}).AddRow(5))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM license_allocations`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(`SELECT asset_type FROM assets`).
+			WithArgs(assetID).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_type"}).AddRow("PC"))
+		mock.ExpectQuery(`INSERT INTO license_allocations`).
+			WithArgs(int64(3), nil, &assetID, &allocatedBy).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "allocated_at"}).AddRow(8, now))
+		mock.ExpectCommit()
+
+		err := model.Allocate(alloc, false)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8), alloc.ID)
+	})
+
+	t.Run("no free seats", func(t *testing.T) {
+		userID := int64(7)
+		alloc := &LicenseAllocation{LicenseID: 3, UserID: &userID, AllocatedBy: &allocatedBy}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT seats FROM licenses`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"seats"}).AddRow(5))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM license_allocations`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectRollback()
+
+		err := model.Allocate(alloc, false)
+		assert.Error(t, err)
+		assert.Equal(t, "no free seats on this license", err.Error())
+	})
+
+	t.Run("asset is not a PC", func(t *testing.T) {
+		assetID := int64(13)
+		alloc := &LicenseAllocation{LicenseID: 3, AssetID: &assetID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT seats FROM licenses`).
+			WillReturnRows(sqlmock.NewRows([]string{"seats"}).AddRow(5))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM license_allocations`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT asset_type FROM assets`).
+			WithArgs(assetID).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_type"}).AddRow("Monitor"))
+		mock.ExpectRollback()
+
+		err := model.Allocate(alloc, false)
+		assert.Error(t, err)
+		assert.Equal(t, "licenses can only be allocated to PC assets", err.Error())
+	})
+
+	t.Run("neither user nor asset", func(t *testing.T) {
+		err := model.Allocate(&LicenseAllocation{LicenseID: 3}, false)
+		assert.Error(t, err)
+		assert.Equal(t, "allocate to either a user or an asset", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssetsModel_UnassignAssetReleasesLicenses(t *testing.T) {
+	model, mock, teardown := setupAssetTest(t)
+	defer teardown()
+
+	t.Run("frees license seats and closes the handover", func(t *testing.T) {
+		receivedBy := int64(2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE assets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs(int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("fair", "Scratched lid", &receivedBy, int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+		mock.ExpectCommit()
+
+		custodyID, err := model.UnassignAsset(12, CustodyCheckIn{ReceivedBy: &receivedBy, Condition: "fair", Notes: "Scratched lid"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), custodyID)
+	})
+
+	t.Run("no open handover", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE assets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs(int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		custodyID, err := model.UnassignAsset(12, CustodyCheckIn{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), custodyID)
+	})
+
+	t.Run("asset not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE assets`).
+			WithArgs(int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}))
+		mock.ExpectRollback()
+
+		_, err := model.UnassignAsset(99, CustodyCheckIn{})
+		assert.Error(t, err)
+		assert.Equal(t, "asset not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaintenancePlan is a recurring preventive maintenance task, e.g. "UPS
+// battery test every 90 days". It covers either every asset of a type or a
+// single asset; a plan on a single asset takes precedence over a type plan
+// with the same service type.
+type MaintenancePlan struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	AssetType    *string   `json:"asset_type"`
+	AssetID      *int64    `json:"asset_id"`
+	ServiceType  string    `json:"service_type"`  // Service logs of this type reset the clock
+	IntervalDays int       `json:"interval_days"` // Days between services
+	LeadDays     int       `json:"lead_days"`     // Raise the ticket this many days before due
+	AssignTo     *int64    `json:"assign_to"`     // Who generated tickets go to
+	Active       bool      `json:"active"`
+	CreatedBy    *int64    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MaintenanceDue is one plan applied to one asset, with when it is next due
+type MaintenanceDue struct {
+	PlanID          int64      `json:"plan_id"`
+	PlanName        string     `json:"plan_name"`
+	ServiceType     string     `json:"service_type"`
+	IntervalDays    int        `json:"interval_days"`
+	LeadDays        int        `json:"lead_days"`
+	AssignTo        *int64     `json:"assign_to"`
+	AssetID         int64      `json:"asset_id"`
+	AssetInternalID string     `json:"asset_internal_id"`
+	AssetType       string     `json:"asset_type"`
+	Location        string     `json:"location"`
+	LastService     *time.Time `json:"last_service"`
+	DueDate         time.Time  `json:"due_date"`
+	Overdue         bool       `json:"overdue"`
+	TicketID        *int64     `json:"ticket_id"` // Ticket already raised for this due date
+}
+
+type MaintenanceModel struct {
+	DB *sql.DB
+}
+
+func NewMaintenanceModel(db *sql.DB) *MaintenanceModel {
+	return &MaintenanceModel{DB: db}
+}
+
+const maintenancePlanColumns = `
+			id, name, asset_type, asset_id, service_type, interval_days, lead_days,
+			assign_to, active, created_by, created_at, updated_at`
+
+func scanMaintenancePlan(row rowScanner) (*MaintenancePlan, error) {
+	var p MaintenancePlan
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.AssetType,
+		&p.AssetID,
+		&p.ServiceType,
+		&p.IntervalDays,
+		&p.LeadDays,
+		&p.AssignTo,
+		&p.Active,
+		&p.CreatedBy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// maintenanceScheduleCTE pairs every active plan with the live assets it
+// covers. An asset is due interval_days after its last service of the plan's
+// type, or after it entered service if it has never had one.
+const maintenanceScheduleCTE = `
+		WITH schedule AS (
+			SELECT p.id AS plan_id, p.name AS plan_name, p.service_type, p.interval_days,
+			       p.lead_days, p.assign_to,
+			       a.id AS asset_id, a.internal_id, a.asset_type, COALESCE(a.location, '') AS location,
+			       (SELECT MAX(s.performed_at)::date FROM asset_service s
+			        WHERE s.asset_id = a.id AND s.service_type = p.service_type) AS last_service,
+			       COALESCE(a.date_purchased, a.created_at::date) AS in_service_since
+			FROM maintenance_plans p
+			JOIN assets a ON a.id = p.asset_id OR a.asset_type = p.asset_type
+			WHERE p.active AND a.deleted_at IS NULL AND a.status != 'RETIRED'
+			AND NOT (p.asset_id IS NULL AND EXISTS (
+				SELECT 1 FROM maintenance_plans o
+				WHERE o.active AND o.asset_id = a.id AND o.service_type = p.service_type))
+		),
+		due AS (
+			SELECT schedule.*, COALESCE(last_service, in_service_since) + interval_days AS due_date
+			FROM schedule
+		)`
+
+const maintenanceDueSelect = `
+		SELECT d.plan_id, d.plan_name, d.service_type, d.interval_days, d.lead_days, d.assign_to,
+		       d.asset_id, d.internal_id, d.asset_type, d.location, d.last_service, d.due_date,
+		       d.due_date < CURRENT_DATE, mt.ticket_id
+		FROM due d
+		LEFT JOIN maintenance_tickets mt
+		  ON mt.plan_id = d.plan_id AND mt.asset_id = d.asset_id AND mt.due_date = d.due_date`
+
+func (m *MaintenanceModel) queryDue(query string, args ...interface{}) ([]MaintenanceDue, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []MaintenanceDue{}
+	for rows.Next() {
+		var d MaintenanceDue
+		err := rows.Scan(
+			&d.PlanID,
+			&d.PlanName,
+			&d.ServiceType,
+			&d.IntervalDays,
+			&d.LeadDays,
+			&d.AssignTo,
+			&d.AssetID,
+			&d.AssetInternalID,
+			&d.AssetType,
+			&d.Location,
+			&d.LastService,
+			&d.DueDate,
+			&d.Overdue,
+			&d.TicketID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, d)
+	}
+
+	return items, rows.Err()
+}
+
+// InsertPlan creates a maintenance plan
+func (m *MaintenanceModel) InsertPlan(p *MaintenancePlan) error {
+	if (p.AssetType == nil) == (p.AssetID == nil) {
+		return errors.New("a plan applies to either an asset_type or an asset_id")
+	}
+
+	query := `
+		INSERT INTO maintenance_plans (name, asset_type, asset_id, service_type, interval_days,
+		                               lead_days, assign_to, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := m.DB.QueryRow(query, p.Name, p.AssetType, p.AssetID, p.ServiceType, p.IntervalDays,
+		p.LeadDays, p.AssignTo, p.Active, p.CreatedBy).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil && strings.Contains(err.Error(), "foreign key") {
+		return errors.New("asset or assignee not found")
+	}
+
+	return err
+}
+
+// Get plan by ID
+func (m *MaintenanceModel) GetPlan(id int64) (*MaintenancePlan, error) {
+	query := `SELECT ` + maintenancePlanColumns + ` FROM maintenance_plans WHERE id = $1`
+
+	p, err := scanMaintenancePlan(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("maintenance plan not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetPlans lists plans, optionally only those for an asset type or a single
+// asset. Filtering by asset also returns the type plans that cover it.
+func (m *MaintenanceModel) GetPlans(assetType string, assetID *int64) ([]MaintenancePlan, error) {
+	query := `SELECT ` + maintenancePlanColumns + ` FROM maintenance_plans
+		WHERE ($1 = '' OR asset_type = $1)
+		AND ($2::bigint IS NULL OR asset_id = $2
+		     OR asset_type = (SELECT asset_type FROM assets WHERE id = $2))
+		ORDER BY name`
+
+	rows, err := m.DB.Query(query, assetType, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []MaintenancePlan{}
+	for rows.Next() {
+		p, err := scanMaintenancePlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *p)
+	}
+
+	return plans, rows.Err()
+}
+
+// UpdatePlan saves a plan's schedule settings. What it applies to is fixed.
+func (m *MaintenanceModel) UpdatePlan(p *MaintenancePlan) error {
+	query := `
+		UPDATE maintenance_plans
+		SET name = $1, service_type = $2, interval_days = $3, lead_days = $4,
+		    assign_to = $5, active = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING updated_at
+	`
+
+	err := m.DB.QueryRow(query, p.Name, p.ServiceType, p.IntervalDays, p.LeadDays,
+		p.AssignTo, p.Active, p.ID).Scan(&p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("maintenance plan not found")
+	} else if err != nil && strings.Contains(err.Error(), "foreign key") {
+		return errors.New("asset or assignee not found")
+	}
+
+	return err
+}
+
+// DeletePlan removes a plan. Tickets it raised are kept.
+func (m *MaintenanceModel) DeletePlan(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM maintenance_plans WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("maintenance plan not found")
+	}
+
+	return nil
+}
+
+// GetCalendar returns maintenance due within the next days days, including
+// anything already overdue, soonest first
+func (m *MaintenanceModel) GetCalendar(days int) ([]MaintenanceDue, error) {
+	query := maintenanceScheduleCTE + maintenanceDueSelect + `
+		WHERE d.due_date <= CURRENT_DATE + $1::int
+		ORDER BY d.due_date, d.internal_id`
+
+	return m.queryDue(query, days)
+}
+
+// GetTicketsDue returns maintenance inside its lead window that has no ticket yet
+func (m *MaintenanceModel) GetTicketsDue() ([]MaintenanceDue, error) {
+	query := maintenanceScheduleCTE + maintenanceDueSelect + `
+		WHERE d.due_date - d.lead_days <= CURRENT_DATE AND mt.id IS NULL
+		ORDER BY d.due_date, d.internal_id`
+
+	return m.queryDue(query)
+}
+
+// RaiseTicket creates the ticket for a plan, asset and due date and records
+// it against them, in one transaction. It returns false, creating nothing,
+// if a ticket was already raised for that due date.
+func (m *MaintenanceModel) RaiseTicket(planID, assetID int64, dueDate time.Time, ticket *Ticket) (bool, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO maintenance_tickets (plan_id, asset_id, due_date)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (plan_id, asset_id, due_date) DO NOTHING
+	`, planID, assetID, dueDate)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := insertTicket(tx, ticket); err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE maintenance_tickets SET ticket_id = $1
+		WHERE plan_id = $2 AND asset_id = $3 AND due_date = $4
+	`, ticket.ID, planID, assetID, dueDate)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NextDueAfterService works out an asset's next service date from its plans,
+// counting a service of serviceType performed at performedAt. It returns nil
+// if no plan covers the asset.
+func (m *MaintenanceModel) NextDueAfterService(assetID int64, serviceType string, performedAt time.Time) (*time.Time, error) {
+	query := maintenanceScheduleCTE + `
+		SELECT MIN(COALESCE(
+			GREATEST(last_service, CASE WHEN service_type = $2 THEN $3::date END),
+			in_service_since) + interval_days)
+		FROM schedule
+		WHERE asset_id = $1`
+
+	var next sql.NullTime
+	if err := m.DB.QueryRow(query, assetID, serviceType, performedAt).Scan(&next); err != nil {
+		return nil, err
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+
+	return &next.Time, nil
+}
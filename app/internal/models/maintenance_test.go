@@ -0,0 +1,145 @@
+// file: app/internal/models/maintenance_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMaintenanceTest(t *testing.T) (*MaintenanceModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewMaintenanceModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestMaintenanceModel_InsertPlan(t *testing.T) {
+	model, mock, teardown := setupMaintenanceTest(t)
+	defer teardown()
+
+	assetType := "UPS"
+
+	t.Run("plan for an asset type", func(t *testing.T) {
+		now := time.Now()
+		plan := &MaintenancePlan{Name: "UPS battery test", AssetType: &assetType, ServiceType: "MAINTENANCE", IntervalDays: 90, LeadDays: 7, Active: true}
+
+		mock.ExpectQuery(`INSERT INTO maintenance_plans`).
+			WithArgs("UPS battery test", &assetType, nil, "MAINTENANCE", 90, 7, nil, true, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		err := model.InsertPlan(plan)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), plan.ID)
+	})
+
+	t.Run("both type and asset", func(t *testing.T) {
+		assetID := int64(12)
+		err := model.InsertPlan(&MaintenancePlan{Name: "x", AssetType: &assetType, AssetID: &assetID, IntervalDays: 30})
+		assert.Error(t, err)
+		assert.Equal(t, "a plan applies to either an asset_type or an asset_id", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMaintenanceModel_NextDueAfterService(t *testing.T) {
+	model, mock, teardown := setupMaintenanceTest(t)
+	defer teardown()
+
+	performedAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("covered by a plan", func(t *testing.T) {
+		due := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`WITH schedule AS`).
+			WithArgs(int64(12), "MAINTENANCE", performedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(due))
+
+		next, err := model.NextDueAfterService(12, "MAINTENANCE", performedAt)
+		assert.NoError(t, err)
+		require.NotNil(t, next)
+		assert.Equal(t, due, *next)
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		mock.ExpectQuery(`WITH schedule AS`).
+			WithArgs(int64(13), "MAINTENANCE", performedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(nil))
+
+		next, err := model.NextDueAfterService(13, "MAINTENANCE", performedAt)
+		assert.NoError(t, err)
+		assert.Nil(t, next)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMaintenanceModel_RaiseTicket(t *testing.T) {
+	model, mock, teardown := setupMaintenanceTest(t)
+	defer teardown()
+
+	now := time.Now()
+	due := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ticket raised and recorded together", func(t *testing.T) {
+		ticket := &Ticket{Title: "Quarterly clean: AM-PC001", Type: "maintenance", Priority: "normal", Status: "open"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO maintenance_tickets`).
+			WithArgs(int64(1), int64(12), due).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT MAX`).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(7))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO tickets`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(30, now, now))
+		mock.ExpectExec(`UPDATE maintenance_tickets SET ticket_id = \$1`).
+			WithArgs(int64(30), int64(1), int64(12), due).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		raised, err := model.RaiseTicket(1, 12, due, ticket)
+		assert.NoError(t, err)
+		assert.True(t, raised)
+		assert.Equal(t, int64(30), ticket.ID)
+	})
+
+	t.Run("already raised", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO maintenance_tickets`).
+			WithArgs(int64(1), int64(12), due).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		raised, err := model.RaiseTicket(1, 12, due, &Ticket{})
+		assert.NoError(t, err)
+		assert.False(t, raised)
+	})
+
+	t.Run("failed ticket leaves the slot free", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO maintenance_tickets`).
+			WithArgs(int64(1), int64(12), due).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT MAX`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err := model.RaiseTicket(1, 12, due, &Ticket{})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
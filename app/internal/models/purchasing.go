@@ -0,0 +1,551 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PurchaseRequest asks for equipment to be bought. An admin approves or
+// rejects it; approved requests are turned into purchase orders.
+type PurchaseRequest struct {
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	Justification string     `json:"justification"`
+	AssetType     string     `json:"asset_type"`
+	Quantity      int        `json:"quantity"`
+	EstimatedCost *float64   `json:"estimated_cost"`
+	Status        string     `json:"status"` // pending, approved, rejected, ordered
+	RequestedBy   *int64     `json:"requested_by"`
+	ReviewedBy    *int64     `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewNotes   string     `json:"review_notes"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Joined fields
+	RequestedByName string `json:"requested_by_name,omitempty"`
+}
+
+// PurchaseOrder is an order placed with a vendor
+type PurchaseOrder struct {
+	ID                int64               `json:"id"`
+	PONumber          string              `json:"po_number"`
+	PurchaseRequestID *int64              `json:"purchase_request_id"`
+	Vendor            string              `json:"vendor"`
+	Status            string              `json:"status"` // open, partially_received, received, cancelled
+	OrderDate         time.Time           `json:"order_date"`
+	ExpectedDelivery  *time.Time          `json:"expected_delivery"`
+	Notes             string              `json:"notes"`
+	CreatedBy         *int64              `json:"created_by"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+	Lines             []PurchaseOrderLine `json:"lines"`
+
+	// Computed fields
+	TotalCost float64 `json:"total_cost"`
+}
+
+// PurchaseOrderLine is one product on a purchase order
+type PurchaseOrderLine struct {
+	ID               int64   `json:"id"`
+	PurchaseOrderID  int64   `json:"purchase_order_id"`
+	AssetType        string  `json:"asset_type"`
+	Manufacturer     string  `json:"manufacturer"`
+	Model            string  `json:"model"`
+	ModelNumber      string  `json:"model_number"`
+	Description      string  `json:"description"`
+	Quantity         int     `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	QuantityReceived int     `json:"quantity_received"`
+}
+
+// GoodsReceiptUnit is one unit received against a PO line
+type GoodsReceiptUnit struct {
+	LineID       int64                  `json:"line_id"`
+	InternalID   string                 `json:"internal_id"`
+	SerialNumber string                 `json:"serial_number"`
+	CustomFields map[string]interface{} `json:"custom_fields"` // Checked against the asset type's schema
+}
+
+type PurchasingModel struct {
+	DB *sql.DB
+}
+
+func NewPurchasingModel(db *sql.DB) *PurchasingModel {
+	return &PurchasingModel{DB: db}
+}
+
+const purchaseRequestColumns = `
+			pr.id, pr.title, pr.justification, pr.asset_type, pr.quantity,
+			pr.estimated_cost, pr.status, pr.requested_by, pr.reviewed_by,
+			pr.reviewed_at, pr.review_notes, pr.created_at, pr.updated_at,
+			COALESCE(u.full_name, '')`
+
+const purchaseRequestJoins = `
+		FROM purchase_requests pr
+		LEFT JOIN users u ON u.id = pr.requested_by`
+
+func scanPurchaseRequest(row rowScanner) (*PurchaseRequest, error) {
+	var pr PurchaseRequest
+	err := row.Scan(
+		&pr.ID,
+		&pr.Title,
+		&pr.Justification,
+		&pr.AssetType,
+		&pr.Quantity,
+		&pr.EstimatedCost,
+		&pr.Status,
+		&pr.RequestedBy,
+		&pr.ReviewedBy,
+		&pr.ReviewedAt,
+		&pr.ReviewNotes,
+		&pr.CreatedAt,
+		&pr.UpdatedAt,
+		&pr.RequestedByName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// InsertRequest opens a new purchase request
+func (m *PurchasingModel) InsertRequest(pr *PurchaseRequest) error {
+	query := `
+		INSERT INTO purchase_requests (title, justification, asset_type, quantity, estimated_cost, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	return m.DB.QueryRow(
+		query,
+		pr.Title,
+		pr.Justification,
+		pr.AssetType,
+		pr.Quantity,
+		pr.EstimatedCost,
+		pr.RequestedBy,
+	).Scan(&pr.ID, &pr.Status, &pr.CreatedAt, &pr.UpdatedAt)
+}
+
+// GetRequest returns a purchase request by ID
+func (m *PurchasingModel) GetRequest(id int64) (*PurchaseRequest, error) {
+	query := `SELECT ` + purchaseRequestColumns + purchaseRequestJoins + ` WHERE pr.id = $1`
+
+	pr, err := scanPurchaseRequest(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("purchase request not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// GetRequests returns purchase requests, optionally filtered by status and
+// requester, newest first
+func (m *PurchasingModel) GetRequests(status string, requestedBy *int64) ([]PurchaseRequest, error) {
+	query := `SELECT ` + purchaseRequestColumns + purchaseRequestJoins + `
+		WHERE ($1 = '' OR pr.status = $1)
+		AND ($2::BIGINT IS NULL OR pr.requested_by = $2)
+		ORDER BY pr.created_at DESC`
+
+	rows, err := m.DB.Query(query, status, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []PurchaseRequest{}
+	for rows.Next() {
+		pr, err := scanPurchaseRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *pr)
+	}
+
+	return requests, rows.Err()
+}
+
+// ReviewRequest approves or rejects a pending purchase request
+func (m *PurchasingModel) ReviewRequest(id, reviewerID int64, approved bool, notes string) error {
+	status := "rejected"
+	if approved {
+		status = "approved"
+	}
+
+	result, err := m.DB.Exec(`
+		UPDATE purchase_requests
+		SET status = $1, reviewed_by = $2, reviewed_at = NOW(), review_notes = $3, updated_at = NOW()
+		WHERE id = $4 AND status = 'pending'
+	`, status, reviewerID, notes, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("purchase request not found or not pending")
+	}
+
+	return nil
+}
+
+// InsertOrder places a purchase order with its lines. An order raised from a
+// purchase request needs the request to be approved, and marks it ordered.
+func (m *PurchasingModel) InsertOrder(po *PurchaseOrder) error {
+	if len(po.Lines) == 0 {
+		return errors.New("a purchase order needs at least one line")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if po.PurchaseRequestID != nil {
+		result, err := tx.Exec(`
+			UPDATE purchase_requests SET status = 'ordered', updated_at = NOW()
+			WHERE id = $1 AND status = 'approved'
+		`, *po.PurchaseRequestID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("purchase request not found or not approved")
+		}
+	}
+
+	var poNumber interface{}
+	if po.PONumber != "" {
+		poNumber = po.PONumber
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO purchase_orders (po_number, purchase_request_id, vendor, order_date, expected_delivery, notes, created_by)
+		VALUES ($1, $2, $3, COALESCE($4, CURRENT_DATE), $5, $6, $7)
+		RETURNING id, status, order_date, created_at, updated_at
+	`, poNumber, po.PurchaseRequestID, po.Vendor, nullTime(po.OrderDate), po.ExpectedDelivery, po.Notes, po.CreatedBy).
+		Scan(&po.ID, &po.Status, &po.OrderDate, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("PO number already exists")
+		}
+		return err
+	}
+
+	if po.PONumber == "" {
+		po.PONumber = fmt.Sprintf("PO-%d-%04d", po.OrderDate.Year(), po.ID)
+		if _, err := tx.Exec(`UPDATE purchase_orders SET po_number = $1 WHERE id = $2`, po.PONumber, po.ID); err != nil {
+			return err
+		}
+	}
+
+	po.TotalCost = 0
+	for i := range po.Lines {
+		line := &po.Lines[i]
+		line.PurchaseOrderID = po.ID
+		err := tx.QueryRow(`
+			INSERT INTO purchase_order_lines (
+				purchase_order_id, asset_type, manufacturer, model, model_number,
+				description, quantity, unit_cost
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, po.ID, line.AssetType, line.Manufacturer, line.Model, line.ModelNumber,
+			line.Description, line.Quantity, line.UnitCost).Scan(&line.ID)
+		if err != nil {
+			return err
+		}
+		po.TotalCost += float64(line.Quantity) * line.UnitCost
+	}
+
+	return tx.Commit()
+}
+
+// nullTime turns a zero time into NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+const purchaseOrderColumns = `
+			po.id, COALESCE(po.po_number, ''), po.purchase_request_id, po.vendor, po.status,
+			po.order_date, po.expected_delivery, po.notes, po.created_by,
+			po.created_at, po.updated_at,
+			COALESCE((SELECT SUM(l.quantity * l.unit_cost) FROM purchase_order_lines l
+			          WHERE l.purchase_order_id = po.id), 0)`
+
+func scanPurchaseOrder(row rowScanner) (*PurchaseOrder, error) {
+	var po PurchaseOrder
+	err := row.Scan(
+		&po.ID,
+		&po.PONumber,
+		&po.PurchaseRequestID,
+		&po.Vendor,
+		&po.Status,
+		&po.OrderDate,
+		&po.ExpectedDelivery,
+		&po.Notes,
+		&po.CreatedBy,
+		&po.CreatedAt,
+		&po.UpdatedAt,
+		&po.TotalCost,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+// GetOrder returns a purchase order with its lines
+func (m *PurchasingModel) GetOrder(id int64) (*PurchaseOrder, error) {
+	query := `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders po WHERE po.id = $1`
+
+	po, err := scanPurchaseOrder(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("purchase order not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT id, purchase_order_id, asset_type, manufacturer, model, model_number,
+		       description, quantity, unit_cost, quantity_received
+		FROM purchase_order_lines
+		WHERE purchase_order_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	po.Lines = []PurchaseOrderLine{}
+	for rows.Next() {
+		var line PurchaseOrderLine
+		err := rows.Scan(
+			&line.ID,
+			&line.PurchaseOrderID,
+			&line.AssetType,
+			&line.Manufacturer,
+			&line.Model,
+			&line.ModelNumber,
+			&line.Description,
+			&line.Quantity,
+			&line.UnitCost,
+			&line.QuantityReceived,
+		)
+		if err != nil {
+			return nil, err
+		}
+		po.Lines = append(po.Lines, line)
+	}
+
+	return po, rows.Err()
+}
+
+// GetOrders returns purchase orders without their lines, optionally filtered
+// by status and vendor
+func (m *PurchasingModel) GetOrders(status, vendor string) ([]PurchaseOrder, error) {
+	query := `
+		SELECT ` + purchaseOrderColumns + `
+		FROM purchase_orders po
+		WHERE ($1 = '' OR po.status = $1)
+		AND ($2 = '' OR po.vendor = $2)
+		ORDER BY po.order_date DESC, po.id DESC
+	`
+
+	rows, err := m.DB.Query(query, status, vendor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []PurchaseOrder{}
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *po)
+	}
+
+	return orders, rows.Err()
+}
+
+// UpdateOrder changes an open order's vendor details and expected delivery
+func (m *PurchasingModel) UpdateOrder(po *PurchaseOrder) error {
+	err := m.DB.QueryRow(`
+		UPDATE purchase_orders
+		SET vendor = $1, expected_delivery = $2, notes = $3, updated_at = NOW()
+		WHERE id = $4 AND status IN ('open', 'partially_received')
+		RETURNING updated_at
+	`, po.Vendor, po.ExpectedDelivery, po.Notes, po.ID).Scan(&po.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("purchase order not found or closed")
+	}
+
+	return err
+}
+
+// CancelOrder cancels an order nothing has been received against yet
+func (m *PurchasingModel) CancelOrder(id int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE purchase_orders SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("only open purchase orders with nothing received can be cancelled")
+	}
+
+	return nil
+}
+
+// Receive books in delivered units. Each unit becomes an IN_STORAGE asset
+// pre-filled from its PO line and linked back to it. The whole receipt is
+// one transaction so a bad serial or internal ID leaves nothing half-booked.
+func (m *PurchasingModel) Receive(poID int64, units []GoodsReceiptUnit, location string) ([]Asset, error) {
+	if len(units) == 0 {
+		return nil, errors.New("nothing to receive")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var orderDate time.Time
+	err = tx.QueryRow(`
+		SELECT status, order_date FROM purchase_orders WHERE id = $1 FOR UPDATE
+	`, poID).Scan(&status, &orderDate)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("purchase order not found")
+	} else if err != nil {
+		return nil, err
+	}
+	if status != "open" && status != "partially_received" {
+		return nil, errors.New("purchase order is not awaiting delivery")
+	}
+
+	assets := make([]Asset, 0, len(units))
+	for _, unit := range units {
+		if strings.TrimSpace(unit.InternalID) == "" {
+			return nil, errors.New("internal_id is required for every received unit")
+		}
+
+		var line PurchaseOrderLine
+		err := tx.QueryRow(`
+			UPDATE purchase_order_lines SET quantity_received = quantity_received + 1
+			WHERE id = $1 AND purchase_order_id = $2 AND quantity_received < quantity
+			RETURNING asset_type, manufacturer, model, model_number, unit_cost
+		`, unit.LineID, poID).Scan(&line.AssetType, &line.Manufacturer, &line.Model, &line.ModelNumber, &line.UnitCost)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("PO line %d is not on this order or is already fully received", unit.LineID)
+		} else if err != nil {
+			return nil, err
+		}
+
+		lineID := unit.LineID
+		cost := line.UnitCost
+		purchased := orderDate
+		asset := Asset{
+			InternalID:          unit.InternalID,
+			AssetType:           line.AssetType,
+			Manufacturer:        line.Manufacturer,
+			Model:               line.Model,
+			ModelNumber:         line.ModelNumber,
+			SerialNumber:        unit.SerialNumber,
+			Status:              "IN_STORAGE",
+			DatePurchased:       &purchased,
+			Location:            location,
+			CustomFields:        unit.CustomFields,
+			PurchaseCost:        &cost,
+			PurchaseOrderLineID: &lineID,
+		}
+
+		if err := createAssetTx(tx, &asset); err != nil {
+			var fieldErr *CustomFieldError
+			if errors.As(err, &fieldErr) {
+				return nil, &CustomFieldError{Message: fmt.Sprintf("asset %s: %s", unit.InternalID, fieldErr.Message)}
+			}
+			if strings.Contains(err.Error(), "duplicate key") {
+				return nil, fmt.Errorf("asset with internal ID %s already exists", unit.InternalID)
+			}
+			return nil, err
+		}
+
+		assets = append(assets, asset)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE purchase_orders
+		SET status = CASE WHEN NOT EXISTS (
+				SELECT 1 FROM purchase_order_lines
+				WHERE purchase_order_id = $1 AND quantity_received < quantity
+			) THEN 'received' ELSE 'partially_received' END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// GetReceivedAssets returns the assets received against a purchase order
+func (m *PurchasingModel) GetReceivedAssets(poID int64) ([]Asset, error) {
+	query := `
+		SELECT ` + assetColumns + `
+		FROM assets
+		WHERE purchase_order_line_id IN (
+			SELECT id FROM purchase_order_lines WHERE purchase_order_id = $1
+		)
+		ORDER BY id
+	`
+
+	rows, err := m.DB.Query(query, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []Asset{}
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *asset)
+	}
+
+	return assets, rows.Err()
+}
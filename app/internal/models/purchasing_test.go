@@ -0,0 +1,184 @@
+// file: app/internal/models/purchasing_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPurchasingTest(t *testing.T) (*PurchasingModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewPurchasingModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestPurchasingModel_InsertOrder(t *testing.T) {
+	model, mock, teardown := setupPurchasingTest(t)
+	defer teardown()
+
+	now := time.Now()
+	requestID := int64(4)
+
+	newOrder := func() *PurchaseOrder {
+		return &PurchaseOrder{
+			PurchaseRequestID: &requestID,
+			Vendor:            "Office Depot",
+			Lines: []PurchaseOrderLine{
+				{AssetType: "Monitor", Manufacturer: "Viewsonic", Model: "VX2776", Quantity: 10, UnitCost: 189.99},
+			},
+		}
+	}
+
+	t.Run("successful order from an approved request", func(t *testing.T) {
+		po := newOrder()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE purchase_requests SET status = 'ordered'`).
+			WithArgs(requestID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO purchase_orders`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "order_date", "created_at", "updated_at"}).
+				AddRow(7, "open", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), now, now))
+		mock.ExpectExec(`UPDATE purchase_orders SET po_number`).
+			WithArgs("PO-2025-0007", int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO purchase_order_lines`).
+			WithArgs(int64(7), "Monitor", "Viewsonic", "VX2776", "", "", 10, 189.99).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21))
+		mock.ExpectCommit()
+
+		err := model.InsertOrder(po)
+		assert.NoError(t, err)
+		assert.Equal(t, "PO-2025-0007", po.PONumber)
+		assert.Equal(t, int64(21), po.Lines[0].ID)
+		assert.InDelta(t, 1899.9, po.TotalCost, 0.001)
+	})
+
+	t.Run("request not approved", func(t *testing.T) {
+		po := newOrder()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE purchase_requests SET status = 'ordered'`).
+			WithArgs(requestID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := model.InsertOrder(po)
+		assert.Error(t, err)
+		assert.Equal(t, "purchase request not found or not approved", err.Error())
+	})
+
+	t.Run("no lines", func(t *testing.T) {
+		err := model.InsertOrder(&PurchaseOrder{Vendor: "Office Depot"})
+		assert.Error(t, err)
+		assert.Equal(t, "a purchase order needs at least one line", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchasingModel_Receive(t *testing.T) {
+	model, mock, teardown := setupPurchasingTest(t)
+	defer teardown()
+
+	now := time.Now()
+	orderDate := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("creates linked assets", func(t *testing.T) {
+		units := []GoodsReceiptUnit{{LineID: 21, InternalID: "AM-M010", SerialNumber: "VS123"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status, order_date FROM purchase_orders`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "order_date"}).AddRow("open", orderDate))
+		mock.ExpectQuery(`UPDATE purchase_order_lines SET quantity_received`).
+			WithArgs(int64(21), int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_type", "manufacturer", "model", "model_number", "unit_cost"}).
+				AddRow("Monitor", "Viewsonic", "VX2776", "VS2776", 189.99))
+		mock.ExpectQuery(`FROM asset_custom_fields`).
+			WithArgs("Monitor").
+			WillReturnRows(sqlmock.NewRows(customFieldSchemaColumns))
+		mock.ExpectQuery(`INSERT INTO assets`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(50, now, now))
+		mock.ExpectExec(`UPDATE purchase_orders`).
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assets, err := model.Receive(7, units, "Store Room")
+		assert.NoError(t, err)
+		require.Len(t, assets, 1)
+		assert.Equal(t, "IN_STORAGE", assets[0].Status)
+		assert.Equal(t, "Viewsonic", assets[0].Manufacturer)
+		assert.Equal(t, orderDate, *assets[0].DatePurchased)
+		assert.Equal(t, 189.99, *assets[0].PurchaseCost)
+		assert.Equal(t, int64(21), *assets[0].PurchaseOrderLineID)
+	})
+
+	t.Run("required custom field missing", func(t *testing.T) {
+		units := []GoodsReceiptUnit{{LineID: 22, InternalID: "AM-PC040"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status, order_date FROM purchase_orders`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "order_date"}).AddRow("open", orderDate))
+		mock.ExpectQuery(`UPDATE purchase_order_lines SET quantity_received`).
+			WithArgs(int64(22), int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_type", "manufacturer", "model", "model_number", "unit_cost"}).
+				AddRow("PC", "Dell", "OptiPlex 7010", "D13S", 899.0))
+		mock.ExpectQuery(`FROM asset_custom_fields`).
+			WithArgs("PC").
+			WillReturnRows(sqlmock.NewRows(customFieldSchemaColumns).
+				AddRow(1, "PC", "hostname", "Hostname", "text", true, true, []byte("[]"), 0, now, now))
+		mock.ExpectRollback()
+
+		_, err := model.Receive(7, units, "Store Room")
+		var fieldErr *CustomFieldError
+		assert.ErrorAs(t, err, &fieldErr)
+		assert.EqualError(t, err, "asset AM-PC040: hostname is required")
+	})
+
+	t.Run("line already fully received", func(t *testing.T) {
+		units := []GoodsReceiptUnit{{LineID: 21, InternalID: "AM-M011"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status, order_date FROM purchase_orders`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "order_date"}).AddRow("partially_received", orderDate))
+		mock.ExpectQuery(`UPDATE purchase_order_lines SET quantity_received`).
+			WithArgs(int64(21), int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_type", "manufacturer", "model", "model_number", "unit_cost"}))
+		mock.ExpectRollback()
+
+		_, err := model.Receive(7, units, "")
+		assert.Error(t, err)
+		assert.Equal(t, "PO line 21 is not on this order or is already fully received", err.Error())
+	})
+
+	t.Run("order cancelled", func(t *testing.T) {
+		units := []GoodsReceiptUnit{{LineID: 21, InternalID: "AM-M011"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status, order_date FROM purchase_orders`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "order_date"}).AddRow("cancelled", orderDate))
+		mock.ExpectRollback()
+
+		_, err := model.Receive(7, units, "")
+		assert.Error(t, err)
+		assert.Equal(t, "purchase order is not awaiting delivery", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
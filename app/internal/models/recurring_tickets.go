@@ -0,0 +1,457 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecurringTicket raises a ticket from its template on every occurrence of
+// its schedule
+type RecurringTicket struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"` // Cron expression or RRULE
+	Timezone string    `json:"timezone"` // IANA name the schedule is read in, e.g. America/Belize
+	StartsAt time.Time `json:"starts_at"`
+
+	// Template for the generated tickets
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Priority    string `json:"priority"`
+	AssignedTo  *int64 `json:"assigned_to"`
+	AssetID     *int64 `json:"asset_id"`
+	IsInternal  bool   `json:"is_internal"`
+
+	IsPaused  bool       `json:"is_paused"`
+	NextRunAt *time.Time `json:"next_run_at"` // nil while paused
+	LastRunAt *time.Time `json:"last_run_at"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// Joined fields
+	AssigneeName    string `json:"assignee_name,omitempty"`
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+}
+
+// RecurringTicketRun is one occurrence of a recurring ticket
+type RecurringTicketRun struct {
+	ID                int64     `json:"id"`
+	RecurringTicketID int64     `json:"recurring_ticket_id"`
+	Occurrence        time.Time `json:"occurrence"`
+	TicketID          *int64    `json:"ticket_id"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	// Joined fields
+	TicketNum    string `json:"ticket_num,omitempty"`
+	TicketStatus string `json:"ticket_status,omitempty"`
+}
+
+// RecurringTicketError is a definition the client got wrong, e.g. a bad schedule
+type RecurringTicketError struct {
+	Message string
+}
+
+func (e *RecurringTicketError) Error() string {
+	return e.Message
+}
+
+var recurringTicketPriorities = map[string]bool{"low": true, "normal": true, "high": true, "critical": true}
+
+// location returns the time zone the schedule is read in
+func (r *RecurringTicket) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, &RecurringTicketError{Message: fmt.Sprintf("unknown timezone %q", r.Timezone)}
+	}
+	return loc, nil
+}
+
+// NextOccurrence returns the first occurrence after after, not before
+// StartsAt, or the zero time if the schedule never runs again
+func (r *RecurringTicket) NextOccurrence(after time.Time) (time.Time, error) {
+	loc, err := r.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	schedule, err := ParseTicketSchedule(r.Schedule, r.StartsAt.In(loc))
+	if err != nil {
+		return time.Time{}, &RecurringTicketError{Message: "invalid schedule: " + err.Error()}
+	}
+
+	// An occurrence exactly at StartsAt counts
+	if bound := r.StartsAt.Add(-time.Nanosecond); after.Before(bound) {
+		after = bound
+	}
+	return schedule.Next(after.In(loc)), nil
+}
+
+// Upcoming lists the next n occurrences after from
+func (r *RecurringTicket) Upcoming(from time.Time, n int) ([]time.Time, error) {
+	occurrences := []time.Time{}
+	for len(occurrences) < n {
+		next, err := r.NextOccurrence(from)
+		if err != nil {
+			return nil, err
+		}
+		if next.IsZero() {
+			break
+		}
+		occurrences = append(occurrences, next)
+		from = next
+	}
+	return occurrences, nil
+}
+
+// validateRecurringTicket checks a definition before it is saved and works
+// out its next run from now
+func validateRecurringTicket(r *RecurringTicket, now time.Time) (time.Time, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Schedule = strings.TrimSpace(r.Schedule)
+	r.Title = strings.TrimSpace(r.Title)
+	r.Type = strings.TrimSpace(r.Type)
+	if r.Name == "" {
+		return time.Time{}, &RecurringTicketError{Message: "name is required"}
+	}
+	if r.Title == "" {
+		return time.Time{}, &RecurringTicketError{Message: "title is required"}
+	}
+	if r.Type == "" {
+		return time.Time{}, &RecurringTicketError{Message: "type is required"}
+	}
+	if r.Priority == "" {
+		r.Priority = "normal"
+	}
+	if !recurringTicketPriorities[r.Priority] {
+		return time.Time{}, &RecurringTicketError{Message: fmt.Sprintf("invalid priority %q", r.Priority)}
+	}
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if r.StartsAt.IsZero() {
+		r.StartsAt = now
+	}
+
+	next, err := r.NextOccurrence(now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if next.IsZero() {
+		return time.Time{}, &RecurringTicketError{Message: "schedule never runs"}
+	}
+	return next, nil
+}
+
+type RecurringTicketModel struct {
+	DB *sql.DB
+}
+
+func NewRecurringTicketModel(db *sql.DB) *RecurringTicketModel {
+	return &RecurringTicketModel{DB: db}
+}
+
+const recurringTicketColumns = `
+			r.id, r.name, r.schedule, r.timezone, r.starts_at,
+			r.title, r.description, r.type, r.priority, r.assigned_to, r.asset_id, r.is_internal,
+			r.is_paused, r.next_run_at, r.last_run_at, r.created_by, r.created_at, r.updated_at,
+			COALESCE(u.full_name, u.username, ''), COALESCE(a.internal_id, '')`
+
+const recurringTicketJoins = `
+		FROM recurring_tickets r
+		LEFT JOIN users u ON u.id = r.assigned_to
+		LEFT JOIN assets a ON a.id = r.asset_id`
+
+func scanRecurringTicket(row rowScanner) (*RecurringTicket, error) {
+	var r RecurringTicket
+	var assignedTo, assetID, createdBy sql.NullInt64
+	var nextRunAt, lastRunAt sql.NullTime
+	err := row.Scan(
+		&r.ID, &r.Name, &r.Schedule, &r.Timezone, &r.StartsAt,
+		&r.Title, &r.Description, &r.Type, &r.Priority, &assignedTo, &assetID, &r.IsInternal,
+		&r.IsPaused, &nextRunAt, &lastRunAt, &createdBy, &r.CreatedAt, &r.UpdatedAt,
+		&r.AssigneeName, &r.AssetInternalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if assignedTo.Valid {
+		r.AssignedTo = &assignedTo.Int64
+	}
+	if assetID.Valid {
+		r.AssetID = &assetID.Int64
+	}
+	if createdBy.Valid {
+		r.CreatedBy = &createdBy.Int64
+	}
+	if nextRunAt.Valid {
+		r.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		r.LastRunAt = &lastRunAt.Time
+	}
+	return &r, nil
+}
+
+// Insert saves a definition and schedules its first run; a definition saved
+// paused has no next run until it is resumed
+func (m *RecurringTicketModel) Insert(r *RecurringTicket) error {
+	next, err := validateRecurringTicket(r, time.Now())
+	if err != nil {
+		return err
+	}
+	r.NextRunAt = &next
+	if r.IsPaused {
+		r.NextRunAt = nil
+	}
+
+	return m.DB.QueryRow(`
+		INSERT INTO recurring_tickets (name, schedule, timezone, starts_at, title, description, type,
+			priority, assigned_to, asset_id, is_internal, is_paused, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at
+	`, r.Name, r.Schedule, r.Timezone, r.StartsAt, r.Title, r.Description, r.Type,
+		r.Priority, r.AssignedTo, r.AssetID, r.IsInternal, r.IsPaused, r.NextRunAt, r.CreatedBy,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// Update changes a definition and reschedules its next run from now. Pausing
+// and resuming go through Pause and Resume.
+func (m *RecurringTicketModel) Update(r *RecurringTicket) error {
+	next, err := validateRecurringTicket(r, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var nextRunAt sql.NullTime
+	err = m.DB.QueryRow(`
+		UPDATE recurring_tickets
+		SET name = $1, schedule = $2, timezone = $3, starts_at = $4, title = $5, description = $6,
+			type = $7, priority = $8, assigned_to = $9, asset_id = $10, is_internal = $11,
+			next_run_at = CASE WHEN is_paused THEN NULL ELSE $12::timestamptz END, updated_at = NOW()
+		WHERE id = $13
+		RETURNING is_paused, next_run_at, created_by, created_at, updated_at
+	`, r.Name, r.Schedule, r.Timezone, r.StartsAt, r.Title, r.Description,
+		r.Type, r.Priority, r.AssignedTo, r.AssetID, r.IsInternal, next, r.ID,
+	).Scan(&r.IsPaused, &nextRunAt, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("recurring ticket not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	r.NextRunAt = nil
+	if nextRunAt.Valid {
+		r.NextRunAt = &nextRunAt.Time
+	}
+	return nil
+}
+
+// Delete removes a definition; tickets it raised are kept
+func (m *RecurringTicketModel) Delete(id int64) error {
+	result, err := m.DB.Exec("DELETE FROM recurring_tickets WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("recurring ticket not found")
+	}
+	return nil
+}
+
+// GetByID returns a definition
+func (m *RecurringTicketModel) GetByID(id int64) (*RecurringTicket, error) {
+	row := m.DB.QueryRow(`
+		SELECT`+recurringTicketColumns+recurringTicketJoins+`
+		WHERE r.id = $1
+	`, id)
+
+	r, err := scanRecurringTicket(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("recurring ticket not found")
+	}
+	return r, err
+}
+
+// GetAll lists definitions, next to run first and paused ones last
+func (m *RecurringTicketModel) GetAll() ([]RecurringTicket, error) {
+	return m.query(`
+		SELECT` + recurringTicketColumns + recurringTicketJoins + `
+		ORDER BY r.is_paused, r.next_run_at, r.name
+	`)
+}
+
+// GetDue lists running definitions whose next run is at or before now
+func (m *RecurringTicketModel) GetDue(now time.Time) ([]RecurringTicket, error) {
+	return m.query(`
+		SELECT`+recurringTicketColumns+recurringTicketJoins+`
+		WHERE NOT r.is_paused AND r.next_run_at <= $1
+		ORDER BY r.next_run_at
+	`, now)
+}
+
+func (m *RecurringTicketModel) query(query string, args ...interface{}) ([]RecurringTicket, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := []RecurringTicket{}
+	for rows.Next() {
+		r, err := scanRecurringTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, *r)
+	}
+
+	return definitions, rows.Err()
+}
+
+// Pause stops a definition raising tickets
+func (m *RecurringTicketModel) Pause(id int64) (*RecurringTicket, error) {
+	result, err := m.DB.Exec(`
+		UPDATE recurring_tickets SET is_paused = true, next_run_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errors.New("recurring ticket not found")
+	}
+	return m.GetByID(id)
+}
+
+// Resume restarts a paused definition from its next occurrence after now.
+// Occurrences that fell while it was paused are skipped.
+func (m *RecurringTicketModel) Resume(id int64) (*RecurringTicket, error) {
+	r, err := m.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsPaused {
+		return r, nil
+	}
+
+	next, err := r.NextOccurrence(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if next.IsZero() {
+		return nil, &RecurringTicketError{Message: "schedule never runs"}
+	}
+
+	_, err = m.DB.Exec(`
+		UPDATE recurring_tickets SET is_paused = false, next_run_at = $1, updated_at = NOW()
+		WHERE id = $2 AND is_paused
+	`, next, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetByID(id)
+}
+
+// RaiseOccurrence creates the ticket for one occurrence and moves the
+// definition on to next, all in one transaction: an occurrence's run row only
+// ever exists alongside its committed ticket, so a crash part way leaves
+// nothing behind and the occurrence is simply raised again. raised is false
+// if the occurrence already had its ticket; advanced is false, and nothing is
+// saved, if the definition was edited, paused or moved on by another process
+// since it was read.
+func (m *RecurringTicketModel) RaiseOccurrence(id int64, occurrence time.Time, next *time.Time, ticket *Ticket) (raised, advanced bool, err error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, false, err
+	}
+	defer tx.Rollback()
+
+	// A second process raising the same occurrence waits here on the unique
+	// index, then finds the first one's row
+	result, err := tx.Exec(`
+		INSERT INTO recurring_ticket_runs (recurring_ticket_id, occurrence)
+		VALUES ($1, $2)
+		ON CONFLICT (recurring_ticket_id, occurrence) DO NOTHING
+	`, id, occurrence)
+	if err != nil {
+		return false, false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, false, err
+	}
+	raised = rows > 0
+
+	if raised {
+		if err := insertTicket(tx, ticket); err != nil {
+			return false, false, err
+		}
+		if _, err := tx.Exec(`
+			UPDATE recurring_ticket_runs SET ticket_id = $1
+			WHERE recurring_ticket_id = $2 AND occurrence = $3
+		`, ticket.ID, id, occurrence); err != nil {
+			return false, false, err
+		}
+	}
+
+	result, err = tx.Exec(`
+		UPDATE recurring_tickets SET next_run_at = $1, last_run_at = $2
+		WHERE id = $3 AND next_run_at = $2 AND NOT is_paused
+	`, next, occurrence, id)
+	if err != nil {
+		return false, false, err
+	}
+	if rows, err = result.RowsAffected(); err != nil {
+		return false, false, err
+	}
+	if rows == 0 {
+		return false, false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, err
+	}
+	return raised, true, nil
+}
+
+// GetRuns lists a definition's occurrences, most recent first
+func (m *RecurringTicketModel) GetRuns(id int64, limit int) ([]RecurringTicketRun, error) {
+	rows, err := m.DB.Query(`
+		SELECT rr.id, rr.recurring_ticket_id, rr.occurrence, rr.ticket_id, rr.created_at,
+			COALESCE(t.ticket_num, ''), COALESCE(t.status, '')
+		FROM recurring_ticket_runs rr
+		LEFT JOIN tickets t ON t.id = rr.ticket_id
+		WHERE rr.recurring_ticket_id = $1
+		ORDER BY rr.occurrence DESC
+		LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []RecurringTicketRun{}
+	for rows.Next() {
+		var run RecurringTicketRun
+		var ticketID sql.NullInt64
+		err := rows.Scan(&run.ID, &run.RecurringTicketID, &run.Occurrence, &ticketID, &run.CreatedAt,
+			&run.TicketNum, &run.TicketStatus)
+		if err != nil {
+			return nil, err
+		}
+		if ticketID.Valid {
+			run.TicketID = &ticketID.Int64
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
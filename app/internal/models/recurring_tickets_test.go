@@ -0,0 +1,258 @@
+// file: app/internal/models/recurring_tickets_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRecurringTicketTest(t *testing.T) (*RecurringTicketModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewRecurringTicketModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var recurringTicketTestColumns = []string{
+	"id", "name", "schedule", "timezone", "starts_at",
+	"title", "description", "type", "priority", "assigned_to", "asset_id", "is_internal",
+	"is_paused", "next_run_at", "last_run_at", "created_by", "created_at", "updated_at",
+	"assignee_name", "asset_internal_id",
+}
+
+func TestRecurringTicket_NextOccurrence(t *testing.T) {
+	t.Run("waits for starts_at", func(t *testing.T) {
+		r := &RecurringTicket{Schedule: "0 9 * * *", Timezone: "UTC", StartsAt: utc(2025, 7, 1, 0, 0)}
+		next, err := r.NextOccurrence(utc(2025, 6, 1, 0, 0))
+		require.NoError(t, err)
+		assert.True(t, next.Equal(utc(2025, 7, 1, 9, 0)), next)
+	})
+
+	t.Run("reads the schedule in its time zone", func(t *testing.T) {
+		r := &RecurringTicket{Schedule: "0 9 1 * *", Timezone: "America/Belize"}
+		next, err := r.NextOccurrence(utc(2025, 6, 15, 0, 0))
+		require.NoError(t, err)
+		assert.True(t, next.Equal(utc(2025, 7, 1, 15, 0)), next)
+	})
+
+	t.Run("invalid time zone", func(t *testing.T) {
+		r := &RecurringTicket{Schedule: "0 9 * * *", Timezone: "Mars/Olympus"}
+		_, err := r.NextOccurrence(utc(2025, 6, 15, 0, 0))
+		var recurringErr *RecurringTicketError
+		assert.ErrorAs(t, err, &recurringErr)
+	})
+
+	t.Run("upcoming", func(t *testing.T) {
+		r := &RecurringTicket{Schedule: "FREQ=WEEKLY;BYDAY=MO", Timezone: "UTC", StartsAt: utc(2025, 6, 2, 9, 0)}
+		upcoming, err := r.Upcoming(utc(2025, 6, 3, 0, 0), 3)
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{utc(2025, 6, 9, 9, 0), utc(2025, 6, 16, 9, 0), utc(2025, 6, 23, 9, 0)}, upcoming)
+	})
+}
+
+func TestRecurringTicketModel_Insert(t *testing.T) {
+	model, mock, teardown := setupRecurringTicketTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		assignee := int64(4)
+		r := &RecurringTicket{
+			Name: " Monthly UPS check ", Schedule: "0 9 1 * *", Timezone: "America/Belize",
+			Title: "UPS check", Type: "maintenance", AssignedTo: &assignee,
+		}
+		mock.ExpectQuery(`INSERT INTO recurring_tickets`).
+			WithArgs("Monthly UPS check", "0 9 1 * *", "America/Belize", sqlmock.AnyArg(), "UPS check", "", "maintenance",
+				"normal", &assignee, nil, false, false, sqlmock.AnyArg(), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, now, now))
+
+		err := model.Insert(r)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), r.ID)
+		require.NotNil(t, r.NextRunAt)
+		assert.True(t, r.NextRunAt.After(now))
+	})
+
+	t.Run("paused has no next run", func(t *testing.T) {
+		r := &RecurringTicket{Name: "Backups", Schedule: "@daily", Title: "Check backups", Type: "maintenance", IsPaused: true}
+		mock.ExpectQuery(`INSERT INTO recurring_tickets`).
+			WithArgs("Backups", "@daily", "UTC", sqlmock.AnyArg(), "Check backups", "", "maintenance",
+				"normal", nil, nil, false, true, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, now, now))
+
+		err := model.Insert(r)
+		assert.NoError(t, err)
+		assert.Nil(t, r.NextRunAt)
+	})
+
+	invalid := []struct {
+		name string
+		r    RecurringTicket
+	}{
+		{"missing name", RecurringTicket{Schedule: "@daily", Title: "T", Type: "maintenance"}},
+		{"missing title", RecurringTicket{Name: "N", Schedule: "@daily", Type: "maintenance"}},
+		{"bad priority", RecurringTicket{Name: "N", Schedule: "@daily", Title: "T", Type: "maintenance", Priority: "whenever"}},
+		{"bad schedule", RecurringTicket{Name: "N", Schedule: "every tuesday", Title: "T", Type: "maintenance"}},
+		{"bad time zone", RecurringTicket{Name: "N", Schedule: "@daily", Timezone: "Nowhere", Title: "T", Type: "maintenance"}},
+		{"never runs", RecurringTicket{Name: "N", Schedule: "0 0 30 2 *", Title: "T", Type: "maintenance"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.r
+			err := model.Insert(&r)
+			var recurringErr *RecurringTicketError
+			assert.ErrorAs(t, err, &recurringErr)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecurringTicketModel_Update(t *testing.T) {
+	model, mock, teardown := setupRecurringTicketTest(t)
+	defer teardown()
+
+	t.Run("not found", func(t *testing.T) {
+		r := &RecurringTicket{ID: 99, Name: "N", Schedule: "@daily", Title: "T", Type: "maintenance"}
+		mock.ExpectQuery(`UPDATE recurring_tickets`).
+			WillReturnError(sql.ErrNoRows)
+
+		err := model.Update(r)
+		assert.EqualError(t, err, "recurring ticket not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecurringTicketModel_Resume(t *testing.T) {
+	model, mock, teardown := setupRecurringTicketTest(t)
+	defer teardown()
+
+	now := time.Now()
+	row := func(paused bool, next interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows(recurringTicketTestColumns).AddRow(
+			1, "Backups", "@daily", "UTC", now.Add(-30*24*time.Hour),
+			"Check backups", "", "maintenance", "normal", nil, nil, false,
+			paused, next, nil, 1, now, now, "", "")
+	}
+
+	t.Run("schedules from now", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM recurring_tickets r`).WithArgs(int64(1)).WillReturnRows(row(true, nil))
+		mock.ExpectExec(`UPDATE recurring_tickets SET is_paused = false, next_run_at = \$1`).
+			WithArgs(sqlmock.AnyArg(), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT .* FROM recurring_tickets r`).WithArgs(int64(1)).WillReturnRows(row(false, now.Add(time.Hour)))
+
+		r, err := model.Resume(1)
+		assert.NoError(t, err)
+		assert.False(t, r.IsPaused)
+		require.NotNil(t, r.NextRunAt)
+	})
+
+	t.Run("already running", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM recurring_tickets r`).WithArgs(int64(1)).WillReturnRows(row(false, now.Add(time.Hour)))
+
+		r, err := model.Resume(1)
+		assert.NoError(t, err)
+		assert.False(t, r.IsPaused)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecurringTicketModel_RaiseOccurrence(t *testing.T) {
+	model, mock, teardown := setupRecurringTicketTest(t)
+	defer teardown()
+
+	now := time.Now()
+	occurrence := utc(2025, 7, 1, 15, 0)
+	next := utc(2025, 8, 1, 15, 0)
+	advance := `UPDATE recurring_tickets SET next_run_at = \$1, last_run_at = \$2\s+WHERE id = \$3 AND next_run_at = \$2 AND NOT is_paused`
+
+	t.Run("ticket, run and next run saved together", func(t *testing.T) {
+		ticket := &Ticket{Title: "UPS check (2025-07-01)", Type: "maintenance", Priority: "normal", Status: "open"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO recurring_ticket_runs`).
+			WithArgs(int64(1), occurrence).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT MAX`).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(41))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO tickets`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(90, now, now))
+		mock.ExpectExec(`UPDATE recurring_ticket_runs SET ticket_id = \$1`).
+			WithArgs(int64(90), int64(1), occurrence).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(advance).
+			WithArgs(&next, occurrence, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		raised, advanced, err := model.RaiseOccurrence(1, occurrence, &next, ticket)
+		require.NoError(t, err)
+		assert.True(t, raised)
+		assert.True(t, advanced)
+		assert.Equal(t, int64(90), ticket.ID)
+	})
+
+	t.Run("occurrence already raised", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO recurring_ticket_runs`).
+			WithArgs(int64(1), occurrence).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(advance).
+			WithArgs(&next, occurrence, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		raised, advanced, err := model.RaiseOccurrence(1, occurrence, &next, &Ticket{})
+		require.NoError(t, err)
+		assert.False(t, raised)
+		assert.True(t, advanced)
+	})
+
+	t.Run("definition changed meanwhile", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO recurring_ticket_runs`).
+			WithArgs(int64(1), occurrence).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(advance).
+			WithArgs(&next, occurrence, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		raised, advanced, err := model.RaiseOccurrence(1, occurrence, &next, &Ticket{})
+		require.NoError(t, err)
+		assert.False(t, raised)
+		assert.False(t, advanced)
+	})
+
+	t.Run("failed ticket leaves no run behind", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO recurring_ticket_runs`).
+			WithArgs(int64(1), occurrence).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT MAX`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, _, err := model.RaiseOccurrence(1, occurrence, &next, &Ticket{})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
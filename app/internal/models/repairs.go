@@ -0,0 +1,358 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AssetRepair is an external repair (RMA) of an asset. It is opened, shipped
+// to the vendor, and closed when the vendor sends it back with an outcome.
+type AssetRepair struct {
+	ID               int64      `json:"id"`
+	AssetID          int64      `json:"asset_id"`
+	Vendor           string     `json:"vendor"`
+	RMANumber        string     `json:"rma_number"`
+	FaultDescription string     `json:"fault_description"`
+	Status           string     `json:"status"` // open, shipped, closed, cancelled
+	PreviousUserID   *int64     `json:"previous_user_id"`
+	ShippedAt        *time.Time `json:"shipped_at"`
+	ExpectedReturn   *time.Time `json:"expected_return"`
+	ReturnedAt       *time.Time `json:"returned_at"`
+	RepairCost       *float64   `json:"repair_cost"`
+	Outcome          string     `json:"outcome"` // repaired, replaced, written_off
+	OldSerialNumber  string     `json:"old_serial_number"`
+	NewSerialNumber  string     `json:"new_serial_number"`
+	Notes            string     `json:"notes"`
+	CreatedBy        *int64     `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Joined fields
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+	AssetType       string `json:"asset_type,omitempty"`
+	SerialNumber    string `json:"serial_number,omitempty"` // Asset's current serial
+	DaysOut         *int   `json:"days_out,omitempty"`      // Days since shipping while still out
+}
+
+// RepairReturn is what comes back from the vendor
+type RepairReturn struct {
+	Outcome         string
+	ReturnedAt      time.Time
+	RepairCost      *float64
+	NewSerialNumber string
+	Notes           string
+	ReceivedBy      *int64
+}
+
+var repairOutcomes = map[string]bool{
+	"repaired": true, "replaced": true, "written_off": true,
+}
+
+// IsValidRepairOutcome reports whether outcome is a known repair outcome
+func IsValidRepairOutcome(outcome string) bool {
+	return repairOutcomes[outcome]
+}
+
+type RepairModel struct {
+	DB *sql.DB
+}
+
+func NewRepairModel(db *sql.DB) *RepairModel {
+	return &RepairModel{DB: db}
+}
+
+const repairColumns = `
+			r.id, r.asset_id, r.vendor, r.rma_number, r.fault_description, r.status,
+			r.previous_user_id, r.shipped_at, r.expected_return, r.returned_at,
+			r.repair_cost, r.outcome, r.old_serial_number, r.new_serial_number,
+			r.notes, r.created_by, r.created_at, r.updated_at,
+			a.internal_id, a.asset_type, COALESCE(a.serial_number, ''),
+			CASE WHEN r.status = 'shipped' THEN CURRENT_DATE - r.shipped_at END`
+
+const repairJoins = `
+		FROM asset_repairs r
+		JOIN assets a ON a.id = r.asset_id`
+
+func scanRepair(row rowScanner) (*AssetRepair, error) {
+	var r AssetRepair
+	err := row.Scan(
+		&r.ID,
+		&r.AssetID,
+		&r.Vendor,
+		&r.RMANumber,
+		&r.FaultDescription,
+		&r.Status,
+		&r.PreviousUserID,
+		&r.ShippedAt,
+		&r.ExpectedReturn,
+		&r.ReturnedAt,
+		&r.RepairCost,
+		&r.Outcome,
+		&r.OldSerialNumber,
+		&r.NewSerialNumber,
+		&r.Notes,
+		&r.CreatedBy,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+		&r.AssetInternalID,
+		&r.AssetType,
+		&r.SerialNumber,
+		&r.DaysOut,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (m *RepairModel) queryRepairs(query string, args ...interface{}) ([]AssetRepair, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	repairs := []AssetRepair{}
+	for rows.Next() {
+		r, err := scanRepair(rows)
+		if err != nil {
+			return nil, err
+		}
+		repairs = append(repairs, *r)
+	}
+
+	return repairs, rows.Err()
+}
+
+// Insert opens a repair for a live asset
+func (m *RepairModel) Insert(r *AssetRepair) error {
+	query := `
+		INSERT INTO asset_repairs (asset_id, vendor, rma_number, fault_description, expected_return, created_by)
+		SELECT id, $2, $3, $4, $5, $6 FROM assets WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, status, created_at, updated_at
+	`
+
+	err := m.DB.QueryRow(query, r.AssetID, r.Vendor, r.RMANumber, r.FaultDescription, r.ExpectedReturn, r.CreatedBy).
+		Scan(&r.ID, &r.Status, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("asset not found")
+	} else if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("asset already has an active repair")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get repair by ID
+func (m *RepairModel) GetByID(id int64) (*AssetRepair, error) {
+	query := `SELECT ` + repairColumns + repairJoins + ` WHERE r.id = $1`
+
+	r, err := scanRepair(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("repair not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetAll returns repairs, optionally filtered by status, newest first
+func (m *RepairModel) GetAll(status string) ([]AssetRepair, error) {
+	query := `SELECT ` + repairColumns + repairJoins + `
+		WHERE ($1 = '' OR r.status = $1)
+		ORDER BY r.created_at DESC`
+
+	return m.queryRepairs(query, status)
+}
+
+// GetByAsset returns an asset's repair history, newest first
+func (m *RepairModel) GetByAsset(assetID int64) ([]AssetRepair, error) {
+	query := `SELECT ` + repairColumns + repairJoins + `
+		WHERE r.asset_id = $1
+		ORDER BY r.created_at DESC`
+
+	return m.queryRepairs(query, assetID)
+}
+
+// GetOutstanding returns assets still with the vendor after more than days
+// days, or past their expected return date, longest out first
+func (m *RepairModel) GetOutstanding(days int) ([]AssetRepair, error) {
+	query := `SELECT ` + repairColumns + repairJoins + `
+		WHERE r.status = 'shipped'
+		AND (r.shipped_at <= CURRENT_DATE - $1::int OR r.expected_return < CURRENT_DATE)
+		ORDER BY r.shipped_at`
+
+	return m.queryRepairs(query, days)
+}
+
+// Ship records that the asset has gone to the vendor. The asset moves to
+// REPAIR, is taken off its user, its open custody record is closed and the
+// license seats and bookings it held are freed. An asset out on loan has to
+// come back first.
+func (m *RepairModel) Ship(id, userID int64, rmaNumber string, shippedAt time.Time, expectedReturn *time.Time) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	var previousUser *int64
+	err = tx.QueryRow(`
+		SELECT r.asset_id, a.in_use_by
+		FROM asset_repairs r
+		JOIN assets a ON a.id = r.asset_id
+		WHERE r.id = $1 AND r.status = 'open'
+		FOR UPDATE OF r, a
+	`, id).Scan(&assetID, &previousUser)
+	if err == sql.ErrNoRows {
+		return errors.New("repair not found or already shipped")
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_repairs
+		SET status = 'shipped', shipped_at = $1, expected_return = COALESCE($2, expected_return),
+		    rma_number = CASE WHEN $3 = '' THEN rma_number ELSE $3 END,
+		    previous_user_id = $4, updated_at = NOW()
+		WHERE id = $5
+	`, shippedAt, expectedReturn, rmaNumber, previousUser, id)
+	if err != nil {
+		return err
+	}
+
+	err = withdrawAssetTx(tx, assetID, "REPAIR", "asset sent for repair", CustodyCheckIn{
+		ReceivedBy: &userID,
+		Notes:      "Sent for external repair",
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Return closes a shipped repair with the vendor's outcome. A repaired or
+// replaced asset goes back into storage; a replacement keeps the same asset
+// record (and so its tickets, custody and service history) under the new
+// serial number. A written-off asset stays in REPAIR and is put forward for
+// disposal.
+func (m *RepairModel) Return(id int64, ret RepairReturn) error {
+	if !IsValidRepairOutcome(ret.Outcome) {
+		return errors.New("outcome must be one of: repaired, replaced, written_off")
+	}
+	if ret.Outcome == "replaced" && strings.TrimSpace(ret.NewSerialNumber) == "" {
+		return errors.New("new_serial_number is required for a replacement")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	var vendor, rmaNumber, oldSerial string
+	err = tx.QueryRow(`
+		SELECT r.asset_id, r.vendor, r.rma_number, COALESCE(a.serial_number, '')
+		FROM asset_repairs r
+		JOIN assets a ON a.id = r.asset_id
+		WHERE r.id = $1 AND r.status = 'shipped'
+		FOR UPDATE OF r, a
+	`, id).Scan(&assetID, &vendor, &rmaNumber, &oldSerial)
+	if err == sql.ErrNoRows {
+		return errors.New("repair not found or not shipped")
+	} else if err != nil {
+		return err
+	}
+
+	newSerial := ""
+	if ret.Outcome == "replaced" {
+		newSerial = ret.NewSerialNumber
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_repairs
+		SET status = 'closed', outcome = $1, returned_at = $2, repair_cost = $3,
+		    old_serial_number = $4, new_serial_number = $5,
+		    notes = CASE WHEN $6 = '' THEN notes ELSE $6 END, updated_at = NOW()
+		WHERE id = $7
+	`, ret.Outcome, ret.ReturnedAt, ret.RepairCost, oldSerial, newSerial, ret.Notes, id)
+	if err != nil {
+		return err
+	}
+
+	reference := vendor
+	if rmaNumber != "" {
+		reference += " RMA " + rmaNumber
+	}
+
+	var serviceNotes string
+	switch ret.Outcome {
+	case "repaired":
+		serviceNotes = fmt.Sprintf("Repaired by %s", reference)
+		_, err = tx.Exec(`
+			UPDATE assets SET status = 'IN_STORAGE', last_service_date = $1, updated_at = NOW()
+			WHERE id = $2
+		`, ret.ReturnedAt, assetID)
+	case "replaced":
+		serviceNotes = fmt.Sprintf("Replaced by %s: serial %s -> %s", reference, oldSerial, newSerial)
+		_, err = tx.Exec(`
+			UPDATE assets SET status = 'IN_STORAGE', serial_number = $1, last_service_date = $2, updated_at = NOW()
+			WHERE id = $3
+		`, newSerial, ret.ReturnedAt, assetID)
+	case "written_off":
+		serviceNotes = fmt.Sprintf("Written off by %s", reference)
+		_, err = tx.Exec(`
+			INSERT INTO asset_disposals (asset_id, reason, requested_by)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, assetID, serviceNotes, ret.ReceivedBy)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ret.Notes != "" {
+		serviceNotes += ". " + ret.Notes
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO asset_service (asset_id, performed_by, performed_at, service_type, notes)
+		VALUES ($1, $2, $3, 'REPAIR', $4)
+	`, assetID, ret.ReceivedBy, ret.ReturnedAt, serviceNotes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel drops a repair that was never shipped
+func (m *RepairModel) Cancel(id int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_repairs SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("repair not found or already shipped")
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// file: app/internal/models/repairs_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRepairTest(t *testing.T) (*RepairModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewRepairModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestRepairModel_Ship(t *testing.T) {
+	model, mock, teardown := setupRepairTest(t)
+	defer teardown()
+
+	shippedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("moves asset to repair, closes custody and frees seats", func(t *testing.T) {
+		previousUser := int64(8)
+		userID := int64(1)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, a.in_use_by`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "in_use_by"}).AddRow(12, previousUser))
+		mock.ExpectExec(`UPDATE asset_repairs`).
+			WithArgs(shippedAt, nil, "RMA-77", &previousUser, int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE assets SET status = \$1, in_use_by = NULL`).
+			WithArgs("REPAIR", int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("", "Sent for external repair", &userID, int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec(`UPDATE license_allocations SET released_at = NOW\(\), release_reason = \$1`).
+			WithArgs("asset sent for repair", int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`UPDATE asset_reservations SET status = 'cancelled'`).
+			WithArgs(int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Ship(3, 1, "RMA-77", shippedAt, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("asset on loan", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, a.in_use_by`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "in_use_by"}).AddRow(12, nil))
+		mock.ExpectExec(`UPDATE asset_repairs`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := model.Ship(3, 1, "", shippedAt, nil)
+		assert.EqualError(t, err, "asset is still on loan")
+	})
+
+	t.Run("already shipped", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, a.in_use_by`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "in_use_by"}))
+		mock.ExpectRollback()
+
+		err := model.Ship(3, 1, "", shippedAt, nil)
+		assert.Error(t, err)
+		assert.Equal(t, "repair not found or already shipped", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepairModel_Return(t *testing.T) {
+	model, mock, teardown := setupRepairTest(t)
+	defer teardown()
+
+	returnedAt := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	receivedBy := int64(1)
+
+	t.Run("replacement keeps history under new serial", func(t *testing.T) {
+		ret := RepairReturn{Outcome: "replaced", ReturnedAt: returnedAt, NewSerialNumber: "NEW456", ReceivedBy: &receivedBy}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, r.vendor, r.rma_number`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "vendor", "rma_number", "serial_number"}).
+				AddRow(12, "Dell", "RMA-77", "OLD123"))
+		mock.ExpectExec(`UPDATE asset_repairs`).
+			WithArgs("replaced", returnedAt, nil, "OLD123", "NEW456", "", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE assets SET status = 'IN_STORAGE', serial_number = \$1`).
+			WithArgs("NEW456", returnedAt, int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO asset_service`).
+			WithArgs(int64(12), &receivedBy, returnedAt, "Replaced by Dell RMA RMA-77: serial OLD123 -> NEW456").
+			WillReturnResult(sqlmock.NewResult(30, 1))
+		mock.ExpectCommit()
+
+		err := model.Return(3, ret)
+		assert.NoError(t, err)
+	})
+
+	t.Run("written off goes to disposal", func(t *testing.T) {
+		ret := RepairReturn{Outcome: "written_off", ReturnedAt: returnedAt, ReceivedBy: &receivedBy}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, r.vendor, r.rma_number`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "vendor", "rma_number", "serial_number"}).
+				AddRow(12, "Dell", "", "OLD123"))
+		mock.ExpectExec(`UPDATE asset_repairs`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO asset_disposals`).
+			WithArgs(int64(12), "Written off by Dell", &receivedBy).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectExec(`INSERT INTO asset_service`).
+			WillReturnResult(sqlmock.NewResult(31, 1))
+		mock.ExpectCommit()
+
+		err := model.Return(3, ret)
+		assert.NoError(t, err)
+	})
+
+	t.Run("replacement without new serial", func(t *testing.T) {
+		err := model.Return(3, RepairReturn{Outcome: "replaced", ReturnedAt: returnedAt})
+		assert.Error(t, err)
+		assert.Equal(t, "new_serial_number is required for a replacement", err.Error())
+	})
+
+	t.Run("not shipped", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT r.asset_id, r.vendor, r.rma_number`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "vendor", "rma_number", "serial_number"}))
+		mock.ExpectRollback()
+
+		err := model.Return(3, RepairReturn{Outcome: "repaired", ReturnedAt: returnedAt})
+		assert.Error(t, err)
+		assert.Equal(t, "repair not found or not shipped", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
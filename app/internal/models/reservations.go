@@ -0,0 +1,409 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// AssetReservation is a time-boxed booking of a loanable asset. Once the
+// borrower collects it the reservation becomes a loan with a due date.
+type AssetReservation struct {
+	ID           int64      `json:"id"`
+	AssetID      int64      `json:"asset_id"`
+	UserID       int64      `json:"user_id"` // Borrower
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	Status       string     `json:"status"` // reserved, checked_out, returned, cancelled
+	Purpose      string     `json:"purpose"`
+	ReservedBy   *int64     `json:"reserved_by"`
+	CheckedOutAt *time.Time `json:"checked_out_at"`
+	DueAt        *time.Time `json:"due_at"` // Expected return date once checked out
+	ReturnedAt   *time.Time `json:"returned_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Overdue      bool       `json:"overdue"` // Checked out past its due date
+
+	// Joined fields
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+	UserFullName    string `json:"user_full_name,omitempty"`
+}
+
+// ReservationFilter narrows GetAll
+type ReservationFilter struct {
+	AssetID *int64
+	UserID  *int64
+	Status  string
+}
+
+type ReservationModel struct {
+	DB *sql.DB
+}
+
+func NewReservationModel(db *sql.DB) *ReservationModel {
+	return &ReservationModel{DB: db}
+}
+
+// reservationBlocks matches reservations that hold an asset during the window
+// [$from, $to). A loan that is past due keeps blocking until it is returned.
+func reservationBlocks(fromArg, toArg int) string {
+	return `r.status IN ('reserved', 'checked_out')
+			AND r.starts_at < $` + strconv.Itoa(toArg) + `
+			AND (CASE WHEN r.status = 'checked_out' THEN GREATEST(r.ends_at, NOW()) ELSE r.ends_at END) > $` + strconv.Itoa(fromArg)
+}
+
+const reservationColumns = `
+			r.id, r.asset_id, r.user_id, r.starts_at, r.ends_at, r.status, r.purpose,
+			r.reserved_by, r.checked_out_at, r.due_at, r.returned_at, r.created_at,
+			(r.status = 'checked_out' AND r.due_at < NOW()) AS overdue,
+			a.internal_id, u.full_name`
+
+const reservationJoins = `
+		FROM asset_reservations r
+		JOIN assets a ON a.id = r.asset_id
+		JOIN users u ON u.id = r.user_id`
+
+func scanReservation(row rowScanner) (*AssetReservation, error) {
+	var res AssetReservation
+	err := row.Scan(
+		&res.ID,
+		&res.AssetID,
+		&res.UserID,
+		&res.StartsAt,
+		&res.EndsAt,
+		&res.Status,
+		&res.Purpose,
+		&res.ReservedBy,
+		&res.CheckedOutAt,
+		&res.DueAt,
+		&res.ReturnedAt,
+		&res.CreatedAt,
+		&res.Overdue,
+		&res.AssetInternalID,
+		&res.UserFullName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (m *ReservationModel) queryReservations(query string, args ...interface{}) ([]AssetReservation, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []AssetReservation
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, *res)
+	}
+
+	return reservations, rows.Err()
+}
+
+// checkLoanable locks the asset row and verifies it can be lent out
+func checkLoanable(tx *sql.Tx, assetID int64) error {
+	var loanable, onLoan bool
+	var status string
+	var inUseBy *int64
+	err := tx.QueryRow(`
+		SELECT a.loanable, a.status, a.in_use_by,
+			EXISTS(SELECT 1 FROM asset_reservations r WHERE r.asset_id = a.id AND r.status = 'checked_out')
+		FROM assets a
+		WHERE a.id = $1 AND a.deleted_at IS NULL
+		FOR UPDATE OF a
+	`, assetID).Scan(&loanable, &status, &inUseBy, &onLoan)
+	if err == sql.ErrNoRows {
+		return errors.New("asset not found")
+	} else if err != nil {
+		return err
+	}
+
+	if !loanable {
+		return errors.New("asset is not loanable")
+	}
+	// Permanently assigned, broken or retired assets can't be booked
+	if status == "RETIRED" || status == "REPAIR" || (inUseBy != nil && !onLoan) {
+		return errors.New("asset is not available for loan")
+	}
+
+	return nil
+}
+
+// checkConflict fails if another reservation holds the asset during [from, to)
+func checkConflict(tx *sql.Tx, assetID int64, from, to time.Time, excludeID int64) error {
+	var conflictID int64
+	err := tx.QueryRow(`
+		SELECT r.id
+		FROM asset_reservations r
+		WHERE r.asset_id = $1 AND r.id != $4 AND `+reservationBlocks(2, 3)+`
+		LIMIT 1
+	`, assetID, from, to, excludeID).Scan(&conflictID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return errors.New("reservation conflicts with an existing reservation")
+}
+
+// Insert books a loanable asset for a time window, rejecting overlaps
+func (m *ReservationModel) Insert(res *AssetReservation) error {
+	if !res.EndsAt.After(res.StartsAt) {
+		return errors.New("reservation must end after it starts")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The asset row lock serialises concurrent requests for the same asset
+	if err := checkLoanable(tx, res.AssetID); err != nil {
+		return err
+	}
+	if err := checkConflict(tx, res.AssetID, res.StartsAt, res.EndsAt, 0); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO asset_reservations (asset_id, user_id, starts_at, ends_at, purpose, reserved_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`, res.AssetID, res.UserID, res.StartsAt, res.EndsAt, res.Purpose, res.ReservedBy).Scan(
+		&res.ID, &res.Status, &res.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get reservation by ID
+func (m *ReservationModel) GetByID(id int64) (*AssetReservation, error) {
+	query := `SELECT ` + reservationColumns + reservationJoins + ` WHERE r.id = $1`
+
+	res, err := scanReservation(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("reservation not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetAll returns reservations matching the filter, soonest first
+func (m *ReservationModel) GetAll(filter ReservationFilter) ([]AssetReservation, error) {
+	query := `SELECT ` + reservationColumns + reservationJoins + ` WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.AssetID != nil {
+		query += ` AND r.asset_id = $` + strconv.Itoa(argPos)
+		args = append(args, *filter.AssetID)
+		argPos++
+	}
+	if filter.UserID != nil {
+		query += ` AND r.user_id = $` + strconv.Itoa(argPos)
+		args = append(args, *filter.UserID)
+		argPos++
+	}
+	if filter.Status != "" {
+		query += ` AND r.status = $` + strconv.Itoa(argPos)
+		args = append(args, filter.Status)
+		argPos++
+	}
+
+	query += ` ORDER BY r.starts_at`
+
+	return m.queryReservations(query, args...)
+}
+
+// Cancel a reservation that has not been collected yet
+func (m *ReservationModel) Cancel(id int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_reservations SET status = 'cancelled'
+		WHERE id = $1 AND status = 'reserved'
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("reservation not found or not reserved")
+	}
+
+	return nil
+}
+
+// CheckOut hands a reserved asset to the borrower until dueAt and opens the
+// pending handover they have to acknowledge, like an assignment, in one
+// transaction
+func (m *ReservationModel) CheckOut(id int64, dueAt time.Time, assignedBy *int64) (*AssetCustody, error) {
+	if !dueAt.After(time.Now()) {
+		return nil, errors.New("due date must be in the future")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var assetID, userID int64
+	err = tx.QueryRow(`
+		SELECT asset_id, user_id FROM asset_reservations
+		WHERE id = $1 AND status = 'reserved'
+		FOR UPDATE
+	`, id).Scan(&assetID, &userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("reservation not found or not reserved")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := checkLoanable(tx, assetID); err != nil {
+		return nil, err
+	}
+
+	var onLoan bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM asset_reservations WHERE asset_id = $1 AND status = 'checked_out')
+	`, assetID).Scan(&onLoan)
+	if err != nil {
+		return nil, err
+	}
+	if onLoan {
+		return nil, errors.New("asset is still on loan")
+	}
+
+	// Collecting early or extending the due date must not collide with other bookings
+	now := time.Now()
+	if err := checkConflict(tx, assetID, now, dueAt, id); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_reservations
+		SET status = 'checked_out', checked_out_at = NOW(),
+		    starts_at = LEAST(starts_at, NOW()), ends_at = $1, due_at = $1
+		WHERE id = $2
+	`, dueAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE assets SET in_use_by = $1, status = 'IN_USE', updated_at = NOW()
+		WHERE id = $2
+	`, userID, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	custody := &AssetCustody{
+		AssetID:       assetID,
+		UserID:        userID,
+		AssignedBy:    assignedBy,
+		AcknowledgeBy: now.Add(DefaultAcknowledgeWindow),
+	}
+	if err := openCustodyTx(tx, custody); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return custody, nil
+}
+
+// Return closes a loan, puts the asset back into storage and closes its
+// handover in one transaction. It returns the closed handover's ID, or 0 if
+// the loan had none.
+func (m *ReservationModel) Return(id int64, checkIn CustodyCheckIn) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var assetID int64
+	err = tx.QueryRow(`
+		UPDATE asset_reservations SET status = 'returned', returned_at = NOW()
+		WHERE id = $1 AND status = 'checked_out'
+		RETURNING asset_id
+	`, id).Scan(&assetID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("reservation not found or not checked out")
+	} else if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE assets SET in_use_by = NULL, status = 'IN_STORAGE', updated_at = NOW()
+		WHERE id = $1
+	`, assetID)
+	if err != nil {
+		return 0, err
+	}
+
+	custodyID, err := checkInCustody(tx, assetID, checkIn)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return custodyID, nil
+}
+
+// GetOverdue returns loans past their due date
+func (m *ReservationModel) GetOverdue() ([]AssetReservation, error) {
+	query := `SELECT ` + reservationColumns + reservationJoins + `
+		WHERE r.status = 'checked_out' AND r.due_at < NOW()
+		ORDER BY r.due_at`
+
+	return m.queryReservations(query)
+}
+
+// GetOverdueToNotify returns overdue loans not reminded about in the last day
+func (m *ReservationModel) GetOverdueToNotify() ([]AssetReservation, error) {
+	query := `SELECT ` + reservationColumns + reservationJoins + `
+		WHERE r.status = 'checked_out' AND r.due_at < NOW()
+		AND (r.overdue_notified_at IS NULL OR r.overdue_notified_at < NOW() - INTERVAL '1 day')
+		ORDER BY r.due_at`
+
+	return m.queryReservations(query)
+}
+
+// MarkOverdueNotified records that reminders went out for these loans
+func (m *ReservationModel) MarkOverdueNotified(ids []int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE asset_reservations SET overdue_notified_at = NOW() WHERE id = $1`, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,238 @@
+// file: app/internal/models/reservations_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReservationTest(t *testing.T) (*ReservationModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewReservationModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestReservationModel_Insert(t *testing.T) {
+	model, mock, teardown := setupReservationTest(t)
+	defer teardown()
+
+	now := time.Now()
+	reservedBy := int64(7)
+
+	newReservation := func() *AssetReservation {
+		return &AssetReservation{
+			AssetID:    3,
+			UserID:     7,
+			StartsAt:   now.Add(24 * time.Hour),
+			EndsAt:     now.Add(72 * time.Hour),
+			Purpose:    "Spare headset while mine is repaired",
+			ReservedBy: &reservedBy,
+		}
+	}
+
+	loanableRow := func(loanable bool, status string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"loanable", "status", "in_use_by", "exists"}).
+			AddRow(loanable, status, nil, false)
+	}
+
+	t.Run("successful reservation", func(t *testing.T) {
+		res := newReservation()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT a.loanable`).
+			WithArgs(int64(3)).
+			WillReturnRows(loanableRow(true, "IN_STORAGE"))
+		mock.ExpectQuery(`SELECT r.id\s+FROM asset_reservations`).
+			WithArgs(int64(3), res.StartsAt, res.EndsAt, int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`INSERT INTO asset_reservations`).
+			WithArgs(int64(3), int64(7), res.StartsAt, res.EndsAt, res.Purpose, &reservedBy).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).
+				AddRow(1, "reserved", now))
+		mock.ExpectCommit()
+
+		err := model.Insert(res)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		assert.Equal(t, "reserved", res.Status)
+	})
+
+	t.Run("overlapping reservation", func(t *testing.T) {
+		res := newReservation()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT a.loanable`).
+			WithArgs(int64(3)).
+			WillReturnRows(loanableRow(true, "IN_STORAGE"))
+		mock.ExpectQuery(`SELECT r.id\s+FROM asset_reservations`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectRollback()
+
+		err := model.Insert(res)
+		assert.Error(t, err)
+		assert.Equal(t, "reservation conflicts with an existing reservation", err.Error())
+	})
+
+	t.Run("asset not loanable", func(t *testing.T) {
+		res := newReservation()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT a.loanable`).
+			WithArgs(int64(3)).
+			WillReturnRows(loanableRow(false, "IN_STORAGE"))
+		mock.ExpectRollback()
+
+		err := model.Insert(res)
+		assert.Error(t, err)
+		assert.Equal(t, "asset is not loanable", err.Error())
+	})
+
+	t.Run("ends before it starts", func(t *testing.T) {
+		res := newReservation()
+		res.EndsAt = res.StartsAt.Add(-time.Hour)
+
+		err := model.Insert(res)
+		assert.Error(t, err)
+		assert.Equal(t, "reservation must end after it starts", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReservationModel_CheckOut(t *testing.T) {
+	model, mock, teardown := setupReservationTest(t)
+	defer teardown()
+
+	dueAt := time.Now().Add(48 * time.Hour)
+	assignedBy := int64(2)
+
+	t.Run("opens the handover with the loan", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT asset_id, user_id FROM asset_reservations`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "user_id"}).AddRow(3, 7))
+		mock.ExpectQuery(`SELECT a.loanable`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"loanable", "status", "in_use_by", "exists"}).
+				AddRow(true, "IN_STORAGE", nil, false))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`SELECT r.id\s+FROM asset_reservations`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`SET status = 'checked_out'`).
+			WithArgs(dueAt, int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE assets SET in_use_by = \$1, status = 'IN_USE'`).
+			WithArgs(int64(7), int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs(int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO asset_custody`).
+			WithArgs(int64(3), int64(7), &assignedBy, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "assigned_at"}).AddRow(9, "pending", time.Now()))
+		mock.ExpectCommit()
+
+		custody, err := model.CheckOut(4, dueAt, &assignedBy)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(9), custody.ID)
+		assert.Equal(t, int64(7), custody.UserID)
+	})
+
+	t.Run("handover fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT asset_id, user_id FROM asset_reservations`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id", "user_id"}).AddRow(3, 7))
+		mock.ExpectQuery(`SELECT a.loanable`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"loanable", "status", "in_use_by", "exists"}).
+				AddRow(true, "IN_STORAGE", nil, false))
+		mock.ExpectQuery(`status = 'checked_out'`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`SELECT r.id\s+FROM asset_reservations`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`SET status = 'checked_out'`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE assets SET in_use_by = \$1, status = 'IN_USE'`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		custody, err := model.CheckOut(4, dueAt, &assignedBy)
+		assert.Error(t, err)
+		assert.Nil(t, custody)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReservationModel_Return(t *testing.T) {
+	model, mock, teardown := setupReservationTest(t)
+	defer teardown()
+
+	t.Run("not checked out", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_reservations`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}))
+		mock.ExpectRollback()
+
+		_, err := model.Return(4, CustodyCheckIn{})
+		assert.Error(t, err)
+		assert.Equal(t, "reservation not found or not checked out", err.Error())
+	})
+
+	t.Run("closes the handover with the loan", func(t *testing.T) {
+		receivedBy := int64(2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_reservations`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}).AddRow(3))
+		mock.ExpectExec(`UPDATE assets SET in_use_by = NULL`).
+			WithArgs(int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("good", "", &receivedBy, int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		mock.ExpectCommit()
+
+		custodyID, err := model.Return(4, CustodyCheckIn{ReceivedBy: &receivedBy, Condition: "good"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), custodyID)
+	})
+
+	t.Run("handover fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE asset_reservations`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"asset_id"}).AddRow(3))
+		mock.ExpectExec(`UPDATE assets SET in_use_by = NULL`).
+			WithArgs(int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err := model.Return(4, CustodyCheckIn{})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
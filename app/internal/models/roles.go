@@ -89,3 +89,15 @@ func (m *RolesModel) GetByID(id int64) (*Role, error) {
 	}
 	return &r, nil
 }
+
+// HasPermission reports whether a role grants a permission
+func (m *RolesModel) HasPermission(roleID int64, permission string) (bool, error) {
+	var has bool
+	err := m.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM role_permissions rp
+			JOIN permissions p ON rp.permission_id = p.id
+			WHERE rp.role_id = $1 AND p.name = $2
+		)`, roleID, permission).Scan(&has)
+	return has, err
+}
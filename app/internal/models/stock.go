@@ -0,0 +1,465 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// StockItem is a quantity-tracked consumable or spare part. Unlike assets these
+// are not individually tagged; only the on-hand quantity per location is kept.
+type StockItem struct {
+	ID               int64     `json:"id"`
+	SKU              string    `json:"sku"`
+	Name             string    `json:"name"`
+	Category         string    `json:"category"`
+	Unit             string    `json:"unit"`              // each, box, pack, metre
+	ReorderThreshold int       `json:"reorder_threshold"` // Low stock at or below this total
+	ReorderQuantity  int       `json:"reorder_quantity"`  // Suggested order size
+	Notes            string    `json:"notes"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Computed fields
+	TotalQuantity int          `json:"total_quantity"`
+	LowStock      bool         `json:"low_stock"`
+	Levels        []StockLevel `json:"levels,omitempty"`
+}
+
+// StockLevel is the on-hand quantity of an item at one location
+type StockLevel struct {
+	Location  string    `json:"location"`
+	Quantity  int       `json:"quantity"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StockMovement records a stock-in or stock-out
+type StockMovement struct {
+	ID           int64     `json:"id"`
+	ItemID       int64     `json:"item_id"`
+	Location     string    `json:"location"`
+	MovementType string    `json:"movement_type"` // in, out
+	Quantity     int       `json:"quantity"`
+	UnitCost     *float64  `json:"unit_cost"`
+	Reference    string    `json:"reference"` // Invoice or order number
+	TicketID     *int64    `json:"ticket_id"`
+	ServiceLogID *int64    `json:"service_log_id"`
+	PerformedBy  *int64    `json:"performed_by"`
+	Notes        string    `json:"notes"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Joined fields
+	ItemName string `json:"item_name,omitempty"`
+}
+
+type StockModel struct {
+	DB *sql.DB
+}
+
+func NewStockModel(db *sql.DB) *StockModel {
+	return &StockModel{DB: db}
+}
+
+const stockItemColumns = `
+			i.id, i.sku, i.name, i.category, i.unit, i.reorder_threshold,
+			i.reorder_quantity, i.notes, i.created_at, i.updated_at,
+			COALESCE((SELECT SUM(s.quantity) FROM stock_levels s WHERE s.item_id = i.id), 0) AS total_quantity`
+
+func scanStockItem(row rowScanner) (*StockItem, error) {
+	var item StockItem
+	err := row.Scan(
+		&item.ID,
+		&item.SKU,
+		&item.Name,
+		&item.Category,
+		&item.Unit,
+		&item.ReorderThreshold,
+		&item.ReorderQuantity,
+		&item.Notes,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.TotalQuantity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	item.LowStock = item.TotalQuantity <= item.ReorderThreshold
+	return &item, nil
+}
+
+// InsertItem adds a new stock item
+func (m *StockModel) InsertItem(item *StockItem) error {
+	query := `
+		INSERT INTO stock_items (sku, name, category, unit, reorder_threshold, reorder_quantity, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := m.DB.QueryRow(
+		query,
+		item.SKU,
+		item.Name,
+		item.Category,
+		item.Unit,
+		item.ReorderThreshold,
+		item.ReorderQuantity,
+		item.Notes,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("SKU already exists")
+		}
+		return err
+	}
+
+	item.LowStock = item.TotalQuantity <= item.ReorderThreshold
+	return nil
+}
+
+// GetItem returns a stock item with its per-location levels
+func (m *StockModel) GetItem(id int64) (*StockItem, error) {
+	query := `SELECT ` + stockItemColumns + ` FROM stock_items i WHERE i.id = $1`
+
+	item, err := scanStockItem(m.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("stock item not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT location, quantity, updated_at
+		FROM stock_levels
+		WHERE item_id = $1
+		ORDER BY location
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	item.Levels = []StockLevel{}
+	for rows.Next() {
+		var level StockLevel
+		if err := rows.Scan(&level.Location, &level.Quantity, &level.UpdatedAt); err != nil {
+			return nil, err
+		}
+		item.Levels = append(item.Levels, level)
+	}
+
+	return item, rows.Err()
+}
+
+// GetItems returns stock items, optionally filtered by category or to those
+// at or below their reorder threshold
+func (m *StockModel) GetItems(category string, lowOnly bool) ([]StockItem, error) {
+	query := `
+		SELECT * FROM (
+			SELECT ` + stockItemColumns + ` FROM stock_items i
+			WHERE ($1 = '' OR i.category = $1)
+		) items
+		WHERE (NOT $2 OR total_quantity <= reorder_threshold)
+		ORDER BY category, name
+	`
+
+	rows, err := m.DB.Query(query, category, lowOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []StockItem{}
+	for rows.Next() {
+		item, err := scanStockItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	return items, rows.Err()
+}
+
+// UpdateItem updates a stock item's details. Quantities only change through
+// stock movements.
+func (m *StockModel) UpdateItem(item *StockItem) error {
+	query := `
+		UPDATE stock_items
+		SET sku = $1, name = $2, category = $3, unit = $4, reorder_threshold = $5,
+		    reorder_quantity = $6, notes = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING updated_at
+	`
+
+	err := m.DB.QueryRow(
+		query,
+		item.SKU,
+		item.Name,
+		item.Category,
+		item.Unit,
+		item.ReorderThreshold,
+		item.ReorderQuantity,
+		item.Notes,
+		item.ID,
+	).Scan(&item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("stock item not found")
+	} else if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return errors.New("SKU already exists")
+		}
+		return err
+	}
+
+	item.LowStock = item.TotalQuantity <= item.ReorderThreshold
+	return nil
+}
+
+// DeleteItem removes a stock item and its levels. Items with movements are
+// refused, since the cascade would take the consumption and parts cost history
+// with them. The row lock keeps a concurrent stock-in or stock-out from slipping in.
+func (m *StockModel) DeleteItem(id int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var itemID int64
+	err = tx.QueryRow(`SELECT id FROM stock_items WHERE id = $1 FOR UPDATE`, id).Scan(&itemID)
+	if err == sql.ErrNoRows {
+		return errors.New("stock item not found")
+	} else if err != nil {
+		return err
+	}
+
+	var hasMovements bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM stock_movements WHERE item_id = $1)`, id).Scan(&hasMovements)
+	if err != nil {
+		return err
+	}
+	if hasMovements {
+		return errors.New("stock item has movement history")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM stock_items WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// StockIn adds received stock at a location. Once the total is back above the
+// reorder threshold the item can raise a low-stock notification again.
+func (m *StockModel) StockIn(mv *StockMovement) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := stockInTx(tx, mv); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// stockInTx does the work of StockIn inside an existing transaction
+func stockInTx(tx *sql.Tx, mv *StockMovement) error {
+	if mv.Quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+
+	var threshold int
+	err := tx.QueryRow(`SELECT reorder_threshold FROM stock_items WHERE id = $1 FOR UPDATE`, mv.ItemID).Scan(&threshold)
+	if err == sql.ErrNoRows {
+		return errors.New("stock item not found")
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_levels (item_id, location, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (item_id, location)
+		DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, mv.ItemID, mv.Location, mv.Quantity)
+	if err != nil {
+		return err
+	}
+
+	mv.MovementType = "in"
+	if err := insertStockMovement(tx, mv); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE stock_items SET low_stock_notified = false
+		WHERE id = $1 AND low_stock_notified
+		AND (SELECT COALESCE(SUM(quantity), 0) FROM stock_levels WHERE item_id = $1) > reorder_threshold
+	`, mv.ItemID)
+	return err
+}
+
+// StockOut takes stock from a location for a ticket or service log. It reports
+// whether the item has just dropped to its reorder threshold, so the caller
+// sends one low-stock notification per dip rather than one per stock-out.
+func (m *StockModel) StockOut(mv *StockMovement) (bool, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	lowStock, err := stockOutTx(tx, mv)
+	if err != nil {
+		return false, err
+	}
+
+	return lowStock, tx.Commit()
+}
+
+// stockOutTx does the work of StockOut inside an existing transaction
+func stockOutTx(tx *sql.Tx, mv *StockMovement) (bool, error) {
+	if mv.Quantity <= 0 {
+		return false, errors.New("quantity must be positive")
+	}
+	if mv.TicketID == nil && mv.ServiceLogID == nil {
+		return false, errors.New("stock-out must be linked to a ticket or service log")
+	}
+
+	var threshold int
+	var notified bool
+	err := tx.QueryRow(`
+		SELECT reorder_threshold, low_stock_notified FROM stock_items WHERE id = $1 FOR UPDATE
+	`, mv.ItemID).Scan(&threshold, &notified)
+	if err == sql.ErrNoRows {
+		return false, errors.New("stock item not found")
+	} else if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE stock_levels SET quantity = quantity - $3, updated_at = NOW()
+		WHERE item_id = $1 AND location = $2 AND quantity >= $3
+	`, mv.ItemID, mv.Location, mv.Quantity)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, errors.New("insufficient stock at this location")
+	}
+
+	mv.MovementType = "out"
+	if err := insertStockMovement(tx, mv); err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			return false, errors.New("ticket or service log not found")
+		}
+		return false, err
+	}
+
+	var total int
+	err = tx.QueryRow(`SELECT COALESCE(SUM(quantity), 0) FROM stock_levels WHERE item_id = $1`, mv.ItemID).Scan(&total)
+	if err != nil {
+		return false, err
+	}
+
+	if total > threshold || notified {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`UPDATE stock_items SET low_stock_notified = true WHERE id = $1`, mv.ItemID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func insertStockMovement(tx *sql.Tx, mv *StockMovement) error {
+	query := `
+		INSERT INTO stock_movements (
+			item_id, location, movement_type, quantity, unit_cost, reference,
+			ticket_id, service_log_id, performed_by, notes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+
+	return tx.QueryRow(
+		query,
+		mv.ItemID,
+		mv.Location,
+		mv.MovementType,
+		mv.Quantity,
+		mv.UnitCost,
+		mv.Reference,
+		mv.TicketID,
+		mv.ServiceLogID,
+		mv.PerformedBy,
+		mv.Notes,
+	).Scan(&mv.ID, &mv.CreatedAt)
+}
+
+// averageStockCostTx returns an item's average purchase cost over all
+// stock-ins that recorded one, or nil if none did
+func averageStockCostTx(tx *sql.Tx, itemID int64) (*float64, error) {
+	var cost sql.NullFloat64
+	err := tx.QueryRow(`
+		SELECT ROUND(SUM(quantity * unit_cost) / NULLIF(SUM(quantity), 0), 2)
+		FROM stock_movements
+		WHERE item_id = $1 AND movement_type = 'in' AND unit_cost IS NOT NULL
+	`, itemID).Scan(&cost)
+	if err != nil || !cost.Valid {
+		return nil, err
+	}
+	return &cost.Float64, nil
+}
+
+// GetMovements returns an item's stock movements, newest first
+func (m *StockModel) GetMovements(itemID int64) ([]StockMovement, error) {
+	query := `
+		SELECT mv.id, mv.item_id, mv.location, mv.movement_type, mv.quantity,
+		       mv.unit_cost, mv.reference, mv.ticket_id, mv.service_log_id,
+		       mv.performed_by, mv.notes, mv.created_at, i.name
+		FROM stock_movements mv
+		JOIN stock_items i ON i.id = mv.item_id
+		WHERE mv.item_id = $1
+		ORDER BY mv.created_at DESC
+	`
+
+	rows, err := m.DB.Query(query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []StockMovement{}
+	for rows.Next() {
+		var mv StockMovement
+		err := rows.Scan(
+			&mv.ID,
+			&mv.ItemID,
+			&mv.Location,
+			&mv.MovementType,
+			&mv.Quantity,
+			&mv.UnitCost,
+			&mv.Reference,
+			&mv.TicketID,
+			&mv.ServiceLogID,
+			&mv.PerformedBy,
+			&mv.Notes,
+			&mv.CreatedAt,
+			&mv.ItemName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, mv)
+	}
+
+	return movements, rows.Err()
+}
@@ -0,0 +1,209 @@
+// file: app/internal/models/stock_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStockTest(t *testing.T) (*StockModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewStockModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestStockModel_StockOut(t *testing.T) {
+	model, mock, teardown := setupStockTest(t)
+	defer teardown()
+
+	now := time.Now()
+	ticketID := int64(42)
+
+	newMovement := func() *StockMovement {
+		return &StockMovement{ItemID: 5, Location: "Store Room", Quantity: 2, TicketID: &ticketID}
+	}
+
+	t.Run("drops to reorder threshold", func(t *testing.T) {
+		mv := newMovement()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT reorder_threshold, low_stock_notified FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold", "low_stock_notified"}).AddRow(3, false))
+		mock.ExpectExec(`UPDATE stock_levels`).
+			WithArgs(int64(5), "Store Room", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO stock_movements`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(11, now))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(quantity\), 0\) FROM stock_levels`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(3))
+		mock.ExpectExec(`UPDATE stock_items SET low_stock_notified = true`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		lowStock, err := model.StockOut(mv)
+		assert.NoError(t, err)
+		assert.True(t, lowStock)
+		assert.Equal(t, "out", mv.MovementType)
+		assert.Equal(t, int64(11), mv.ID)
+	})
+
+	t.Run("already notified", func(t *testing.T) {
+		mv := newMovement()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT reorder_threshold, low_stock_notified FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold", "low_stock_notified"}).AddRow(3, true))
+		mock.ExpectExec(`UPDATE stock_levels`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO stock_movements`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(12, now))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(quantity\), 0\) FROM stock_levels`).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+		mock.ExpectCommit()
+
+		lowStock, err := model.StockOut(mv)
+		assert.NoError(t, err)
+		assert.False(t, lowStock)
+	})
+
+	t.Run("insufficient stock", func(t *testing.T) {
+		mv := newMovement()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT reorder_threshold, low_stock_notified FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold", "low_stock_notified"}).AddRow(3, false))
+		mock.ExpectExec(`UPDATE stock_levels`).
+			WithArgs(int64(5), "Store Room", 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := model.StockOut(mv)
+		assert.Error(t, err)
+		assert.Equal(t, "insufficient stock at this location", err.Error())
+	})
+
+	t.Run("not linked to a ticket or service log", func(t *testing.T) {
+		mv := newMovement()
+		mv.TicketID = nil
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		_, err := model.StockOut(mv)
+		assert.Error(t, err)
+		assert.Equal(t, "stock-out must be linked to a ticket or service log", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockModel_StockIn(t *testing.T) {
+	model, mock, teardown := setupStockTest(t)
+	defer teardown()
+
+	t.Run("successful stock-in", func(t *testing.T) {
+		cost := 4.5
+		mv := &StockMovement{ItemID: 5, Location: "Store Room", Quantity: 20, UnitCost: &cost, Reference: "INV-1001"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT reorder_threshold FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold"}).AddRow(3))
+		mock.ExpectExec(`INSERT INTO stock_levels`).
+			WithArgs(int64(5), "Store Room", 20).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO stock_movements`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(13, time.Now()))
+		mock.ExpectExec(`UPDATE stock_items SET low_stock_notified = false`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.StockIn(mv)
+		assert.NoError(t, err)
+		assert.Equal(t, "in", mv.MovementType)
+	})
+
+	t.Run("item not found", func(t *testing.T) {
+		mv := &StockMovement{ItemID: 99, Quantity: 1}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT reorder_threshold FROM stock_items`).
+			WithArgs(int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"reorder_threshold"}))
+		mock.ExpectRollback()
+
+		err := model.StockIn(mv)
+		assert.Error(t, err)
+		assert.Equal(t, "stock item not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockModel_DeleteItem(t *testing.T) {
+	model, mock, teardown := setupStockTest(t)
+	defer teardown()
+
+	t.Run("no movements", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM stock_movements`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`DELETE FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.DeleteItem(5)
+		assert.NoError(t, err)
+	})
+
+	t.Run("has movement history", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM stock_items`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM stock_movements`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := model.DeleteItem(5)
+		assert.Error(t, err)
+		assert.Equal(t, "stock item has movement history", err.Error())
+	})
+
+	t.Run("item not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM stock_items`).
+			WithArgs(int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := model.DeleteItem(99)
+		assert.Error(t, err)
+		assert.Equal(t, "stock item not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,684 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StocktakeSession struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`       // e.g. "Floor 2 quarterly count"
+	Location  string     `json:"location"`   // Empty = all locations
+	AssetType string     `json:"asset_type"` // Empty = all asset types
+	Status    string     `json:"status"`     // open, closed
+	Notes     string     `json:"notes"`
+	StartedBy *int64     `json:"started_by"`
+	ClosedBy  *int64     `json:"closed_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+
+	// Counts for list views
+	ScanCount int `json:"scan_count"`
+}
+
+type StocktakeScan struct {
+	ID             int64     `json:"id"`
+	SessionID      int64     `json:"session_id"`
+	ScannedCode    string    `json:"scanned_code"`     // internal_id or serial_number read from the label
+	AssetID        *int64    `json:"asset_id"`         // Matched asset, nil when the code is unknown
+	Location       string    `json:"location"`         // Where the item was found
+	ObservedUserID *int64    `json:"observed_user_id"` // Who was using the item when scanned
+	ScannedBy      *int64    `json:"scanned_by"`
+	Notes          string    `json:"notes"`
+	ScannedAt      time.Time `json:"scanned_at"`
+}
+
+type StocktakeCorrection struct {
+	ID        int64     `json:"id"`
+	SessionID int64     `json:"session_id"`
+	AssetID   *int64    `json:"asset_id"`
+	Action    string    `json:"action"` // update_location, update_assignee
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	AppliedBy *int64    `json:"applied_by"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// StocktakeFinding describes one asset (or unknown code) in a reconciliation
+type StocktakeFinding struct {
+	AssetID          *int64 `json:"asset_id"`
+	InternalID       string `json:"internal_id,omitempty"`
+	SerialNumber     string `json:"serial_number,omitempty"`
+	AssetType        string `json:"asset_type,omitempty"`
+	ScannedCode      string `json:"scanned_code,omitempty"`
+	ExpectedLocation string `json:"expected_location"`
+	ScannedLocation  string `json:"scanned_location"`
+	ExpectedUserID   *int64 `json:"expected_user_id"`
+	ObservedUserID   *int64 `json:"observed_user_id"`
+}
+
+type StocktakeSummary struct {
+	Expected      int `json:"expected"`
+	Found         int `json:"found"`
+	Missing       int `json:"missing"`
+	Unexpected    int `json:"unexpected"`
+	WrongLocation int `json:"wrong_location"`
+	WrongAssignee int `json:"wrong_assignee"`
+}
+
+type StocktakeReconciliation struct {
+	SessionID     int64              `json:"session_id"`
+	Summary       StocktakeSummary   `json:"summary"`
+	Found         []StocktakeFinding `json:"found"`
+	Missing       []StocktakeFinding `json:"missing"`
+	Unexpected    []StocktakeFinding `json:"unexpected"`
+	WrongLocation []StocktakeFinding `json:"wrong_location"`
+	WrongAssignee []StocktakeFinding `json:"wrong_assignee"`
+	GeneratedAt   time.Time          `json:"generated_at"`
+}
+
+type StocktakeModel struct {
+	DB *sql.DB
+}
+
+func NewStocktakeModel(db *sql.DB) *StocktakeModel {
+	return &StocktakeModel{DB: db}
+}
+
+// Insert a new stocktake session
+func (m *StocktakeModel) Insert(session *StocktakeSession) error {
+	query := `
+		INSERT INTO stocktake_sessions (name, location, asset_type, notes, started_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	return m.DB.QueryRow(
+		query,
+		session.Name,
+		session.Location,
+		session.AssetType,
+		session.Notes,
+		session.StartedBy,
+	).Scan(&session.ID, &session.Status, &session.CreatedAt)
+}
+
+// Get stocktake session by ID
+func (m *StocktakeModel) GetByID(id int64) (*StocktakeSession, error) {
+	query := `
+		SELECT
+			s.id, s.name, s.location, s.asset_type, s.status, s.notes,
+			s.started_by, s.closed_by, s.created_at, s.closed_at,
+			(SELECT COUNT(*) FROM stocktake_scans sc WHERE sc.session_id = s.id)
+		FROM stocktake_sessions s
+		WHERE s.id = $1
+	`
+
+	var session StocktakeSession
+	err := m.DB.QueryRow(query, id).Scan(
+		&session.ID,
+		&session.Name,
+		&session.Location,
+		&session.AssetType,
+		&session.Status,
+		&session.Notes,
+		&session.StartedBy,
+		&session.ClosedBy,
+		&session.CreatedAt,
+		&session.ClosedAt,
+		&session.ScanCount,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("stocktake session not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Get all stocktake sessions, optionally filtered by status
+func (m *StocktakeModel) GetAll(status string) ([]StocktakeSession, error) {
+	query := `
+		SELECT
+			s.id, s.name, s.location, s.asset_type, s.status, s.notes,
+			s.started_by, s.closed_by, s.created_at, s.closed_at,
+			(SELECT COUNT(*) FROM stocktake_scans sc WHERE sc.session_id = s.id)
+		FROM stocktake_sessions s
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+	if status != "" {
+		query += " AND s.status = $1"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY s.created_at DESC"
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []StocktakeSession
+	for rows.Next() {
+		var session StocktakeSession
+		err := rows.Scan(
+			&session.ID,
+			&session.Name,
+			&session.Location,
+			&session.AssetType,
+			&session.Status,
+			&session.Notes,
+			&session.StartedBy,
+			&session.ClosedBy,
+			&session.CreatedAt,
+			&session.ClosedAt,
+			&session.ScanCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// InsertScan records a scan against an open session. The code is matched to an
+// asset by internal_id first, then serial_number. Scans from several technicians
+// can arrive at once; each is an independent insert guarded by the session status.
+func (m *StocktakeModel) InsertScan(scan *StocktakeScan) error {
+	query := `
+		INSERT INTO stocktake_scans (
+			session_id, scanned_code, asset_id, location, observed_user_id, scanned_by, notes
+		)
+		SELECT $1, $2, (
+			SELECT id FROM assets
+			WHERE (LOWER(internal_id) = LOWER($2) OR LOWER(serial_number) = LOWER($2))
+			AND deleted_at IS NULL
+			ORDER BY (LOWER(internal_id) = LOWER($2)) DESC, id
+			LIMIT 1
+		), $3, $4, $5, $6
+		WHERE EXISTS (SELECT 1 FROM stocktake_sessions WHERE id = $1 AND status = 'open')
+		RETURNING id, asset_id, scanned_at
+	`
+
+	err := m.DB.QueryRow(
+		query,
+		scan.SessionID,
+		scan.ScannedCode,
+		scan.Location,
+		scan.ObservedUserID,
+		scan.ScannedBy,
+		scan.Notes,
+	).Scan(&scan.ID, &scan.AssetID, &scan.ScannedAt)
+
+	if err == sql.ErrNoRows {
+		return errors.New("stocktake session not found or closed")
+	}
+	return err
+}
+
+// Get scans for a session
+func (m *StocktakeModel) GetScans(sessionID int64) ([]StocktakeScan, error) {
+	query := `
+		SELECT
+			id, session_id, scanned_code, asset_id, location,
+			observed_user_id, scanned_by, notes, scanned_at
+		FROM stocktake_scans
+		WHERE session_id = $1
+		ORDER BY scanned_at, id
+	`
+
+	rows, err := m.DB.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scans []StocktakeScan
+	for rows.Next() {
+		var scan StocktakeScan
+		err := rows.Scan(
+			&scan.ID,
+			&scan.SessionID,
+			&scan.ScannedCode,
+			&scan.AssetID,
+			&scan.Location,
+			&scan.ObservedUserID,
+			&scan.ScannedBy,
+			&scan.Notes,
+			&scan.ScannedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+
+	return scans, nil
+}
+
+// GetExpectedAssets returns the non-retired assets that fall inside the session scope
+func (m *StocktakeModel) GetExpectedAssets(session *StocktakeSession) ([]Asset, error) {
+	query := `
+		SELECT ` + assetColumns + `
+		FROM assets
+		WHERE status != 'RETIRED' AND deleted_at IS NULL
+		AND ($1 = '' OR asset_type = $1)
+		AND ($2 = '' OR LOWER(location) = LOWER($2))
+		ORDER BY internal_id
+	`
+
+	return m.queryAssets(query, session.AssetType, session.Location)
+}
+
+// GetScannedAssets returns the current records of every asset matched by a scan
+func (m *StocktakeModel) GetScannedAssets(sessionID int64) ([]Asset, error) {
+	query := `
+		SELECT ` + assetColumns + `
+		FROM assets
+		WHERE id IN (SELECT asset_id FROM stocktake_scans WHERE session_id = $1)
+		AND deleted_at IS NULL
+		ORDER BY internal_id
+	`
+
+	return m.queryAssets(query, sessionID)
+}
+
+func (m *StocktakeModel) queryAssets(query string, args ...interface{}) ([]Asset, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assets []Asset
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *asset)
+	}
+
+	return assets, nil
+}
+
+// Reconcile compares the scans of a session against the asset register. Closed
+// sessions return the snapshot taken at close time so the report stays stable.
+func (m *StocktakeModel) Reconcile(sessionID int64) (*StocktakeReconciliation, error) {
+	session, err := m.GetByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == "closed" {
+		var snapshot []byte
+		err := m.DB.QueryRow(
+			"SELECT reconciliation FROM stocktake_sessions WHERE id = $1",
+			sessionID,
+		).Scan(&snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapshot) > 0 {
+			var rec StocktakeReconciliation
+			if err := json.Unmarshal(snapshot, &rec); err != nil {
+				return nil, err
+			}
+			return &rec, nil
+		}
+	}
+
+	expected, err := m.GetExpectedAssets(session)
+	if err != nil {
+		return nil, err
+	}
+
+	scanned, err := m.GetScannedAssets(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	scans, err := m.GetScans(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReconcileStocktake(session, expected, scanned, scans), nil
+}
+
+// ReconcileStocktake classifies expected assets and scans into found, missing,
+// unexpected, wrong-location and wrong-assignee findings. When an asset is
+// scanned more than once the latest scan wins. An asset can be both in the
+// wrong location and with the wrong person.
+func ReconcileStocktake(session *StocktakeSession, expected, scanned []Asset, scans []StocktakeScan) *StocktakeReconciliation {
+	rec := &StocktakeReconciliation{
+		SessionID:     session.ID,
+		Found:         []StocktakeFinding{},
+		Missing:       []StocktakeFinding{},
+		Unexpected:    []StocktakeFinding{},
+		WrongLocation: []StocktakeFinding{},
+		WrongAssignee: []StocktakeFinding{},
+		GeneratedAt:   time.Now(),
+	}
+
+	assetsByID := make(map[int64]Asset, len(scanned))
+	for _, asset := range scanned {
+		assetsByID[asset.ID] = asset
+	}
+
+	// Latest scan per asset, and per unknown code
+	latestByAsset := make(map[int64]StocktakeScan)
+	var assetOrder []int64
+	latestByCode := make(map[string]StocktakeScan)
+	var codeOrder []string
+	for _, scan := range scans {
+		if scan.AssetID != nil {
+			if _, seen := latestByAsset[*scan.AssetID]; !seen {
+				assetOrder = append(assetOrder, *scan.AssetID)
+			}
+			latestByAsset[*scan.AssetID] = scan
+			continue
+		}
+		code := strings.ToUpper(strings.TrimSpace(scan.ScannedCode))
+		if _, seen := latestByCode[code]; !seen {
+			codeOrder = append(codeOrder, code)
+		}
+		latestByCode[code] = scan
+	}
+
+	for _, assetID := range assetOrder {
+		scan := latestByAsset[assetID]
+		asset, ok := assetsByID[assetID]
+		if !ok {
+			// Asset was deleted after it was scanned
+			rec.Unexpected = append(rec.Unexpected, StocktakeFinding{
+				AssetID:         scan.AssetID,
+				ScannedCode:     scan.ScannedCode,
+				ScannedLocation: scan.Location,
+				ObservedUserID:  scan.ObservedUserID,
+			})
+			continue
+		}
+
+		scannedLocation := scan.Location
+		if scannedLocation == "" {
+			scannedLocation = session.Location
+		}
+
+		id := asset.ID
+		finding := StocktakeFinding{
+			AssetID:          &id,
+			InternalID:       asset.InternalID,
+			SerialNumber:     asset.SerialNumber,
+			AssetType:        asset.AssetType,
+			ScannedCode:      scan.ScannedCode,
+			ExpectedLocation: asset.Location,
+			ScannedLocation:  scannedLocation,
+			ExpectedUserID:   asset.InUseBy,
+			ObservedUserID:   scan.ObservedUserID,
+		}
+
+		if asset.Status == "RETIRED" || (session.AssetType != "" && asset.AssetType != session.AssetType) {
+			rec.Unexpected = append(rec.Unexpected, finding)
+			continue
+		}
+
+		wrongLocation := scannedLocation != "" &&
+			!strings.EqualFold(strings.TrimSpace(asset.Location), strings.TrimSpace(scannedLocation))
+		wrongAssignee := scan.ObservedUserID != nil &&
+			(asset.InUseBy == nil || *asset.InUseBy != *scan.ObservedUserID)
+
+		if wrongLocation {
+			rec.WrongLocation = append(rec.WrongLocation, finding)
+		}
+		if wrongAssignee {
+			rec.WrongAssignee = append(rec.WrongAssignee, finding)
+		}
+		if !wrongLocation && !wrongAssignee {
+			rec.Found = append(rec.Found, finding)
+		}
+	}
+
+	for _, code := range codeOrder {
+		scan := latestByCode[code]
+		rec.Unexpected = append(rec.Unexpected, StocktakeFinding{
+			ScannedCode:     scan.ScannedCode,
+			ScannedLocation: scan.Location,
+			ObservedUserID:  scan.ObservedUserID,
+		})
+	}
+
+	for _, asset := range expected {
+		if _, seen := latestByAsset[asset.ID]; seen {
+			continue
+		}
+		id := asset.ID
+		rec.Missing = append(rec.Missing, StocktakeFinding{
+			AssetID:          &id,
+			InternalID:       asset.InternalID,
+			SerialNumber:     asset.SerialNumber,
+			AssetType:        asset.AssetType,
+			ExpectedLocation: asset.Location,
+			ExpectedUserID:   asset.InUseBy,
+		})
+	}
+
+	rec.Summary = StocktakeSummary{
+		Expected:      len(expected),
+		Found:         len(rec.Found),
+		Missing:       len(rec.Missing),
+		Unexpected:    len(rec.Unexpected),
+		WrongLocation: len(rec.WrongLocation),
+		WrongAssignee: len(rec.WrongAssignee),
+	}
+
+	return rec
+}
+
+// Close an open session and store the reconciliation snapshot
+func (m *StocktakeModel) Close(sessionID, closedBy int64, rec *StocktakeReconciliation) error {
+	snapshot, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE stocktake_sessions
+		SET status = 'closed', closed_by = $1, closed_at = NOW(), reconciliation = $2
+		WHERE id = $3 AND status = 'open'
+	`
+
+	result, err := m.DB.Exec(query, closedBy, snapshot, sessionID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("stocktake session not found or already closed")
+	}
+	return nil
+}
+
+// InsertCorrection records a correction applied to an asset
+func (m *StocktakeModel) InsertCorrection(correction *StocktakeCorrection) error {
+	return insertStocktakeCorrection(m.DB, correction)
+}
+
+func insertStocktakeCorrection(db dbtx, correction *StocktakeCorrection) error {
+	query := `
+		INSERT INTO stocktake_corrections (session_id, asset_id, action, old_value, new_value, applied_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, applied_at
+	`
+
+	return db.QueryRow(
+		query,
+		correction.SessionID,
+		correction.AssetID,
+		correction.Action,
+		correction.OldValue,
+		correction.NewValue,
+		correction.AppliedBy,
+	).Scan(&correction.ID, &correction.AppliedAt)
+}
+
+// StocktakeCorrectionError is a correction that could not be applied, e.g. an
+// asset that has since been retired, moved or reassigned
+type StocktakeCorrectionError struct {
+	Message string
+}
+
+func (e *StocktakeCorrectionError) Error() string {
+	return e.Message
+}
+
+// ApplyCorrections updates each asset to what the stocktake found, moving it
+// to NewValue for update_location or assigning it to user NewValue for
+// update_assignee, and records the corrections. A reassigned asset's old
+// handover is closed and a pending one opened for its new holder, as in
+// AssignWithCustody; the handovers opened are returned. An asset whose
+// location or assignee is no longer OldValue has changed since the stocktake
+// and is left alone, as is one the session already corrected. They are
+// applied together: if one fails, none are.
+func (m *StocktakeModel) ApplyCorrections(corrections []StocktakeCorrection) ([]*AssetCustody, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var handovers []*AssetCustody
+
+	for i := range corrections {
+		c := &corrections[i]
+		if c.AssetID == nil {
+			return nil, &StocktakeCorrectionError{Message: "correction has no asset"}
+		}
+
+		// The row lock also serialises concurrent applies of the same correction
+		var location string
+		var inUseBy *int64
+		err = tx.QueryRow(`
+			SELECT location, in_use_by FROM assets
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		`, *c.AssetID).Scan(&location, &inUseBy)
+		if err == sql.ErrNoRows {
+			return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: asset not found", *c.AssetID)}
+		} else if err != nil {
+			return nil, err
+		}
+
+		var applied bool
+		err = tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM stocktake_corrections WHERE session_id = $1 AND asset_id = $2 AND action = $3)
+		`, c.SessionID, *c.AssetID, c.Action).Scan(&applied)
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: %s already applied for this stocktake", *c.AssetID, c.Action)}
+		}
+
+		current := location
+		if c.Action == "update_assignee" {
+			current = ""
+			if inUseBy != nil {
+				current = strconv.FormatInt(*inUseBy, 10)
+			}
+		}
+		if current != c.OldValue {
+			return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: changed since the stocktake (now %q, expected %q)", *c.AssetID, current, c.OldValue)}
+		}
+
+		switch c.Action {
+		case "update_location":
+			err = updateAssetLocation(tx, *c.AssetID, c.NewValue)
+		case "update_assignee":
+			userID, convErr := strconv.ParseInt(c.NewValue, 10, 64)
+			if convErr != nil {
+				return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: invalid assignee %q", *c.AssetID, c.NewValue)}
+			}
+			err = assignAsset(tx, *c.AssetID, userID)
+			if err == nil {
+				// Closes the previous holder's handover and opens the new one's
+				custody := &AssetCustody{
+					AssetID:       *c.AssetID,
+					UserID:        userID,
+					AssignedBy:    c.AppliedBy,
+					AcknowledgeBy: time.Now().Add(DefaultAcknowledgeWindow),
+				}
+				err = openCustodyTx(tx, custody)
+				handovers = append(handovers, custody)
+			}
+		default:
+			return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: unknown action %s", *c.AssetID, c.Action)}
+		}
+		if err != nil {
+			if err.Error() == "asset not found" || err.Error() == "user not found" ||
+				strings.HasPrefix(err.Error(), "asset not found or cannot be assigned") {
+				return nil, &StocktakeCorrectionError{Message: fmt.Sprintf("asset %d: %s", *c.AssetID, err.Error())}
+			}
+			return nil, err
+		}
+
+		if err := insertStocktakeCorrection(tx, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return handovers, nil
+}
+
+// Get corrections applied for a session
+func (m *StocktakeModel) GetCorrections(sessionID int64) ([]StocktakeCorrection, error) {
+	query := `
+		SELECT id, session_id, asset_id, action, old_value, new_value, applied_by, applied_at
+		FROM stocktake_corrections
+		WHERE session_id = $1
+		ORDER BY applied_at, id
+	`
+
+	rows, err := m.DB.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var corrections []StocktakeCorrection
+	for rows.Next() {
+		var correction StocktakeCorrection
+		err := rows.Scan(
+			&correction.ID,
+			&correction.SessionID,
+			&correction.AssetID,
+			&correction.Action,
+			&correction.OldValue,
+			&correction.NewValue,
+			&correction.AppliedBy,
+			&correction.AppliedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, correction)
+	}
+
+	return corrections, nil
+}
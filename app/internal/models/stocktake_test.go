@@ -0,0 +1,254 @@
+// file: app/internal/models/stocktake_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStocktakeTest(t *testing.T) (*StocktakeModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewStocktakeModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestStocktakeModel_InsertScan(t *testing.T) {
+	model, mock, teardown := setupStocktakeTest(t)
+	defer teardown()
+
+	now := time.Now()
+	userID := int64(3)
+
+	t.Run("matched scan", func(t *testing.T) {
+		scan := &StocktakeScan{
+			SessionID:   1,
+			ScannedCode: "DPA-PC001",
+			Location:    "Floor 2",
+			ScannedBy:   &userID,
+		}
+
+		mock.ExpectQuery(`INSERT INTO stocktake_scans`).
+			WithArgs(int64(1), "DPA-PC001", "Floor 2", nil, &userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "asset_id", "scanned_at"}).
+				AddRow(10, int64(5), now))
+
+		err := model.InsertScan(scan)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), scan.ID)
+		assert.Equal(t, int64(5), *scan.AssetID)
+	})
+
+	t.Run("closed session", func(t *testing.T) {
+		scan := &StocktakeScan{SessionID: 2, ScannedCode: "DPA-PC001"}
+
+		mock.ExpectQuery(`INSERT INTO stocktake_scans`).
+			WillReturnError(sql.ErrNoRows)
+
+		err := model.InsertScan(scan)
+		assert.Error(t, err)
+		assert.Equal(t, "stocktake session not found or closed", err.Error())
+	})
+}
+
+func TestStocktakeModel_Close(t *testing.T) {
+	model, mock, teardown := setupStocktakeTest(t)
+	defer teardown()
+
+	rec := &StocktakeReconciliation{SessionID: 1}
+
+	t.Run("successful close", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE stocktake_sessions`).
+			WithArgs(int64(2), sqlmock.AnyArg(), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := model.Close(1, 2, rec)
+		assert.NoError(t, err)
+	})
+
+	t.Run("already closed", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE stocktake_sessions`).
+			WithArgs(int64(2), sqlmock.AnyArg(), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.Close(1, 2, rec)
+		assert.Error(t, err)
+		assert.Equal(t, "stocktake session not found or already closed", err.Error())
+	})
+}
+
+func TestStocktakeModel_ApplyCorrections(t *testing.T) {
+	model, mock, teardown := setupStocktakeTest(t)
+	defer teardown()
+
+	appliedBy := int64(1)
+	assetA, assetB := int64(4), int64(6)
+	now := time.Now()
+	corrections := func() []StocktakeCorrection {
+		return []StocktakeCorrection{
+			{SessionID: 2, AssetID: &assetA, Action: "update_location", OldValue: "Room 1", NewValue: "Room 2", AppliedBy: &appliedBy},
+			{SessionID: 2, AssetID: &assetB, Action: "update_assignee", OldValue: "3", NewValue: "8", AppliedBy: &appliedBy},
+		}
+	}
+
+	expectCurrent := func(assetID int64, location string, inUseBy interface{}, applied bool) {
+		mock.ExpectQuery(`SELECT location, in_use_by FROM assets`).
+			WithArgs(assetID).
+			WillReturnRows(sqlmock.NewRows([]string{"location", "in_use_by"}).AddRow(location, inUseBy))
+		mock.ExpectQuery(`FROM stocktake_corrections`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(applied))
+	}
+
+	t.Run("all applied and recorded together", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCurrent(assetA, "Room 1", nil, false)
+		mock.ExpectQuery(`UPDATE assets\s+SET location = \$1`).
+			WithArgs("Room 2", assetA).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectQuery(`INSERT INTO stocktake_corrections`).
+			WithArgs(int64(2), &assetA, "update_location", "Room 1", "Room 2", &appliedBy).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "applied_at"}).AddRow(10, now))
+		expectCurrent(assetB, "Floor 2", 3, false)
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`UPDATE assets\s+SET in_use_by = \$1`).
+			WithArgs(int64(8), assetB).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectExec(`UPDATE asset_custody`).
+			WithArgs(assetB).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO asset_custody`).
+			WithArgs(assetB, int64(8), &appliedBy, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "assigned_at"}).AddRow(21, "pending", now))
+		mock.ExpectQuery(`INSERT INTO stocktake_corrections`).
+			WithArgs(int64(2), &assetB, "update_assignee", "3", "8", &appliedBy).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "applied_at"}).AddRow(11, now))
+		mock.ExpectCommit()
+
+		applied := corrections()
+		handovers, err := model.ApplyCorrections(applied)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), applied[0].ID)
+		assert.Equal(t, int64(11), applied[1].ID)
+		require.Len(t, handovers, 1)
+		assert.Equal(t, int64(21), handovers[0].ID)
+		assert.Equal(t, int64(8), handovers[0].UserID)
+	})
+
+	t.Run("one failure rolls back the rest", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCurrent(assetA, "Room 1", nil, false)
+		mock.ExpectQuery(`UPDATE assets\s+SET location = \$1`).
+			WithArgs("Room 2", assetA).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectQuery(`INSERT INTO stocktake_corrections`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "applied_at"}).AddRow(10, now))
+		expectCurrent(assetB, "Floor 2", 3, false)
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`UPDATE assets\s+SET in_use_by = \$1`).
+			WithArgs(int64(8), assetB).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := model.ApplyCorrections(corrections())
+		var correctionErr *StocktakeCorrectionError
+		require.ErrorAs(t, err, &correctionErr)
+		assert.Equal(t, "asset 6: asset not found or cannot be assigned (might be retired or in repair)", err.Error())
+	})
+
+	t.Run("asset reassigned since the stocktake", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCurrent(assetA, "Room 1", nil, false)
+		mock.ExpectQuery(`UPDATE assets\s+SET location = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mock.ExpectQuery(`INSERT INTO stocktake_corrections`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "applied_at"}).AddRow(10, now))
+		expectCurrent(assetB, "Floor 2", 5, false)
+		mock.ExpectRollback()
+
+		_, err := model.ApplyCorrections(corrections())
+		var correctionErr *StocktakeCorrectionError
+		require.ErrorAs(t, err, &correctionErr)
+		assert.Equal(t, `asset 6: changed since the stocktake (now "5", expected "3")`, err.Error())
+	})
+
+	t.Run("already applied", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectCurrent(assetA, "Room 2", nil, true)
+		mock.ExpectRollback()
+
+		_, err := model.ApplyCorrections(corrections()[:1])
+		var correctionErr *StocktakeCorrectionError
+		require.ErrorAs(t, err, &correctionErr)
+		assert.Equal(t, "asset 4: update_location already applied for this stocktake", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconcileStocktake(t *testing.T) {
+	agent := int64(7)
+	otherAgent := int64(8)
+
+	session := &StocktakeSession{ID: 1, Location: "Floor 2", AssetType: "Headset"}
+
+	expected := []Asset{
+		{ID: 1, InternalID: "HS-001", AssetType: "Headset", Location: "Floor 2", InUseBy: &agent},
+		{ID: 2, InternalID: "HS-002", AssetType: "Headset", Location: "Floor 2"},
+		{ID: 3, InternalID: "HS-003", AssetType: "Headset", Location: "Floor 2", InUseBy: &agent},
+	}
+
+	scanned := []Asset{
+		expected[0],
+		expected[2],
+		{ID: 4, InternalID: "HS-010", AssetType: "Headset", Location: "Floor 1"},
+		{ID: 5, InternalID: "DPA-PC001", AssetType: "PC", Location: "Floor 2"},
+	}
+
+	scans := []StocktakeScan{
+		{AssetID: int64Ptr(1), ScannedCode: "HS-001", ObservedUserID: &agent},
+		{AssetID: int64Ptr(1), ScannedCode: "HS-001"}, // duplicate scan of the same headset
+		{AssetID: int64Ptr(3), ScannedCode: "HS-003", ObservedUserID: &otherAgent},
+		{AssetID: int64Ptr(4), ScannedCode: "HS-010"},
+		{AssetID: int64Ptr(5), ScannedCode: "DPA-PC001"},
+		{ScannedCode: "UNKNOWN-1"},
+	}
+
+	rec := ReconcileStocktake(session, expected, scanned, scans)
+
+	assert.Equal(t, 3, rec.Summary.Expected)
+
+	require.Len(t, rec.Found, 1)
+	assert.Equal(t, "HS-001", rec.Found[0].InternalID)
+
+	require.Len(t, rec.Missing, 1)
+	assert.Equal(t, "HS-002", rec.Missing[0].InternalID)
+
+	require.Len(t, rec.WrongAssignee, 1)
+	assert.Equal(t, "HS-003", rec.WrongAssignee[0].InternalID)
+	assert.Equal(t, otherAgent, *rec.WrongAssignee[0].ObservedUserID)
+
+	require.Len(t, rec.WrongLocation, 1)
+	assert.Equal(t, "HS-010", rec.WrongLocation[0].InternalID)
+	assert.Equal(t, "Floor 1", rec.WrongLocation[0].ExpectedLocation)
+	assert.Equal(t, "Floor 2", rec.WrongLocation[0].ScannedLocation)
+
+	require.Len(t, rec.Unexpected, 2)
+	assert.Equal(t, "DPA-PC001", rec.Unexpected[0].InternalID)
+	assert.Nil(t, rec.Unexpected[1].AssetID)
+	assert.Equal(t, "UNKNOWN-1", rec.Unexpected[1].ScannedCode)
+}
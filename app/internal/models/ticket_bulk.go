@@ -0,0 +1,307 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// MaxBulkTickets caps how many tickets one bulk operation may touch
+const MaxBulkTickets = 500
+
+// TicketBulkChanges are the edits a bulk operation makes to every ticket it
+// selects. Fields left empty are not changed.
+type TicketBulkChanges struct {
+	Status     string   `json:"status,omitempty"`
+	Priority   string   `json:"priority,omitempty"`
+	AssignedTo *int64   `json:"assigned_to,omitempty"`
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	Close      bool     `json:"close,omitempty"` // Same as status "closed"
+}
+
+// TicketBulkResult reports what a bulk operation did to one ticket
+type TicketBulkResult struct {
+	TicketID  int64    `json:"ticket_id"`
+	TicketNum string   `json:"ticket_num,omitempty"`
+	Result    string   `json:"result"` // updated, unchanged, not_found, forbidden, failed, rolled_back
+	Error     string   `json:"error,omitempty"`
+	Changes   []string `json:"changes,omitempty"`
+
+	// The ticket before and after, for notifications; set when updated
+	Before *Ticket `json:"-"`
+	After  *Ticket `json:"-"`
+}
+
+// TicketBulkError is a bulk operation the client got wrong, e.g. no changes
+type TicketBulkError struct {
+	Message string
+}
+
+func (e *TicketBulkError) Error() string {
+	return e.Message
+}
+
+var (
+	bulkTicketStatuses = map[string]bool{
+		"open": true, "received": true, "in_progress": true, "resolved": true, "closed": true,
+	}
+	bulkTicketPriorities = map[string]bool{"low": true, "normal": true, "high": true, "critical": true}
+)
+
+// validateBulkChanges checks the changes and folds Close into Status
+func validateBulkChanges(c *TicketBulkChanges) error {
+	if c.Close {
+		if c.Status != "" && c.Status != "closed" {
+			return &TicketBulkError{Message: "close cannot be combined with another status"}
+		}
+		c.Status = "closed"
+	}
+	if c.Status != "" && !bulkTicketStatuses[c.Status] {
+		return &TicketBulkError{Message: fmt.Sprintf("invalid status %q", c.Status)}
+	}
+	if c.Priority != "" && !bulkTicketPriorities[c.Priority] {
+		return &TicketBulkError{Message: fmt.Sprintf("invalid priority %q", c.Priority)}
+	}
+	if c.Status == "" && c.Priority == "" && c.AssignedTo == nil && len(c.AddTags) == 0 && len(c.RemoveTags) == 0 {
+		return &TicketBulkError{Message: "no changes given"}
+	}
+	return nil
+}
+
+// TicketEditDenied applies UpdateTicket's rule to one ticket: admins may edit
+// any ticket, IT staff those assigned to them or to nobody, everyone else the
+// tickets they created. It returns why the user may not, or "".
+func TicketEditDenied(t *Ticket, userID, roleID int64) string {
+	switch roleID {
+	case 1:
+		return ""
+	case 2:
+		if t.AssignedTo != nil && *t.AssignedTo != userID {
+			return "you can only update tickets assigned to you"
+		}
+	default:
+		if t.CreatedBy == nil || *t.CreatedBy != userID {
+			return "you can only update tickets you created"
+		}
+	}
+	return ""
+}
+
+// bulkTag is a tag a bulk operation adds or removes
+type bulkTag struct {
+	ID   int64
+	Name string
+}
+
+// resolveBulkTags looks up tag names; tags being added must be active
+func resolveBulkTags(tx *sql.Tx, names []string, adding bool) ([]bulkTag, error) {
+	var tags []bulkTag
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag bulkTag
+		var isActive bool
+		err := tx.QueryRow(
+			"SELECT id, name, is_active FROM ticket_tags WHERE name = $1", name,
+		).Scan(&tag.ID, &tag.Name, &isActive)
+		if err == sql.ErrNoRows {
+			return nil, &TicketTagError{Message: fmt.Sprintf("unknown tag %q", name)}
+		} else if err != nil {
+			return nil, err
+		}
+		if adding && !isActive {
+			return nil, &TicketTagError{Message: fmt.Sprintf("tag %q is retired", name)}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// BulkUpdate applies the same changes to many tickets in one transaction,
+// checking the user may edit each. Tickets the user may not edit, or that
+// cannot take the change, are skipped and reported; the rest are saved
+// together. With allOrNothing, any skipped ticket rolls every change back.
+// Tickets are worked through newest first, so children usually close before
+// their parents, and reported in ID order.
+func (m *TicketModel) BulkUpdate(ids []int64, changes TicketBulkChanges, userID, roleID int64, allOrNothing bool) ([]TicketBulkResult, error) {
+	if err := validateBulkChanges(&changes); err != nil {
+		return nil, err
+	}
+
+	ids = append([]int64(nil), ids...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	ids = unique
+	if len(ids) == 0 {
+		return nil, &TicketBulkError{Message: "no tickets selected"}
+	}
+	if len(ids) > MaxBulkTickets {
+		return nil, &TicketBulkError{Message: fmt.Sprintf("at most %d tickets can be updated at once", MaxBulkTickets)}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	addTags, err := resolveBulkTags(tx, changes.AddTags, true)
+	if err != nil {
+		return nil, err
+	}
+	removeTags, err := resolveBulkTags(tx, changes.RemoveTags, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TicketBulkResult, 0, len(ids))
+	skipped := false
+	for _, id := range ids {
+		result, err := bulkUpdateTicket(tx, id, changes, addTags, removeTags, userID, roleID)
+		if err != nil {
+			return nil, err
+		}
+		if result.Result != "updated" && result.Result != "unchanged" {
+			skipped = true
+		}
+		results = append(results, result)
+	}
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+
+	if allOrNothing && skipped {
+		for i := range results {
+			if results[i].Result == "updated" {
+				results[i].Result = "rolled_back"
+				results[i].Before, results[i].After = nil, nil
+			}
+		}
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// bulkUpdateTicket applies a bulk operation's changes to one locked ticket.
+// Everything that can skip the ticket is checked before it is written to.
+func bulkUpdateTicket(tx *sql.Tx, id int64, changes TicketBulkChanges, addTags, removeTags []bulkTag, userID, roleID int64) (TicketBulkResult, error) {
+	result := TicketBulkResult{TicketID: id}
+
+	var before Ticket
+	var createdBy, assignedTo sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT id, ticket_num, title, status, priority, completion, created_by, assigned_to
+		FROM tickets WHERE id = $1 FOR UPDATE
+	`, id).Scan(&before.ID, &before.TicketNum, &before.Title, &before.Status, &before.Priority,
+		&before.Completion, &createdBy, &assignedTo)
+	if err == sql.ErrNoRows {
+		result.Result = "not_found"
+		result.Error = "ticket not found"
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+	if createdBy.Valid {
+		before.CreatedBy = &createdBy.Int64
+	}
+	if assignedTo.Valid {
+		before.AssignedTo = &assignedTo.Int64
+	}
+
+	if reason := TicketEditDenied(&before, userID, roleID); reason != "" {
+		result.Result = "forbidden"
+		result.Error = reason
+		return result, nil
+	}
+	result.TicketNum = before.TicketNum
+
+	after := before
+	if changes.Status != "" && changes.Status != before.Status {
+		if changes.Status == "closed" {
+			var openChildren int
+			if err := tx.QueryRow(openChildrenQuery, id).Scan(&openChildren); err != nil {
+				return result, err
+			}
+			if openChildren > 0 {
+				result.Result = "failed"
+				result.Error = fmt.Sprintf("ticket has %d open child tickets", openChildren)
+				return result, nil
+			}
+			after.Completion = 100
+		}
+		after.Status = changes.Status
+		result.Changes = append(result.Changes, fmt.Sprintf("status: %s -> %s", before.Status, after.Status))
+	}
+	if changes.Priority != "" && changes.Priority != before.Priority {
+		after.Priority = changes.Priority
+		result.Changes = append(result.Changes, fmt.Sprintf("priority: %s -> %s", before.Priority, after.Priority))
+	}
+	if changes.AssignedTo != nil && (before.AssignedTo == nil || *before.AssignedTo != *changes.AssignedTo) {
+		after.AssignedTo = changes.AssignedTo
+		from := "unassigned"
+		if before.AssignedTo != nil {
+			from = fmt.Sprintf("user %d", *before.AssignedTo)
+		}
+		result.Changes = append(result.Changes, fmt.Sprintf("assigned_to: %s -> user %d", from, *after.AssignedTo))
+	}
+
+	if len(result.Changes) > 0 {
+		if _, err := tx.Exec(`
+			UPDATE tickets
+			SET status = $1, priority = $2, completion = $3, assigned_to = $4, updated_at = NOW(),
+				closed_at = CASE WHEN $1 = 'closed' AND closed_at IS NULL THEN NOW() ELSE closed_at END
+			WHERE id = $5
+		`, after.Status, after.Priority, after.Completion, after.AssignedTo, id); err != nil {
+			return result, err
+		}
+	}
+
+	for _, tag := range addTags {
+		res, err := tx.Exec(`
+			INSERT INTO ticket_tag_assignments (ticket_id, tag_id, added_by)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (ticket_id, tag_id) DO NOTHING
+		`, id, tag.ID, userID)
+		if err != nil {
+			return result, err
+		}
+		if rows, _ := res.RowsAffected(); rows > 0 {
+			result.Changes = append(result.Changes, "tag added: "+tag.Name)
+		}
+	}
+	for _, tag := range removeTags {
+		res, err := tx.Exec(
+			"DELETE FROM ticket_tag_assignments WHERE ticket_id = $1 AND tag_id = $2", id, tag.ID,
+		)
+		if err != nil {
+			return result, err
+		}
+		if rows, _ := res.RowsAffected(); rows > 0 {
+			result.Changes = append(result.Changes, "tag removed: "+tag.Name)
+		}
+	}
+
+	if len(result.Changes) == 0 {
+		result.Result = "unchanged"
+		return result, nil
+	}
+	result.Result = "updated"
+	result.Before = &before
+	result.After = &after
+	return result, nil
+}
@@ -0,0 +1,168 @@
+// file: app/internal/models/ticket_bulk_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ticketBulkTestColumns = []string{
+	"id", "ticket_num", "title", "status", "priority", "completion", "created_by", "assigned_to",
+}
+
+func TestTicketModel_BulkUpdate_Validation(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	tests := []struct {
+		name    string
+		ids     []int64
+		changes TicketBulkChanges
+		want    string
+	}{
+		{"no changes", []int64{1}, TicketBulkChanges{}, "no changes given"},
+		{"bad status", []int64{1}, TicketBulkChanges{Status: "done"}, `invalid status "done"`},
+		{"bad priority", []int64{1}, TicketBulkChanges{Priority: "urgent"}, `invalid priority "urgent"`},
+		{"close with another status", []int64{1}, TicketBulkChanges{Close: true, Status: "open"}, "close cannot be combined with another status"},
+		{"no tickets", nil, TicketBulkChanges{Close: true}, "no tickets selected"},
+		{"too many tickets", make([]int64, MaxBulkTickets+1), TicketBulkChanges{Close: true}, ""},
+	}
+
+	for i := range tests[len(tests)-1].ids {
+		tests[len(tests)-1].ids[i] = int64(i + 1)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := model.BulkUpdate(tt.ids, tt.changes, 1, 1, false)
+			var bulkErr *TicketBulkError
+			require.ErrorAs(t, err, &bulkErr)
+			if tt.want != "" {
+				assert.EqualError(t, err, tt.want)
+			}
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketModel_BulkUpdate(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	creator := int64(3)
+	techA := int64(7)
+
+	t.Run("close and tag", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, name, is_active FROM ticket_tags WHERE name = \$1`).
+			WithArgs("handover").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active"}).AddRow(9, "handover", true))
+
+		// Newest first, so children close before their parents
+		mock.ExpectQuery(`SELECT id, ticket_num, title, status, priority, completion, created_by, assigned_to\s+FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(3, "TCK-2025-0003", "Headset", "resolved", "normal", 90, creator, techA))
+		mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM ticket_links`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(`UPDATE tickets`).
+			WithArgs("closed", "normal", 100, &techA, int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO ticket_tag_assignments`).
+			WithArgs(int64(3), int64(9), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(2, "TCK-2025-0002", "Printer", "closed", "low", 100, creator, nil))
+		mock.ExpectExec(`INSERT INTO ticket_tag_assignments`).
+			WithArgs(int64(2), int64(9), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		results, err := model.BulkUpdate([]int64{3, 2, 3}, TicketBulkChanges{Close: true, AddTags: []string{" Handover "}}, 1, 1, false)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, int64(2), results[0].TicketID)
+		assert.Equal(t, "unchanged", results[0].Result)
+		assert.Nil(t, results[0].After)
+
+		assert.Equal(t, int64(3), results[1].TicketID)
+		assert.Equal(t, "updated", results[1].Result)
+		assert.Equal(t, []string{"status: resolved -> closed", "tag added: handover"}, results[1].Changes)
+		require.NotNil(t, results[1].After)
+		assert.Equal(t, "resolved", results[1].Before.Status)
+		assert.Equal(t, "closed", results[1].After.Status)
+	})
+
+	t.Run("skips tickets it cannot change", func(t *testing.T) {
+		mock.ExpectBegin()
+		// IT staff member 7 may not touch a ticket assigned to someone else
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(6)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(6, "TCK-2025-0006", "VPN", "open", "normal", 0, creator, int64(8)))
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns))
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(4, "TCK-2025-0004", "Parent", "in_progress", "normal", 50, creator, techA))
+		mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM ticket_links`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectCommit()
+
+		results, err := model.BulkUpdate([]int64{4, 5, 6}, TicketBulkChanges{Status: "closed"}, techA, 2, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		assert.Equal(t, "failed", results[0].Result)
+		assert.Equal(t, "ticket has 2 open child tickets", results[0].Error)
+		assert.Equal(t, "not_found", results[1].Result)
+		assert.Equal(t, "forbidden", results[2].Result)
+		assert.Empty(t, results[2].TicketNum)
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		newAssignee := int64(4)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(11)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(11, "TCK-2025-0011", "Laptop", "open", "high", 0, int64(2), techA))
+		mock.ExpectQuery(`FROM tickets WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows(ticketBulkTestColumns).AddRow(10, "TCK-2025-0010", "Phone", "open", "normal", 0, creator, techA))
+		mock.ExpectExec(`UPDATE tickets`).
+			WithArgs("open", "normal", 0, &newAssignee, int64(10)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		// Staff member 3 created ticket 10 but not ticket 11
+		results, err := model.BulkUpdate([]int64{10, 11}, TicketBulkChanges{AssignedTo: &newAssignee}, creator, 3, true)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, "rolled_back", results[0].Result)
+		assert.Nil(t, results[0].After)
+		assert.Equal(t, "forbidden", results[1].Result)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, name, is_active FROM ticket_tags`).
+			WithArgs("nope").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active"}))
+		mock.ExpectRollback()
+
+		_, err := model.BulkUpdate([]int64{1}, TicketBulkChanges{RemoveTags: []string{"nope"}}, 1, 1, false)
+		var tagErr *TicketTagError
+		assert.ErrorAs(t, err, &tagErr)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
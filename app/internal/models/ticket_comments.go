@@ -29,13 +29,22 @@ func NewTicketCommentModel(db *sql.DB) *TicketCommentModel {
 
 // Insert a new ticket comment
 func (m *TicketCommentModel) Insert(comment *TicketComment) error {
+	return insertTicketComment(m.DB, comment)
+}
+
+// InsertTx inserts a ticket comment as part of the caller's transaction
+func (m *TicketCommentModel) InsertTx(tx *sql.Tx, comment *TicketComment) error {
+	return insertTicketComment(tx, comment)
+}
+
+func insertTicketComment(db dbtx, comment *TicketComment) error {
 	query := `
 		INSERT INTO ticket_comments (ticket_id, author_id, comment, is_internal)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at
 	`
 	
-	err := m.DB.QueryRow(
+	err := db.QueryRow(
 		query,
 		comment.TicketID,
 		comment.AuthorID,
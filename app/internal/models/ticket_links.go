@@ -0,0 +1,367 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TicketLink is a relationship between two tickets, seen from one of them.
+// Links are stored one way (duplicates, blocks, relates_to, parent_of) and
+// Relation gives the inverse when the ticket is on the receiving end.
+type TicketLink struct {
+	ID        int64     `json:"id"`
+	Relation  string    `json:"relation"` // duplicates, duplicated_by, blocks, blocked_by, relates_to, parent_of, child_of
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// The ticket on the other end
+	TicketID  int64  `json:"ticket_id"`
+	TicketNum string `json:"ticket_num"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+}
+
+// TicketRelationError is a link, merge or close that the ticket relationships
+// do not allow, as opposed to a database error
+type TicketRelationError struct {
+	Message string
+}
+
+func (e *TicketRelationError) Error() string {
+	return e.Message
+}
+
+// ticketRelations maps each relation a client can ask for onto the stored
+// link type, and whether the link is stored from the other ticket
+var ticketRelations = map[string]struct {
+	linkType string
+	reversed bool
+}{
+	"duplicates":    {"duplicates", false},
+	"duplicated_by": {"duplicates", true},
+	"blocks":        {"blocks", false},
+	"blocked_by":    {"blocks", true},
+	"relates_to":    {"relates_to", false},
+	"parent_of":     {"parent_of", false},
+	"child_of":      {"parent_of", true},
+}
+
+// inverseTicketRelations names a stored link type from the receiving ticket
+var inverseTicketRelations = map[string]string{
+	"duplicates": "duplicated_by",
+	"blocks":     "blocked_by",
+	"relates_to": "relates_to",
+	"parent_of":  "child_of",
+}
+
+// IsValidTicketRelation reports whether relation can be used to link tickets
+func IsValidTicketRelation(relation string) bool {
+	_, ok := ticketRelations[relation]
+	return ok
+}
+
+const openChildrenQuery = `
+		SELECT COUNT(*)
+		FROM ticket_links l
+		JOIN tickets c ON c.id = l.linked_ticket_id
+		WHERE l.ticket_id = $1 AND l.link_type = 'parent_of' AND c.status <> 'closed'`
+
+// checkCanClose refuses to close a parent ticket while any child is open
+func checkCanClose(db dbtx, ticketID int64) error {
+	var open int
+	if err := db.QueryRow(openChildrenQuery, ticketID).Scan(&open); err != nil {
+		return err
+	}
+	if open > 0 {
+		return &TicketRelationError{Message: fmt.Sprintf("ticket has %d open child tickets", open)}
+	}
+	return nil
+}
+
+// AddLink relates one ticket to another and returns the new link's ID
+func (m *TicketModel) AddLink(ticketID, otherID int64, relation string, createdBy *int64) (int64, error) {
+	rel, ok := ticketRelations[relation]
+	if !ok {
+		return 0, &TicketRelationError{Message: "unknown relation " + relation}
+	}
+	if ticketID == otherID {
+		return 0, &TicketRelationError{Message: "a ticket cannot be linked to itself"}
+	}
+
+	var exists bool
+	err := m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1)", ticketID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errors.New("ticket not found")
+	}
+	err = m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1)", otherID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, &TicketRelationError{Message: "linked ticket not found"}
+	}
+
+	from, to := ticketID, otherID
+	if rel.reversed {
+		from, to = otherID, ticketID
+	}
+	// relates_to works both ways, so keep one row per pair
+	if rel.linkType == "relates_to" && from > to {
+		from, to = to, from
+	}
+
+	switch rel.linkType {
+	case "duplicates":
+		err = m.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM ticket_links WHERE ticket_id = $1 AND link_type = 'duplicates')
+		`, from).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, &TicketRelationError{Message: "ticket is already marked as a duplicate"}
+		}
+	case "blocks":
+		err = m.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM ticket_links WHERE ticket_id = $1 AND linked_ticket_id = $2 AND link_type = 'blocks')
+		`, to, from).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, &TicketRelationError{Message: "tickets cannot block each other"}
+		}
+	case "parent_of":
+		err = m.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM ticket_links WHERE linked_ticket_id = $1 AND link_type = 'parent_of')
+		`, to).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, &TicketRelationError{Message: "ticket already has a parent"}
+		}
+
+		// The child must not be the parent's own parent, grandparent, ...
+		err = m.DB.QueryRow(`
+			WITH RECURSIVE ancestors AS (
+				SELECT ticket_id FROM ticket_links
+				WHERE linked_ticket_id = $1 AND link_type = 'parent_of'
+				UNION
+				SELECT l.ticket_id FROM ticket_links l
+				JOIN ancestors a ON l.linked_ticket_id = a.ticket_id
+				WHERE l.link_type = 'parent_of'
+			)
+			SELECT EXISTS(SELECT 1 FROM ancestors WHERE ticket_id = $2)
+		`, from, to).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, &TicketRelationError{Message: "linking these tickets would create a parent/child loop"}
+		}
+	}
+
+	var id int64
+	err = m.DB.QueryRow(`
+		INSERT INTO ticket_links (ticket_id, linked_ticket_id, link_type, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ticket_id, linked_ticket_id, link_type) DO NOTHING
+		RETURNING id
+	`, from, to, rel.linkType, createdBy).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, &TicketRelationError{Message: "tickets are already linked this way"}
+	}
+	return id, err
+}
+
+// RemoveLink deletes a link that involves ticketID
+func (m *TicketModel) RemoveLink(ticketID, linkID int64) error {
+	result, err := m.DB.Exec(`
+		DELETE FROM ticket_links
+		WHERE id = $1 AND (ticket_id = $2 OR linked_ticket_id = $2)
+	`, linkID, ticketID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("link not found")
+	}
+	return nil
+}
+
+// GetLinks lists every relationship of a ticket from its point of view
+func (m *TicketModel) GetLinks(ticketID int64) ([]TicketLink, error) {
+	rows, err := m.DB.Query(`
+		SELECT l.id, l.link_type, l.ticket_id = $1, l.created_by, l.created_at,
+			o.id, o.ticket_num, o.title, o.status
+		FROM ticket_links l
+		JOIN tickets o ON o.id = CASE WHEN l.ticket_id = $1 THEN l.linked_ticket_id ELSE l.ticket_id END
+		WHERE l.ticket_id = $1 OR l.linked_ticket_id = $1
+		ORDER BY l.created_at, l.id
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []TicketLink{}
+	for rows.Next() {
+		var link TicketLink
+		var linkType string
+		var outgoing bool
+		var createdBy sql.NullInt64
+		err := rows.Scan(
+			&link.ID, &linkType, &outgoing, &createdBy, &link.CreatedAt,
+			&link.TicketID, &link.TicketNum, &link.Title, &link.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		link.Relation = linkType
+		if !outgoing {
+			link.Relation = inverseTicketRelations[linkType]
+		}
+		if createdBy.Valid {
+			link.CreatedBy = &createdBy.Int64
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// Merge folds duplicate tickets into a primary one. Comments and watchers move
+// to the primary, the duplicates' creators start watching it, and each
+// duplicate is closed with a comment pointing at the primary.
+func (m *TicketModel) Merge(primaryID int64, duplicateIDs []int64, mergedBy *int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var primaryNum, primaryStatus string
+	err = tx.QueryRow(
+		"SELECT ticket_num, status FROM tickets WHERE id = $1 FOR UPDATE", primaryID,
+	).Scan(&primaryNum, &primaryStatus)
+	if err == sql.ErrNoRows {
+		return errors.New("ticket not found")
+	} else if err != nil {
+		return err
+	}
+	if primaryStatus == "closed" {
+		return &TicketRelationError{Message: "cannot merge into a closed ticket"}
+	}
+
+	var merged []string
+	seen := map[int64]bool{}
+	for _, dupID := range duplicateIDs {
+		if seen[dupID] {
+			continue
+		}
+		seen[dupID] = true
+		if dupID == primaryID {
+			return &TicketRelationError{Message: "a ticket cannot be merged into itself"}
+		}
+
+		var dupNum, dupStatus string
+		err = tx.QueryRow(
+			"SELECT ticket_num, status FROM tickets WHERE id = $1 FOR UPDATE", dupID,
+		).Scan(&dupNum, &dupStatus)
+		if err == sql.ErrNoRows {
+			return &TicketRelationError{Message: fmt.Sprintf("ticket %d not found", dupID)}
+		} else if err != nil {
+			return err
+		}
+		if dupStatus == "closed" {
+			return &TicketRelationError{Message: fmt.Sprintf("ticket %s is already closed", dupNum)}
+		}
+
+		var openChildren int
+		if err := tx.QueryRow(openChildrenQuery, dupID).Scan(&openChildren); err != nil {
+			return err
+		}
+		if openChildren > 0 {
+			return &TicketRelationError{Message: fmt.Sprintf("ticket %s has open child tickets", dupNum)}
+		}
+
+		if _, err := tx.Exec(
+			"UPDATE ticket_comments SET ticket_id = $1 WHERE ticket_id = $2", primaryID, dupID,
+		); err != nil {
+			return err
+		}
+
+		// Whoever followed the duplicate, and whoever raised it, now follows the primary
+		if _, err := tx.Exec(`
+			INSERT INTO ticket_watchers (ticket_id, user_id, source, added_by)
+			SELECT $1, user_id, source, added_by FROM ticket_watchers WHERE ticket_id = $2
+			ON CONFLICT (ticket_id, user_id) DO NOTHING
+		`, primaryID, dupID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO ticket_watchers (ticket_id, user_id, source, added_by)
+			SELECT $1, created_by, 'merge', $3 FROM tickets WHERE id = $2 AND created_by IS NOT NULL
+			ON CONFLICT (ticket_id, user_id) DO NOTHING
+		`, primaryID, dupID, mergedBy); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM ticket_watchers WHERE ticket_id = $1", dupID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			"DELETE FROM ticket_links WHERE ticket_id = $1 AND link_type = 'duplicates'", dupID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO ticket_links (ticket_id, linked_ticket_id, link_type, created_by)
+			VALUES ($1, $2, 'duplicates', $3)
+		`, dupID, primaryID, mergedBy); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE tickets
+			SET status = 'closed', completion = 100, closed_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+		`, dupID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO ticket_comments (ticket_id, author_id, comment, is_internal)
+			VALUES ($1, $2, $3, false)
+		`, dupID, mergedBy, fmt.Sprintf("Closed as a duplicate of %s. Follow that ticket for updates.", primaryNum)); err != nil {
+			return err
+		}
+
+		merged = append(merged, dupNum)
+	}
+
+	if len(merged) == 0 {
+		return &TicketRelationError{Message: "no tickets to merge"}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO ticket_comments (ticket_id, author_id, comment, is_internal)
+		VALUES ($1, $2, $3, false)
+	`, primaryID, mergedBy, "Merged duplicate tickets: "+strings.Join(merged, ", ")); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE tickets SET updated_at = NOW() WHERE id = $1", primaryID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,173 @@
+// file: app/internal/models/ticket_links_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ticketLinkColumns = []string{
+	"id", "link_type", "outgoing", "created_by", "created_at",
+	"ticket_id", "ticket_num", "title", "status",
+}
+
+func TestTicketModel_AddLink(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	userID := int64(3)
+
+	t.Run("child_of is stored from the parent", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`WHERE linked_ticket_id = \$1 AND link_type = 'parent_of'`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`WITH RECURSIVE ancestors`).
+			WithArgs(int64(9), int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO ticket_links`).
+			WithArgs(int64(9), int64(12), "parent_of", &userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+		id, err := model.AddLink(12, 9, "child_of", &userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), id)
+	})
+
+	t.Run("parent/child loop", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`WHERE linked_ticket_id = \$1 AND link_type = 'parent_of'`).
+			WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`WITH RECURSIVE ancestors`).
+			WithArgs(int64(12), int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		_, err := model.AddLink(12, 9, "parent_of", &userID)
+		require.Error(t, err)
+		assert.IsType(t, &TicketRelationError{}, err)
+	})
+
+	t.Run("unknown relation", func(t *testing.T) {
+		_, err := model.AddLink(12, 9, "sibling_of", &userID)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketModel_UpdateStatus_OpenChildren(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	err := model.UpdateStatus(9, "closed", 100, nil)
+	require.Error(t, err)
+	assert.IsType(t, &TicketRelationError{}, err)
+	assert.Equal(t, "ticket has 2 open child tickets", err.Error())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketModel_UpdateStatusTx_OpenChildren(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	tx, err := model.DB.Begin()
+	require.NoError(t, err)
+	err = model.UpdateStatusTx(tx, 9, "closed", 100, nil)
+	assert.IsType(t, &TicketRelationError{}, err)
+	require.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketModel_Merge(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	userID := int64(3)
+
+	t.Run("duplicate folded into primary", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT ticket_num, status FROM tickets`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"ticket_num", "status"}).AddRow("TCK-2025-0001", "open"))
+		mock.ExpectQuery(`SELECT ticket_num, status FROM tickets`).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"ticket_num", "status"}).AddRow("TCK-2025-0002", "received"))
+		mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(`UPDATE ticket_comments SET ticket_id`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`INSERT INTO ticket_watchers`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO ticket_watchers`).
+			WithArgs(int64(1), int64(2), &userID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM ticket_watchers`).
+			WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM ticket_links`).
+			WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO ticket_links`).
+			WithArgs(int64(2), int64(1), &userID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`SET status = 'closed'`).
+			WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO ticket_comments`).
+			WithArgs(int64(2), &userID, "Closed as a duplicate of TCK-2025-0001. Follow that ticket for updates.").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO ticket_comments`).
+			WithArgs(int64(1), &userID, "Merged duplicate tickets: TCK-2025-0002").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`UPDATE tickets SET updated_at`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Merge(1, []int64{2, 2}, &userID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("primary closed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT ticket_num, status FROM tickets`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"ticket_num", "status"}).AddRow("TCK-2025-0001", "closed"))
+		mock.ExpectRollback()
+
+		err := model.Merge(1, []int64{2}, &userID)
+		require.Error(t, err)
+		assert.Equal(t, "cannot merge into a closed ticket", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
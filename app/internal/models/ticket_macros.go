@@ -0,0 +1,249 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TicketMacro is a canned response. Applying it to a ticket posts the
+// rendered body as a comment and optionally sets status and priority.
+type TicketMacro struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	IsInternal  bool      `json:"is_internal"`
+	SetStatus   string    `json:"set_status"`   // Empty leaves the status alone
+	SetPriority string    `json:"set_priority"` // Empty leaves the priority alone
+	IsShared    bool      `json:"is_shared"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Joined fields
+	OwnerName string `json:"owner_name,omitempty"`
+}
+
+// TicketMacroError is a macro the client got wrong, e.g. an unknown placeholder
+type TicketMacroError struct {
+	Message string
+}
+
+func (e *TicketMacroError) Error() string {
+	return e.Message
+}
+
+// MacroPlaceholders are the {{placeholders}} a macro body can use
+var MacroPlaceholders = []string{
+	"ticket_num", "ticket_title", "requester_name", "assignee_name", "asset_internal_id",
+}
+
+// Statuses and priorities a macro can set
+var (
+	macroStatuses   = map[string]bool{"open": true, "received": true, "in_progress": true, "resolved": true, "closed": true}
+	macroPriorities = map[string]bool{"low": true, "normal": true, "high": true, "critical": true}
+)
+
+var macroPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// validateTicketMacro checks a macro before it is saved
+func validateTicketMacro(m *TicketMacro) error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Body = strings.TrimSpace(m.Body)
+	if m.Name == "" {
+		return &TicketMacroError{Message: "macro name is required"}
+	}
+	if m.Body == "" {
+		return &TicketMacroError{Message: "macro body is required"}
+	}
+
+	known := map[string]bool{}
+	for _, p := range MacroPlaceholders {
+		known[p] = true
+	}
+	for _, match := range macroPlaceholderPattern.FindAllStringSubmatch(m.Body, -1) {
+		if !known[match[1]] {
+			return &TicketMacroError{Message: fmt.Sprintf("unknown placeholder {{%s}}", match[1])}
+		}
+	}
+
+	if m.SetStatus != "" && !macroStatuses[m.SetStatus] {
+		return &TicketMacroError{Message: fmt.Sprintf("invalid set_status %q", m.SetStatus)}
+	}
+	if m.SetPriority != "" && !macroPriorities[m.SetPriority] {
+		return &TicketMacroError{Message: fmt.Sprintf("invalid set_priority %q", m.SetPriority)}
+	}
+	return nil
+}
+
+// RenderMacro fills a macro body's placeholders from a ticket loaded with
+// GetByID. Values the ticket doesn't have render as empty text.
+func RenderMacro(body string, ticket *Ticket) string {
+	values := map[string]string{
+		"ticket_num":   ticket.TicketNum,
+		"ticket_title": ticket.Title,
+	}
+	if u := ticket.CreatedByUser; u != nil {
+		values["requester_name"] = u.FullName
+		if values["requester_name"] == "" {
+			values["requester_name"] = u.Username
+		}
+	}
+	if u := ticket.AssignedToUser; u != nil {
+		values["assignee_name"] = u.FullName
+		if values["assignee_name"] == "" {
+			values["assignee_name"] = u.Username
+		}
+	}
+	if ticket.Asset != nil {
+		values["asset_internal_id"] = ticket.Asset.InternalID
+	}
+
+	return macroPlaceholderPattern.ReplaceAllStringFunc(body, func(match string) string {
+		name := macroPlaceholderPattern.FindStringSubmatch(match)[1]
+		return values[name]
+	})
+}
+
+type TicketMacroModel struct {
+	DB *sql.DB
+}
+
+func NewTicketMacroModel(db *sql.DB) *TicketMacroModel {
+	return &TicketMacroModel{DB: db}
+}
+
+const ticketMacroColumns = `
+			m.id, m.user_id, m.name, m.body, m.is_internal, m.set_status, m.set_priority,
+			m.is_shared, m.created_at, m.updated_at, COALESCE(u.full_name, u.username)`
+
+func scanTicketMacro(row rowScanner) (*TicketMacro, error) {
+	var m TicketMacro
+	err := row.Scan(
+		&m.ID, &m.UserID, &m.Name, &m.Body, &m.IsInternal, &m.SetStatus, &m.SetPriority,
+		&m.IsShared, &m.CreatedAt, &m.UpdatedAt, &m.OwnerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Insert saves a new macro for its owner
+func (m *TicketMacroModel) Insert(macro *TicketMacro) error {
+	if err := validateTicketMacro(macro); err != nil {
+		return err
+	}
+
+	err := m.DB.QueryRow(`
+		INSERT INTO ticket_macros (user_id, name, body, is_internal, set_status, set_priority, is_shared)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`, macro.UserID, macro.Name, macro.Body, macro.IsInternal, macro.SetStatus, macro.SetPriority, macro.IsShared,
+	).Scan(&macro.ID, &macro.CreatedAt, &macro.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("a macro with this name already exists")
+	}
+	return err
+}
+
+// Update changes a macro. Owners edit their own macros; admins can edit any.
+func (m *TicketMacroModel) Update(macro *TicketMacro, userID int64, isAdmin bool) error {
+	if err := validateTicketMacro(macro); err != nil {
+		return err
+	}
+
+	err := m.DB.QueryRow(`
+		UPDATE ticket_macros
+		SET name = $1, body = $2, is_internal = $3, set_status = $4, set_priority = $5,
+			is_shared = $6, updated_at = NOW()
+		WHERE id = $7 AND (user_id = $8 OR $9)
+		  AND NOT EXISTS (
+			SELECT 1 FROM ticket_macros d
+			WHERE d.user_id = ticket_macros.user_id AND d.name = $1 AND d.id <> $7)
+		RETURNING user_id, created_at, updated_at
+	`, macro.Name, macro.Body, macro.IsInternal, macro.SetStatus, macro.SetPriority,
+		macro.IsShared, macro.ID, userID, isAdmin,
+	).Scan(&macro.UserID, &macro.CreatedAt, &macro.UpdatedAt)
+	if err == sql.ErrNoRows {
+		// Tell a name clash apart from a macro the user cannot edit
+		var clash bool
+		err := m.DB.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM ticket_macros o
+				JOIN ticket_macros d ON d.user_id = o.user_id AND d.name = $1 AND d.id <> o.id
+				WHERE o.id = $2 AND (o.user_id = $3 OR $4))
+		`, macro.Name, macro.ID, userID, isAdmin).Scan(&clash)
+		if err != nil {
+			return err
+		}
+		if clash {
+			return errors.New("a macro with this name already exists")
+		}
+		return errors.New("macro not found")
+	}
+	return err
+}
+
+// Delete removes a macro. Owners delete their own macros; admins can delete any.
+func (m *TicketMacroModel) Delete(id, userID int64, isAdmin bool) error {
+	result, err := m.DB.Exec(
+		"DELETE FROM ticket_macros WHERE id = $1 AND (user_id = $2 OR $3)",
+		id, userID, isAdmin,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("macro not found")
+	}
+	return nil
+}
+
+// GetVisible lists the user's own macros followed by shared ones
+func (m *TicketMacroModel) GetVisible(userID int64) ([]TicketMacro, error) {
+	rows, err := m.DB.Query(`
+		SELECT`+ticketMacroColumns+`
+		FROM ticket_macros m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1 OR m.is_shared
+		ORDER BY m.user_id <> $1, m.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	macros := []TicketMacro{}
+	for rows.Next() {
+		macro, err := scanTicketMacro(rows)
+		if err != nil {
+			return nil, err
+		}
+		macros = append(macros, *macro)
+	}
+
+	return macros, rows.Err()
+}
+
+// GetByID returns a macro the user owns or that is shared
+func (m *TicketMacroModel) GetByID(id, userID int64) (*TicketMacro, error) {
+	row := m.DB.QueryRow(`
+		SELECT`+ticketMacroColumns+`
+		FROM ticket_macros m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.id = $1 AND (m.user_id = $2 OR m.is_shared)
+	`, id, userID)
+
+	macro, err := scanTicketMacro(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("macro not found")
+	}
+	return macro, err
+}
@@ -0,0 +1,150 @@
+// file: app/internal/models/ticket_macros_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTicketMacroTest(t *testing.T) (*TicketMacroModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTicketMacroModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+var ticketMacroTestColumns = []string{
+	"id", "user_id", "name", "body", "is_internal", "set_status", "set_priority",
+	"is_shared", "created_at", "updated_at", "owner_name",
+}
+
+func TestRenderMacro(t *testing.T) {
+	ticket := &Ticket{
+		TicketNum:      "TCK-2025-0001",
+		Title:          "Headset broken",
+		CreatedByUser:  &User{Username: "jdoe", FullName: "Jane Doe"},
+		AssignedToUser: &User{Username: "itstaff"},
+		Asset:          &Asset{InternalID: "DPA-PC001"},
+	}
+
+	body := "Hi {{requester_name}}, {{ticket_num}} ({{ticket_title}}) on {{ asset_internal_id }} is with {{assignee_name}}."
+	assert.Equal(t,
+		"Hi Jane Doe, TCK-2025-0001 (Headset broken) on DPA-PC001 is with itstaff.",
+		RenderMacro(body, ticket))
+
+	// Missing values render empty
+	assert.Equal(t, "Asset: ", RenderMacro("Asset: {{asset_internal_id}}", &Ticket{}))
+}
+
+func TestTicketMacroModel_Insert(t *testing.T) {
+	model, mock, teardown := setupTicketMacroTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		macro := &TicketMacro{UserID: 2, Name: " Resolved ", Body: "Hi {{requester_name}}", SetStatus: "resolved", IsShared: true}
+		mock.ExpectQuery(`INSERT INTO ticket_macros`).
+			WithArgs(int64(2), "Resolved", "Hi {{requester_name}}", false, "resolved", "", true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(5, now, now))
+
+		require.NoError(t, model.Insert(macro))
+		assert.Equal(t, int64(5), macro.ID)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		macro := &TicketMacro{UserID: 2, Name: "Resolved", Body: "Done"}
+		mock.ExpectQuery(`INSERT INTO ticket_macros`).
+			WillReturnError(sql.ErrNoRows)
+
+		err := model.Insert(macro)
+		assert.EqualError(t, err, "a macro with this name already exists")
+	})
+
+	t.Run("unknown placeholder", func(t *testing.T) {
+		err := model.Insert(&TicketMacro{UserID: 2, Name: "Bad", Body: "Hi {{first_name}}"})
+		var macroErr *TicketMacroError
+		require.ErrorAs(t, err, &macroErr)
+		assert.Equal(t, "unknown placeholder {{first_name}}", macroErr.Message)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		err := model.Insert(&TicketMacro{UserID: 2, Name: "Bad", Body: "Done", SetStatus: "done"})
+		var macroErr *TicketMacroError
+		require.ErrorAs(t, err, &macroErr)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketMacroModel_Update(t *testing.T) {
+	model, mock, teardown := setupTicketMacroTest(t)
+	defer teardown()
+
+	t.Run("not the owner", func(t *testing.T) {
+		macro := &TicketMacro{ID: 5, Name: "Resolved", Body: "Done"}
+		mock.ExpectQuery(`UPDATE ticket_macros`).
+			WithArgs("Resolved", "Done", false, "", "", false, int64(5), int64(3), false).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs("Resolved", int64(5), int64(3), false).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := model.Update(macro, 3, false)
+		assert.EqualError(t, err, "macro not found")
+	})
+
+	t.Run("name clash", func(t *testing.T) {
+		macro := &TicketMacro{ID: 5, Name: "Closed", Body: "Done"}
+		mock.ExpectQuery(`UPDATE ticket_macros`).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := model.Update(macro, 2, false)
+		assert.EqualError(t, err, "a macro with this name already exists")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketMacroModel_GetByID(t *testing.T) {
+	model, mock, teardown := setupTicketMacroTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("shared macro", func(t *testing.T) {
+		mock.ExpectQuery(`FROM ticket_macros m`).
+			WithArgs(int64(5), int64(3)).
+			WillReturnRows(sqlmock.NewRows(ticketMacroTestColumns).
+				AddRow(5, 2, "Resolved", "Done", false, "resolved", "", true, now, now, "IT Staff"))
+
+		macro, err := model.GetByID(5, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "resolved", macro.SetStatus)
+		assert.Equal(t, "IT Staff", macro.OwnerName)
+	})
+
+	t.Run("not visible", func(t *testing.T) {
+		mock.ExpectQuery(`FROM ticket_macros m`).
+			WithArgs(int64(6), int64(3)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := model.GetByID(6, 3)
+		assert.EqualError(t, err, "macro not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
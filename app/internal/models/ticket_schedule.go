@@ -0,0 +1,457 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TicketSchedule works out when a recurring ticket is next due
+type TicketSchedule interface {
+	// Next returns the first occurrence strictly after t, in t's location,
+	// or the zero time if there is none within scheduleHorizon
+	Next(t time.Time) time.Time
+}
+
+// scheduleHorizon is how far ahead Next looks before giving up, enough for
+// "29 February" schedules
+const scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+// ParseTicketSchedule parses a cron expression ("0 9 1 * *", "@weekly") or an
+// RRULE ("FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1;BYHOUR=9"). An RRULE counts
+// its INTERVAL from anchor and takes the day and time it doesn't specify
+// from it.
+func ParseTicketSchedule(expr string, anchor time.Time) (TicketSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("schedule is required")
+	}
+
+	upper := strings.ToUpper(expr)
+	if strings.HasPrefix(upper, "RRULE:") || strings.Contains(upper, "FREQ=") {
+		return parseRRule(strings.TrimPrefix(upper, "RRULE:"), anchor)
+	}
+	return parseCron(expr)
+}
+
+// nextDay returns midnight on the day after t
+func nextDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+// --- cron ---
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n set when value n matches
+	domStar, dowStar              bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronDayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// parseCron parses a five-field cron expression: minute hour day-of-month
+// month day-of-week, with *, lists, ranges, steps and month and day names
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("cron schedule needs 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day-of-month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day-of-week: %w", err)
+	}
+
+	// 7 is Sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max // "5/15" runs from 5 to the end
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// dayMatches follows cron: when both day fields are restricted, a day
+// matching either runs
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(scheduleHorizon)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			next = nextDay(t)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+
+		// Daylight saving changes can send a wall-clock jump backwards
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// --- RRULE ---
+
+// rruleDay is a BYDAY entry; n is the weekday's ordinal within the month
+// (1 first, -1 last), or 0 for every one
+type rruleDay struct {
+	weekday time.Weekday
+	n       int
+}
+
+type rruleSchedule struct {
+	freq       string // DAILY, WEEKLY, MONTHLY, YEARLY
+	interval   int
+	byMonth    []int
+	byMonthDay []int
+	byDay      []rruleDay
+	hours      []int
+	minutes    []int
+	anchor     time.Time
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule parses the parts of an RFC 5545 RRULE that recurring tickets
+// need: FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYHOUR and BYMINUTE
+func parseRRule(rule string, anchor time.Time) (*rruleSchedule, error) {
+	r := &rruleSchedule{interval: 1, anchor: anchor}
+
+	for _, part := range strings.Split(strings.TrimSpace(rule), ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key, value := kv[0], kv[1]
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+		case "BYMONTH":
+			r.byMonth, err = rruleInts(value, 1, 12, false)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = rruleInts(value, 1, 31, true)
+		case "BYHOUR":
+			r.hours, err = rruleInts(value, 0, 23, false)
+		case "BYMINUTE":
+			r.minutes, err = rruleInts(value, 0, 59, false)
+		case "BYDAY":
+			r.byDay, err = rruleDays(value)
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, errors.New("RRULE needs a FREQ")
+	}
+	for _, d := range r.byDay {
+		if d.n != 0 && r.freq != "MONTHLY" && r.freq != "YEARLY" {
+			return nil, errors.New("BYDAY ordinals such as 1MO need FREQ=MONTHLY or YEARLY")
+		}
+	}
+
+	// What the rule leaves out comes from the anchor, as in RFC 5545
+	if len(r.hours) == 0 {
+		r.hours = []int{anchor.Hour()}
+	}
+	if len(r.minutes) == 0 {
+		r.minutes = []int{anchor.Minute()}
+	}
+	switch r.freq {
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			r.byDay = []rruleDay{{weekday: anchor.Weekday()}}
+		}
+	case "MONTHLY":
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			r.byMonthDay = []int{anchor.Day()}
+		}
+	case "YEARLY":
+		if len(r.byMonth) == 0 {
+			r.byMonth = []int{int(anchor.Month())}
+		}
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			r.byMonthDay = []int{anchor.Day()}
+		}
+	}
+	sort.Ints(r.hours)
+	sort.Ints(r.minutes)
+
+	return r, nil
+}
+
+// rruleInts parses a comma-separated list; negative values count back from
+// the end when allowNegative is set (-1 is the last day of the month)
+func rruleInts(value string, min, max int, allowNegative bool) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", s)
+		}
+		if allowNegative && v < 0 && -v >= min && -v <= max {
+			values = append(values, v)
+			continue
+		}
+		if v < min || v > max {
+			return nil, fmt.Errorf("%d is out of range %d-%d", v, min, max)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func rruleDays(value string) ([]rruleDay, error) {
+	var days []rruleDay
+	for _, s := range strings.Split(value, ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("invalid day %q", s)
+		}
+		weekday, ok := rruleWeekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", s)
+		}
+		day := rruleDay{weekday: weekday}
+		if ordinal := s[:len(s)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid day %q", s)
+			}
+			day.n = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// civilDays counts days since the epoch for t's calendar date
+func civilDays(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// inInterval reports whether day falls in a period INTERVAL periods on from the anchor's
+func (r *rruleSchedule) inInterval(day time.Time) bool {
+	if r.interval == 1 {
+		return true
+	}
+	switch r.freq {
+	case "DAILY":
+		return (civilDays(day)-civilDays(r.anchor))%r.interval == 0
+	case "WEEKLY":
+		// Weeks start on Monday
+		monday := func(t time.Time) int { return civilDays(t) - (int(t.Weekday())+6)%7 }
+		return (monday(day)-monday(r.anchor))/7%r.interval == 0
+	case "MONTHLY":
+		months := (day.Year()-r.anchor.Year())*12 + int(day.Month()) - int(r.anchor.Month())
+		return months%r.interval == 0
+	default:
+		return (day.Year()-r.anchor.Year())%r.interval == 0
+	}
+}
+
+func (r *rruleSchedule) dayMatches(day time.Time) bool {
+	if !r.inInterval(day) {
+		return false
+	}
+
+	if len(r.byMonth) > 0 && !containsInt(r.byMonth, int(day.Month())) {
+		return false
+	}
+
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.byMonthDay) > 0 {
+		matched := false
+		for _, d := range r.byMonthDay {
+			if d == day.Day() || (d < 0 && daysInMonth+d+1 == day.Day()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.byDay) > 0 {
+		matched := false
+		for _, d := range r.byDay {
+			if d.weekday != day.Weekday() {
+				continue
+			}
+			if d.n == 0 ||
+				(d.n > 0 && (day.Day()-1)/7+1 == d.n) ||
+				(d.n < 0 && (daysInMonth-day.Day())/7+1 == -d.n) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rruleSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	anchor := r.anchor.In(loc)
+	limit := t.Add(scheduleHorizon)
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if start := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, loc); day.Before(start) {
+		day = start
+	}
+
+	for ; day.Before(limit); day = nextDay(day) {
+		if !r.dayMatches(day) {
+			continue
+		}
+		for _, h := range r.hours {
+			for _, m := range r.minutes {
+				occurrence := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc)
+				if occurrence.After(t) && !occurrence.Before(anchor) {
+					return occurrence
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,108 @@
+// file: app/internal/models/ticket_schedule_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utc(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseTicketSchedule_Cron(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"monthly", "0 9 1 * *", utc(2025, 6, 15, 10, 0), utc(2025, 7, 1, 9, 0)},
+		{"step", "*/15 * * * *", utc(2025, 6, 15, 10, 7), utc(2025, 6, 15, 10, 15)},
+		{"exactly on an occurrence", "0 9 1 * *", utc(2025, 7, 1, 9, 0), utc(2025, 8, 1, 9, 0)},
+		{"weekday names", "0 9 * * MON-FRI", utc(2025, 6, 14, 12, 0), utc(2025, 6, 16, 9, 0)},
+		{"day of month or week", "0 0 13 * FRI", utc(2025, 6, 1, 0, 0), utc(2025, 6, 6, 0, 0)},
+		{"sunday as 7", "30 8 * * 7", utc(2025, 6, 11, 0, 0), utc(2025, 6, 15, 8, 30)},
+		{"month names", "0 6 1 JAN,JUL *", utc(2025, 2, 1, 0, 0), utc(2025, 7, 1, 6, 0)},
+		{"macro", "@weekly", utc(2025, 6, 11, 0, 0), utc(2025, 6, 15, 0, 0)},
+		{"leap day", "0 0 29 2 *", utc(2025, 3, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseTicketSchedule(tt.expr, time.Time{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(tt.from))
+		})
+	}
+
+	t.Run("never runs", func(t *testing.T) {
+		schedule, err := ParseTicketSchedule("0 0 31 2 *", time.Time{})
+		require.NoError(t, err)
+		assert.True(t, schedule.Next(utc(2025, 1, 1, 0, 0)).IsZero())
+	})
+
+	t.Run("in a time zone", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		schedule, err := ParseTicketSchedule("0 9 * * *", time.Time{})
+		require.NoError(t, err)
+		next := schedule.Next(utc(2025, 6, 1, 12, 0).In(ny))
+		assert.True(t, next.Equal(utc(2025, 6, 1, 13, 0)), next)
+	})
+
+	for _, expr := range []string{"61 * * * *", "* * *", "0 9 1 * BLUE", "0 9 5-1 * *", "*/0 * * * *"} {
+		t.Run("invalid "+expr, func(t *testing.T) {
+			_, err := ParseTicketSchedule(expr, time.Time{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseTicketSchedule_RRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		anchor time.Time
+		from   time.Time
+		want   time.Time
+	}{
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1",
+			utc(2025, 1, 1, 9, 0), utc(2025, 2, 10, 0, 0), utc(2025, 4, 1, 9, 0)},
+		{"weekly with time", "RRULE:FREQ=WEEKLY;BYDAY=FR;BYHOUR=16;BYMINUTE=0",
+			utc(2025, 6, 2, 8, 0), utc(2025, 6, 6, 17, 0), utc(2025, 6, 13, 16, 0)},
+		{"fortnightly from the anchor", "FREQ=WEEKLY;INTERVAL=2",
+			utc(2025, 6, 2, 9, 0), utc(2025, 6, 2, 9, 0), utc(2025, 6, 16, 9, 0)},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR",
+			utc(2025, 6, 1, 9, 0), utc(2025, 6, 1, 9, 0), utc(2025, 6, 27, 9, 0)},
+		{"first monday", "FREQ=MONTHLY;BYDAY=1MO",
+			utc(2025, 6, 1, 9, 0), utc(2025, 6, 3, 0, 0), utc(2025, 7, 7, 9, 0)},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1",
+			utc(2025, 1, 1, 9, 0), utc(2025, 2, 1, 0, 0), utc(2025, 2, 28, 9, 0)},
+		{"weekdays", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			utc(2025, 6, 2, 7, 30), utc(2025, 6, 13, 8, 0), utc(2025, 6, 16, 7, 30)},
+		{"yearly from the anchor", "FREQ=YEARLY",
+			utc(2025, 3, 15, 10, 0), utc(2025, 3, 15, 10, 0), utc(2026, 3, 15, 10, 0)},
+		{"not before the anchor", "FREQ=DAILY",
+			utc(2025, 9, 1, 9, 0), utc(2025, 6, 1, 0, 0), utc(2025, 9, 1, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseTicketSchedule(tt.rule, tt.anchor)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(tt.from))
+		})
+	}
+
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=DAILY;COUNT=3", "FREQ=WEEKLY;BYDAY=1MO", "INTERVAL=2", "FREQ=MONTHLY;BYMONTHDAY=32"} {
+		t.Run("invalid "+rule, func(t *testing.T) {
+			_, err := ParseTicketSchedule(rule, utc(2025, 1, 1, 9, 0))
+			assert.Error(t, err)
+		})
+	}
+}
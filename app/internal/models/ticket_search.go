@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// TicketSearchResult is a ticket matched by full-text search, with the
+// matching text highlighted between <mark> tags. Highlights and snippets are
+// HTML-escaped, so they can be shown as HTML.
+type TicketSearchResult struct {
+	ID         int64     `json:"id"`
+	TicketNum  string    `json:"ticket_num"`
+	Title      string    `json:"title"`
+	Type       string    `json:"type"`
+	Priority   string    `json:"priority"`
+	Status     string    `json:"status"`
+	CreatedBy  *int64    `json:"created_by"`
+	AssignedTo *int64    `json:"assigned_to"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Rank       float64   `json:"rank"`
+
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"` // From the description
+	// Best matching comment, when a comment matched
+	CommentID      *int64  `json:"comment_id,omitempty"`
+	CommentSnippet *string `json:"comment_snippet,omitempty"`
+}
+
+// ts_headline does not escape the text it highlights, so it marks matches
+// with placeholders that survive HTML escaping and highlightHTML turns them
+// into <mark> tags once the rest of the text is escaped
+const ticketHeadlineOptions = `'StartSel={{mark}}, StopSel={{/mark}}, MaxWords=35, MinWords=15, MaxFragments=2'`
+
+var headlineMarks = strings.NewReplacer("{{mark}}", "<mark>", "{{/mark}}", "</mark>")
+
+// highlightHTML escapes a ts_headline result and puts in its <mark> tags
+func highlightHTML(headline string) string {
+	return headlineMarks.Replace(html.EscapeString(headline))
+}
+
+// Search finds tickets whose number, title, description or comments match q,
+// best match first. Internal comments are only searched when includeInternal
+// is set. Status, type, priority, assignee, creator and AgentView filters apply
+// as in GetAll. Returns the page of results and the total number of matches.
+func (m *TicketModel) Search(q string, includeInternal bool, filters TicketFilters) ([]TicketSearchResult, int, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return []TicketSearchResult{}, 0, nil
+	}
+
+	from := `
+		FROM tickets t
+		CROSS JOIN search
+		LEFT JOIN LATERAL (
+			SELECT tc.id, tc.comment, ts_rank(tc.search_vector, search.query) AS rank
+			FROM ticket_comments tc
+			WHERE tc.ticket_id = t.id
+			  AND tc.search_vector @@ search.query
+			  AND (tc.is_internal IS NOT TRUE OR $2)
+			ORDER BY rank DESC, tc.created_at DESC
+			LIMIT 1
+		) c ON TRUE
+		WHERE (t.search_vector @@ search.query OR c.id IS NOT NULL)
+	`
+
+	args := []interface{}{q, includeInternal}
+	argPos := 3
+
+	if filters.Status != "" {
+		from += fmt.Sprintf(" AND t.status = $%d", argPos)
+		args = append(args, filters.Status)
+		argPos++
+	}
+
+	if filters.Type != "" {
+		from += fmt.Sprintf(" AND t.type = $%d", argPos)
+		args = append(args, filters.Type)
+		argPos++
+	}
+
+	if filters.Priority != "" {
+		from += fmt.Sprintf(" AND t.priority = $%d", argPos)
+		args = append(args, filters.Priority)
+		argPos++
+	}
+
+	if filters.AssignedTo != nil {
+		from += fmt.Sprintf(" AND t.assigned_to = $%d", argPos)
+		args = append(args, *filters.AssignedTo)
+		argPos++
+	}
+
+	if filters.CreatedBy != nil {
+		from += fmt.Sprintf(" AND t.created_by = $%d", argPos)
+		args = append(args, *filters.CreatedBy)
+		argPos++
+	}
+
+	// For agents: can only see tickets they created
+	if filters.AgentView != nil {
+		from += fmt.Sprintf(" AND t.created_by = $%d", argPos)
+		args = append(args, *filters.AgentView)
+		argPos++
+	}
+
+	var total int
+	countQuery := `WITH search AS (SELECT websearch_to_tsquery('english', $1) AS query) SELECT COUNT(*)` + from
+	if err := m.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		WITH search AS (SELECT websearch_to_tsquery('english', $1) AS query)
+		SELECT
+			t.id, t.ticket_num, t.title, t.type, t.priority, t.status,
+			t.created_by, t.assigned_to, t.created_at, t.updated_at,
+			ts_rank(t.search_vector, search.query) + COALESCE(c.rank, 0) AS rank,
+			ts_headline('english', t.title, search.query, ` + ticketHeadlineOptions + `),
+			ts_headline('english', COALESCE(t.description, ''), search.query, ` + ticketHeadlineOptions + `),
+			c.id,
+			CASE WHEN c.id IS NOT NULL
+				THEN ts_headline('english', c.comment, search.query, ` + ticketHeadlineOptions + `)
+			END` + from + `
+		ORDER BY rank DESC, t.created_at DESC`
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filters.Limit)
+		argPos++
+
+		if filters.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET $%d", argPos)
+			args = append(args, filters.Offset)
+		}
+	}
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []TicketSearchResult{}
+	for rows.Next() {
+		var res TicketSearchResult
+		var createdBy, assignedTo, commentID sql.NullInt64
+		var commentSnippet sql.NullString
+		err := rows.Scan(
+			&res.ID, &res.TicketNum, &res.Title, &res.Type, &res.Priority, &res.Status,
+			&createdBy, &assignedTo, &res.CreatedAt, &res.UpdatedAt,
+			&res.Rank, &res.TitleHighlight, &res.Snippet,
+			&commentID, &commentSnippet,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		if createdBy.Valid {
+			res.CreatedBy = &createdBy.Int64
+		}
+		if assignedTo.Valid {
+			res.AssignedTo = &assignedTo.Int64
+		}
+		res.TitleHighlight = highlightHTML(res.TitleHighlight)
+		res.Snippet = highlightHTML(res.Snippet)
+		if commentID.Valid {
+			snippet := highlightHTML(commentSnippet.String)
+			res.CommentID = &commentID.Int64
+			res.CommentSnippet = &snippet
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
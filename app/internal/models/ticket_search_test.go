@@ -0,0 +1,89 @@
+// file: app/internal/models/ticket_search_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketModel_Search(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	now := time.Now()
+	columns := []string{
+		"id", "ticket_num", "title", "type", "priority", "status",
+		"created_by", "assigned_to", "created_at", "updated_at",
+		"rank", "title_highlight", "snippet", "comment_id", "comment_snippet",
+	}
+
+	t.Run("agent sees own tickets only", func(t *testing.T) {
+		agentID := int64(12)
+		mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM tickets t.*AND t.created_by = \$3\s*$`).
+			WithArgs("headset", false, agentID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+		mock.ExpectQuery(`websearch_to_tsquery.*tc.is_internal IS NOT TRUE OR \$2.*AND t.created_by = \$3\s+ORDER BY rank DESC, t.created_at DESC LIMIT \$4 OFFSET \$5`).
+			WithArgs("headset", false, agentID, 20, 20).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(5, "TCK-2025-0005", "Headset broken", "it_help", "normal", "open",
+					12, nil, now, now, 0.6, "{{mark}}Headset{{/mark}} broken", "Left ear is dead", nil, nil).
+				AddRow(9, "TCK-2025-0009", "Audio issue", "it_help", "high", "open",
+					12, 3, now, now, 0.2, "Audio issue", "", 40, "Swapped the {{mark}}headset{{/mark}}"))
+
+		results, total, err := model.Search(" headset ", false, TicketFilters{AgentView: &agentID, Limit: 20, Offset: 20})
+		require.NoError(t, err)
+		assert.Equal(t, 21, total)
+		require.Len(t, results, 2)
+		assert.Equal(t, "<mark>Headset</mark> broken", results[0].TitleHighlight)
+		assert.Nil(t, results[0].CommentID)
+		assert.Nil(t, results[0].AssignedTo)
+		require.NotNil(t, results[1].CommentSnippet)
+		assert.Equal(t, "Swapped the <mark>headset</mark>", *results[1].CommentSnippet)
+	})
+
+	t.Run("highlighted text is escaped", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT`).
+			WithArgs("printer", true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`websearch_to_tsquery`).
+			WithArgs("printer", true).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, "TCK-2025-0007", "<b>Printer</b>", "it_help", "normal", "open",
+					12, nil, now, now, 0.5, "<b>{{mark}}Printer{{/mark}}</b>",
+					`<img src=x onerror="alert(1)"> {{mark}}printer{{/mark}} jam`, nil, nil))
+
+		results, total, err := model.Search("printer", true, TicketFilters{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, total)
+		require.Len(t, results, 1)
+		assert.Equal(t, "&lt;b&gt;<mark>Printer</mark>&lt;/b&gt;", results[0].TitleHighlight)
+		assert.Equal(t, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>printer</mark> jam", results[0].Snippet)
+	})
+
+	t.Run("page past the last match keeps the total", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT`).
+			WithArgs("printer", true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(`websearch_to_tsquery`).
+			WithArgs("printer", true, 20, 40).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		results, total, err := model.Search("printer", true, TicketFilters{Limit: 20, Offset: 40})
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Empty(t, results)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		results, total, err := model.Search("   ", true, TicketFilters{})
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, results)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,262 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TicketTag is a label from the admin-curated list that can be put on tickets
+type TicketTag struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"` // Retired tags stay on tickets but cannot be added
+	CreatedAt   time.Time `json:"created_at"`
+	TicketCount int       `json:"ticket_count"`
+}
+
+// TicketTagError is an invalid tag, or one that cannot be put on a ticket, as
+// opposed to a database error
+type TicketTagError struct {
+	Message string
+}
+
+func (e *TicketTagError) Error() string {
+	return e.Message
+}
+
+type TicketTagModel struct {
+	DB *sql.DB
+}
+
+func NewTicketTagModel(db *sql.DB) *TicketTagModel {
+	return &TicketTagModel{DB: db}
+}
+
+var (
+	tagNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9 _-]{0,49}$`)
+	tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// NormalizeTagName trims and lower-cases a tag name
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateTag checks a tag's name and colour
+func ValidateTag(tag *TicketTag) error {
+	tag.Name = NormalizeTagName(tag.Name)
+	if !tagNamePattern.MatchString(tag.Name) {
+		return &TicketTagError{Message: "tag name must be 1-50 letters, digits, spaces, dashes or underscores"}
+	}
+	if tag.Color != "" && !tagColorPattern.MatchString(tag.Color) {
+		return &TicketTagError{Message: "color must be a hex colour like #d73a4a"}
+	}
+	return nil
+}
+
+// ticketTagsColumn selects a ticket's tag names as a JSON array
+const ticketTagsColumn = `
+			COALESCE((
+				SELECT json_agg(tg.name ORDER BY tg.name)
+				FROM ticket_tag_assignments ta
+				JOIN ticket_tags tg ON tg.id = ta.tag_id
+				WHERE ta.ticket_id = t.id
+			), '[]')`
+
+// GetAll lists the tag list with how many tickets carry each tag
+func (m *TicketTagModel) GetAll(includeInactive bool) ([]TicketTag, error) {
+	query := `
+		SELECT tg.id, tg.name, tg.color, tg.description, tg.is_active, tg.created_at,
+			(SELECT COUNT(*) FROM ticket_tag_assignments ta WHERE ta.tag_id = tg.id)
+		FROM ticket_tags tg
+	`
+	if !includeInactive {
+		query += " WHERE tg.is_active = true"
+	}
+	query += " ORDER BY tg.name"
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TicketTag{}
+	for rows.Next() {
+		var tag TicketTag
+		err := rows.Scan(
+			&tag.ID, &tag.Name, &tag.Color, &tag.Description, &tag.IsActive, &tag.CreatedAt,
+			&tag.TicketCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// Insert adds a tag to the list
+func (m *TicketTagModel) Insert(tag *TicketTag) error {
+	if err := ValidateTag(tag); err != nil {
+		return err
+	}
+
+	err := m.DB.QueryRow(`
+		INSERT INTO ticket_tags (name, color, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, is_active, created_at
+	`, tag.Name, tag.Color, tag.Description).Scan(&tag.ID, &tag.IsActive, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("tag already exists")
+	}
+	return err
+}
+
+// Update renames, recolours or retires a tag
+func (m *TicketTagModel) Update(tag *TicketTag) error {
+	if err := ValidateTag(tag); err != nil {
+		return err
+	}
+
+	var exists bool
+	err := m.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM ticket_tags WHERE name = $1 AND id <> $2)", tag.Name, tag.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("tag already exists")
+	}
+
+	err = m.DB.QueryRow(`
+		UPDATE ticket_tags
+		SET name = $1, color = $2, description = $3, is_active = $4
+		WHERE id = $5
+		RETURNING created_at
+	`, tag.Name, tag.Color, tag.Description, tag.IsActive, tag.ID).Scan(&tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("tag not found")
+	}
+	return err
+}
+
+// Delete removes a tag from the list and from every ticket
+func (m *TicketTagModel) Delete(id int64) error {
+	result, err := m.DB.Exec("DELETE FROM ticket_tags WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("tag not found")
+	}
+	return nil
+}
+
+// SetTicketTags replaces the tags on a ticket. New tags must be active on the
+// list; retired tags the ticket already has may stay.
+func (m *TicketTagModel) SetTicketTags(ticketID int64, names []string, addedBy *int64) ([]string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1)", ticketID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("ticket not found")
+	}
+
+	keep := map[int64]bool{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tagID int64
+		var isActive, onTicket bool
+		err := tx.QueryRow(`
+			SELECT tg.id, tg.is_active,
+				EXISTS(SELECT 1 FROM ticket_tag_assignments WHERE ticket_id = $2 AND tag_id = tg.id)
+			FROM ticket_tags tg
+			WHERE tg.name = $1
+		`, name, ticketID).Scan(&tagID, &isActive, &onTicket)
+		if err == sql.ErrNoRows {
+			return nil, &TicketTagError{Message: fmt.Sprintf("unknown tag %q", name)}
+		} else if err != nil {
+			return nil, err
+		}
+		if !isActive && !onTicket {
+			return nil, &TicketTagError{Message: fmt.Sprintf("tag %q is retired", name)}
+		}
+		keep[tagID] = true
+
+		// Tags already on the ticket keep who added them and when
+		if !onTicket {
+			if _, err := tx.Exec(`
+				INSERT INTO ticket_tag_assignments (ticket_id, tag_id, added_by)
+				VALUES ($1, $2, $3)
+			`, ticketID, tagID, addedBy); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rows, err := tx.Query("SELECT tag_id FROM ticket_tag_assignments WHERE ticket_id = $1", ticketID)
+	if err != nil {
+		return nil, err
+	}
+	var remove []int64
+	for rows.Next() {
+		var tagID int64
+		if err := rows.Scan(&tagID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !keep[tagID] {
+			remove = append(remove, tagID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, tagID := range remove {
+		if _, err := tx.Exec(
+			"DELETE FROM ticket_tag_assignments WHERE ticket_id = $1 AND tag_id = $2", ticketID, tagID,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	for name := range seen {
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
@@ -0,0 +1,100 @@
+// file: app/internal/models/ticket_tags_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTicketTagTest(t *testing.T) (*TicketTagModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTicketTagModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestValidateTag(t *testing.T) {
+	tag := &TicketTag{Name: "  Headsets ", Color: "#D73A4A"}
+	assert.NoError(t, ValidateTag(tag))
+	assert.Equal(t, "headsets", tag.Name)
+
+	assert.Error(t, ValidateTag(&TicketTag{Name: ""}))
+	assert.Error(t, ValidateTag(&TicketTag{Name: "a,b"}))
+	assert.Error(t, ValidateTag(&TicketTag{Name: "ok", Color: "red"}))
+}
+
+func TestTicketTagModel_SetTicketTags(t *testing.T) {
+	model, mock, teardown := setupTicketTagTest(t)
+	defer teardown()
+
+	userID := int64(2)
+	tagRows := []string{"id", "is_active", "on_ticket"}
+
+	t.Run("replaces tags", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`FROM ticket_tags tg`).
+			WithArgs("outage", int64(1)).
+			WillReturnRows(sqlmock.NewRows(tagRows).AddRow(4, true, true))
+		mock.ExpectQuery(`FROM ticket_tags tg`).
+			WithArgs("headsets", int64(1)).
+			WillReturnRows(sqlmock.NewRows(tagRows).AddRow(7, true, false))
+		mock.ExpectExec(`INSERT INTO ticket_tag_assignments`).
+			WithArgs(int64(1), int64(7), &userID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT tag_id FROM ticket_tag_assignments`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"tag_id"}).AddRow(4).AddRow(7).AddRow(9))
+		mock.ExpectExec(`DELETE FROM ticket_tag_assignments`).
+			WithArgs(int64(1), int64(9)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tags, err := model.SetTicketTags(1, []string{"Outage", "headsets", "outage "}, &userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"headsets", "outage"}, tags)
+	})
+
+	t.Run("retired tag cannot be added", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`FROM ticket_tags tg`).
+			WithArgs("legacy", int64(1)).
+			WillReturnRows(sqlmock.NewRows(tagRows).AddRow(3, false, false))
+		mock.ExpectRollback()
+
+		_, err := model.SetTicketTags(1, []string{"legacy"}, &userID)
+		require.Error(t, err)
+		assert.IsType(t, &TicketTagError{}, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketModel_GetAll_TagsAndSort(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	mock.ExpectQuery(`AND tg.name = \$1\).*AND tg.name = \$2\) ORDER BY CASE t.priority`).
+		WithArgs("headsets", "outage").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	tickets, err := model.GetAll(TicketFilters{Tags: []string{"Headsets", "outage"}, Sort: "priority"})
+	assert.NoError(t, err)
+	assert.Empty(t, tickets)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -112,6 +112,7 @@ func TestTicketModel_Insert(t *testing.T) {
 				ticket.AssignedTo,
 				ticket.AssetID,
 				ticket.IsInternal,
+				ticket.Campaign,
 			).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 				AddRow(1, now, now))
@@ -152,21 +153,29 @@ func TestTicketModel_GetByID(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{
 				"id", "ticket_num", "title", "description", "type", "priority",
 				"status", "completion", "created_by", "assigned_to", "asset_id",
-				"is_internal", "verification_status", "verification_notes",
+				"is_internal", "campaign", "verification_status", "verification_notes",
 				"verified_by", "verified_at", "created_at", "updated_at", "closed_at",
 				"creator_id", "creator_username", "creator_full_name", "creator_email",
 				"assignee_id", "assignee_username", "assignee_full_name", "assignee_email",
 				"verifier_id", "verifier_username", "verifier_full_name", "verifier_email",
 				"asset_id", "asset_internal_id", "asset_type", "asset_manufacturer", "asset_model",
+				"tags",
 			}).AddRow(
 				1, "TCK-2025-0001", "Test Ticket", "Test Description", "it_help", "normal",
 				"open", 0, userID, nil, nil,
-				false, "not_required", "", nil, nil, now, now, nil,
+				false, "", "not_required", "", nil, nil, now, now, nil,
 				userID, "testuser", "Test User", "test@example.com",
 				nil, nil, nil, nil,
 				nil, nil, nil, nil,
 				nil, nil, nil, nil, nil,
+				[]byte(`[]`),
 			))
+		mock.ExpectQuery(`FROM ticket_links l`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(ticketLinkColumns))
+		mock.ExpectQuery(`FROM ticket_worklogs`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"total", "billable", "internal", "entries"}).AddRow(0, 0, 0, 0))
 
 		ticket, err := model.GetByID(1)
 		assert.NoError(t, err)
@@ -197,21 +206,30 @@ func TestTicketModel_GetByID(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{
 				"id", "ticket_num", "title", "description", "type", "priority",
 				"status", "completion", "created_by", "assigned_to", "asset_id",
-				"is_internal", "verification_status", "verification_notes",
+				"is_internal", "campaign", "verification_status", "verification_notes",
 				"verified_by", "verified_at", "created_at", "updated_at", "closed_at",
 				"creator_id", "creator_username", "creator_full_name", "creator_email",
 				"assignee_id", "assignee_username", "assignee_full_name", "assignee_email",
 				"verifier_id", "verifier_username", "verifier_full_name", "verifier_email",
 				"asset_id", "asset_internal_id", "asset_type", "asset_manufacturer", "asset_model",
+				"tags",
 			}).AddRow(
 				1, "TCK-2025-0001", "Test Ticket", "Test Description", "it_help", "normal",
 				"open", 0, userID, assigneeID, assetID,
-				false, "not_required", "", nil, nil, now, now, nil,
+				false, "", "not_required", "", nil, nil, now, now, nil,
 				userID, "testuser", "Test User", "test@example.com",
 				assigneeID, "itstaff", "IT Staff", "it@example.com",
 				nil, nil, nil, nil,
 				assetID, "DPA-PC001", "PC", "Dell", "OptiPlex 7070",
+				[]byte(`["headsets","outage"]`),
 			))
+		mock.ExpectQuery(`FROM ticket_links l`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(ticketLinkColumns).
+				AddRow(4, "parent_of", false, userID, now, 9, "TCK-2025-0009", "Campaign headsets", "open"))
+		mock.ExpectQuery(`FROM ticket_worklogs`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"total", "billable", "internal", "entries"}).AddRow(95, 60, 35, 3))
 
 		ticket, err := model.GetByID(1)
 		assert.NoError(t, err)
@@ -219,7 +237,16 @@ func TestTicketModel_GetByID(t *testing.T) {
 		assert.Equal(t, "itstaff", ticket.AssignedToUser.Username)
 		assert.NotNil(t, ticket.Asset)
 		assert.Equal(t, "DPA-PC001", ticket.Asset.InternalID)
+		assert.Equal(t, []string{"headsets", "outage"}, ticket.Tags)
+		assert.Len(t, ticket.Links, 1)
+		assert.Equal(t, "child_of", ticket.Links[0].Relation)
+		assert.Equal(t, "TCK-2025-0009", ticket.Links[0].TicketNum)
+		require.NotNil(t, ticket.TimeSpent)
+		assert.Equal(t, 95, ticket.TimeSpent.TotalMinutes)
+		assert.Equal(t, 60, ticket.TimeSpent.BillableMinutes)
 	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestTicketModel_GetAll(t *testing.T) {
@@ -238,6 +265,7 @@ func TestTicketModel_GetAll(t *testing.T) {
 				"creator_username", "creator_full_name",
 				"assignee_username", "assignee_full_name",
 				"asset_internal_id",
+				"tags",
 			}).AddRow(
 				1, "TCK-2025-0001", "Ticket 1", "Desc 1", "it_help", "normal",
 				"open", 0, userID, nil, nil,
@@ -245,6 +273,7 @@ func TestTicketModel_GetAll(t *testing.T) {
 				"user1", "User One",
 				nil, nil,
 				nil,
+				[]byte(`[]`),
 			).AddRow(
 				2, "TCK-2025-0002", "Ticket 2", "Desc 2", "activation", "high",
 				"in_progress", 50, userID, nil, nil,
@@ -252,6 +281,7 @@ func TestTicketModel_GetAll(t *testing.T) {
 				"user1", "User One",
 				nil, nil,
 				nil,
+				[]byte(`["activation"]`),
 			))
 
 		filters := TicketFilters{}
@@ -280,6 +310,7 @@ func TestTicketModel_GetAll(t *testing.T) {
 				"creator_username", "creator_full_name",
 				"assignee_username", "assignee_full_name",
 				"asset_internal_id",
+				"tags",
 			}).AddRow(
 				1, "TCK-2025-0001", "Ticket 1", "Desc 1", "it_help", "normal",
 				"open", 0, userID, &assignedTo, nil,
@@ -287,6 +318,7 @@ func TestTicketModel_GetAll(t *testing.T) {
 				"user1", "User One",
 				"itstaff", "IT Staff",
 				nil,
+				[]byte(`[]`),
 			))
 
 		tickets, err := model.GetAll(filters)
@@ -327,6 +359,7 @@ func TestTicketModel_Update(t *testing.T) {
 				ticket.AssetID,
 				ticket.IsInternal,
 				ticket.ID,
+				ticket.Campaign,
 			).
 			WillReturnRows(sqlmock.NewRows([]string{"updated_at", "closed_at"}).
 				AddRow(now, nil))
@@ -361,6 +394,9 @@ func TestTicketModel_UpdateStatus(t *testing.T) {
 	})
 
 	t.Run("ticket not found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+			WithArgs(int64(999)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 		mock.ExpectExec(`UPDATE tickets`).
 			WithArgs("closed", 100, nil, int64(999)).
 			WillReturnResult(sqlmock.NewResult(0, 0))
@@ -458,6 +494,9 @@ func TestTicketModel_VerifyTicket(t *testing.T) {
 	defer teardown()
 
 	t.Run("successful verification approval", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 		mock.ExpectExec(`UPDATE tickets`).
 			WithArgs("verified", "Approved", int64(1), true, int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
@@ -532,4 +571,18 @@ func TestTicketModel_ResetVerification(t *testing.T) {
 		err := model.ResetVerification(1, 1)
 		assert.Error(t, err)
 	})
-}
\ No newline at end of file
+}
+
+func TestTicketModel_UpdateStatus_Invalid(t *testing.T) {
+	model, mock, teardown := setupTicketTest(t)
+	defer teardown()
+
+	err := model.UpdateStatus(9, "done", 0, nil)
+	assert.IsType(t, &TicketStatusError{}, err)
+	assert.EqualError(t, err, "Invalid status")
+
+	err = model.UpdateStatus(9, "in_progress", 120, nil)
+	assert.EqualError(t, err, "Completion must be between 0 and 100")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// TicketView is a named filter and sort combination a user opens as a queue,
+// e.g. "my critical activation tickets". A view can be shared with a role.
+type TicketView struct {
+	ID           int64             `json:"id"`
+	UserID       int64             `json:"user_id"`
+	Name         string            `json:"name"`
+	Filters      TicketViewFilters `json:"filters"`
+	Sort         string            `json:"sort"` // One of TicketSortOptions, empty for newest first
+	SharedRoleID *int64            `json:"shared_role_id"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+
+	// Joined fields
+	OwnerName string `json:"owner_name,omitempty"`
+}
+
+// TicketViewFilters are the ticket filters a view saves. The "me" flags are
+// resolved against whoever opens the view, so a shared view works for all.
+type TicketViewFilters struct {
+	Status       string   `json:"status,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Priority     string   `json:"priority,omitempty"`
+	AssignedTo   *int64   `json:"assigned_to,omitempty"`
+	AssignedToMe bool     `json:"assigned_to_me,omitempty"`
+	CreatedBy    *int64   `json:"created_by,omitempty"`
+	CreatedByMe  bool     `json:"created_by_me,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// TicketFilters turns saved filters into query filters for the given user
+func (f TicketViewFilters) TicketFilters(userID int64, sort string) TicketFilters {
+	filters := TicketFilters{
+		Status:     f.Status,
+		Type:       f.Type,
+		Priority:   f.Priority,
+		AssignedTo: f.AssignedTo,
+		CreatedBy:  f.CreatedBy,
+		Tags:       f.Tags,
+		Sort:       sort,
+	}
+	if f.AssignedToMe {
+		filters.AssignedTo = &userID
+	}
+	if f.CreatedByMe {
+		filters.CreatedBy = &userID
+	}
+	return filters
+}
+
+type TicketViewModel struct {
+	DB *sql.DB
+}
+
+func NewTicketViewModel(db *sql.DB) *TicketViewModel {
+	return &TicketViewModel{DB: db}
+}
+
+// validateTicketView checks a view before it is saved
+func validateTicketView(v *TicketView) error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return errors.New("view name is required")
+	}
+	if _, ok := TicketSortOptions[v.Sort]; !ok && v.Sort != "" {
+		return errors.New("unknown sort")
+	}
+	for i, tag := range v.Filters.Tags {
+		v.Filters.Tags[i] = NormalizeTagName(tag)
+	}
+	return nil
+}
+
+const ticketViewColumns = `
+			v.id, v.user_id, v.name, v.filters, v.sort, v.shared_role_id,
+			v.created_at, v.updated_at, COALESCE(u.full_name, u.username)`
+
+func scanTicketView(row rowScanner) (*TicketView, error) {
+	var v TicketView
+	var filters []byte
+	var sharedRoleID sql.NullInt64
+	err := row.Scan(
+		&v.ID, &v.UserID, &v.Name, &filters, &v.Sort, &sharedRoleID,
+		&v.CreatedAt, &v.UpdatedAt, &v.OwnerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filters, &v.Filters); err != nil {
+		return nil, err
+	}
+	if sharedRoleID.Valid {
+		v.SharedRoleID = &sharedRoleID.Int64
+	}
+	return &v, nil
+}
+
+// Insert saves a new view for its owner
+func (m *TicketViewModel) Insert(v *TicketView) error {
+	if err := validateTicketView(v); err != nil {
+		return err
+	}
+	filters, err := json.Marshal(v.Filters)
+	if err != nil {
+		return err
+	}
+
+	err = m.DB.QueryRow(`
+		INSERT INTO ticket_views (user_id, name, filters, sort, shared_role_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`, v.UserID, v.Name, filters, v.Sort, v.SharedRoleID).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("a view with this name already exists")
+	}
+	return err
+}
+
+// Update changes a view; only its owner can
+func (m *TicketViewModel) Update(v *TicketView) error {
+	if err := validateTicketView(v); err != nil {
+		return err
+	}
+	filters, err := json.Marshal(v.Filters)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = m.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM ticket_views WHERE user_id = $1 AND name = $2 AND id <> $3)",
+		v.UserID, v.Name, v.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("a view with this name already exists")
+	}
+
+	err = m.DB.QueryRow(`
+		UPDATE ticket_views
+		SET name = $1, filters = $2, sort = $3, shared_role_id = $4, updated_at = NOW()
+		WHERE id = $5 AND user_id = $6
+		RETURNING created_at, updated_at
+	`, v.Name, filters, v.Sort, v.SharedRoleID, v.ID, v.UserID).Scan(&v.CreatedAt, &v.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("view not found")
+	}
+	return err
+}
+
+// Delete removes a view; only its owner can
+func (m *TicketViewModel) Delete(id, userID int64) error {
+	result, err := m.DB.Exec("DELETE FROM ticket_views WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("view not found")
+	}
+	return nil
+}
+
+// GetVisible lists a user's own views followed by those shared with their role
+func (m *TicketViewModel) GetVisible(userID, roleID int64) ([]TicketView, error) {
+	rows, err := m.DB.Query(`
+		SELECT`+ticketViewColumns+`
+		FROM ticket_views v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.user_id = $1 OR v.shared_role_id = $2
+		ORDER BY v.user_id <> $1, v.name
+	`, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []TicketView{}
+	for rows.Next() {
+		v, err := scanTicketView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *v)
+	}
+
+	return views, rows.Err()
+}
+
+// GetByID returns a view the user owns or that is shared with their role
+func (m *TicketViewModel) GetByID(id, userID, roleID int64) (*TicketView, error) {
+	row := m.DB.QueryRow(`
+		SELECT`+ticketViewColumns+`
+		FROM ticket_views v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.id = $1 AND (v.user_id = $2 OR v.shared_role_id = $3)
+	`, id, userID, roleID)
+
+	v, err := scanTicketView(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("view not found")
+	}
+	return v, err
+}
@@ -0,0 +1,119 @@
+// file: app/internal/models/ticket_views_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTicketViewTest(t *testing.T) (*TicketViewModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTicketViewModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestTicketViewFilters_TicketFilters(t *testing.T) {
+	saved := TicketViewFilters{
+		Type:         "activation",
+		Priority:     "critical",
+		AssignedToMe: true,
+		Tags:         []string{"outage"},
+	}
+
+	filters := saved.TicketFilters(7, "priority")
+	assert.Equal(t, "activation", filters.Type)
+	assert.Equal(t, "critical", filters.Priority)
+	require.NotNil(t, filters.AssignedTo)
+	assert.Equal(t, int64(7), *filters.AssignedTo)
+	assert.Nil(t, filters.CreatedBy)
+	assert.Equal(t, []string{"outage"}, filters.Tags)
+	assert.Equal(t, "priority", filters.Sort)
+}
+
+func TestTicketViewModel_Insert(t *testing.T) {
+	model, mock, teardown := setupTicketViewTest(t)
+	defer teardown()
+
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_views`).
+			WithArgs(int64(7), "My critical activations", []byte(`{"type":"activation","priority":"critical","assigned_to_me":true}`), "priority", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		view := &TicketView{
+			UserID:  7,
+			Name:    " My critical activations ",
+			Filters: TicketViewFilters{Type: "activation", Priority: "critical", AssignedToMe: true},
+			Sort:    "priority",
+		}
+		require.NoError(t, model.Insert(view))
+		assert.Equal(t, int64(1), view.ID)
+		assert.Equal(t, "My critical activations", view.Name)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_views`).
+			WillReturnError(sql.ErrNoRows)
+
+		err := model.Insert(&TicketView{UserID: 7, Name: "My critical activations"})
+		require.Error(t, err)
+		assert.Equal(t, "a view with this name already exists", err.Error())
+	})
+
+	t.Run("unknown sort", func(t *testing.T) {
+		err := model.Insert(&TicketView{UserID: 7, Name: "Oops", Sort: "random"})
+		require.Error(t, err)
+		assert.Equal(t, "unknown sort", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketViewModel_GetByID(t *testing.T) {
+	model, mock, teardown := setupTicketViewTest(t)
+	defer teardown()
+
+	now := time.Now()
+	columns := []string{
+		"id", "user_id", "name", "filters", "sort", "shared_role_id",
+		"created_at", "updated_at", "owner_name",
+	}
+
+	t.Run("shared with role", func(t *testing.T) {
+		mock.ExpectQuery(`FROM ticket_views v`).
+			WithArgs(int64(3), int64(9), int64(2)).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(3, 7, "Outage queue", []byte(`{"tags":["outage"]}`), "", 2, now, now, "Lead"))
+
+		view, err := model.GetByID(3, 9, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"outage"}, view.Filters.Tags)
+		require.NotNil(t, view.SharedRoleID)
+		assert.Equal(t, int64(2), *view.SharedRoleID)
+	})
+
+	t.Run("not visible", func(t *testing.T) {
+		mock.ExpectQuery(`FROM ticket_views v`).
+			WithArgs(int64(3), int64(9), int64(4)).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := model.GetByID(3, 9, 4)
+		require.Error(t, err)
+		assert.Equal(t, "view not found", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TicketWatcher is a user who follows a ticket and is notified about it on
+// top of its creator and assignee. Watchers add themselves, are CC'd when the
+// ticket is raised, are @mentioned in a comment, or followed a duplicate that
+// was merged into the ticket.
+type TicketWatcher struct {
+	ID        int64     `json:"id"`
+	TicketID  int64     `json:"ticket_id"`
+	UserID    int64     `json:"user_id"`
+	Source    string    `json:"source"` // manual, cc, mention, merge
+	AddedBy   *int64    `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Joined fields
+	Username string `json:"username,omitempty"`
+	FullName string `json:"full_name,omitempty"`
+}
+
+type TicketWatcherModel struct {
+	DB *sql.DB
+}
+
+func NewTicketWatcherModel(db *sql.DB) *TicketWatcherModel {
+	return &TicketWatcherModel{DB: db}
+}
+
+// CanViewInternalComments reports whether a role may read internal ticket
+// comments. Only admins and IT staff can.
+func CanViewInternalComments(roleID int64) bool {
+	return roleID == 1 || roleID == 2
+}
+
+// mentionPattern matches @username not preceded by a word character, so
+// email addresses in a comment are not taken for mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// ExtractMentions returns the distinct usernames @mentioned in text, in the
+// order they first appear
+func ExtractMentions(text string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// A mention at the end of a sentence keeps its full stop
+		username := strings.TrimRight(match[1], ".-")
+		key := strings.ToLower(username)
+		if username == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// Add makes a user watch a ticket. Watching a ticket twice keeps the
+// original record.
+func (m *TicketWatcherModel) Add(ticketID, userID int64, source string, addedBy *int64) error {
+	var exists bool
+	err := m.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1)", ticketID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("ticket not found")
+	}
+
+	var isActive bool
+	err = m.DB.QueryRow("SELECT is_active FROM users WHERE id = $1", userID).Scan(&isActive)
+	if err == sql.ErrNoRows || (err == nil && !isActive) {
+		return errors.New("user not found or inactive")
+	} else if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec(`
+		INSERT INTO ticket_watchers (ticket_id, user_id, source, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ticket_id, user_id) DO NOTHING
+	`, ticketID, userID, source, addedBy)
+	return err
+}
+
+// Remove stops a user watching a ticket
+func (m *TicketWatcherModel) Remove(ticketID, userID int64) error {
+	result, err := m.DB.Exec(
+		"DELETE FROM ticket_watchers WHERE ticket_id = $1 AND user_id = $2", ticketID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("watcher not found")
+	}
+	return nil
+}
+
+// GetByTicket lists the watchers of a ticket, oldest first
+func (m *TicketWatcherModel) GetByTicket(ticketID int64) ([]TicketWatcher, error) {
+	rows, err := m.DB.Query(`
+		SELECT w.id, w.ticket_id, w.user_id, w.source, w.added_by, w.created_at,
+			u.username, COALESCE(u.full_name, '')
+		FROM ticket_watchers w
+		JOIN users u ON u.id = w.user_id
+		WHERE w.ticket_id = $1
+		ORDER BY w.created_at, w.id
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchers := []TicketWatcher{}
+	for rows.Next() {
+		var w TicketWatcher
+		var addedBy sql.NullInt64
+		err := rows.Scan(
+			&w.ID, &w.TicketID, &w.UserID, &w.Source, &addedBy, &w.CreatedAt,
+			&w.Username, &w.FullName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if addedBy.Valid {
+			w.AddedBy = &addedBy.Int64
+		}
+		watchers = append(watchers, w)
+	}
+
+	return watchers, rows.Err()
+}
+
+// ResolveMentions looks up the active users behind a list of @mentioned
+// usernames. Names that match nobody are ignored.
+func (m *TicketWatcherModel) ResolveMentions(usernames []string) ([]User, error) {
+	var users []User
+	for _, username := range usernames {
+		var u User
+		err := m.DB.QueryRow(`
+			SELECT id, username, COALESCE(full_name, ''), email, role_id
+			FROM users
+			WHERE LOWER(username) = LOWER($1) AND is_active = true
+		`, username).Scan(&u.ID, &u.Username, &u.FullName, &u.Email, &u.RoleID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// GetRecipients returns the active users to notify about a ticket: its
+// creator, its assignee and its watchers
+func (m *TicketWatcherModel) GetRecipients(ticketID int64) ([]User, error) {
+	rows, err := m.DB.Query(`
+		SELECT u.id, u.username, COALESCE(u.full_name, ''), u.email, u.role_id
+		FROM users u
+		WHERE u.is_active = true
+		AND (
+			u.id IN (SELECT user_id FROM ticket_watchers WHERE ticket_id = $1)
+			OR u.id IN (SELECT created_by FROM tickets WHERE id = $1)
+			OR u.id IN (SELECT assigned_to FROM tickets WHERE id = $1)
+		)
+		ORDER BY u.id
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.FullName, &u.Email, &u.RoleID); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
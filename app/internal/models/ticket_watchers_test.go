@@ -0,0 +1,120 @@
+// file: app/internal/models/ticket_watchers_test.go
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTicketWatcherTest(t *testing.T) (*TicketWatcherModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTicketWatcherModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestExtractMentions(t *testing.T) {
+	assert.Equal(t, []string{"jdoe", "mary.k"}, ExtractMentions("@jdoe can you check with @mary.k? Thanks @JDoe."))
+	assert.Equal(t, []string{"it_lead"}, ExtractMentions("(cc @it_lead)"))
+	assert.Nil(t, ExtractMentions("mail jdoe@example.com about it"))
+	assert.Nil(t, ExtractMentions(""))
+}
+
+func TestCanViewInternalComments(t *testing.T) {
+	assert.True(t, CanViewInternalComments(1))
+	assert.True(t, CanViewInternalComments(2))
+	assert.False(t, CanViewInternalComments(3))
+	assert.False(t, CanViewInternalComments(4))
+	assert.False(t, CanViewInternalComments(5))
+}
+
+func TestTicketWatcherModel_Add(t *testing.T) {
+	model, mock, teardown := setupTicketWatcherTest(t)
+	defer teardown()
+
+	addedBy := int64(3)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT is_active FROM users`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+		mock.ExpectExec(`INSERT INTO ticket_watchers`).
+			WithArgs(int64(10), int64(7), "cc", &addedBy).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		assert.NoError(t, model.Add(10, 7, "cc", &addedBy))
+	})
+
+	t.Run("ticket not found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(99)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := model.Add(99, 7, "manual", nil)
+		assert.Error(t, err)
+		assert.Equal(t, "ticket not found", err.Error())
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT is_active FROM users`).
+			WithArgs(int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(false))
+
+		err := model.Add(10, 8, "mention", &addedBy)
+		assert.Error(t, err)
+		assert.Equal(t, "user not found or inactive", err.Error())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketWatcherModel_Remove(t *testing.T) {
+	model, mock, teardown := setupTicketWatcherTest(t)
+	defer teardown()
+
+	mock.ExpectExec(`DELETE FROM ticket_watchers`).
+		WithArgs(int64(10), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := model.Remove(10, 7)
+	assert.Error(t, err)
+	assert.Equal(t, "watcher not found", err.Error())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketWatcherModel_ResolveMentions(t *testing.T) {
+	model, mock, teardown := setupTicketWatcherTest(t)
+	defer teardown()
+
+	mock.ExpectQuery(`WHERE LOWER\(username\) = LOWER\(\$1\)`).
+		WithArgs("jdoe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "full_name", "email", "role_id"}).
+			AddRow(7, "JDoe", "John Doe", "jdoe@example.com", 2))
+	mock.ExpectQuery(`WHERE LOWER\(username\) = LOWER\(\$1\)`).
+		WithArgs("nobody").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "full_name", "email", "role_id"}))
+
+	users, err := model.ResolveMentions([]string{"jdoe", "nobody"})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, int64(7), users[0].ID)
+	assert.Equal(t, "JDoe", users[0].Username)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
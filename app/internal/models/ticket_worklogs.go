@@ -0,0 +1,303 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// TicketWorklog is time a user spent on a ticket
+type TicketWorklog struct {
+	ID        int64     `json:"id"`
+	TicketID  int64     `json:"ticket_id"`
+	UserID    *int64    `json:"user_id"`
+	Minutes   int       `json:"minutes"`
+	WorkDate  string    `json:"work_date"` // YYYY-MM-DD, defaults to today
+	Note      string    `json:"note"`
+	Billable  bool      `json:"billable"` // false for internal time
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Joined fields
+	UserName string `json:"user_name,omitempty"`
+}
+
+// TicketTimer is a user's running timer; a user has at most one
+type TicketTimer struct {
+	ID        int64     `json:"id"`
+	TicketID  int64     `json:"ticket_id"`
+	UserID    int64     `json:"user_id"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Joined fields
+	TicketNum string `json:"ticket_num,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// TicketTimeTotals sums the time logged on a ticket
+type TicketTimeTotals struct {
+	TotalMinutes    int `json:"total_minutes"`
+	BillableMinutes int `json:"billable_minutes"`
+	InternalMinutes int `json:"internal_minutes"`
+	Entries         int `json:"entries"`
+}
+
+// WorklogError is a worklog the client got wrong, e.g. zero minutes
+type WorklogError struct {
+	Message string
+}
+
+func (e *WorklogError) Error() string {
+	return e.Message
+}
+
+// maxWorklogMinutes caps a single entry at one day
+const maxWorklogMinutes = 24 * 60
+
+// validateWorklog checks an entry before it is saved
+func validateWorklog(w *TicketWorklog) error {
+	if w.Minutes <= 0 {
+		return &WorklogError{Message: "minutes must be greater than zero"}
+	}
+	if w.Minutes > maxWorklogMinutes {
+		return &WorklogError{Message: "a single worklog cannot exceed 24 hours"}
+	}
+
+	w.WorkDate = strings.TrimSpace(w.WorkDate)
+	if w.WorkDate == "" {
+		w.WorkDate = time.Now().Format("2006-01-02")
+	}
+	date, err := time.Parse("2006-01-02", w.WorkDate)
+	if err != nil {
+		return &WorklogError{Message: "invalid work_date, expected YYYY-MM-DD"}
+	}
+	if date.After(time.Now()) {
+		return &WorklogError{Message: "work_date cannot be in the future"}
+	}
+
+	w.Note = strings.TrimSpace(w.Note)
+	return nil
+}
+
+type TicketWorklogModel struct {
+	DB *sql.DB
+}
+
+func NewTicketWorklogModel(db *sql.DB) *TicketWorklogModel {
+	return &TicketWorklogModel{DB: db}
+}
+
+const ticketWorklogColumns = `
+			w.id, w.ticket_id, w.user_id, w.minutes, TO_CHAR(w.work_date, 'YYYY-MM-DD'),
+			w.note, w.billable, w.created_at, w.updated_at,
+			COALESCE(u.full_name, u.username, '')`
+
+func scanTicketWorklog(row rowScanner) (*TicketWorklog, error) {
+	var w TicketWorklog
+	var userID sql.NullInt64
+	err := row.Scan(
+		&w.ID, &w.TicketID, &userID, &w.Minutes, &w.WorkDate,
+		&w.Note, &w.Billable, &w.CreatedAt, &w.UpdatedAt,
+		&w.UserName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		w.UserID = &userID.Int64
+	}
+	return &w, nil
+}
+
+// Insert logs time on a ticket
+func (m *TicketWorklogModel) Insert(w *TicketWorklog) error {
+	if err := validateWorklog(w); err != nil {
+		return err
+	}
+
+	err := m.DB.QueryRow(`
+		INSERT INTO ticket_worklogs (ticket_id, user_id, minutes, work_date, note, billable)
+		SELECT t.id, $2, $3, $4::date, $5, $6
+		FROM tickets t
+		WHERE t.id = $1
+		RETURNING id, created_at, updated_at
+	`, w.TicketID, w.UserID, w.Minutes, w.WorkDate, w.Note, w.Billable).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("ticket not found")
+	}
+	return err
+}
+
+// Update changes a worklog. Users edit their own entries; admins can edit any.
+func (m *TicketWorklogModel) Update(w *TicketWorklog, userID int64, isAdmin bool) error {
+	if err := validateWorklog(w); err != nil {
+		return err
+	}
+
+	err := m.DB.QueryRow(`
+		UPDATE ticket_worklogs
+		SET minutes = $1, work_date = $2::date, note = $3, billable = $4, updated_at = NOW()
+		WHERE id = $5 AND ticket_id = $6 AND (user_id = $7 OR $8)
+		RETURNING user_id, created_at, updated_at
+	`, w.Minutes, w.WorkDate, w.Note, w.Billable, w.ID, w.TicketID, userID, isAdmin).Scan(&w.UserID, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("worklog not found")
+	}
+	return err
+}
+
+// Delete removes a worklog. Users delete their own entries; admins can delete any.
+func (m *TicketWorklogModel) Delete(id, ticketID, userID int64, isAdmin bool) error {
+	result, err := m.DB.Exec(`
+		DELETE FROM ticket_worklogs
+		WHERE id = $1 AND ticket_id = $2 AND (user_id = $3 OR $4)
+	`, id, ticketID, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("worklog not found")
+	}
+	return nil
+}
+
+// GetByTicket lists a ticket's worklogs, most recent work first
+func (m *TicketWorklogModel) GetByTicket(ticketID int64) ([]TicketWorklog, error) {
+	rows, err := m.DB.Query(`
+		SELECT`+ticketWorklogColumns+`
+		FROM ticket_worklogs w
+		LEFT JOIN users u ON u.id = w.user_id
+		WHERE w.ticket_id = $1
+		ORDER BY w.work_date DESC, w.created_at DESC
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	worklogs := []TicketWorklog{}
+	for rows.Next() {
+		w, err := scanTicketWorklog(rows)
+		if err != nil {
+			return nil, err
+		}
+		worklogs = append(worklogs, *w)
+	}
+
+	return worklogs, rows.Err()
+}
+
+// StartTimer starts the user's timer on a ticket
+func (m *TicketWorklogModel) StartTimer(ticketID, userID int64) (*TicketTimer, error) {
+	var exists bool
+	err := m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE id = $1)", ticketID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("ticket not found")
+	}
+
+	timer := &TicketTimer{TicketID: ticketID, UserID: userID}
+	err = m.DB.QueryRow(`
+		INSERT INTO ticket_timers (ticket_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING id, started_at
+	`, ticketID, userID).Scan(&timer.ID, &timer.StartedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("a timer is already running")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return timer, nil
+}
+
+// GetRunningTimer returns the user's running timer, or nil if none is running
+func (m *TicketWorklogModel) GetRunningTimer(userID int64) (*TicketTimer, error) {
+	var timer TicketTimer
+	err := m.DB.QueryRow(`
+		SELECT tm.id, tm.ticket_id, tm.user_id, tm.started_at, t.ticket_num, t.title
+		FROM ticket_timers tm
+		JOIN tickets t ON t.id = tm.ticket_id
+		WHERE tm.user_id = $1
+	`, userID).Scan(&timer.ID, &timer.TicketID, &timer.UserID, &timer.StartedAt, &timer.TicketNum, &timer.Title)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timer, nil
+}
+
+// StopTimer stops the user's timer on a ticket and logs the elapsed time,
+// rounded up to the minute, on the day the timer was started
+func (m *TicketWorklogModel) StopTimer(ticketID, userID int64, note string, billable bool) (*TicketWorklog, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var workDate string
+	var minutes int
+	err = tx.QueryRow(`
+		DELETE FROM ticket_timers
+		WHERE ticket_id = $1 AND user_id = $2
+		RETURNING TO_CHAR(started_at, 'YYYY-MM-DD'),
+			GREATEST(1, CEIL(EXTRACT(EPOCH FROM NOW() - started_at) / 60))::INTEGER
+	`, ticketID, userID).Scan(&workDate, &minutes)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("no timer running on this ticket")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A timer left running overnight still logs at most one day
+	if minutes > maxWorklogMinutes {
+		minutes = maxWorklogMinutes
+	}
+
+	w := &TicketWorklog{
+		TicketID: ticketID,
+		UserID:   &userID,
+		Minutes:  minutes,
+		WorkDate: workDate,
+		Note:     strings.TrimSpace(note),
+		Billable: billable,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO ticket_worklogs (ticket_id, user_id, minutes, work_date, note, billable)
+		VALUES ($1, $2, $3, $4::date, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, w.TicketID, w.UserID, w.Minutes, w.WorkDate, w.Note, w.Billable).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// GetTimeTotals sums the time logged on a ticket
+func (m *TicketModel) GetTimeTotals(ticketID int64) (TicketTimeTotals, error) {
+	var totals TicketTimeTotals
+	err := m.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(minutes), 0),
+			COALESCE(SUM(minutes) FILTER (WHERE billable), 0),
+			COALESCE(SUM(minutes) FILTER (WHERE NOT billable), 0),
+			COUNT(*)
+		FROM ticket_worklogs
+		WHERE ticket_id = $1
+	`, ticketID).Scan(&totals.TotalMinutes, &totals.BillableMinutes, &totals.InternalMinutes, &totals.Entries)
+	return totals, err
+}
@@ -0,0 +1,137 @@
+// file: app/internal/models/ticket_worklogs_test.go
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTicketWorklogTest(t *testing.T) (*TicketWorklogModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTicketWorklogModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestTicketWorklogModel_Insert(t *testing.T) {
+	model, mock, teardown := setupTicketWorklogTest(t)
+	defer teardown()
+
+	now := time.Now()
+	userID := int64(2)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_worklogs`).
+			WithArgs(int64(1), &userID, 45, "2025-06-02", "Reimaged PC", true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now))
+
+		worklog := &TicketWorklog{TicketID: 1, UserID: &userID, Minutes: 45, WorkDate: "2025-06-02", Note: " Reimaged PC ", Billable: true}
+		require.NoError(t, model.Insert(worklog))
+		assert.Equal(t, int64(1), worklog.ID)
+	})
+
+	t.Run("defaults to today", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_worklogs`).
+			WithArgs(int64(1), &userID, 10, time.Now().Format("2006-01-02"), "", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(2, now, now))
+
+		worklog := &TicketWorklog{TicketID: 1, UserID: &userID, Minutes: 10}
+		require.NoError(t, model.Insert(worklog))
+	})
+
+	t.Run("ticket not found", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO ticket_worklogs`).
+			WillReturnError(sql.ErrNoRows)
+
+		err := model.Insert(&TicketWorklog{TicketID: 99, UserID: &userID, Minutes: 10})
+		require.Error(t, err)
+		assert.Equal(t, "ticket not found", err.Error())
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		for _, w := range []TicketWorklog{
+			{TicketID: 1, Minutes: 0},
+			{TicketID: 1, Minutes: 24*60 + 1},
+			{TicketID: 1, Minutes: 30, WorkDate: "02/06/2025"},
+			{TicketID: 1, Minutes: 30, WorkDate: time.Now().AddDate(0, 0, 2).Format("2006-01-02")},
+		} {
+			err := model.Insert(&w)
+			require.Error(t, err)
+			assert.IsType(t, &WorklogError{}, err)
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTicketWorklogModel_Timers(t *testing.T) {
+	model, mock, teardown := setupTicketWorklogTest(t)
+	defer teardown()
+
+	now := time.Now()
+	userID := int64(2)
+
+	t.Run("start while another is running", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM tickets`).
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`INSERT INTO ticket_timers`).
+			WithArgs(int64(1), userID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := model.StartTimer(1, userID)
+		require.Error(t, err)
+		assert.Equal(t, "a timer is already running", err.Error())
+	})
+
+	t.Run("stop logs elapsed time", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM ticket_timers`).
+			WithArgs(int64(1), userID).
+			WillReturnRows(sqlmock.NewRows([]string{"work_date", "minutes"}).AddRow("2025-06-02", 23))
+		mock.ExpectQuery(`INSERT INTO ticket_worklogs`).
+			WithArgs(int64(1), &userID, 23, "2025-06-02", "Replaced headset", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(7, now, now))
+		mock.ExpectCommit()
+
+		worklog, err := model.StopTimer(1, userID, "Replaced headset", false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), worklog.ID)
+		assert.Equal(t, 23, worklog.Minutes)
+	})
+
+	t.Run("stop without a timer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM ticket_timers`).
+			WithArgs(int64(3), userID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := model.StopTimer(3, userID, "", false)
+		require.Error(t, err)
+		assert.Equal(t, "no timer running on this ticket", err.Error())
+	})
+
+	t.Run("no running timer", func(t *testing.T) {
+		mock.ExpectQuery(`FROM ticket_timers tm`).
+			WithArgs(userID).
+			WillReturnError(sql.ErrNoRows)
+
+		timer, err := model.GetRunningTimer(userID)
+		require.NoError(t, err)
+		assert.Nil(t, timer)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
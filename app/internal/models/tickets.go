@@ -3,6 +3,7 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -13,7 +14,7 @@ type Ticket struct {
 	TicketNum   string     `json:"ticket_num"`   // TCK-2025-0001
 	Title       string     `json:"title"`        // Subject line
 	Description string     `json:"description"`  // Issue details
-	Type        string     `json:"type"`         // activation, deactivation, it_help, transition
+	Type        string     `json:"type"`         // activation, deactivation, it_help, transition, maintenance
 	Priority    string     `json:"priority"`     // low, normal, high, critical
 	Status      string     `json:"status"`       // open, received, in_progress, Investigating, resolved, closed
 	Completion  int        `json:"completion"`   // 0-100 percentage
@@ -21,6 +22,7 @@ type Ticket struct {
 	AssignedTo  *int64     `json:"assigned_to"`  // IT staff assigned
 	AssetID     *int64     `json:"asset_id"`     // Related asset (optional)
 	IsInternal  bool       `json:"is_internal"`  // Internal ticket
+	Campaign    string     `json:"campaign"`     // Campaign the work is for, empty for general IT
 
 	// Verification fields
 	VerificationStatus string     `json:"verification_status"` // not_required, pending, verified, rejected
@@ -37,6 +39,17 @@ type Ticket struct {
 	AssignedToUser *User `json:"assigned_to_user,omitempty"`
 	Asset          *Asset `json:"asset,omitempty"`
 	VerifiedByUser *User `json:"verified_by_user,omitempty"`
+
+	Tags []string `json:"tags"`
+
+	// Relationships to other tickets, loaded by GetByID
+	Links []TicketLink `json:"links,omitempty"`
+
+	// Time logged on the ticket, loaded by GetByID
+	TimeSpent *TicketTimeTotals `json:"time_spent,omitempty"`
+
+	// Knowledge base articles that may answer the ticket, set when it is created
+	SuggestedArticles []KBArticleSummary `json:"suggested_articles,omitempty"`
 }
 
 type TicketModel struct {
@@ -51,6 +64,10 @@ func NewTicketModel(db *sql.DB) *TicketModel {
 // GenerateTicketNum generates a unique ticket number
 // GenerateTicketNum generates a unique ticket number
 func (m *TicketModel) GenerateTicketNum() (string, error) {
+	return generateTicketNum(m.DB)
+}
+
+func generateTicketNum(db dbtx) (string, error) {
 	year := time.Now().Year()
 	
 	// Try multiple approaches to get the next number
@@ -58,7 +75,7 @@ func (m *TicketModel) GenerateTicketNum() (string, error) {
 	
 	// Approach 1: Get max number from existing tickets
 	var maxNum sql.NullInt64
-	err := m.DB.QueryRow(`
+	err := db.QueryRow(`
 		SELECT MAX(NULLIF(REGEXP_REPLACE(ticket_num, '^TCK-[0-9]+-', ''), '')::INTEGER)
 		FROM tickets 
 		WHERE ticket_num ~ '^TCK-[0-9]+-[0-9]+$'
@@ -79,7 +96,7 @@ func (m *TicketModel) GenerateTicketNum() (string, error) {
 	
 	// Double-check it doesn't exist (race condition protection)
 	var exists bool
-	err = m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE ticket_num = $1)", ticketNum).Scan(&exists)
+	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM tickets WHERE ticket_num = $1)", ticketNum).Scan(&exists)
 	if err != nil {
 		return "", fmt.Errorf("failed to check ticket number existence: %v", err)
 	}
@@ -95,8 +112,17 @@ func (m *TicketModel) GenerateTicketNum() (string, error) {
 
 // Insert a new ticket
 func (m *TicketModel) Insert(ticket *Ticket) error {
+	return insertTicket(m.DB, ticket)
+}
+
+// InsertTx inserts a new ticket as part of the caller's transaction
+func (m *TicketModel) InsertTx(tx *sql.Tx, ticket *Ticket) error {
+	return insertTicket(tx, ticket)
+}
+
+func insertTicket(db dbtx, ticket *Ticket) error {
 	// Generate ticket number
-	ticketNum, err := m.GenerateTicketNum()
+	ticketNum, err := generateTicketNum(db)
 	if err != nil {
 		return err
 	}
@@ -105,12 +131,12 @@ func (m *TicketModel) Insert(ticket *Ticket) error {
 	query := `
 		INSERT INTO tickets (
 			ticket_num, title, description, type, priority, status, 
-			completion, created_by, assigned_to, asset_id, is_internal
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			completion, created_by, assigned_to, asset_id, is_internal, campaign
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at
 	`
 	
-	err = m.DB.QueryRow(
+	err = db.QueryRow(
 		query,
 		ticket.TicketNum,
 		ticket.Title,
@@ -123,6 +149,7 @@ func (m *TicketModel) Insert(ticket *Ticket) error {
 		ticket.AssignedTo,
 		ticket.AssetID,
 		ticket.IsInternal,
+		ticket.Campaign,
 	).Scan(&ticket.ID, &ticket.CreatedAt, &ticket.UpdatedAt)
 	
 	return err
@@ -135,12 +162,12 @@ func (m *TicketModel) GetByID(id int64) (*Ticket, error) {
 		SELECT 
 			t.id, t.ticket_num, t.title, t.description, t.type, t.priority,
 			t.status, t.completion, t.created_by, t.assigned_to, t.asset_id,
-			t.is_internal, t.verification_status, t.verification_notes,
+			t.is_internal, t.campaign, t.verification_status, t.verification_notes,
 			t.verified_by, t.verified_at, t.created_at, t.updated_at, t.closed_at,
 			creator.id, creator.username, creator.full_name, creator.email,
 			assignee.id, assignee.username, assignee.full_name, assignee.email,
 			verifier.id, verifier.username, verifier.full_name, verifier.email,
-			a.id, a.internal_id, a.asset_type, a.manufacturer, a.model
+			a.id, a.internal_id, a.asset_type, a.manufacturer, a.model,` + ticketTagsColumn + `
 		FROM tickets t
 		LEFT JOIN users creator ON t.created_by = creator.id
 		LEFT JOIN users assignee ON t.assigned_to = assignee.id
@@ -156,6 +183,7 @@ func (m *TicketModel) GetByID(id int64) (*Ticket, error) {
 	var verifierUsername, verifierFullName, verifierEmail sql.NullString
 	var assetInternalID, assetType, assetManufacturer, assetModel sql.NullString
 	var verifiedAt sql.NullTime
+	var tags []byte
 	
 	err := m.DB.QueryRow(query, id).Scan(
 		&ticket.ID,
@@ -170,6 +198,7 @@ func (m *TicketModel) GetByID(id int64) (*Ticket, error) {
 		&assigneeID,
 		&assetID,
 		&ticket.IsInternal,
+		&ticket.Campaign,
 		&ticket.VerificationStatus,
 		&ticket.VerificationNotes,
 		&verifiedByID,
@@ -181,6 +210,7 @@ func (m *TicketModel) GetByID(id int64) (*Ticket, error) {
 		&assigneeID, &assigneeUsername, &assigneeFullName, &assigneeEmail,
 		&verifiedByID, &verifierUsername, &verifierFullName, &verifierEmail,
 		&assetID, &assetInternalID, &assetType, &assetManufacturer, &assetModel,
+		&tags,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -234,6 +264,21 @@ func (m *TicketModel) GetByID(id int64) (*Ticket, error) {
 		}
 		ticket.AssetID = &assetID.Int64
 	}
+
+	if err := json.Unmarshal(tags, &ticket.Tags); err != nil {
+		return nil, err
+	}
+
+	ticket.Links, err = m.GetLinks(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeSpent, err := m.GetTimeTotals(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	ticket.TimeSpent = &timeSpent
 	
 	return &ticket, nil
 }
@@ -247,7 +292,7 @@ func (m *TicketModel) GetAll(filters TicketFilters) ([]Ticket, error) {
 			t.is_internal, t.created_at, t.updated_at, t.closed_at,
 			creator.username, creator.full_name,
 			assignee.username, assignee.full_name,
-			a.internal_id
+			a.internal_id,` + ticketTagsColumn + `
 		FROM tickets t
 		LEFT JOIN users creator ON t.created_by = creator.id
 		LEFT JOIN users assignee ON t.assigned_to = assignee.id
@@ -295,8 +340,22 @@ func (m *TicketModel) GetAll(filters TicketFilters) ([]Ticket, error) {
 		args = append(args, *filters.AgentView)
 		argPos++
 	}
+
+	// Tickets must carry every tag asked for
+	for _, tag := range filters.Tags {
+		query += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM ticket_tag_assignments ta
+			JOIN ticket_tags tg ON tg.id = ta.tag_id
+			WHERE ta.ticket_id = t.id AND tg.name = $%d)`, argPos)
+		args = append(args, NormalizeTagName(tag))
+		argPos++
+	}
 	
-	query += " ORDER BY t.created_at DESC"
+	orderBy, ok := TicketSortOptions[filters.Sort]
+	if !ok {
+		orderBy = TicketSortOptions["newest"]
+	}
+	query += " ORDER BY " + orderBy
 	
 	// Pagination
 	if filters.Limit > 0 {
@@ -323,6 +382,7 @@ func (m *TicketModel) GetAll(filters TicketFilters) ([]Ticket, error) {
 		var assigneeUsername, assigneeFullName sql.NullString
 		var assetInternalID sql.NullString
 		var createdBy, assignedTo, assetID sql.NullInt64
+		var tags []byte
 		
 		err := rows.Scan(
 			&ticket.ID,
@@ -343,10 +403,14 @@ func (m *TicketModel) GetAll(filters TicketFilters) ([]Ticket, error) {
 			&creatorUsername, &creatorFullName,
 			&assigneeUsername, &assigneeFullName,
 			&assetInternalID,
+			&tags,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err := json.Unmarshal(tags, &ticket.Tags); err != nil {
+			return nil, err
+		}
 		
 		// Populate user info
 		if createdBy.Valid {
@@ -385,7 +449,7 @@ func (m *TicketModel) Update(ticket *Ticket) error {
 		SET 
 			title = $1, description = $2, type = $3, priority = $4,
 			status = $5, completion = $6, assigned_to = $7, asset_id = $8,
-			is_internal = $9, updated_at = NOW(),
+			is_internal = $9, campaign = $11, updated_at = NOW(),
 			closed_at = CASE WHEN $5 = 'closed' AND closed_at IS NULL THEN NOW() ELSE closed_at END
 		WHERE id = $10
 		RETURNING updated_at, closed_at
@@ -403,6 +467,7 @@ func (m *TicketModel) Update(ticket *Ticket) error {
 		ticket.AssetID,
 		ticket.IsInternal,
 		ticket.ID,
+		ticket.Campaign,
 	).Scan(&ticket.UpdatedAt, &ticket.ClosedAt)
 	
 	if err == sql.ErrNoRows {
@@ -411,8 +476,53 @@ func (m *TicketModel) Update(ticket *Ticket) error {
 	return err
 }
 
+// ticketStatuses are the statuses a ticket can be moved to
+var ticketStatuses = map[string]bool{
+	"open": true, "received": true, "in_progress": true,
+	"resolved": true, "closed": true,
+}
+
+// TicketStatusError is a status change that is not valid, as opposed to a
+// database error
+type TicketStatusError struct {
+	Message string
+}
+
+func (e *TicketStatusError) Error() string {
+	return e.Message
+}
+
+// ValidateStatusChange checks a new status and completion percentage
+func ValidateStatusChange(status string, completion int) error {
+	if !ticketStatuses[status] {
+		return &TicketStatusError{Message: "Invalid status"}
+	}
+	if completion < 0 || completion > 100 {
+		return &TicketStatusError{Message: "Completion must be between 0 and 100"}
+	}
+	return nil
+}
+
 // Update ticket status and completion
 func (m *TicketModel) UpdateStatus(id int64, status string, completion int, assignedTo *int64) error {
+	return updateTicketStatus(m.DB, id, status, completion, assignedTo)
+}
+
+// UpdateStatusTx updates a ticket's status as part of the caller's transaction
+func (m *TicketModel) UpdateStatusTx(tx *sql.Tx, id int64, status string, completion int, assignedTo *int64) error {
+	return updateTicketStatus(tx, id, status, completion, assignedTo)
+}
+
+func updateTicketStatus(db dbtx, id int64, status string, completion int, assignedTo *int64) error {
+	if err := ValidateStatusChange(status, completion); err != nil {
+		return err
+	}
+	if status == "closed" {
+		if err := checkCanClose(db, id); err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE tickets 
 		SET status = $1, completion = $2, assigned_to = $3, updated_at = NOW(),
@@ -420,7 +530,7 @@ func (m *TicketModel) UpdateStatus(id int64, status string, completion int, assi
 		WHERE id = $4
 	`
 	
-	result, err := m.DB.Exec(query, status, completion, assignedTo, id)
+	result, err := db.Exec(query, status, completion, assignedTo, id)
 	if err != nil {
 		return err
 	}
@@ -432,6 +542,32 @@ func (m *TicketModel) UpdateStatus(id int64, status string, completion int, assi
 	return nil
 }
 
+// UpdatePriority changes a ticket's priority
+func (m *TicketModel) UpdatePriority(id int64, priority string) error {
+	return updateTicketPriority(m.DB, id, priority)
+}
+
+// UpdatePriorityTx changes a ticket's priority as part of the caller's transaction
+func (m *TicketModel) UpdatePriorityTx(tx *sql.Tx, id int64, priority string) error {
+	return updateTicketPriority(tx, id, priority)
+}
+
+func updateTicketPriority(db dbtx, id int64, priority string) error {
+	result, err := db.Exec(
+		"UPDATE tickets SET priority = $1, updated_at = NOW() WHERE id = $2",
+		priority, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("ticket not found")
+	}
+	return nil
+}
+
 // Reassign ticket
 func (m *TicketModel) ReassignTicket(ticketID, newAssigneeID int64) error {
 	query := `
@@ -460,10 +596,22 @@ type TicketFilters struct {
 	AssignedTo *int64
 	CreatedBy  *int64
 	AgentView  *int64 // For agent-specific view
+	Tags       []string // Tickets must have all of these tags
+	Sort       string   // One of TicketSortOptions, defaults to newest
 	Limit      int
 	Offset     int
 }
 
+// TicketSortOptions maps the sorts a ticket list or saved view can use to
+// their ORDER BY clause
+var TicketSortOptions = map[string]string{
+	"newest":           "t.created_at DESC",
+	"oldest":           "t.created_at ASC",
+	"recently_updated": "t.updated_at DESC",
+	"priority": `CASE t.priority WHEN 'critical' THEN 0 WHEN 'high' THEN 1
+		WHEN 'normal' THEN 2 WHEN 'low' THEN 3 ELSE 4 END, t.created_at DESC`,
+}
+
 // Delete ticket by ID
 func (m *TicketModel) Delete(id int64) error {
 	// First, delete related comments to maintain referential integrity
@@ -520,6 +668,10 @@ func (m *TicketModel) RequestVerification(ticketID int64, notes string) error {
 func (m *TicketModel) VerifyTicket(ticketID, userID int64, approved bool, notes string, userRoleID int) error {
 	var newStatus string
 	if approved {
+		// Approving closes the ticket
+		if err := checkCanClose(m.DB, ticketID); err != nil {
+			return err
+		}
 		newStatus = "verified"
 	} else {
 		newStatus = "rejected"
@@ -587,6 +739,10 @@ func (m *TicketModel) CanVerifyTicket(ticketID, userID int64, userRoleID int) (b
 }
 // Skip verification for a ticket
 func (m *TicketModel) SkipVerification(ticketID int64) error {
+	if err := checkCanClose(m.DB, ticketID); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE tickets 
 		SET 
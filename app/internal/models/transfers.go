@@ -0,0 +1,462 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AssetTransfer moves one asset, or a kit of assets, straight from one user
+// to another. A transfer that requires approval waits as pending until a team
+// lead approves or rejects it; otherwise it completes as soon as it is made.
+// Completing closes each asset's custody record with the sender and opens a
+// pending one with the recipient, all in one transaction.
+type AssetTransfer struct {
+	ID               int64      `json:"id"`
+	FromUserID       int64      `json:"from_user_id"`
+	ToUserID         int64      `json:"to_user_id"`
+	Status           string     `json:"status"` // pending, completed, rejected, cancelled
+	Reason           string     `json:"reason"`
+	RequiresApproval bool       `json:"requires_approval"`
+	AcknowledgeHours int        `json:"acknowledge_hours"` // Window the recipient gets to accept the handover
+	RequestedBy      *int64     `json:"requested_by"`
+	RequestedAt      time.Time  `json:"requested_at"`
+	ReviewedBy       *int64     `json:"reviewed_by"`
+	ReviewedAt       *time.Time `json:"reviewed_at"`
+	ReviewNotes      string     `json:"review_notes"`
+	CompletedAt      *time.Time `json:"completed_at"`
+
+	Items []AssetTransferItem `json:"items"`
+
+	// Joined fields
+	FromUserName string `json:"from_user_name,omitempty"`
+	ToUserName   string `json:"to_user_name,omitempty"`
+}
+
+// AssetTransferItem is one asset in a transfer
+type AssetTransferItem struct {
+	ID              int64  `json:"id"`
+	AssetID         int64  `json:"asset_id"`
+	FromCustodyID   *int64 `json:"from_custody_id"` // Custody record closed with the sender, if any
+	ToCustodyID     *int64 `json:"to_custody_id"`   // Custody record opened with the recipient
+	AssetInternalID string `json:"asset_internal_id,omitempty"`
+}
+
+// TransferError is a transfer that cannot go ahead because of the state of
+// its assets or users, as opposed to a database error
+type TransferError struct {
+	Message string
+}
+
+func (e *TransferError) Error() string {
+	return e.Message
+}
+
+type TransferModel struct {
+	DB *sql.DB
+}
+
+func NewTransferModel(db *sql.DB) *TransferModel {
+	return &TransferModel{DB: db}
+}
+
+const transferColumns = `
+			t.id, t.from_user_id, t.to_user_id, t.status, t.reason, t.requires_approval,
+			t.acknowledge_hours, t.requested_by, t.requested_at, t.reviewed_by, t.reviewed_at,
+			t.review_notes, t.completed_at, COALESCE(fu.full_name, fu.username), COALESCE(tu.full_name, tu.username)`
+
+const transferJoins = `
+		FROM asset_transfers t
+		JOIN users fu ON fu.id = t.from_user_id
+		JOIN users tu ON tu.id = t.to_user_id`
+
+func scanTransfer(row rowScanner) (*AssetTransfer, error) {
+	var t AssetTransfer
+	err := row.Scan(
+		&t.ID,
+		&t.FromUserID,
+		&t.ToUserID,
+		&t.Status,
+		&t.Reason,
+		&t.RequiresApproval,
+		&t.AcknowledgeHours,
+		&t.RequestedBy,
+		&t.RequestedAt,
+		&t.ReviewedBy,
+		&t.ReviewedAt,
+		&t.ReviewNotes,
+		&t.CompletedAt,
+		&t.FromUserName,
+		&t.ToUserName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.Items = []AssetTransferItem{}
+	return &t, nil
+}
+
+// checkTransferableTx locks an asset and checks it is in use by the sender
+func checkTransferableTx(tx *sql.Tx, assetID, fromUserID int64) (string, error) {
+	var internalID, status string
+	var inUseBy *int64
+	err := tx.QueryRow(`
+		SELECT internal_id, status, in_use_by FROM assets
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, assetID).Scan(&internalID, &status, &inUseBy)
+	if err == sql.ErrNoRows {
+		return "", &TransferError{Message: fmt.Sprintf("asset %d not found", assetID)}
+	} else if err != nil {
+		return "", err
+	}
+
+	if status != "IN_USE" || inUseBy == nil || *inUseBy != fromUserID {
+		return "", &TransferError{Message: fmt.Sprintf("asset %s is not in use by the sending user", internalID)}
+	}
+
+	return internalID, nil
+}
+
+// completeTransferTx hands every asset in a transfer to the recipient
+func completeTransferTx(tx *sql.Tx, t *AssetTransfer, performedBy *int64) error {
+	acknowledgeBy := time.Now().Add(time.Duration(t.AcknowledgeHours) * time.Hour)
+
+	for i := range t.Items {
+		item := &t.Items[i]
+		if _, err := checkTransferableTx(tx, item.AssetID, t.FromUserID); err != nil {
+			return err
+		}
+
+		// Close the sender's handover; assets assigned before custody tracking have none
+		var fromCustodyID int64
+		err := tx.QueryRow(`
+			UPDATE asset_custody
+			SET status = CASE WHEN status = 'pending' THEN 'cancelled' ELSE 'returned' END,
+			    returned_at = NOW(), return_notes = $1, received_by = $2
+			WHERE asset_id = $3 AND status IN ('pending', 'accepted')
+			RETURNING id
+		`, fmt.Sprintf("Transferred to user %d (transfer #%d)", t.ToUserID, t.ID), performedBy, item.AssetID).Scan(&fromCustodyID)
+		if err == nil {
+			item.FromCustodyID = &fromCustodyID
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE assets SET in_use_by = $1, updated_at = NOW() WHERE id = $2`, t.ToUserID, item.AssetID)
+		if err != nil {
+			return err
+		}
+
+		var toCustodyID int64
+		err = tx.QueryRow(`
+			INSERT INTO asset_custody (asset_id, user_id, assigned_by, acknowledge_by)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, item.AssetID, t.ToUserID, performedBy, acknowledgeBy).Scan(&toCustodyID)
+		if err != nil {
+			return err
+		}
+		item.ToCustodyID = &toCustodyID
+
+		_, err = tx.Exec(`
+			UPDATE asset_transfer_items SET from_custody_id = $1, to_custody_id = $2 WHERE id = $3
+		`, item.FromCustodyID, item.ToCustodyID, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var completedAt time.Time
+	err := tx.QueryRow(`
+		UPDATE asset_transfers SET status = 'completed', completed_at = NOW()
+		WHERE id = $1
+		RETURNING completed_at
+	`, t.ID).Scan(&completedAt)
+	if err != nil {
+		return err
+	}
+	t.Status = "completed"
+	t.CompletedAt = &completedAt
+
+	return nil
+}
+
+// Insert records a transfer of the assets in t.Items. Each asset must be in
+// use by the sender and not already in another pending transfer. Transfers
+// that do not require approval are completed straight away.
+func (m *TransferModel) Insert(t *AssetTransfer) error {
+	if len(t.Items) == 0 {
+		return &TransferError{Message: "a transfer needs at least one asset"}
+	}
+	if t.FromUserID == t.ToUserID {
+		return &TransferError{Message: "an asset cannot be transferred to the user who has it"}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var recipientActive bool
+	err = tx.QueryRow(`SELECT is_active FROM users WHERE id = $1`, t.ToUserID).Scan(&recipientActive)
+	if err == sql.ErrNoRows || (err == nil && !recipientActive) {
+		return &TransferError{Message: "recipient not found or inactive"}
+	} else if err != nil {
+		return err
+	}
+
+	seen := map[int64]bool{}
+	for i := range t.Items {
+		item := &t.Items[i]
+		if seen[item.AssetID] {
+			return &TransferError{Message: fmt.Sprintf("asset %d is listed twice", item.AssetID)}
+		}
+		seen[item.AssetID] = true
+
+		item.AssetInternalID, err = checkTransferableTx(tx, item.AssetID, t.FromUserID)
+		if err != nil {
+			return err
+		}
+
+		var pending bool
+		err = tx.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM asset_transfer_items i
+				JOIN asset_transfers t ON t.id = i.transfer_id
+				WHERE i.asset_id = $1 AND t.status = 'pending'
+			)
+		`, item.AssetID).Scan(&pending)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return &TransferError{Message: fmt.Sprintf("asset %s already has a pending transfer", item.AssetInternalID)}
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO asset_transfers (from_user_id, to_user_id, reason, requires_approval,
+		                             acknowledge_hours, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, requested_at
+	`, t.FromUserID, t.ToUserID, t.Reason, t.RequiresApproval, t.AcknowledgeHours, t.RequestedBy).Scan(
+		&t.ID, &t.Status, &t.RequestedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range t.Items {
+		err = tx.QueryRow(`
+			INSERT INTO asset_transfer_items (transfer_id, asset_id) VALUES ($1, $2)
+			RETURNING id
+		`, t.ID, t.Items[i].AssetID).Scan(&t.Items[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !t.RequiresApproval {
+		if err := completeTransferTx(tx, t, t.RequestedBy); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Approve completes a pending transfer. The requester cannot approve their
+// own transfer, and every asset must still be with the sender.
+func (m *TransferModel) Approve(id, reviewerID int64, notes string) (*AssetTransfer, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTransfer(tx.QueryRow(`SELECT `+transferColumns+transferJoins+`
+		WHERE t.id = $1 AND t.status = 'pending'
+		FOR UPDATE OF t`, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("transfer not found or not pending")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if t.RequestedBy != nil && *t.RequestedBy == reviewerID {
+		return nil, &TransferError{Message: "a transfer cannot be approved by the user who requested it"}
+	}
+
+	t.Items, err = getTransferItems(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE asset_transfers SET reviewed_by = $1, reviewed_at = NOW(), review_notes = $2
+		WHERE id = $3
+	`, reviewerID, notes, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := completeTransferTx(tx, t, &reviewerID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.GetByID(id)
+}
+
+// Reject turns down a pending transfer; the assets stay with the sender
+func (m *TransferModel) Reject(id, reviewerID int64, notes string) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_transfers
+		SET status = 'rejected', reviewed_by = $1, reviewed_at = NOW(), review_notes = $2
+		WHERE id = $3 AND status = 'pending'
+	`, reviewerID, notes, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("transfer not found or not pending")
+	}
+
+	return nil
+}
+
+// Cancel withdraws a pending transfer
+func (m *TransferModel) Cancel(id int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE asset_transfers SET status = 'cancelled' WHERE id = $1 AND status = 'pending'
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("transfer not found or not pending")
+	}
+
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx, for writes that run on their
+// own or as part of a caller's transaction
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func getTransferItems(q queryer, transferID int64) ([]AssetTransferItem, error) {
+	rows, err := q.Query(`
+		SELECT i.id, i.asset_id, i.from_custody_id, i.to_custody_id, a.internal_id
+		FROM asset_transfer_items i
+		JOIN assets a ON a.id = i.asset_id
+		WHERE i.transfer_id = $1
+		ORDER BY i.id
+	`, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []AssetTransferItem{}
+	for rows.Next() {
+		var item AssetTransferItem
+		err := rows.Scan(&item.ID, &item.AssetID, &item.FromCustodyID, &item.ToCustodyID, &item.AssetInternalID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Get transfer by ID, with its assets
+func (m *TransferModel) GetByID(id int64) (*AssetTransfer, error) {
+	t, err := scanTransfer(m.DB.QueryRow(`SELECT `+transferColumns+transferJoins+` WHERE t.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("transfer not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	t.Items, err = getTransferItems(m.DB, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (m *TransferModel) queryTransfers(query string, args ...interface{}) ([]AssetTransfer, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []AssetTransfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range transfers {
+		transfers[i].Items, err = getTransferItems(m.DB, transfers[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return transfers, nil
+}
+
+// GetAll lists transfers, optionally by status and by a user on either side,
+// newest first
+func (m *TransferModel) GetAll(status string, userID *int64) ([]AssetTransfer, error) {
+	query := `SELECT ` + transferColumns + transferJoins + `
+		WHERE ($1 = '' OR t.status = $1)
+		AND ($2::bigint IS NULL OR t.from_user_id = $2 OR t.to_user_id = $2)
+		ORDER BY t.requested_at DESC, t.id DESC`
+
+	return m.queryTransfers(query, status, userID)
+}
+
+// GetByAsset returns the transfers an asset has been part of, newest first
+func (m *TransferModel) GetByAsset(assetID int64) ([]AssetTransfer, error) {
+	query := `SELECT ` + transferColumns + transferJoins + `
+		WHERE t.id IN (SELECT transfer_id FROM asset_transfer_items WHERE asset_id = $1)
+		ORDER BY t.requested_at DESC, t.id DESC`
+
+	return m.queryTransfers(query, assetID)
+}
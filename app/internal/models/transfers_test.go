@@ -0,0 +1,137 @@
+// file: app/internal/models/transfers_test.go
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTransferTest(t *testing.T) (*TransferModel, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	model := NewTransferModel(db)
+
+	teardown := func() {
+		db.Close()
+	}
+
+	return model, mock, teardown
+}
+
+func TestTransferModel_Insert(t *testing.T) {
+	model, mock, teardown := setupTransferTest(t)
+	defer teardown()
+
+	now := time.Now()
+	requester := int64(1)
+	sender := int64(7)
+
+	t.Run("immediate transfer moves the asset and custody", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT is_active FROM users`).
+			WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+		mock.ExpectQuery(`SELECT internal_id, status, in_use_by FROM assets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"internal_id", "status", "in_use_by"}).AddRow("DPA-PC012", "IN_USE", sender))
+		mock.ExpectQuery(`FROM asset_transfer_items i`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO asset_transfers`).
+			WithArgs(sender, int64(9), "Team change", false, 48, &requester).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "requested_at"}).AddRow(3, "pending", now))
+		mock.ExpectQuery(`INSERT INTO asset_transfer_items`).
+			WithArgs(int64(3), int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+
+		// completeTransferTx
+		mock.ExpectQuery(`SELECT internal_id, status, in_use_by FROM assets`).
+			WithArgs(int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"internal_id", "status", "in_use_by"}).AddRow("DPA-PC012", "IN_USE", sender))
+		mock.ExpectQuery(`UPDATE asset_custody`).
+			WithArgs("Transferred to user 9 (transfer #3)", &requester, int64(12)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(50))
+		mock.ExpectExec(`UPDATE assets SET in_use_by`).
+			WithArgs(int64(9), int64(12)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO asset_custody`).
+			WithArgs(int64(12), int64(9), &requester, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(51))
+		mock.ExpectExec(`UPDATE asset_transfer_items`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE asset_transfers SET status = 'completed'`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(now))
+		mock.ExpectCommit()
+
+		transfer := &AssetTransfer{
+			FromUserID:       sender,
+			ToUserID:         9,
+			Reason:           "Team change",
+			AcknowledgeHours: 48,
+			RequestedBy:      &requester,
+			Items:            []AssetTransferItem{{AssetID: 12}},
+		}
+
+		err := model.Insert(transfer)
+		require.NoError(t, err)
+		assert.Equal(t, "completed", transfer.Status)
+		assert.Equal(t, int64(50), *transfer.Items[0].FromCustodyID)
+		assert.Equal(t, int64(51), *transfer.Items[0].ToCustodyID)
+	})
+
+	t.Run("asset not with the sender", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT is_active FROM users`).
+			WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+		mock.ExpectQuery(`SELECT internal_id, status, in_use_by FROM assets`).
+			WithArgs(int64(13)).
+			WillReturnRows(sqlmock.NewRows([]string{"internal_id", "status", "in_use_by"}).AddRow("DPA-PC013", "IN_STORAGE", nil))
+		mock.ExpectRollback()
+
+		err := model.Insert(&AssetTransfer{
+			FromUserID: sender,
+			ToUserID:   9,
+			Items:      []AssetTransferItem{{AssetID: 13}},
+		})
+		require.Error(t, err)
+		assert.IsType(t, &TransferError{}, err)
+		assert.Equal(t, "asset DPA-PC013 is not in use by the sending user", err.Error())
+	})
+
+	t.Run("same user on both sides", func(t *testing.T) {
+		err := model.Insert(&AssetTransfer{FromUserID: sender, ToUserID: sender, Items: []AssetTransferItem{{AssetID: 12}}})
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransferModel_Approve(t *testing.T) {
+	model, mock, teardown := setupTransferTest(t)
+	defer teardown()
+
+	requester := int64(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM asset_transfers t`).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "from_user_id", "to_user_id", "status", "reason", "requires_approval",
+			"acknowledge_hours", "requested_by", "requested_at", "reviewed_by", "reviewed_at",
+			"review_notes", "completed_at", "from_name", "to_name",
+		}).AddRow(3, 7, 9, "pending", "", true, 48, requester, time.Now(), nil, nil, "", nil, "Ana", "Ben"))
+	mock.ExpectRollback()
+
+	_, err := model.Approve(3, requester, "")
+	require.Error(t, err)
+	assert.Equal(t, "a transfer cannot be approved by the user who requested it", err.Error())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
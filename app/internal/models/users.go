@@ -51,7 +51,22 @@ func (m *UsersModel) Update(u *User) error {
 }
 
 func (m *UsersModel) Delete(id int64) error {
-	res, err := m.DB.Exec(`DELETE FROM users WHERE id=$1`, id)
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Free the user's license seats; the allocation rows stay as history
+	_, err = tx.Exec(`
+		UPDATE license_allocations SET released_at = NOW(), release_reason = 'user deleted'
+		WHERE user_id = $1 AND released_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM users WHERE id=$1`, id)
 	if err != nil {
 		return err
 	}
@@ -59,7 +74,7 @@ func (m *UsersModel) Delete(id int64) error {
 	if rows == 0 {
 		return errors.New("user not found")
 	}
-	return nil
+	return tx.Commit()
 }
 
 // ADD THESE MISSING METHODS:
@@ -116,18 +116,28 @@ func TestUsersModel_Delete(t *testing.T) {
 	defer teardown()
 
 	t.Run("successful deletion", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
 		mock.ExpectExec(`DELETE FROM users`).
 			WithArgs(int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
 		err := model.Delete(1)
 		assert.NoError(t, err)
 	})
 
 	t.Run("user not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs(int64(999)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
 		mock.ExpectExec(`DELETE FROM users`).
 			WithArgs(int64(999)).
 			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
 
 		err := model.Delete(999)
 		assert.Error(t, err)
@@ -135,13 +145,20 @@ func TestUsersModel_Delete(t *testing.T) {
 	})
 
 	t.Run("database error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE license_allocations`).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
 		mock.ExpectExec(`DELETE FROM users`).
 			WithArgs(int64(1)).
 			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
 
 		err := model.Delete(1)
 		assert.Error(t, err)
 	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUsersModel_GetAll(t *testing.T) {
@@ -21,6 +21,29 @@ func RegisterRoutes(
 	ticketCommentsHandler *handlers.TicketCommentsHandler, // ticket comments handler
 	notificationsHandler *handlers.NotificationsHandler, // notifications handler
 	reportsHandler *handlers.ReportsHandler, // reports handler
+	stocktakeHandler *handlers.StocktakeHandler, // stocktake sessions handler
+	custodyHandler *handlers.CustodyHandler, // asset custody handler
+	reservationsHandler *handlers.ReservationsHandler, // loaner reservations handler
+	disposalsHandler *handlers.DisposalsHandler, // asset disposal handler
+	customFieldsHandler *handlers.CustomFieldsHandler, // asset custom field schema handler
+	licensesHandler *handlers.LicensesHandler, // software license handler
+	stockHandler *handlers.StockHandler, // consumables stock handler
+	purchasingHandler *handlers.PurchasingHandler, // purchase request and order handler
+	repairsHandler *handlers.RepairsHandler, // external repair (RMA) handler
+	attachmentsHandler *handlers.AttachmentsHandler, // asset and service log file attachments handler
+	maintenanceHandler *handlers.MaintenanceHandler, // preventive maintenance plan handler
+	dataQualityHandler *handlers.DataQualityHandler, // asset data-quality checks handler
+	discoveryHandler *handlers.DiscoveryHandler, // network and hardware discovery handler
+	transfersHandler *handlers.TransfersHandler, // asset transfer between users handler
+	ticketWatchersHandler *handlers.TicketWatchersHandler, // ticket watchers handler
+	ticketLinksHandler *handlers.TicketLinksHandler, // ticket relationships and merge handler
+	ticketTagsHandler *handlers.TicketTagsHandler, // ticket tags handler
+	ticketViewsHandler *handlers.TicketViewsHandler, // saved ticket views handler
+	ticketWorklogsHandler *handlers.TicketWorklogsHandler, // ticket worklogs and timers handler
+	csatHandler *handlers.CSATHandler, // ticket satisfaction survey handler
+	ticketMacrosHandler *handlers.TicketMacrosHandler, // canned responses handler
+	knowledgeBaseHandler *handlers.KnowledgeBaseHandler, // knowledge base handler
+	recurringTicketsHandler *handlers.RecurringTicketsHandler, // recurring ticket definitions handler
 	authHandler *handlers.AuthHandler,// new auth handler
 	jwtSecret string,
 ) http.Handler {
@@ -44,6 +67,10 @@ func RegisterRoutes(
 	r.Post("/api/v1/login", authHandler.Login)
 	r.Post("/api/v1/refresh", authHandler.RefreshToken)
 
+	// Satisfaction survey answers from email links, authorised by a signed token
+	r.Get("/api/v1/csat/respond", csatHandler.RespondFromLink)
+	r.Post("/api/v1/csat/respond", csatHandler.RespondWithToken)
+
 	// -----------------------
 	// Protected routes
 	// -----------------------
@@ -104,13 +131,22 @@ func RegisterRoutes(
 			r.With(authMiddleware.RequirePermission("assets:read")).Get("/stats", assetSearchHandler.GetAssetStats)// Asset stats
 			r.With(authMiddleware.RequirePermission("assets:read")).Get("/types", assetSearchHandler.GetAssetTypes)// Asset types
 			r.With(authMiddleware.RequirePermission("assets:read")).Get("/manufacturers", assetSearchHandler.GetManufacturers)// Manufacturers
+			r.With(authMiddleware.RequirePermission("assets:manage")).Get("/data-quality", dataQualityHandler.GetReport) // Data-quality findings
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/data-quality/fix", dataQualityHandler.ApplyFix) // Bulk fix safe findings
 			
 			r.Route("/{id}", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", assetsHandler.GetAsset)// Get asset
 				r.With(authMiddleware.RequirePermission("assets:update")).Put("/", assetsHandler.UpdateAsset)// Update asset
-				r.With(authMiddleware.RequirePermission("assets:delete")).Delete("/", assetsHandler.DeleteAsset)// Delete asset
+				r.With(authMiddleware.RequirePermission("system:admin")).Delete("/", assetsHandler.DeleteAsset)// Dispose of an asset without approval - Admin only
 				r.With(authMiddleware.RequirePermission("assets:update")).Post("/assign", assetAssignmentHandler.AssignAsset)// Assign asset
 				r.With(authMiddleware.RequirePermission("assets:update")).Post("/unassign", assetAssignmentHandler.UnassignAsset)// Unassign asset
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/custody", custodyHandler.GetAssetCustody)// Custody history
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/disposal", disposalsHandler.RequestDisposal)// Request retirement
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/repairs", repairsHandler.GetAssetRepairs)// Repair history
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/repairs", repairsHandler.CreateRepair)// Open external repair
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/files", attachmentsHandler.GetAssetFiles)// List attachments
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/files", attachmentsHandler.UploadAssetFile)// Upload attachment
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/transfers", transfersHandler.GetAssetTransfers)// Transfer history
 				
 				// Service logs for specific asset
 				r.Route("/service-logs", func(r chi.Router) {
@@ -125,6 +161,18 @@ func RegisterRoutes(
 			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketsHandler.ListTickets)
 			r.With(authMiddleware.RequirePermission("tickets:create")).Post("/", ticketsHandler.CreateTicket)
 			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/stats", ticketsHandler.GetTicketStats)
+			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/search", ticketsHandler.SearchTickets)
+			r.With(authMiddleware.RequirePermission("tickets:update")).Post("/bulk", ticketsHandler.BulkUpdateTickets)
+			r.With(authMiddleware.RequirePermission("tickets:update")).Get("/timer", ticketWorklogsHandler.GetRunningTimer)
+
+			// Saved views; open one with GET /api/v1/tickets?view={id}
+			r.Route("/views", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketViewsHandler.ListViews)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Post("/", ticketViewsHandler.CreateView)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Get("/{viewID}", ticketViewsHandler.GetView)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Put("/{viewID}", ticketViewsHandler.UpdateView)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Delete("/{viewID}", ticketViewsHandler.DeleteView)
+			})
 			
 			r.Route("/{id}", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketsHandler.GetTicket)
@@ -149,6 +197,110 @@ r.With(authMiddleware.RequirePermission("tickets:verify")).Post("/reset-verifica
 					r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketCommentsHandler.GetComments)
 					r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", ticketCommentsHandler.CreateComment)
 				})
+
+				// Ticket watchers
+				r.With(authMiddleware.RequirePermission("tickets:read")).Post("/watch", ticketWatchersHandler.Watch)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Delete("/watch", ticketWatchersHandler.Unwatch)
+				r.Route("/watchers", func(r chi.Router) {
+					r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketWatchersHandler.GetWatchers)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", ticketWatchersHandler.AddWatcher)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Delete("/{userID}", ticketWatchersHandler.RemoveWatcher)
+				})
+
+				// Ticket relationships
+				r.Route("/links", func(r chi.Router) {
+					r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketLinksHandler.GetLinks)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", ticketLinksHandler.AddLink)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Delete("/{linkID}", ticketLinksHandler.RemoveLink)
+				})
+				r.With(authMiddleware.RequirePermission("tickets:manage")).Post("/merge", ticketLinksHandler.MergeTickets)
+
+				// Ticket tags
+				r.With(authMiddleware.RequirePermission("tickets:update")).Put("/tags", ticketTagsHandler.SetTicketTags)
+
+				// Time tracking
+				r.Route("/worklogs", func(r chi.Router) {
+					r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketWorklogsHandler.GetWorklogs)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", ticketWorklogsHandler.AddWorklog)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Put("/{worklogID}", ticketWorklogsHandler.UpdateWorklog)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Delete("/{worklogID}", ticketWorklogsHandler.DeleteWorklog)
+				})
+				r.With(authMiddleware.RequirePermission("tickets:update")).Post("/timer/start", ticketWorklogsHandler.StartTimer)
+				r.With(authMiddleware.RequirePermission("tickets:update")).Post("/timer/stop", ticketWorklogsHandler.StopTimer)
+
+				// Canned responses
+				r.With(authMiddleware.RequirePermission("tickets:update")).Get("/macros/{macroID}/render", ticketMacrosHandler.RenderMacro)
+				r.With(authMiddleware.RequirePermission("tickets:update")).Post("/macros/{macroID}/apply", ticketMacrosHandler.ApplyMacro)
+
+				// Knowledge base articles linked to the ticket
+				r.Route("/articles", func(r chi.Router) {
+					r.With(authMiddleware.RequirePermission("kb:read")).Get("/", knowledgeBaseHandler.GetTicketArticles)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", knowledgeBaseHandler.LinkTicketArticle)
+					r.With(authMiddleware.RequirePermission("tickets:update")).Delete("/{articleID}", knowledgeBaseHandler.UnlinkTicketArticle)
+				})
+			})
+		})
+
+		// Recurring tickets, raised by the scheduler in the API process
+		protected.Route("/api/v1/recurring-tickets", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", recurringTicketsHandler.ListRecurringTickets)
+			r.With(authMiddleware.RequirePermission("tickets:manage")).Post("/", recurringTicketsHandler.CreateRecurringTicket)
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", recurringTicketsHandler.GetRecurringTicket)
+				r.With(authMiddleware.RequirePermission("tickets:manage")).Put("/", recurringTicketsHandler.UpdateRecurringTicket)
+				r.With(authMiddleware.RequirePermission("tickets:manage")).Delete("/", recurringTicketsHandler.DeleteRecurringTicket)
+				r.With(authMiddleware.RequirePermission("tickets:manage")).Post("/pause", recurringTicketsHandler.PauseRecurringTicket)
+				r.With(authMiddleware.RequirePermission("tickets:manage")).Post("/resume", recurringTicketsHandler.ResumeRecurringTicket)
+				r.With(authMiddleware.RequirePermission("tickets:read")).Get("/runs", recurringTicketsHandler.GetRuns)
+			})
+		})
+
+		// Knowledge base: everyone reads, IT writes
+		protected.Route("/api/v1/kb", func(r chi.Router) {
+			r.Route("/categories", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("kb:read")).Get("/", knowledgeBaseHandler.ListCategories)
+				r.With(authMiddleware.RequirePermission("kb:manage")).Post("/", knowledgeBaseHandler.CreateCategory)
+				r.With(authMiddleware.RequirePermission("kb:manage")).Put("/{id}", knowledgeBaseHandler.UpdateCategory)
+				r.With(authMiddleware.RequirePermission("kb:manage")).Delete("/{id}", knowledgeBaseHandler.DeleteCategory)
+			})
+			r.Route("/articles", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("kb:read")).Get("/", knowledgeBaseHandler.ListArticles)
+				r.With(authMiddleware.RequirePermission("kb:manage")).Post("/", knowledgeBaseHandler.CreateArticle)
+				r.With(authMiddleware.RequirePermission("kb:read")).Get("/suggest", knowledgeBaseHandler.SuggestArticles)
+				r.Route("/{id}", func(r chi.Router) {
+					r.With(authMiddleware.RequirePermission("kb:read")).Get("/", knowledgeBaseHandler.GetArticle)
+					r.With(authMiddleware.RequirePermission("kb:manage")).Put("/", knowledgeBaseHandler.UpdateArticle)
+					r.With(authMiddleware.RequirePermission("kb:manage")).Delete("/", knowledgeBaseHandler.DeleteArticle)
+					r.With(authMiddleware.RequirePermission("kb:manage")).Get("/versions", knowledgeBaseHandler.GetVersions)
+					r.With(authMiddleware.RequirePermission("kb:manage")).Post("/versions/{version}/restore", knowledgeBaseHandler.RestoreVersion)
+				})
+			})
+		})
+
+		// Canned responses: personal, or shared with everyone by their owner
+		protected.Route("/api/v1/ticket-macros", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("tickets:update")).Get("/", ticketMacrosHandler.ListMacros)
+			r.With(authMiddleware.RequirePermission("tickets:update")).Post("/", ticketMacrosHandler.CreateMacro)
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("tickets:update")).Get("/", ticketMacrosHandler.GetMacro)
+				r.With(authMiddleware.RequirePermission("tickets:update")).Put("/", ticketMacrosHandler.UpdateMacro)
+				r.With(authMiddleware.RequirePermission("tickets:update")).Delete("/", ticketMacrosHandler.DeleteMacro)
+			})
+		})
+
+		// Satisfaction surveys for the current user
+		protected.Route("/api/v1/csat", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/pending", csatHandler.ListPending)
+			r.With(authMiddleware.RequirePermission("tickets:read")).Post("/{id}/respond", csatHandler.Respond)
+		})
+
+		// Ticket tag list, curated by admins
+		protected.Route("/api/v1/ticket-tags", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("tickets:read")).Get("/", ticketTagsHandler.ListTags)
+			r.With(authMiddleware.RequirePermission("system:admin")).Post("/", ticketTagsHandler.CreateTag)
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("system:admin")).Put("/", ticketTagsHandler.UpdateTag)
+				r.With(authMiddleware.RequirePermission("system:admin")).Delete("/", ticketTagsHandler.DeleteTag)
 			})
 		})
 
@@ -156,6 +308,10 @@ r.With(authMiddleware.RequirePermission("tickets:verify")).Post("/reset-verifica
 		protected.Route("/api/v1/service-logs", func(r chi.Router) {
 			r.Route("/{id}", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", assetServiceHandler.GetServiceLog)// Get service log
+				r.With(authMiddleware.RequirePermission("assets:update")).Put("/", assetServiceHandler.UpdateServiceLog)// Update service log
+				r.With(authMiddleware.RequirePermission("assets:update")).Delete("/", assetServiceHandler.DeleteServiceLog)// Delete service log
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/files", attachmentsHandler.GetServiceLogFiles)// List attachments
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/files", attachmentsHandler.UploadServiceLogFile)// Upload attachment
 			})
 		})
 
@@ -179,11 +335,204 @@ r.With(authMiddleware.RequirePermission("tickets:verify")).Post("/reset-verifica
 			})
 		})
 
+		// Stocktake (physical inventory audit) routes
+		protected.Route("/api/v1/stocktakes", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", stocktakeHandler.ListSessions)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/", stocktakeHandler.CreateSession)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", stocktakeHandler.GetSession)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/scans", stocktakeHandler.RecordScan)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/scans", stocktakeHandler.GetScans)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/reconciliation", stocktakeHandler.GetReconciliation)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/close", stocktakeHandler.CloseSession)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/apply", stocktakeHandler.ApplyCorrections)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/report", stocktakeHandler.GetReport)
+			})
+		})
+
+		// Asset custody (handover acknowledgement) routes
+		protected.Route("/api/v1/custody", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/pending", custodyHandler.GetMyPendingHandovers)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Get("/overdue", custodyHandler.GetOverdueHandovers)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/overdue/remind", custodyHandler.RemindOverdueHandovers)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Post("/accept", custodyHandler.AcceptHandover)
+			})
+		})
+
+		// Loaner pool reservations and loans
+		protected.Route("/api/v1/reservations", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", reservationsHandler.ListReservations)
+			r.With(authMiddleware.RequirePermission("assets:read")).Post("/", reservationsHandler.CreateReservation)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Get("/overdue", reservationsHandler.GetOverdueLoans)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/overdue/notify", reservationsHandler.NotifyOverdueLoans)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", reservationsHandler.GetReservation)
+				r.With(authMiddleware.RequirePermission("assets:read")).Post("/cancel", reservationsHandler.CancelReservation)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/checkout", reservationsHandler.CheckOutLoan)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/return", reservationsHandler.ReturnLoan)
+			})
+		})
+
+		// Asset disposal workflow
+		protected.Route("/api/v1/disposals", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", disposalsHandler.ListDisposals)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", disposalsHandler.GetDisposal)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/approve", disposalsHandler.ApproveDisposal)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/reject", disposalsHandler.RejectDisposal)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/data-wipe", disposalsHandler.ConfirmDataWipe)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/complete", disposalsHandler.CompleteDisposal)
+			})
+		})
+
+		// Custom field schemas per asset type - Admin defines, everyone with asset access reads
+		protected.Route("/api/v1/custom-fields", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", customFieldsHandler.ListCustomFields)
+			r.With(authMiddleware.RequirePermission("system:admin")).Post("/", customFieldsHandler.CreateCustomField)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("system:admin")).Put("/", customFieldsHandler.UpdateCustomField)
+				r.With(authMiddleware.RequirePermission("system:admin")).Delete("/", customFieldsHandler.DeleteCustomField)
+			})
+		})
+
+		// Software license routes
+		protected.Route("/api/v1/licenses", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", licensesHandler.ListLicenses)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/", licensesHandler.CreateLicense)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/over-allocated", licensesHandler.GetOverAllocated)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/renewals", licensesHandler.GetRenewals)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/renewals/notify", licensesHandler.NotifyRenewals)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", licensesHandler.GetLicense)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Put("/", licensesHandler.UpdateLicense)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Delete("/", licensesHandler.DeleteLicense)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/allocations", licensesHandler.GetAllocations)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/allocations", licensesHandler.AllocateSeat)
+			})
+		})
+
+		protected.Route("/api/v1/license-allocations", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:update")).Post("/{id}/release", licensesHandler.ReleaseSeat)
+		})
+
+		// Consumables and spare-parts stock routes
+		protected.Route("/api/v1/stock", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/low", stockHandler.GetLowStock)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/items", stockHandler.ListItems)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/items", stockHandler.CreateItem)
+
+			r.Route("/items/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", stockHandler.GetItem)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Put("/", stockHandler.UpdateItem)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Delete("/", stockHandler.DeleteItem)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/movements", stockHandler.GetMovements)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/in", stockHandler.StockIn)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/out", stockHandler.StockOut)
+			})
+		})
+
+		// Purchase request and purchase order routes
+		protected.Route("/api/v1/purchase-requests", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", purchasingHandler.ListRequests)
+			r.With(authMiddleware.RequirePermission("assets:create")).Post("/", purchasingHandler.CreateRequest)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", purchasingHandler.GetRequest)
+				r.With(authMiddleware.RequirePermission("system:admin")).Post("/approve", purchasingHandler.ApproveRequest)
+				r.With(authMiddleware.RequirePermission("system:admin")).Post("/reject", purchasingHandler.RejectRequest)
+			})
+		})
+
+		protected.Route("/api/v1/purchase-orders", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", purchasingHandler.ListOrders)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/", purchasingHandler.CreateOrder)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", purchasingHandler.GetOrder)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Put("/", purchasingHandler.UpdateOrder)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/cancel", purchasingHandler.CancelOrder)
+				r.With(authMiddleware.RequirePermission("assets:create")).Post("/receive", purchasingHandler.ReceiveGoods)
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/assets", purchasingHandler.GetOrderAssets)
+			})
+		})
+
+		// Attachment download and removal
+		protected.Route("/api/v1/files/{id}", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", attachmentsHandler.DownloadFile)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/thumbnail", attachmentsHandler.GetThumbnail)
+			r.With(authMiddleware.RequirePermission("assets:update")).Delete("/", attachmentsHandler.DeleteFile)
+		})
+
+		// External repair (RMA) routes
+		protected.Route("/api/v1/repairs", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", repairsHandler.ListRepairs)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/overdue", repairsHandler.GetOverdueRepairs)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", repairsHandler.GetRepair)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/ship", repairsHandler.ShipRepair)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/return", repairsHandler.ReturnRepair)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/cancel", repairsHandler.CancelRepair)
+			})
+		})
+
+		// Preventive maintenance plans and calendar
+		protected.Route("/api/v1/maintenance", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/calendar", maintenanceHandler.GetCalendar)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/generate-tickets", maintenanceHandler.GenerateTickets)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/plans", maintenanceHandler.ListPlans)
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/plans", maintenanceHandler.CreatePlan)
+
+			r.Route("/plans/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", maintenanceHandler.GetPlan)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Put("/", maintenanceHandler.UpdatePlan)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Delete("/", maintenanceHandler.DeletePlan)
+			})
+		})
+
+		// Asset transfers between users
+		protected.Route("/api/v1/asset-transfers", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/", transfersHandler.ListTransfers)
+			r.With(authMiddleware.RequirePermission("assets:update")).Post("/", transfersHandler.CreateTransfer)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", transfersHandler.GetTransfer)
+				r.With(authMiddleware.RequirePermission("assets:approve_transfer")).Post("/approve", transfersHandler.ApproveTransfer)
+				r.With(authMiddleware.RequirePermission("assets:approve_transfer")).Post("/reject", transfersHandler.RejectTransfer)
+				r.With(authMiddleware.RequirePermission("assets:update")).Post("/cancel", transfersHandler.CancelTransfer)
+			})
+		})
+
+		// Network and hardware discovery snapshots
+		protected.Route("/api/v1/discovery", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission("assets:manage")).Post("/import", discoveryHandler.Import)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/imports", discoveryHandler.ListImports)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/devices", discoveryHandler.ListDevices)
+			r.With(authMiddleware.RequirePermission("assets:read")).Get("/stale", discoveryHandler.GetStale)
+
+			r.Route("/devices/{id}", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("assets:read")).Get("/", discoveryHandler.GetDevice)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/link", discoveryHandler.LinkDevice)
+				r.With(authMiddleware.RequirePermission("assets:manage")).Post("/ignore", discoveryHandler.IgnoreDevice)
+			})
+		})
+
 		// Reports routes
 		protected.Route("/api/v1/reports", func(r chi.Router) {
 			r.With(authMiddleware.RequirePermission("reports:read")).Post("/analytics", reportsHandler.GetAnalytics)
 			r.With(authMiddleware.RequirePermission("reports:export")).Post("/export/csv", reportsHandler.ExportCSV)
 			r.With(authMiddleware.RequirePermission("reports:read")).Get("/types", reportsHandler.GetReportTypes)
+			r.With(authMiddleware.RequirePermission("reports:read")).Get("/consumption", reportsHandler.GetConsumptionReport)
+			r.With(authMiddleware.RequirePermission("reports:read")).Get("/tco", reportsHandler.GetTCOReport)
+			r.With(authMiddleware.RequirePermission("reports:read")).Get("/time", reportsHandler.GetTimeReport)
+			r.With(authMiddleware.RequirePermission("reports:read")).Get("/csat", reportsHandler.GetCSATReport)
 		})
 	}) // This closes the protected group
 
@@ -21,22 +21,49 @@ func NewServer(db *sql.DB, cfg *config.Config) *http.Server {
 	// Initialize email service
 	emailService := services.NewEmailService(cfg)
 
+	// Attachment files are kept on local disk
+	fileStorage := services.NewLocalFileStorage(cfg.UploadDir)
+
 	// Initialize handlers with config
 	usersHandler := handlers.NewUsersHandler(db, emailService)// New users handler with email service
 	rolesHandler := handlers.NewRolesHandler(db)// New roles handler
-	assetsHandler := handlers.NewAssetsHandler(db)// New assets handler
-	assetServiceHandler := handlers.NewAssetServiceHandler(db)// New asset service handler
+	assetsHandler := handlers.NewAssetsHandler(db, fileStorage)// New assets handler
+	assetServiceHandler := handlers.NewAssetServiceHandler(db, fileStorage)// New asset service handler
 	assetAssignmentHandler := handlers.NewAssetAssignmentHandler(db) // New asset assignment handler
 	ticketsHandler := handlers.NewTicketsHandler(db, emailService) //tickets handler with email service
 	ticketCommentsHandler := handlers.NewTicketCommentsHandler(db,emailService) // ticket comments handler with email service
 	assetSearchHandler := handlers.NewAssetSearchHandler(db)// New asset search handler
 	notificationsHandler := handlers.NewNotificationsHandler(db) // New notifications handler
 	reportsHandler := handlers.NewReportsHandler(db) // New reports handler
+	stocktakeHandler := handlers.NewStocktakeHandler(db) // stocktake sessions handler
+	custodyHandler := handlers.NewCustodyHandler(db) // asset custody handler
+	reservationsHandler := handlers.NewReservationsHandler(db) // loaner reservations handler
+	disposalsHandler := handlers.NewDisposalsHandler(db) // asset disposal handler
+	customFieldsHandler := handlers.NewCustomFieldsHandler(db) // asset custom field schema handler
+	licensesHandler := handlers.NewLicensesHandler(db) // software license handler
+	stockHandler := handlers.NewStockHandler(db) // consumables stock handler
+	purchasingHandler := handlers.NewPurchasingHandler(db) // purchase request and order handler
+	repairsHandler := handlers.NewRepairsHandler(db) // external repair (RMA) handler
+	attachmentsHandler := handlers.NewAttachmentsHandler(db, fileStorage) // asset and service log file attachments handler
+	maintenanceHandler := handlers.NewMaintenanceHandler(db) // preventive maintenance plan handler
+	dataQualityHandler := handlers.NewDataQualityHandler(db) // asset data-quality checks handler
+	discoveryHandler := handlers.NewDiscoveryHandler(db) // network and hardware discovery handler
+	transfersHandler := handlers.NewTransfersHandler(db) // asset transfer between users handler
+	ticketWatchersHandler := handlers.NewTicketWatchersHandler(db) // ticket watchers handler
+	ticketLinksHandler := handlers.NewTicketLinksHandler(db) // ticket relationships and merge handler
+	ticketTagsHandler := handlers.NewTicketTagsHandler(db) // ticket tags handler
+	ticketViewsHandler := handlers.NewTicketViewsHandler(db) // saved ticket views handler
+	ticketWorklogsHandler := handlers.NewTicketWorklogsHandler(db) // ticket worklogs and timers handler
+	csatHandler := handlers.NewCSATHandler(db, cfg.JWTSecret) // ticket satisfaction survey handler
+	ticketMacrosHandler := handlers.NewTicketMacrosHandler(db, emailService) // canned responses handler
+	knowledgeBaseHandler := handlers.NewKnowledgeBaseHandler(db) // knowledge base handler
+	recurringTicketsHandler := handlers.NewRecurringTicketsHandler(db) // recurring ticket definitions handler
 	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret)// New auth handler
 
 	// Register routes using handlers and JWT secret
 	router := routes.RegisterRoutes(usersHandler, rolesHandler, assetsHandler, assetServiceHandler, assetAssignmentHandler, assetSearchHandler,
-		                           ticketsHandler, ticketCommentsHandler,notificationsHandler, reportsHandler, authHandler, cfg.JWTSecret) // Register routes
+		                           ticketsHandler, ticketCommentsHandler,notificationsHandler, reportsHandler,
+		                           stocktakeHandler, custodyHandler, reservationsHandler, disposalsHandler, customFieldsHandler, licensesHandler, stockHandler, purchasingHandler, repairsHandler, attachmentsHandler, maintenanceHandler, dataQualityHandler, discoveryHandler, transfersHandler, ticketWatchersHandler, ticketLinksHandler, ticketTagsHandler, ticketViewsHandler, ticketWorklogsHandler, csatHandler, ticketMacrosHandler, knowledgeBaseHandler, recurringTicketsHandler, authHandler, cfg.JWTSecret) // Register routes
 
 	return &http.Server{
 		Addr:         ":" + port,
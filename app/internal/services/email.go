@@ -2,7 +2,10 @@ package services
 
 import (
 	"fmt"
+	"html"
 	"net/smtp"
+	"net/url"
+	"strings"
     "time"
 	"victortillett.net/internal-inventory-tracker/internal/config"
 )
@@ -244,6 +247,72 @@ IT Support Team
 	return es.SendHTMLEmail(to, subject, htmlBody, textBody)
 }
 
+// SendTicketBulkUpdateEmail sends one digest of the changes a bulk update
+// made to the recipient's tickets, one line per ticket
+func (es *EmailService) SendTicketBulkUpdateEmail(to, updatedBy string, lines []string) error {
+	subject := fmt.Sprintf("%d Tickets Updated", len(lines))
+	if len(lines) == 1 {
+		subject = "1 Ticket Updated"
+	}
+
+	var htmlItems, textItems strings.Builder
+	for _, line := range lines {
+		htmlItems.WriteString("            <li>" + html.EscapeString(line) + "</li>\n")
+		textItems.WriteString("- " + line + "\n")
+	}
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; }
+        .header { background: #f4f4f4; padding: 10px; border-left: 4px solid #28a745; }
+        .content { padding: 20px; }
+        .ticket-info { background: #f9f9f9; padding: 15px; border-radius: 5px; }
+        .button { background: #007cba; color: white; padding: 10px 20px; text-decoration: none; border-radius: 3px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h2>Tickets Updated</h2>
+    </div>
+    <div class="content">
+        <p>Hello,</p>
+        <p>%s updated these tickets at %s:</p>
+        
+        <div class="ticket-info">
+          <ul>
+%s          </ul>
+        </div>
+        
+        <p>Please log in to the system for more details.</p>
+        
+        <p>
+            <a href="http://localhost:8081" class="button">View Tickets</a>
+        </p>
+        
+        <p>Best regards,<br>IT Support Team</p>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(updatedBy), es.getCurrentTime(), htmlItems.String())
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+%s updated these tickets at %s:
+
+%s
+Please log in to the system for more details.
+
+Best regards,
+IT Support Team
+	`, updatedBy, es.getCurrentTime(), textItems.String())
+
+	return es.SendHTMLEmail(to, subject, htmlBody, textBody)
+}
+
 // SendTicketCommentEmail notifies about new comments
 func (es *EmailService) SendTicketCommentEmail(to, ticketNumber, ticketTitle, comment, commentBy string) error {
 	subject := fmt.Sprintf("New Comment on Ticket: %s", ticketNumber)
@@ -305,6 +374,73 @@ IT Support Team
 	return es.SendHTMLEmail(to, subject, htmlBody, textBody)
 }
 
+// SendCSATSurveyEmail asks a ticket's creator to rate how it was handled.
+// Each rating links, signed for this survey, to a page confirming it.
+func (es *EmailService) SendCSATSurveyEmail(to, ticketNumber, ticketTitle, technician string, surveyID int64) error {
+	subject := fmt.Sprintf("How did we do? Ticket %s", ticketNumber)
+
+	token := SignSurveyToken(surveyID, es.config.JWTSecret)
+	labels := []string{"Very poor", "Poor", "Okay", "Good", "Excellent"}
+
+	var htmlLinks, textLinks string
+	for rating := 1; rating <= 5; rating++ {
+		link := fmt.Sprintf("%s/api/v1/csat/respond?token=%s&rating=%d",
+			strings.TrimSuffix(es.config.AppURL, "/"), url.QueryEscape(token), rating)
+		htmlLinks += fmt.Sprintf(`<a href="%s" class="rating">%d - %s</a> `, link, rating, labels[rating-1])
+		textLinks += fmt.Sprintf("%d - %s: %s\n", rating, labels[rating-1], link)
+	}
+
+	if technician == "" {
+		technician = "IT Support"
+	}
+
+	htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; }
+        .header { background: #f4f4f4; padding: 10px; border-left: 4px solid #28a745; }
+        .content { padding: 20px; }
+        .rating { display: inline-block; padding: 8px 12px; margin: 4px; background: #28a745; color: white; text-decoration: none; border-radius: 4px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h2>Your Ticket Is Closed</h2>
+    </div>
+    <div class="content">
+        <p>Hello,</p>
+        <p>Ticket <strong>%s</strong> (%s), handled by %s, has been verified and closed.</p>
+        <p>How satisfied are you with how it was handled? Click a rating:</p>
+
+        <p>%s</p>
+
+        <p>You can change your answer or add a comment in the app until the survey closes in 14 days.</p>
+
+        <p>Best regards,<br>IT Support Team</p>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(ticketNumber), html.EscapeString(ticketTitle), html.EscapeString(technician), htmlLinks)
+
+	textBody := fmt.Sprintf(`
+Hello,
+
+Ticket %s (%s), handled by %s, has been verified and closed.
+
+How satisfied are you with how it was handled? Open a link to rate it:
+
+%s
+You can change your answer or add a comment in the app until the survey closes in 14 days.
+
+Best regards,
+IT Support Team
+	`, ticketNumber, ticketTitle, technician, textLinks)
+
+	return es.SendHTMLEmail(to, subject, htmlBody, textBody)
+}
+
 // SendAssetServiceReminder sends reminder for asset service
 func (es *EmailService) SendAssetServiceReminder(to, assetID, assetType, assetModel, nextServiceDate string) error {
 	subject := fmt.Sprintf("Service Reminder: %s", assetID)
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+// MaintenanceTicketScheduler raises a ticket for each asset entering its
+// maintenance plan's lead window. Each ticket is created in the same
+// transaction that records it against the plan, asset and due date, so every
+// due date gets exactly one ticket however often generation runs.
+type MaintenanceTicketScheduler struct {
+	MaintenanceModel    *models.MaintenanceModel
+	NotificationService *NotificationService
+	Interval            time.Duration // How often to look for maintenance coming due
+}
+
+func NewMaintenanceTicketScheduler(db *sql.DB) *MaintenanceTicketScheduler {
+	return &MaintenanceTicketScheduler{
+		MaintenanceModel:    models.NewMaintenanceModel(db),
+		NotificationService: NewNotificationService(db),
+		Interval:            time.Hour,
+	}
+}
+
+// Run raises due maintenance tickets every Interval until ctx is cancelled
+func (s *MaintenanceTicketScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		tickets, err := s.GenerateDue(nil)
+		if err != nil {
+			fmt.Printf("Failed to raise maintenance tickets: %v\n", err)
+		}
+		for _, ticket := range tickets {
+			if err := s.NotificationService.NotifyTicketCreated(ticket); err != nil {
+				fmt.Printf("Failed to send maintenance ticket notifications: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GenerateDue raises the tickets for maintenance inside its lead window that
+// has none yet and returns them. createdBy is nil when run by the scheduler.
+// Tickets raised before an error are returned with it.
+func (s *MaintenanceTicketScheduler) GenerateDue(createdBy *int64) ([]*models.Ticket, error) {
+	due, err := s.MaintenanceModel.GetTicketsDue()
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []*models.Ticket{}
+	for _, item := range due {
+		priority := "normal"
+		if item.Overdue {
+			priority = "high"
+		}
+
+		assetID := item.AssetID
+		ticket := &models.Ticket{
+			Title: fmt.Sprintf("%s: %s", item.PlanName, item.AssetInternalID),
+			Description: fmt.Sprintf("Preventive maintenance (%s) for %s %s is due on %s. Log a %s service on the asset when done.",
+				item.PlanName, item.AssetType, item.AssetInternalID, item.DueDate.Format("2006-01-02"), item.ServiceType),
+			Type:       "maintenance",
+			Priority:   priority,
+			Status:     "open",
+			CreatedBy:  createdBy,
+			AssignedTo: item.AssignTo,
+			AssetID:    &assetID,
+			IsInternal: true,
+		}
+
+		raised, err := s.MaintenanceModel.RaiseTicket(item.PlanID, item.AssetID, item.DueDate, ticket)
+		if err != nil {
+			return tickets, err
+		}
+		if raised { // Otherwise raised by a concurrent run
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"victortillett.net/internal-inventory-tracker/internal/models"
 )
@@ -10,12 +11,14 @@ import (
 type NotificationService struct {
 	NotificationModel *models.NotificationModel
 	UserModel         *models.UsersModel
+	WatcherModel      *models.TicketWatcherModel
 }
 
 func NewNotificationService(db *sql.DB) *NotificationService {
 	return &NotificationService{
 		NotificationModel: models.NewNotificationModel(db),
 		UserModel:         models.NewUsersModel(db),
+		WatcherModel:      models.NewTicketWatcherModel(db),
 	}
 }
 
@@ -207,6 +210,10 @@ func (s *NotificationService) NotifyTicketCreated(ticket *models.Ticket) error {
 	if err != nil {
 		return err
 	}
+	users, err = s.addTicketWatchers(users, ticket.ID)
+	if err != nil {
+		return err
+	}
 
 	var notifications []models.Notification
 	ticketID := ticket.ID
@@ -233,6 +240,10 @@ func (s *NotificationService) NotifyTicketUpdated(ticket *models.Ticket, updater
 	if err != nil {
 		return err
 	}
+	users, err = s.addTicketWatchers(users, ticket.ID)
+	if err != nil {
+		return err
+	}
 
 	var notifications []models.Notification
 	ticketID := ticket.ID
@@ -258,6 +269,84 @@ func (s *NotificationService) NotifyTicketUpdated(ticket *models.Ticket, updater
 	return s.NotificationModel.CreateBulk(notifications)
 }
 
+// NotifyTicketsBulkUpdated sends each recipient one notification covering
+// every ticket a bulk update changed that they follow, rather than one per
+// ticket
+func (s *NotificationService) NotifyTicketsBulkUpdated(tickets []*models.Ticket, updaterUserID int64) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	users, err := s.getUsersForTicketNotifications()
+	if err != nil {
+		return err
+	}
+
+	// Ticket notification users hear about every ticket; watchers only
+	// about the ones they watch
+	perUser := map[int64][]*models.Ticket{}
+	watcherOnly := map[int64]bool{}
+	for _, user := range users {
+		perUser[user.ID] = tickets
+	}
+	for _, ticket := range tickets {
+		watchers, err := s.WatcherModel.GetRecipients(ticket.ID)
+		if err != nil {
+			return err
+		}
+		for _, watcher := range watchers {
+			if _, ok := perUser[watcher.ID]; !ok {
+				users = append(users, watcher)
+				watcherOnly[watcher.ID] = true
+			}
+			if watcherOnly[watcher.ID] {
+				perUser[watcher.ID] = append(perUser[watcher.ID], ticket)
+			}
+		}
+	}
+
+	var notifications []models.Notification
+	for _, user := range users {
+		// Don't notify the user who made the update
+		if user.ID == updaterUserID {
+			continue
+		}
+
+		userTickets := perUser[user.ID]
+		if len(userTickets) == 1 {
+			ticketID := userTickets[0].ID
+			notifications = append(notifications, models.Notification{
+				UserID:      user.ID,
+				Title:       "Ticket updated",
+				Message:     fmt.Sprintf("Ticket #%s: %s - updated", userTickets[0].TicketNum, userTickets[0].Title),
+				Type:        "ticket_updated",
+				RelatedID:   &ticketID,
+				RelatedType: stringPtr("ticket"),
+				IsRead:      false,
+			})
+			continue
+		}
+
+		var nums []string
+		for i, ticket := range userTickets {
+			if i == 5 {
+				nums = append(nums, fmt.Sprintf("and %d more", len(userTickets)-5))
+				break
+			}
+			nums = append(nums, "#"+ticket.TicketNum)
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:  user.ID,
+			Title:   fmt.Sprintf("%d tickets updated", len(userTickets)),
+			Message: "Bulk update of tickets " + strings.Join(nums, ", "),
+			Type:    "ticket_updated",
+			IsRead:  false,
+		})
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
 // NotifyAssetCreated sends notifications when an asset is created
 func (s *NotificationService) NotifyAssetCreated(asset *models.Asset) error {
 	// Only notify admins and IT staff for assets
@@ -285,6 +374,480 @@ func (s *NotificationService) NotifyAssetCreated(asset *models.Asset) error {
 	return s.NotificationModel.CreateBulk(notifications)
 }
 
+// NotifyCustodyPending asks the recipient of a handover to acknowledge it
+func (s *NotificationService) NotifyCustodyPending(custody *models.AssetCustody, asset *models.Asset) error {
+	custodyID := custody.ID
+	notification := models.Notification{
+		UserID:      custody.UserID,
+		Title:       "Asset Handover Pending",
+		Message:     fmt.Sprintf("Asset %s (%s %s) has been assigned to you. Please confirm receipt by %s", asset.InternalID, asset.Manufacturer, asset.Model, custody.AcknowledgeBy.Format("2006-01-02 15:04")),
+		Type:        "custody_pending",
+		RelatedID:   &custodyID,
+		RelatedType: stringPtr("custody"),
+		IsRead:      false,
+	}
+
+	return s.NotificationModel.Create(&notification)
+}
+
+// NotifyCustodyOverdue reminds recipients of unacknowledged handovers and flags them to IT staff
+func (s *NotificationService) NotifyCustodyOverdue(records []models.AssetCustody) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	itStaff, err := s.getITStaffUsers()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	for _, record := range records {
+		custodyID := record.ID
+
+		notifications = append(notifications, models.Notification{
+			UserID:      record.UserID,
+			Title:       "Asset Handover Overdue",
+			Message:     fmt.Sprintf("Please confirm receipt of asset %s", record.AssetInternalID),
+			Type:        "custody_overdue",
+			RelatedID:   &custodyID,
+			RelatedType: stringPtr("custody"),
+			IsRead:      false,
+		})
+
+		for _, staff := range itStaff {
+			notifications = append(notifications, models.Notification{
+				UserID:      staff.ID,
+				Title:       "Unacknowledged Asset Handover",
+				Message:     fmt.Sprintf("%s has not acknowledged asset %s (due %s)", record.UserFullName, record.AssetInternalID, record.AcknowledgeBy.Format("2006-01-02 15:04")),
+				Type:        "custody_overdue",
+				RelatedID:   &custodyID,
+				RelatedType: stringPtr("custody"),
+				IsRead:      false,
+			})
+		}
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyLoansOverdue reminds borrowers of overdue loans and flags them to IT staff
+func (s *NotificationService) NotifyLoansOverdue(loans []models.AssetReservation) error {
+	if len(loans) == 0 {
+		return nil
+	}
+
+	itStaff, err := s.getITStaffUsers()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	for _, loan := range loans {
+		loanID := loan.ID
+		due := ""
+		if loan.DueAt != nil {
+			due = loan.DueAt.Format("2006-01-02 15:04")
+		}
+
+		notifications = append(notifications, models.Notification{
+			UserID:      loan.UserID,
+			Title:       "Loan Overdue",
+			Message:     fmt.Sprintf("Asset %s was due back on %s. Please return it to IT", loan.AssetInternalID, due),
+			Type:        "loan_overdue",
+			RelatedID:   &loanID,
+			RelatedType: stringPtr("reservation"),
+			IsRead:      false,
+		})
+
+		for _, staff := range itStaff {
+			notifications = append(notifications, models.Notification{
+				UserID:      staff.ID,
+				Title:       "Overdue Loan",
+				Message:     fmt.Sprintf("%s has not returned asset %s (due %s)", loan.UserFullName, loan.AssetInternalID, due),
+				Type:        "loan_overdue",
+				RelatedID:   &loanID,
+				RelatedType: stringPtr("reservation"),
+				IsRead:      false,
+			})
+		}
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyDisposalRequested asks asset managers to review a disposal request
+func (s *NotificationService) NotifyDisposalRequested(disposal *models.AssetDisposal) error {
+	users, err := s.getUsersForAssetNotifications()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	disposalID := disposal.ID
+
+	for _, user := range users {
+		// Don't notify the requester
+		if disposal.RequestedBy != nil && user.ID == *disposal.RequestedBy {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:      user.ID,
+			Title:       "Disposal Approval Needed",
+			Message:     fmt.Sprintf("Asset %s has been put forward for disposal: %s", disposal.AssetInternalID, disposal.Reason),
+			Type:        "disposal_requested",
+			RelatedID:   &disposalID,
+			RelatedType: stringPtr("disposal"),
+			IsRead:      false,
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyDisposalReviewed tells the requester whether their disposal request was approved
+func (s *NotificationService) NotifyDisposalReviewed(disposal *models.AssetDisposal, approved bool) error {
+	if disposal.RequestedBy == nil {
+		return nil
+	}
+
+	action := "approved"
+	if !approved {
+		action = "rejected"
+	}
+
+	disposalID := disposal.ID
+	notification := models.Notification{
+		UserID:      *disposal.RequestedBy,
+		Title:       fmt.Sprintf("Disposal %s", action),
+		Message:     fmt.Sprintf("Disposal of asset %s was %s", disposal.AssetInternalID, action),
+		Type:        "disposal_reviewed",
+		RelatedID:   &disposalID,
+		RelatedType: stringPtr("disposal"),
+		IsRead:      false,
+	}
+
+	return s.NotificationModel.Create(&notification)
+}
+
+// NotifyLicenseRenewals warns asset managers about licenses coming up for renewal
+func (s *NotificationService) NotifyLicenseRenewals(licenses []models.License) error {
+	if len(licenses) == 0 {
+		return nil
+	}
+
+	users, err := s.getUsersForAssetNotifications()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	for _, license := range licenses {
+		licenseID := license.ID
+		renewal := ""
+		if license.RenewalDate != nil {
+			renewal = license.RenewalDate.Format("2006-01-02")
+		}
+
+		for _, user := range users {
+			notifications = append(notifications, models.Notification{
+				UserID:      user.ID,
+				Title:       "License Renewal Due",
+				Message:     fmt.Sprintf("%s (%s) renews on %s: %d of %d seats in use", license.Product, license.Vendor, renewal, license.SeatsUsed, license.Seats),
+				Type:        "license_renewal",
+				RelatedID:   &licenseID,
+				RelatedType: stringPtr("license"),
+				IsRead:      false,
+			})
+		}
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyLowStock tells IT staff that a consumable has reached its reorder threshold
+func (s *NotificationService) NotifyLowStock(item *models.StockItem) error {
+	itStaff, err := s.getITStaffUsers()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	itemID := item.ID
+
+	for _, staff := range itStaff {
+		notification := models.Notification{
+			UserID:      staff.ID,
+			Title:       "Low Stock",
+			Message:     fmt.Sprintf("%s (%s) is down to %d %s. Reorder %d", item.Name, item.SKU, item.TotalQuantity, item.Unit, item.ReorderQuantity),
+			Type:        "low_stock",
+			RelatedID:   &itemID,
+			RelatedType: stringPtr("stock_item"),
+			IsRead:      false,
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyPurchaseRequested asks admins to review a purchase request
+func (s *NotificationService) NotifyPurchaseRequested(request *models.PurchaseRequest) error {
+	itStaff, err := s.getITStaffUsers()
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	requestID := request.ID
+
+	for _, user := range itStaff {
+		// Only admins can approve purchases
+		if user.RoleID != 1 {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:      user.ID,
+			Title:       "Purchase Approval Needed",
+			Message:     fmt.Sprintf("%s requested: %s (quantity %d)", request.RequestedByName, request.Title, request.Quantity),
+			Type:        "purchase_requested",
+			RelatedID:   &requestID,
+			RelatedType: stringPtr("purchase_request"),
+			IsRead:      false,
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyPurchaseReviewed tells the requester whether their purchase request was approved
+func (s *NotificationService) NotifyPurchaseReviewed(request *models.PurchaseRequest) error {
+	if request.RequestedBy == nil {
+		return nil
+	}
+
+	requestID := request.ID
+	notification := models.Notification{
+		UserID:      *request.RequestedBy,
+		Title:       fmt.Sprintf("Purchase request %s", request.Status),
+		Message:     fmt.Sprintf("Your purchase request \"%s\" was %s", request.Title, request.Status),
+		Type:        "purchase_reviewed",
+		RelatedID:   &requestID,
+		RelatedType: stringPtr("purchase_request"),
+		IsRead:      false,
+	}
+
+	return s.NotificationModel.Create(&notification)
+}
+
+// transferAssetList names the assets in a transfer for notification messages
+func transferAssetList(transfer *models.AssetTransfer) string {
+	ids := make([]string, len(transfer.Items))
+	for i, item := range transfer.Items {
+		ids[i] = item.AssetInternalID
+	}
+	return strings.Join(ids, ", ")
+}
+
+// NotifyTransferRequested asks team leads to approve a transfer and lets both
+// users know it is on the way
+func (s *NotificationService) NotifyTransferRequested(transfer *models.AssetTransfer) error {
+	approvers, err := s.getUsersWithPermission("assets:approve_transfer")
+	if err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	transferID := transfer.ID
+	assets := transferAssetList(transfer)
+
+	for _, user := range approvers {
+		// The requester cannot approve their own transfer
+		if transfer.RequestedBy != nil && user.ID == *transfer.RequestedBy {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:      user.ID,
+			Title:       "Asset Transfer Approval Needed",
+			Message:     fmt.Sprintf("Transfer of %s from %s to %s needs approval: %s", assets, transfer.FromUserName, transfer.ToUserName, transfer.Reason),
+			Type:        "transfer_requested",
+			RelatedID:   &transferID,
+			RelatedType: stringPtr("asset_transfer"),
+			IsRead:      false,
+		})
+	}
+
+	for _, userID := range []int64{transfer.FromUserID, transfer.ToUserID} {
+		notifications = append(notifications, models.Notification{
+			UserID:      userID,
+			Title:       "Asset Transfer Requested",
+			Message:     fmt.Sprintf("%s is to be transferred from %s to %s, pending approval", assets, transfer.FromUserName, transfer.ToUserName),
+			Type:        "transfer_requested",
+			RelatedID:   &transferID,
+			RelatedType: stringPtr("asset_transfer"),
+			IsRead:      false,
+		})
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyTransferCompleted tells both users that the assets have moved. The
+// recipient is asked to confirm receipt, as with any handover.
+func (s *NotificationService) NotifyTransferCompleted(transfer *models.AssetTransfer) error {
+	transferID := transfer.ID
+	assets := transferAssetList(transfer)
+
+	notifications := []models.Notification{
+		{
+			UserID:      transfer.FromUserID,
+			Title:       "Asset Transferred",
+			Message:     fmt.Sprintf("%s has been transferred from you to %s", assets, transfer.ToUserName),
+			Type:        "transfer_completed",
+			RelatedID:   &transferID,
+			RelatedType: stringPtr("asset_transfer"),
+			IsRead:      false,
+		},
+		{
+			UserID:      transfer.ToUserID,
+			Title:       "Asset Transferred to You",
+			Message:     fmt.Sprintf("%s has been transferred to you from %s. Please confirm receipt", assets, transfer.FromUserName),
+			Type:        "transfer_completed",
+			RelatedID:   &transferID,
+			RelatedType: stringPtr("asset_transfer"),
+			IsRead:      false,
+		},
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyTransferRejected tells the requester and the sender that a transfer was turned down
+func (s *NotificationService) NotifyTransferRejected(transfer *models.AssetTransfer) error {
+	transferID := transfer.ID
+
+	recipients := []int64{transfer.FromUserID}
+	if transfer.RequestedBy != nil && *transfer.RequestedBy != transfer.FromUserID {
+		recipients = append(recipients, *transfer.RequestedBy)
+	}
+
+	var notifications []models.Notification
+	for _, userID := range recipients {
+		notifications = append(notifications, models.Notification{
+			UserID:      userID,
+			Title:       "Asset Transfer Rejected",
+			Message:     fmt.Sprintf("Transfer of %s to %s was rejected: %s", transferAssetList(transfer), transfer.ToUserName, transfer.ReviewNotes),
+			Type:        "transfer_rejected",
+			RelatedID:   &transferID,
+			RelatedType: stringPtr("asset_transfer"),
+			IsRead:      false,
+		})
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyTicketComment tells the creator, assignee and watchers of a ticket
+// about a new comment. Users @mentioned in it get a mention instead, and an
+// internal comment only reaches users who can read internal comments.
+func (s *NotificationService) NotifyTicketComment(ticket *models.Ticket, comment *models.TicketComment, mentioned []models.User) error {
+	recipients, err := s.WatcherModel.GetRecipients(ticket.ID)
+	if err != nil {
+		return err
+	}
+	for _, user := range mentioned {
+		if !s.containsUser(recipients, user.ID) {
+			recipients = append(recipients, user)
+		}
+	}
+
+	var notifications []models.Notification
+	ticketID := ticket.ID
+
+	for _, user := range recipients {
+		if comment.AuthorID != nil && user.ID == *comment.AuthorID {
+			continue
+		}
+		if comment.IsInternal && !models.CanViewInternalComments(user.RoleID) {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:      user.ID,
+			Title:       "New Comment",
+			Message:     fmt.Sprintf("New comment on ticket #%s: %s", ticket.TicketNum, ticket.Title),
+			Type:        "ticket_comment",
+			RelatedID:   &ticketID,
+			RelatedType: stringPtr("ticket"),
+			IsRead:      false,
+		}
+		if s.containsUser(mentioned, user.ID) {
+			notification.Title = "You Were Mentioned"
+			notification.Message = fmt.Sprintf("You were mentioned on ticket #%s: %s", ticket.TicketNum, ticket.Title)
+			notification.Type = "ticket_mention"
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// NotifyTicketMentioned tells users they were @mentioned in an edited comment
+func (s *NotificationService) NotifyTicketMentioned(ticket *models.Ticket, comment *models.TicketComment, mentioned []models.User) error {
+	var notifications []models.Notification
+	ticketID := ticket.ID
+
+	for _, user := range mentioned {
+		if comment.AuthorID != nil && user.ID == *comment.AuthorID {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:      user.ID,
+			Title:       "You Were Mentioned",
+			Message:     fmt.Sprintf("You were mentioned on ticket #%s: %s", ticket.TicketNum, ticket.Title),
+			Type:        "ticket_mention",
+			RelatedID:   &ticketID,
+			RelatedType: stringPtr("ticket"),
+			IsRead:      false,
+		})
+	}
+
+	return s.NotificationModel.CreateBulk(notifications)
+}
+
+// addTicketWatchers appends a ticket's watchers to a notification list,
+// skipping users already on it
+func (s *NotificationService) addTicketWatchers(users []models.User, ticketID int64) ([]models.User, error) {
+	watchers, err := s.WatcherModel.GetRecipients(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	for _, watcher := range watchers {
+		if !s.containsUser(users, watcher.ID) {
+			users = append(users, watcher)
+		}
+	}
+	return users, nil
+}
+
+// NotifyCSATSurvey asks a closed ticket's creator to rate how it was handled
+func (s *NotificationService) NotifyCSATSurvey(ticket *models.Ticket, survey *models.CSATSurvey) error {
+	surveyID := survey.ID
+	return s.NotificationModel.Create(&models.Notification{
+		UserID:      survey.UserID,
+		Title:       "How Did We Do?",
+		Message:     fmt.Sprintf("Ticket #%s is closed. Rate how it was handled: %s", ticket.TicketNum, ticket.Title),
+		Type:        "csat_survey",
+		RelatedID:   &surveyID,
+		RelatedType: stringPtr("csat_survey"),
+		IsRead:      false,
+	})
+}
+
 // Get users who should receive ticket notifications (Admin, IT, Staff, Agent)
 func (s *NotificationService) getUsersForTicketNotifications() ([]models.User, error) {
 	query := `
@@ -353,6 +916,41 @@ func (s *NotificationService) getUsersForAssetNotifications() ([]models.User, er
 	return users, nil
 }
 
+// Get active users whose role grants a permission
+func (s *NotificationService) getUsersWithPermission(permission string) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, u.email, u.role_id
+		FROM users u
+		JOIN role_permissions rp ON rp.role_id = u.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE p.name = $1 AND u.is_active = true
+	`
+
+	rows, err := s.UserModel.DB.Query(query, permission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.FullName,
+			&user.Email,
+			&user.RoleID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
 // Helper function
 func stringPtr(s string) *string {
 	return &s
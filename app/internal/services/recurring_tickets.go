@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"victortillett.net/internal-inventory-tracker/internal/models"
+)
+
+// RecurringTicketScheduler raises the tickets of recurring ticket definitions
+// as they fall due. Each occurrence's ticket is created in the same
+// transaction that records the occurrence as run, so it gets exactly one
+// ticket across restarts and even with several API processes running.
+type RecurringTicketScheduler struct {
+	RecurringModel      *models.RecurringTicketModel
+	TicketModel         *models.TicketModel
+	NotificationService *NotificationService
+	Interval            time.Duration // How often to look for due definitions
+}
+
+func NewRecurringTicketScheduler(db *sql.DB) *RecurringTicketScheduler {
+	return &RecurringTicketScheduler{
+		RecurringModel:      models.NewRecurringTicketModel(db),
+		TicketModel:         models.NewTicketModel(db),
+		NotificationService: NewNotificationService(db),
+		Interval:            time.Minute,
+	}
+}
+
+// Run raises due tickets every Interval until ctx is cancelled
+func (s *RecurringTicketScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		tickets, err := s.RunDue(time.Now())
+		if err != nil {
+			fmt.Printf("Failed to raise recurring tickets: %v\n", err)
+		}
+		for _, ticket := range tickets {
+			if err := s.NotificationService.NotifyTicketCreated(ticket); err != nil {
+				fmt.Printf("Failed to send recurring ticket notifications: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maxOccurrencesPerRun bounds how far one run catches up a definition that
+// missed many occurrences; the rest are raised on the following runs
+const maxOccurrencesPerRun = 50
+
+// RunDue raises a ticket for every occurrence due at now and returns them.
+// A definition that fails is logged and retried on the next run.
+func (s *RecurringTicketScheduler) RunDue(now time.Time) ([]*models.Ticket, error) {
+	due, err := s.RecurringModel.GetDue(now)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []*models.Ticket{}
+	for i := range due {
+		raised, err := s.raise(&due[i], now)
+		tickets = append(tickets, raised...)
+		if err != nil {
+			fmt.Printf("Failed to raise recurring ticket %q: %v\n", due[i].Name, err)
+		}
+	}
+	return tickets, nil
+}
+
+// raise creates a ticket for each of a due definition's occurrences up to
+// now, oldest first, including any missed while the API was down
+func (s *RecurringTicketScheduler) raise(r *models.RecurringTicket, now time.Time) ([]*models.Ticket, error) {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	var tickets []*models.Ticket
+	occurrence := *r.NextRunAt
+	for n := 0; n < maxOccurrencesPerRun && !occurrence.After(now); n++ {
+		next, err := r.NextOccurrence(occurrence)
+		if err != nil {
+			return tickets, err
+		}
+		var nextRunAt *time.Time
+		if !next.IsZero() {
+			nextRunAt = &next
+		}
+
+		description := r.Description
+		if description != "" {
+			description += "\n\n"
+		}
+		description += fmt.Sprintf("Raised automatically by the recurring ticket %q for %s.",
+			r.Name, occurrence.In(loc).Format("2006-01-02 15:04 MST"))
+
+		ticket := &models.Ticket{
+			Title:       fmt.Sprintf("%s (%s)", r.Title, occurrence.In(loc).Format("2006-01-02")),
+			Description: description,
+			Type:        r.Type,
+			Priority:    r.Priority,
+			Status:      "open",
+			CreatedBy:   r.CreatedBy,
+			AssignedTo:  r.AssignedTo,
+			AssetID:     r.AssetID,
+			IsInternal:  r.IsInternal,
+		}
+		raised, advanced, err := s.RecurringModel.RaiseOccurrence(r.ID, occurrence, nextRunAt, ticket)
+		if err != nil {
+			return tickets, err
+		}
+		if raised {
+			tickets = append(tickets, ticket)
+		}
+
+		// Edited, paused or taken over elsewhere: the next run starts
+		// from the stored state
+		if !advanced || nextRunAt == nil {
+			break
+		}
+		occurrence = next
+	}
+	return tickets, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStorage stores uploaded attachment files under opaque keys such as
+// "assets/12/3f9c...e1.pdf". Keys are generated by the server, never taken
+// from the client.
+type FileStorage interface {
+	// Save writes r under key and returns the number of bytes written
+	Save(key string, r io.Reader) (int64, error)
+	// Open returns the stored file; the caller must close it
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the file. Deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// LocalFileStorage keeps files on the local filesystem under Root
+type LocalFileStorage struct {
+	Root string
+}
+
+func NewLocalFileStorage(root string) *LocalFileStorage {
+	return &LocalFileStorage{Root: root}
+}
+
+// path resolves a key to a file under Root, rejecting keys that would escape it
+func (s *LocalFileStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.Root, clean), nil
+}
+
+func (s *LocalFileStorage) Save(key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+
+	// Write to a temp file first so a failed upload never leaves a partial file behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (s *LocalFileStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.New("file not found")
+	}
+	return f, err
+}
+
+func (s *LocalFileStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	_ "image/png" // register PNG decoder
+	"io"
+)
+
+// ThumbnailSize is the longest edge of generated thumbnails, in pixels
+const ThumbnailSize = 256
+
+// maxThumbnailPixels guards against decompression bombs: a tiny file that
+// declares enormous dimensions
+const maxThumbnailPixels = 50_000_000
+
+// ThumbnailableTypes are the image types the standard library can decode
+var ThumbnailableTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// MakeThumbnail decodes an image and returns a JPEG scaled down so its
+// longest edge is at most ThumbnailSize. Smaller images are re-encoded as is.
+func MakeThumbnail(r io.ReadSeeker) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, errors.New("image too large to thumbnail")
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			tw, th = ThumbnailSize, h*ThumbnailSize/w
+		} else {
+			tw, th = w*ThumbnailSize/h, ThumbnailSize
+		}
+		if tw < 1 {
+			tw = 1
+		}
+		if th < 1 {
+			th = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+
+	// Box filter: each thumbnail pixel is the average of the source pixels it covers
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+
+			var rs, gs, bs, as, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					rs += uint64(cr)
+					gs += uint64(cg)
+					bs += uint64(cb)
+					as += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(rs / n),
+				G: uint16(gs / n),
+				B: uint16(bs / n),
+				A: uint16(as / n),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
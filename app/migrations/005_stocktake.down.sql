@@ -0,0 +1,11 @@
+-- 005_stocktake.down.sql
+DROP INDEX IF EXISTS idx_stocktake_corrections_session_id;
+DROP INDEX IF EXISTS idx_stocktake_scans_session_id;
+DROP INDEX IF EXISTS idx_stocktake_sessions_status;
+DROP INDEX IF EXISTS idx_assets_location;
+
+DROP TABLE IF EXISTS stocktake_corrections;
+DROP TABLE IF EXISTS stocktake_scans;
+DROP TABLE IF EXISTS stocktake_sessions;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS location;
@@ -0,0 +1,49 @@
+-- 005_stocktake.up.sql
+
+-- Physical location of each asset (site, floor, room)
+ALTER TABLE assets ADD COLUMN location TEXT NOT NULL DEFAULT '';
+
+-- stocktake sessions scoped to a location and/or asset type
+CREATE TABLE stocktake_sessions (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  location TEXT NOT NULL DEFAULT '',    -- empty = all locations
+  asset_type TEXT NOT NULL DEFAULT '',  -- empty = all asset types
+  status TEXT NOT NULL DEFAULT 'open',  -- open, closed
+  notes TEXT NOT NULL DEFAULT '',
+  started_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  closed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reconciliation JSONB,                 -- snapshot taken when the session is closed
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  closed_at TIMESTAMP
+);
+
+-- scans recorded by technicians during a session
+CREATE TABLE stocktake_scans (
+  id BIGSERIAL PRIMARY KEY,
+  session_id BIGINT NOT NULL REFERENCES stocktake_sessions(id) ON DELETE CASCADE,
+  scanned_code TEXT NOT NULL,           -- internal_id or serial_number as read from the label
+  asset_id BIGINT REFERENCES assets(id) ON DELETE SET NULL,
+  location TEXT NOT NULL DEFAULT '',    -- where the item was found
+  observed_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL, -- who was using it
+  scanned_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  notes TEXT NOT NULL DEFAULT '',
+  scanned_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- corrections applied to assets from a reconciliation
+CREATE TABLE stocktake_corrections (
+  id BIGSERIAL PRIMARY KEY,
+  session_id BIGINT NOT NULL REFERENCES stocktake_sessions(id) ON DELETE CASCADE,
+  asset_id BIGINT REFERENCES assets(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,                 -- update_location, update_assignee
+  old_value TEXT NOT NULL DEFAULT '',
+  new_value TEXT NOT NULL DEFAULT '',
+  applied_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_assets_location ON assets (location);
+CREATE INDEX idx_stocktake_sessions_status ON stocktake_sessions (status);
+CREATE INDEX idx_stocktake_scans_session_id ON stocktake_scans (session_id);
+CREATE INDEX idx_stocktake_corrections_session_id ON stocktake_corrections (session_id);
@@ -0,0 +1,6 @@
+-- 006_asset_custody.down.sql
+DROP INDEX IF EXISTS idx_asset_custody_status;
+DROP INDEX IF EXISTS idx_asset_custody_user_id;
+DROP INDEX IF EXISTS idx_asset_custody_open;
+
+DROP TABLE IF EXISTS asset_custody;
@@ -0,0 +1,24 @@
+-- 006_asset_custody.up.sql
+
+-- custody records: one row per handover of an asset to a user
+CREATE TABLE asset_custody (
+  id BIGSERIAL PRIMARY KEY,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending',     -- pending, accepted, returned, cancelled
+  assigned_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  assigned_at TIMESTAMP NOT NULL DEFAULT now(),
+  acknowledge_by TIMESTAMP NOT NULL,          -- deadline for the recipient to accept
+  accepted_at TIMESTAMP,
+  accept_condition TEXT NOT NULL DEFAULT '',  -- new, good, fair, poor, damaged
+  accept_notes TEXT NOT NULL DEFAULT '',
+  returned_at TIMESTAMP,
+  return_condition TEXT NOT NULL DEFAULT '',
+  return_notes TEXT NOT NULL DEFAULT '',
+  received_by BIGINT REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- at most one open (pending or accepted) custody record per asset
+CREATE UNIQUE INDEX idx_asset_custody_open ON asset_custody (asset_id) WHERE status IN ('pending', 'accepted');
+CREATE INDEX idx_asset_custody_user_id ON asset_custody (user_id);
+CREATE INDEX idx_asset_custody_status ON asset_custody (status);
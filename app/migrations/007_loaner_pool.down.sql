@@ -0,0 +1,9 @@
+-- 007_loaner_pool.down.sql
+DROP INDEX IF EXISTS idx_asset_reservations_status;
+DROP INDEX IF EXISTS idx_asset_reservations_user_id;
+DROP INDEX IF EXISTS idx_asset_reservations_asset_id;
+DROP INDEX IF EXISTS idx_assets_loanable;
+
+DROP TABLE IF EXISTS asset_reservations;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS loanable;
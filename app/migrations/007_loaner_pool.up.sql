@@ -0,0 +1,27 @@
+-- 007_loaner_pool.up.sql
+
+-- Assets in the loaner pool can be reserved and borrowed temporarily
+ALTER TABLE assets ADD COLUMN loanable BOOLEAN NOT NULL DEFAULT false;
+
+-- time-boxed reservations and loans of loanable assets
+CREATE TABLE asset_reservations (
+  id BIGSERIAL PRIMARY KEY,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- borrower
+  starts_at TIMESTAMP NOT NULL,
+  ends_at TIMESTAMP NOT NULL,
+  status TEXT NOT NULL DEFAULT 'reserved',  -- reserved, checked_out, returned, cancelled
+  purpose TEXT NOT NULL DEFAULT '',
+  reserved_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  checked_out_at TIMESTAMP,
+  due_at TIMESTAMP,                         -- expected return date once checked out
+  returned_at TIMESTAMP,
+  overdue_notified_at TIMESTAMP,            -- last overdue reminder sent
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_assets_loanable ON assets (loanable);
+CREATE INDEX idx_asset_reservations_asset_id ON asset_reservations (asset_id);
+CREATE INDEX idx_asset_reservations_user_id ON asset_reservations (user_id);
+CREATE INDEX idx_asset_reservations_status ON asset_reservations (status);
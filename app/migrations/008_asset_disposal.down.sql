@@ -0,0 +1,8 @@
+-- 008_asset_disposal.down.sql
+DROP INDEX IF EXISTS idx_assets_deleted_at;
+DROP INDEX IF EXISTS idx_asset_disposals_status;
+DROP INDEX IF EXISTS idx_asset_disposals_open;
+
+DROP TABLE IF EXISTS asset_disposals;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS deleted_at;
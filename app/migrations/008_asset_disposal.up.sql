@@ -0,0 +1,30 @@
+-- 008_asset_disposal.up.sql
+
+-- Assets are soft-deleted so service history and reports survive disposal
+ALTER TABLE assets ADD COLUMN deleted_at TIMESTAMP;
+
+-- retirement requests and their approval, data wipe and disposal details
+CREATE TABLE asset_disposals (
+  id BIGSERIAL PRIMARY KEY,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'requested',  -- requested, approved, rejected, completed
+  reason TEXT NOT NULL,
+  requested_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  requested_at TIMESTAMP NOT NULL DEFAULT now(),
+  reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMP,
+  review_notes TEXT NOT NULL DEFAULT '',
+  data_wipe_confirmed BOOLEAN NOT NULL DEFAULT false,  -- required for PCs before completion
+  data_wipe_method TEXT NOT NULL DEFAULT '',
+  data_wipe_confirmed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  data_wipe_confirmed_at TIMESTAMP,
+  disposal_method TEXT NOT NULL DEFAULT '',  -- recycle, donate, sell
+  certificate_ref TEXT NOT NULL DEFAULT '',
+  completed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  completed_at TIMESTAMP
+);
+
+-- one disposal in progress per asset
+CREATE UNIQUE INDEX idx_asset_disposals_open ON asset_disposals (asset_id) WHERE status IN ('requested', 'approved');
+CREATE INDEX idx_asset_disposals_status ON asset_disposals (status);
+CREATE INDEX idx_assets_deleted_at ON assets (deleted_at);
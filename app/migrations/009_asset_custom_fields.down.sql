@@ -0,0 +1,7 @@
+-- 009_asset_custom_fields.down.sql
+DROP INDEX IF EXISTS idx_asset_custom_fields_asset_type;
+DROP INDEX IF EXISTS idx_assets_custom_fields;
+
+DROP TABLE IF EXISTS asset_custom_fields;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS custom_fields;
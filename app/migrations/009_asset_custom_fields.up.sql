@@ -0,0 +1,23 @@
+-- 009_asset_custom_fields.up.sql
+
+-- Custom field values keyed by field key, validated against asset_custom_fields
+ALTER TABLE assets ADD COLUMN custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- admin-defined custom field schema per asset type
+CREATE TABLE asset_custom_fields (
+  id BIGSERIAL PRIMARY KEY,
+  asset_type TEXT NOT NULL,
+  key TEXT NOT NULL,                      -- e.g. cpu, ram_gb, hostname, mac_address
+  label TEXT NOT NULL,
+  field_type TEXT NOT NULL,               -- text, number, date, enum, boolean
+  required BOOLEAN NOT NULL DEFAULT false,
+  is_unique BOOLEAN NOT NULL DEFAULT false,  -- unique among assets of the same type
+  options JSONB NOT NULL DEFAULT '[]',    -- allowed values for enum fields
+  sort_order INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (asset_type, key)
+);
+
+CREATE INDEX idx_assets_custom_fields ON assets USING GIN (custom_fields);
+CREATE INDEX idx_asset_custom_fields_asset_type ON asset_custom_fields (asset_type);
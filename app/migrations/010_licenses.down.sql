@@ -0,0 +1,9 @@
+-- 010_licenses.down.sql
+DROP INDEX IF EXISTS idx_licenses_renewal_date;
+DROP INDEX IF EXISTS idx_license_allocations_asset_id;
+DROP INDEX IF EXISTS idx_license_allocations_user_id;
+DROP INDEX IF EXISTS idx_license_allocations_asset_active;
+DROP INDEX IF EXISTS idx_license_allocations_user_active;
+
+DROP TABLE IF EXISTS license_allocations;
+DROP TABLE IF EXISTS licenses;
@@ -0,0 +1,37 @@
+-- 010_licenses.up.sql
+
+-- software licenses and subscriptions
+CREATE TABLE licenses (
+  id BIGSERIAL PRIMARY KEY,
+  product TEXT NOT NULL,                  -- e.g. softphone, CRM, Windows 11 Pro
+  vendor TEXT NOT NULL DEFAULT '',
+  seats INT NOT NULL CHECK (seats >= 0),
+  license_key TEXT NOT NULL DEFAULT '',
+  term TEXT NOT NULL DEFAULT 'annual',    -- perpetual, monthly, annual
+  start_date DATE,
+  renewal_date DATE,
+  notes TEXT NOT NULL DEFAULT '',
+  renewal_notified_for DATE,              -- renewal_date the last reminder was sent for
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- seats allocated to a user or a PC; released rows are kept as history
+CREATE TABLE license_allocations (
+  id BIGSERIAL PRIMARY KEY,
+  license_id BIGINT NOT NULL REFERENCES licenses(id) ON DELETE CASCADE,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  asset_id BIGINT REFERENCES assets(id) ON DELETE SET NULL,
+  allocated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  allocated_at TIMESTAMP NOT NULL DEFAULT now(),
+  released_at TIMESTAMP,
+  release_reason TEXT NOT NULL DEFAULT '',
+  -- an active seat belongs to exactly one user or asset
+  CHECK (released_at IS NOT NULL OR (user_id IS NULL) <> (asset_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_license_allocations_user_active ON license_allocations (license_id, user_id) WHERE released_at IS NULL AND user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_license_allocations_asset_active ON license_allocations (license_id, asset_id) WHERE released_at IS NULL AND asset_id IS NOT NULL;
+CREATE INDEX idx_license_allocations_user_id ON license_allocations (user_id);
+CREATE INDEX idx_license_allocations_asset_id ON license_allocations (asset_id);
+CREATE INDEX idx_licenses_renewal_date ON licenses (renewal_date);
@@ -0,0 +1,9 @@
+-- 011_consumables.down.sql
+DROP INDEX IF EXISTS idx_stock_movements_service_log_id;
+DROP INDEX IF EXISTS idx_stock_movements_ticket_id;
+DROP INDEX IF EXISTS idx_stock_movements_created_at;
+DROP INDEX IF EXISTS idx_stock_movements_item_id;
+
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS stock_items;
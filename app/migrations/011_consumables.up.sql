@@ -0,0 +1,46 @@
+-- 011_consumables.up.sql
+
+-- quantity-tracked consumables and spare parts (cables, ear cushions, batteries)
+CREATE TABLE stock_items (
+  id BIGSERIAL PRIMARY KEY,
+  sku TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  category TEXT NOT NULL DEFAULT '',      -- e.g. cables, headset spares, batteries
+  unit TEXT NOT NULL DEFAULT 'each',
+  reorder_threshold INT NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0), -- low stock at or below this total
+  reorder_quantity INT NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0),   -- suggested order size
+  notes TEXT NOT NULL DEFAULT '',
+  low_stock_notified BOOLEAN NOT NULL DEFAULT false, -- reset once stock is back above the threshold
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- on-hand quantity per item and location
+CREATE TABLE stock_levels (
+  item_id BIGINT NOT NULL REFERENCES stock_items(id) ON DELETE CASCADE,
+  location TEXT NOT NULL DEFAULT '',
+  quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (item_id, location)
+);
+
+-- every stock-in and stock-out
+CREATE TABLE stock_movements (
+  id BIGSERIAL PRIMARY KEY,
+  item_id BIGINT NOT NULL REFERENCES stock_items(id) ON DELETE CASCADE,
+  location TEXT NOT NULL DEFAULT '',
+  movement_type TEXT NOT NULL,            -- in, out
+  quantity INT NOT NULL CHECK (quantity > 0),
+  unit_cost NUMERIC(12,2),                -- purchase price for stock-in
+  reference TEXT NOT NULL DEFAULT '',     -- invoice or order number for stock-in
+  ticket_id BIGINT REFERENCES tickets(id) ON DELETE SET NULL,
+  service_log_id BIGINT REFERENCES asset_service(id) ON DELETE SET NULL,
+  performed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_stock_movements_item_id ON stock_movements (item_id);
+CREATE INDEX idx_stock_movements_created_at ON stock_movements (created_at);
+CREATE INDEX idx_stock_movements_ticket_id ON stock_movements (ticket_id);
+CREATE INDEX idx_stock_movements_service_log_id ON stock_movements (service_log_id);
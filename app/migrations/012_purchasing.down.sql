@@ -0,0 +1,12 @@
+-- 012_purchasing.down.sql
+DROP INDEX IF EXISTS idx_assets_purchase_order_line_id;
+DROP INDEX IF EXISTS idx_purchase_order_lines_order_id;
+DROP INDEX IF EXISTS idx_purchase_orders_status;
+DROP INDEX IF EXISTS idx_purchase_requests_status;
+
+ALTER TABLE assets DROP COLUMN IF EXISTS purchase_order_line_id;
+ALTER TABLE assets DROP COLUMN IF EXISTS purchase_cost;
+
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS purchase_requests;
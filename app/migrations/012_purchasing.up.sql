@@ -0,0 +1,57 @@
+-- 012_purchasing.up.sql
+
+-- requests to buy equipment, approved by an admin before anything is ordered
+CREATE TABLE purchase_requests (
+  id BIGSERIAL PRIMARY KEY,
+  title TEXT NOT NULL,                    -- e.g. "10x 27in monitors for the new team"
+  justification TEXT NOT NULL DEFAULT '',
+  asset_type TEXT NOT NULL DEFAULT '',
+  quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+  estimated_cost NUMERIC(12,2),
+  status TEXT NOT NULL DEFAULT 'pending', -- pending, approved, rejected, ordered
+  requested_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMP,
+  review_notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- purchase orders placed with a vendor
+CREATE TABLE purchase_orders (
+  id BIGSERIAL PRIMARY KEY,
+  po_number TEXT UNIQUE,                  -- PO-2025-0001 unless the vendor's number is given
+  purchase_request_id BIGINT REFERENCES purchase_requests(id) ON DELETE SET NULL,
+  vendor TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'open',    -- open, partially_received, received, cancelled
+  order_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  expected_delivery DATE,
+  notes TEXT NOT NULL DEFAULT '',
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- what was ordered; each received unit becomes an asset
+CREATE TABLE purchase_order_lines (
+  id BIGSERIAL PRIMARY KEY,
+  purchase_order_id BIGINT NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+  asset_type TEXT NOT NULL,
+  manufacturer TEXT NOT NULL DEFAULT '',
+  model TEXT NOT NULL DEFAULT '',
+  model_number TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  quantity INT NOT NULL CHECK (quantity > 0),
+  unit_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  quantity_received INT NOT NULL DEFAULT 0,
+  CHECK (quantity_received >= 0 AND quantity_received <= quantity)
+);
+
+-- purchase details carried onto the asset
+ALTER TABLE assets ADD COLUMN purchase_cost NUMERIC(12,2);
+ALTER TABLE assets ADD COLUMN purchase_order_line_id BIGINT REFERENCES purchase_order_lines(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_purchase_requests_status ON purchase_requests (status);
+CREATE INDEX idx_purchase_orders_status ON purchase_orders (status);
+CREATE INDEX idx_purchase_order_lines_order_id ON purchase_order_lines (purchase_order_id);
+CREATE INDEX idx_assets_purchase_order_line_id ON assets (purchase_order_line_id);
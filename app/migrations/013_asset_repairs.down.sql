@@ -0,0 +1,5 @@
+-- 013_asset_repairs.down.sql
+DROP INDEX IF EXISTS idx_asset_repairs_status;
+DROP INDEX IF EXISTS idx_asset_repairs_active;
+
+DROP TABLE IF EXISTS asset_repairs;
@@ -0,0 +1,27 @@
+-- 013_asset_repairs.up.sql
+
+-- external repairs / RMAs: one row per time an asset goes back to a vendor
+CREATE TABLE asset_repairs (
+  id BIGSERIAL PRIMARY KEY,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  vendor TEXT NOT NULL,
+  rma_number TEXT NOT NULL DEFAULT '',
+  fault_description TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open',      -- open, shipped, closed, cancelled
+  previous_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL, -- who had the asset when it was shipped
+  shipped_at DATE,
+  expected_return DATE,
+  returned_at DATE,
+  repair_cost NUMERIC(12,2),
+  outcome TEXT NOT NULL DEFAULT '',         -- repaired, replaced, written_off
+  old_serial_number TEXT NOT NULL DEFAULT '',
+  new_serial_number TEXT NOT NULL DEFAULT '', -- set when the vendor swaps the unit
+  notes TEXT NOT NULL DEFAULT '',
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- at most one active repair per asset
+CREATE UNIQUE INDEX idx_asset_repairs_active ON asset_repairs (asset_id) WHERE status IN ('open', 'shipped');
+CREATE INDEX idx_asset_repairs_status ON asset_repairs (status);
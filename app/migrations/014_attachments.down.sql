@@ -0,0 +1,5 @@
+-- 014_attachments.down.sql
+DROP INDEX IF EXISTS idx_attachments_service_log_id;
+DROP INDEX IF EXISTS idx_attachments_asset_id;
+
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,19 @@
+-- 014_attachments.up.sql
+
+-- uploaded photos and documents, attached to either an asset or a service log
+CREATE TABLE attachments (
+  id BIGSERIAL PRIMARY KEY,
+  asset_id BIGINT REFERENCES assets(id) ON DELETE CASCADE,
+  service_log_id BIGINT REFERENCES asset_service(id) ON DELETE CASCADE,
+  file_name TEXT NOT NULL,                   -- original name as uploaded
+  content_type TEXT NOT NULL,                -- sniffed from the file contents
+  size_bytes BIGINT NOT NULL,
+  storage_key TEXT NOT NULL UNIQUE,
+  thumbnail_key TEXT NOT NULL DEFAULT '',    -- set for images
+  uploaded_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  CHECK ((asset_id IS NULL) <> (service_log_id IS NULL))
+);
+
+CREATE INDEX idx_attachments_asset_id ON attachments (asset_id);
+CREATE INDEX idx_attachments_service_log_id ON attachments (service_log_id);
@@ -0,0 +1,6 @@
+-- 015_maintenance_plans.down.sql
+DROP INDEX IF EXISTS idx_maintenance_plans_asset_id;
+DROP INDEX IF EXISTS idx_maintenance_plans_asset_type;
+
+DROP TABLE IF EXISTS maintenance_tickets;
+DROP TABLE IF EXISTS maintenance_plans;
@@ -0,0 +1,32 @@
+-- 015_maintenance_plans.up.sql
+
+-- preventive maintenance plans, for every asset of a type or for one asset
+CREATE TABLE maintenance_plans (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,                           -- e.g. "UPS battery test"
+  asset_type TEXT,                              -- applies to all assets of this type...
+  asset_id BIGINT REFERENCES assets(id) ON DELETE CASCADE, -- ...or to a single asset
+  service_type TEXT NOT NULL DEFAULT 'MAINTENANCE', -- service logs of this type reset the clock
+  interval_days INTEGER NOT NULL CHECK (interval_days > 0),
+  lead_days INTEGER NOT NULL DEFAULT 7 CHECK (lead_days >= 0), -- raise the ticket this many days before due
+  assign_to BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  active BOOLEAN NOT NULL DEFAULT true,
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  CHECK ((asset_type IS NULL) <> (asset_id IS NULL))
+);
+
+-- one row per maintenance ticket raised, so each due date gets a single ticket
+CREATE TABLE maintenance_tickets (
+  id BIGSERIAL PRIMARY KEY,
+  plan_id BIGINT NOT NULL REFERENCES maintenance_plans(id) ON DELETE CASCADE,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  due_date DATE NOT NULL,
+  ticket_id BIGINT REFERENCES tickets(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (plan_id, asset_id, due_date)
+);
+
+CREATE INDEX idx_maintenance_plans_asset_type ON maintenance_plans (asset_type);
+CREATE INDEX idx_maintenance_plans_asset_id ON maintenance_plans (asset_id);
@@ -0,0 +1,5 @@
+-- 016_service_log_costs.down.sql
+ALTER TABLE asset_service DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE asset_service DROP COLUMN IF EXISTS technician;
+ALTER TABLE asset_service DROP COLUMN IF EXISTS external_cost;
+ALTER TABLE asset_service DROP COLUMN IF EXISTS labor_minutes;
@@ -0,0 +1,8 @@
+-- 016_service_log_costs.up.sql
+
+-- labor, cost and technician on service logs; parts used are stock_movements linked by service_log_id
+ALTER TABLE asset_service
+  ADD COLUMN labor_minutes INTEGER NOT NULL DEFAULT 0 CHECK (labor_minutes >= 0),
+  ADD COLUMN external_cost NUMERIC(12,2),          -- contractor or vendor invoice, excluding parts from stock
+  ADD COLUMN technician TEXT NOT NULL DEFAULT '',  -- who did the work when it was not a user (e.g. a contractor)
+  ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
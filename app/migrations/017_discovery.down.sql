@@ -0,0 +1,9 @@
+-- 017_discovery.down.sql
+DROP INDEX IF EXISTS idx_assets_serial_number_lower;
+DROP INDEX IF EXISTS idx_discovered_devices_mac_address;
+DROP INDEX IF EXISTS idx_discovered_devices_serial_number;
+DROP INDEX IF EXISTS idx_discovered_devices_status;
+DROP INDEX IF EXISTS idx_discovered_devices_asset_id;
+
+DROP TABLE IF EXISTS discovered_devices;
+DROP TABLE IF EXISTS discovery_imports;
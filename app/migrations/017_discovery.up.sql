@@ -0,0 +1,37 @@
+-- 017_discovery.up.sql
+
+-- inventory snapshots from endpoint agents or network scanners
+CREATE TABLE discovery_imports (
+  id BIGSERIAL PRIMARY KEY,
+  source TEXT NOT NULL DEFAULT '',              -- e.g. agent, scanner name
+  device_count INTEGER NOT NULL DEFAULT 0,
+  matched_count INTEGER NOT NULL DEFAULT 0,     -- devices matched to an asset
+  updated_count INTEGER NOT NULL DEFAULT 0,     -- assets whose custom fields changed
+  candidate_count INTEGER NOT NULL DEFAULT 0,   -- devices with no matching asset
+  imported_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  imported_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- every device seen on the network, matched to an asset by serial or MAC
+CREATE TABLE discovered_devices (
+  id BIGSERIAL PRIMARY KEY,
+  hostname TEXT NOT NULL DEFAULT '',
+  mac_address TEXT NOT NULL DEFAULT '',         -- normalised to aa:bb:cc:dd:ee:ff
+  serial_number TEXT NOT NULL DEFAULT '',
+  os TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  asset_id BIGINT REFERENCES assets(id) ON DELETE SET NULL,
+  status TEXT NOT NULL DEFAULT 'candidate',     -- matched, candidate, ignored
+  notes TEXT NOT NULL DEFAULT '',               -- why a device was not matched or updated
+  first_seen TIMESTAMP NOT NULL,
+  last_seen TIMESTAMP NOT NULL,
+  last_import_id BIGINT REFERENCES discovery_imports(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_discovered_devices_asset_id ON discovered_devices (asset_id);
+CREATE INDEX idx_discovered_devices_status ON discovered_devices (status);
+CREATE INDEX idx_discovered_devices_serial_number ON discovered_devices (LOWER(serial_number)) WHERE serial_number != '';
+CREATE INDEX idx_discovered_devices_mac_address ON discovered_devices (mac_address) WHERE mac_address != '';
+CREATE INDEX idx_assets_serial_number_lower ON assets (LOWER(TRIM(serial_number)));
@@ -0,0 +1,10 @@
+-- 018_asset_transfers.down.sql
+DELETE FROM permissions WHERE name = 'assets:approve_transfer';
+
+DROP INDEX IF EXISTS idx_asset_transfer_items_asset_id;
+DROP INDEX IF EXISTS idx_asset_transfers_to_user_id;
+DROP INDEX IF EXISTS idx_asset_transfers_from_user_id;
+DROP INDEX IF EXISTS idx_asset_transfers_status;
+
+DROP TABLE IF EXISTS asset_transfer_items;
+DROP TABLE IF EXISTS asset_transfers;
@@ -0,0 +1,42 @@
+-- 018_asset_transfers.up.sql
+
+-- a transfer moves one asset, or a kit of assets, from one user to another
+CREATE TABLE asset_transfers (
+  id BIGSERIAL PRIMARY KEY,
+  from_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  to_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending',        -- pending, completed, rejected, cancelled
+  reason TEXT NOT NULL DEFAULT '',
+  requires_approval BOOLEAN NOT NULL DEFAULT false,
+  acknowledge_hours INTEGER NOT NULL DEFAULT 48, -- window the recipient gets to accept the handover
+  requested_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  requested_at TIMESTAMP NOT NULL DEFAULT now(),
+  reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMP,
+  review_notes TEXT NOT NULL DEFAULT '',
+  completed_at TIMESTAMP,
+  CHECK (from_user_id <> to_user_id)
+);
+
+-- assets in a transfer, linked to the custody records it closed and opened
+CREATE TABLE asset_transfer_items (
+  id BIGSERIAL PRIMARY KEY,
+  transfer_id BIGINT NOT NULL REFERENCES asset_transfers(id) ON DELETE CASCADE,
+  asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  from_custody_id BIGINT REFERENCES asset_custody(id) ON DELETE SET NULL,
+  to_custody_id BIGINT REFERENCES asset_custody(id) ON DELETE SET NULL,
+  UNIQUE (transfer_id, asset_id)
+);
+
+CREATE INDEX idx_asset_transfers_status ON asset_transfers (status);
+CREATE INDEX idx_asset_transfers_from_user_id ON asset_transfers (from_user_id);
+CREATE INDEX idx_asset_transfers_to_user_id ON asset_transfers (to_user_id);
+CREATE INDEX idx_asset_transfer_items_asset_id ON asset_transfer_items (asset_id);
+
+-- approving transfers is a team-lead duty
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('assets:approve_transfer', 'assets', 'approve_transfer', 'Approve asset transfers between users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name IN ('admin', 'it', 'staff') AND p.name = 'assets:approve_transfer';
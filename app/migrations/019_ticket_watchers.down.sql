@@ -0,0 +1,5 @@
+-- 019_ticket_watchers.down.sql
+DROP INDEX IF EXISTS idx_users_lower_username;
+DROP INDEX IF EXISTS idx_ticket_watchers_user_id;
+
+DROP TABLE IF EXISTS ticket_watchers;
@@ -0,0 +1,17 @@
+-- 019_ticket_watchers.up.sql
+
+-- users who follow a ticket on top of its creator and assignee
+CREATE TABLE ticket_watchers (
+  id BIGSERIAL PRIMARY KEY,
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  source TEXT NOT NULL DEFAULT 'manual', -- manual, cc, mention
+  added_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (ticket_id, user_id)
+);
+
+CREATE INDEX idx_ticket_watchers_user_id ON ticket_watchers (user_id);
+
+-- @mentions are matched on username regardless of case
+CREATE INDEX idx_users_lower_username ON users (LOWER(username));
@@ -0,0 +1,6 @@
+-- 020_ticket_links.down.sql
+DROP INDEX IF EXISTS idx_ticket_links_one_primary;
+DROP INDEX IF EXISTS idx_ticket_links_one_parent;
+DROP INDEX IF EXISTS idx_ticket_links_linked_ticket_id;
+
+DROP TABLE IF EXISTS ticket_links;
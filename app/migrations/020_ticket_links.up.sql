@@ -0,0 +1,20 @@
+-- 020_ticket_links.up.sql
+
+-- directed relationships between tickets; the inverse (duplicated by,
+-- blocked by, child of) is read from the other end
+CREATE TABLE ticket_links (
+  id BIGSERIAL PRIMARY KEY,
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  linked_ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  link_type TEXT NOT NULL, -- duplicates, blocks, relates_to, parent_of
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (ticket_id, linked_ticket_id, link_type),
+  CHECK (ticket_id <> linked_ticket_id)
+);
+
+CREATE INDEX idx_ticket_links_linked_ticket_id ON ticket_links (linked_ticket_id);
+
+-- a ticket has at most one parent and duplicates at most one primary
+CREATE UNIQUE INDEX idx_ticket_links_one_parent ON ticket_links (linked_ticket_id) WHERE link_type = 'parent_of';
+CREATE UNIQUE INDEX idx_ticket_links_one_primary ON ticket_links (ticket_id) WHERE link_type = 'duplicates';
@@ -0,0 +1,7 @@
+-- 021_ticket_tags_views.down.sql
+DROP INDEX IF EXISTS idx_ticket_views_shared_role_id;
+DROP INDEX IF EXISTS idx_ticket_tag_assignments_tag_id;
+
+DROP TABLE IF EXISTS ticket_views;
+DROP TABLE IF EXISTS ticket_tag_assignments;
+DROP TABLE IF EXISTS ticket_tags;
@@ -0,0 +1,36 @@
+-- 021_ticket_tags_views.up.sql
+
+-- tags admins make available for labelling tickets
+CREATE TABLE ticket_tags (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,            -- stored lower-case
+  color TEXT NOT NULL DEFAULT '',       -- hex colour for the UI, e.g. #d73a4a
+  description TEXT NOT NULL DEFAULT '',
+  is_active BOOLEAN NOT NULL DEFAULT true, -- retired tags stay on tickets but cannot be added
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE ticket_tag_assignments (
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES ticket_tags(id) ON DELETE CASCADE,
+  added_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (ticket_id, tag_id)
+);
+
+CREATE INDEX idx_ticket_tag_assignments_tag_id ON ticket_tag_assignments (tag_id);
+
+-- named filter and sort combinations a user can open as a queue
+CREATE TABLE ticket_views (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  filters JSONB NOT NULL DEFAULT '{}',
+  sort TEXT NOT NULL DEFAULT '',
+  shared_role_id INTEGER REFERENCES roles(id) ON DELETE SET NULL, -- also visible to this role
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_ticket_views_shared_role_id ON ticket_views (shared_role_id);
@@ -0,0 +1,6 @@
+-- 022_ticket_search.down.sql
+DROP INDEX IF EXISTS idx_ticket_comments_search_vector;
+DROP INDEX IF EXISTS idx_tickets_search_vector;
+
+ALTER TABLE ticket_comments DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tickets DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,16 @@
+-- 022_ticket_search.up.sql
+
+-- full-text search vectors, kept up to date by PostgreSQL as rows change.
+-- ticket numbers use the simple config so TCK-2025-0001 is not stemmed.
+ALTER TABLE tickets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce(ticket_num, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE ticket_comments ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(comment, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_tickets_search_vector ON tickets USING GIN (search_vector);
+CREATE INDEX idx_ticket_comments_search_vector ON ticket_comments USING GIN (search_vector);
@@ -0,0 +1,10 @@
+-- 023_ticket_worklogs.down.sql
+DROP INDEX IF EXISTS idx_tickets_campaign;
+DROP INDEX IF EXISTS idx_ticket_worklogs_user_id;
+DROP INDEX IF EXISTS idx_ticket_worklogs_work_date;
+DROP INDEX IF EXISTS idx_ticket_worklogs_ticket_id;
+
+DROP TABLE IF EXISTS ticket_timers;
+DROP TABLE IF EXISTS ticket_worklogs;
+
+ALTER TABLE tickets DROP COLUMN IF EXISTS campaign;
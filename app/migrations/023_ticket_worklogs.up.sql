@@ -0,0 +1,30 @@
+-- 023_ticket_worklogs.up.sql
+
+-- campaign the ticket supports, so IT time can be reported per campaign; empty for general IT work
+ALTER TABLE tickets ADD COLUMN campaign TEXT NOT NULL DEFAULT '';
+
+-- time spent on tickets, logged by hand or by stopping a timer
+CREATE TABLE ticket_worklogs (
+  id BIGSERIAL PRIMARY KEY,
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  minutes INTEGER NOT NULL CHECK (minutes > 0),
+  work_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  note TEXT NOT NULL DEFAULT '',
+  billable BOOLEAN NOT NULL DEFAULT false, -- false for internal time
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- running timers; a user has at most one, stopping it writes a worklog
+CREATE TABLE ticket_timers (
+  id BIGSERIAL PRIMARY KEY,
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  started_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ticket_worklogs_ticket_id ON ticket_worklogs(ticket_id);
+CREATE INDEX idx_ticket_worklogs_work_date ON ticket_worklogs(work_date);
+CREATE INDEX idx_ticket_worklogs_user_id ON ticket_worklogs(user_id);
+CREATE INDEX idx_tickets_campaign ON tickets(campaign) WHERE campaign <> '';
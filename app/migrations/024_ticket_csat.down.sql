@@ -0,0 +1,5 @@
+-- 024_ticket_csat.down.sql
+DROP INDEX IF EXISTS idx_ticket_csat_surveys_technician_id;
+DROP INDEX IF EXISTS idx_ticket_csat_surveys_user_id;
+
+DROP TABLE IF EXISTS ticket_csat_surveys;
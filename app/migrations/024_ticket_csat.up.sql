@@ -0,0 +1,17 @@
+-- 024_ticket_csat.up.sql
+
+-- satisfaction surveys sent to a ticket's creator when verification closes it
+CREATE TABLE ticket_csat_surveys (
+  id BIGSERIAL PRIMARY KEY,
+  ticket_id BIGINT NOT NULL UNIQUE REFERENCES tickets(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,       -- who is asked to rate
+  technician_id BIGINT REFERENCES users(id) ON DELETE SET NULL,         -- assignee when the ticket closed
+  rating SMALLINT CHECK (rating BETWEEN 1 AND 5),                       -- NULL until answered
+  comment TEXT NOT NULL DEFAULT '',
+  sent_at TIMESTAMP NOT NULL DEFAULT now(),
+  expires_at TIMESTAMP NOT NULL DEFAULT now() + INTERVAL '14 days',
+  responded_at TIMESTAMP
+);
+
+CREATE INDEX idx_ticket_csat_surveys_user_id ON ticket_csat_surveys(user_id) WHERE rating IS NULL;
+CREATE INDEX idx_ticket_csat_surveys_technician_id ON ticket_csat_surveys(technician_id);
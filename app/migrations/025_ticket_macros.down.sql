@@ -0,0 +1,4 @@
+-- 025_ticket_macros.down.sql
+DROP INDEX IF EXISTS idx_ticket_macros_shared;
+
+DROP TABLE IF EXISTS ticket_macros;
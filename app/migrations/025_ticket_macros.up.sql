@@ -0,0 +1,19 @@
+-- 025_ticket_macros.up.sql
+
+-- canned responses; applying one posts its rendered body as a comment and
+-- can set the ticket's status and priority at the same time
+CREATE TABLE ticket_macros (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- owner
+  name TEXT NOT NULL,
+  body TEXT NOT NULL,                       -- may use {{ticket_num}}, {{requester_name}}, ...
+  is_internal BOOLEAN NOT NULL DEFAULT false, -- post the comment as internal
+  set_status TEXT NOT NULL DEFAULT '',      -- empty leaves the status alone
+  set_priority TEXT NOT NULL DEFAULT '',    -- empty leaves the priority alone
+  is_shared BOOLEAN NOT NULL DEFAULT false, -- usable by everyone, editable by the owner
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_ticket_macros_shared ON ticket_macros(is_shared) WHERE is_shared;
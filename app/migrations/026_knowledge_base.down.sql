@@ -0,0 +1,12 @@
+-- 026_knowledge_base.down.sql
+DELETE FROM permissions WHERE name IN ('kb:read', 'kb:manage');
+
+DROP INDEX IF EXISTS idx_kb_article_tickets_ticket_id;
+DROP INDEX IF EXISTS idx_kb_articles_search_vector;
+DROP INDEX IF EXISTS idx_kb_articles_ticket_type;
+DROP INDEX IF EXISTS idx_kb_articles_category_id;
+
+DROP TABLE IF EXISTS kb_article_tickets;
+DROP TABLE IF EXISTS kb_article_versions;
+DROP TABLE IF EXISTS kb_articles;
+DROP TABLE IF EXISTS kb_categories;
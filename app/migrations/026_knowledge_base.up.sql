@@ -0,0 +1,68 @@
+-- 026_knowledge_base.up.sql
+
+CREATE TABLE kb_categories (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- markdown articles; title and body always hold the latest version
+CREATE TABLE kb_articles (
+  id BIGSERIAL PRIMARY KEY,
+  category_id BIGINT REFERENCES kb_categories(id) ON DELETE SET NULL,
+  title TEXT NOT NULL,
+  body TEXT NOT NULL,
+  ticket_type TEXT NOT NULL DEFAULT '',             -- ticket type the article helps with, for suggestions
+  visibility TEXT NOT NULL DEFAULT 'everyone',      -- everyone, staff (admin, it, staff), it (admin, it)
+  is_published BOOLEAN NOT NULL DEFAULT false,      -- drafts are only seen by knowledge base editors
+  version INTEGER NOT NULL DEFAULT 1,
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now(),
+  search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(body, '')), 'B')
+  ) STORED,
+  CHECK (visibility IN ('everyone', 'staff', 'it'))
+);
+
+-- every saved version of an article, including the current one
+CREATE TABLE kb_article_versions (
+  id BIGSERIAL PRIMARY KEY,
+  article_id BIGINT NOT NULL REFERENCES kb_articles(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  body TEXT NOT NULL,
+  edited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (article_id, version)
+);
+
+-- articles that helped with (or answer) a ticket
+CREATE TABLE kb_article_tickets (
+  article_id BIGINT NOT NULL REFERENCES kb_articles(id) ON DELETE CASCADE,
+  ticket_id BIGINT NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  linked_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (article_id, ticket_id)
+);
+
+CREATE INDEX idx_kb_articles_category_id ON kb_articles (category_id);
+CREATE INDEX idx_kb_articles_ticket_type ON kb_articles (ticket_type);
+CREATE INDEX idx_kb_articles_search_vector ON kb_articles USING GIN (search_vector);
+CREATE INDEX idx_kb_article_tickets_ticket_id ON kb_article_tickets (ticket_id);
+
+-- everyone can read the knowledge base; IT keeps it up to date
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('kb:read', 'kb', 'read', 'Read knowledge base articles'),
+    ('kb:manage', 'kb', 'manage', 'Write and publish knowledge base articles');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name IN ('admin', 'it', 'staff', 'agent', 'viewer') AND p.name = 'kb:read';
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name IN ('admin', 'it') AND p.name = 'kb:manage';
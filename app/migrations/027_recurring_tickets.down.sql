@@ -0,0 +1,6 @@
+-- 027_recurring_tickets.down.sql
+DROP INDEX IF EXISTS idx_recurring_ticket_runs_ticket_id;
+DROP INDEX IF EXISTS idx_recurring_tickets_next_run_at;
+
+DROP TABLE IF EXISTS recurring_ticket_runs;
+DROP TABLE IF EXISTS recurring_tickets;
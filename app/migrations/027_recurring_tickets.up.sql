@@ -0,0 +1,42 @@
+-- 027_recurring_tickets.up.sql
+
+-- definitions the API's scheduler raises tickets from; schedule is a cron
+-- expression or an RRULE, evaluated in timezone
+CREATE TABLE recurring_tickets (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  schedule TEXT NOT NULL,                   -- "0 9 1 * *" or "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1"
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  starts_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- no occurrence before this; RRULE intervals count from it
+
+  -- template for the generated tickets
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  type TEXT NOT NULL,
+  priority TEXT NOT NULL DEFAULT 'normal',
+  assigned_to BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  asset_id BIGINT REFERENCES assets(id) ON DELETE SET NULL,
+  is_internal BOOLEAN NOT NULL DEFAULT false,
+
+  is_paused BOOLEAN NOT NULL DEFAULT false,
+  next_run_at TIMESTAMPTZ,                  -- NULL while paused
+  last_run_at TIMESTAMPTZ,
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- one row per occurrence, written in the same transaction as its ticket; the
+-- unique key is what stops a ticket being raised twice, even when the API
+-- restarts or runs more than once
+CREATE TABLE recurring_ticket_runs (
+  id BIGSERIAL PRIMARY KEY,
+  recurring_ticket_id BIGINT NOT NULL REFERENCES recurring_tickets(id) ON DELETE CASCADE,
+  occurrence TIMESTAMPTZ NOT NULL,
+  ticket_id BIGINT REFERENCES tickets(id) ON DELETE SET NULL, -- NULL once the ticket is deleted
+  created_at TIMESTAMP NOT NULL DEFAULT now(),  -- when the ticket was raised
+  UNIQUE (recurring_ticket_id, occurrence)
+);
+
+CREATE INDEX idx_recurring_tickets_next_run_at ON recurring_tickets (next_run_at) WHERE NOT is_paused;
+CREATE INDEX idx_recurring_ticket_runs_ticket_id ON recurring_ticket_runs (ticket_id);